@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/innoai-tech/infra/pkg/cli"
+	"github.com/innoai-tech/infra/pkg/configuration"
+	"github.com/innoai-tech/infra/pkg/otel"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/api"
+	"github.com/octohelm/unifs/pkg/strfmt"
+	"github.com/octohelm/unifs/pkg/units"
+)
+
+func init() {
+	cli.AddTo(App, &Find{})
+}
+
+// Find files in backend
+type Find struct {
+	cli.C
+	Otel otel.Otel
+
+	Finder
+}
+
+var _ configuration.Runner = &Finder{}
+
+type Finder struct {
+	// Root dir, or glob pattern of paths like /logs/2024-*/*.log
+	Path    string          `arg:""`
+	Backend strfmt.Endpoint `flag:"backend"`
+	// Only files which base name matched the pattern
+	Name string `flag:"name,omitzero"`
+	// Only files not smaller than it
+	MinSize units.BinarySize `flag:"min-size,omitzero"`
+	// Only files not larger than it
+	MaxSize units.BinarySize `flag:"max-size,omitzero"`
+	// Only files modified within the duration, like 24h
+	NewerThan string `flag:"newer-than,omitzero"`
+	// Only files modified before the duration, like 24h
+	OlderThan string `flag:"older-than,omitzero"`
+	// Print size and mtime before path
+	Long bool `flag:"long,omitzero"`
+}
+
+func (s *Finder) Run(ctx context.Context) error {
+	b := &api.FileSystemBackend{}
+	b.Backend = s.Backend
+	if err := b.Init(ctx); err != nil {
+		return err
+	}
+
+	root := s.Path
+	opts := make([]filesystem.FindOption, 0)
+
+	if strings.ContainsAny(s.Path, `*?[\`) {
+		root = "/"
+		opts = append(opts, filesystem.FindPath(s.Path))
+	}
+
+	if s.Name != "" {
+		opts = append(opts, filesystem.FindName(s.Name))
+	}
+	if !s.MinSize.IsZero() {
+		opts = append(opts, filesystem.FindMinSize(int64(s.MinSize)))
+	}
+	if !s.MaxSize.IsZero() {
+		opts = append(opts, filesystem.FindMaxSize(int64(s.MaxSize)))
+	}
+
+	now := time.Now()
+
+	if s.NewerThan != "" {
+		d, err := time.ParseDuration(s.NewerThan)
+		if err != nil {
+			return fmt.Errorf("invalid newer-than: %w", err)
+		}
+		opts = append(opts, filesystem.FindNewerThan(now.Add(-d)))
+	}
+	if s.OlderThan != "" {
+		d, err := time.ParseDuration(s.OlderThan)
+		if err != nil {
+			return fmt.Errorf("invalid older-than: %w", err)
+		}
+		opts = append(opts, filesystem.FindOlderThan(now.Add(-d)))
+	}
+
+	for e, err := range filesystem.Find(ctx, b.FileSystem(), root, opts...) {
+		if err != nil {
+			return err
+		}
+		if s.Long {
+			_, _ = fmt.Fprintf(os.Stdout, "%12d %s %s\n", e.Size(), e.ModTime().Format(time.RFC3339), e.Path)
+		} else {
+			_, _ = fmt.Fprintln(os.Stdout, e.Path)
+		}
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/hanwen/go-fuse/v2/fs"
 	fusefuse "github.com/hanwen/go-fuse/v2/fuse"
@@ -15,9 +16,17 @@ import (
 	"github.com/octohelm/x/logr"
 
 	"github.com/octohelm/unifs/pkg/csidriver/mounter"
+	"github.com/octohelm/unifs/pkg/filesystem"
 	"github.com/octohelm/unifs/pkg/filesystem/api"
+	"github.com/octohelm/unifs/pkg/filesystem/blockcache"
+	"github.com/octohelm/unifs/pkg/filesystem/compress"
+	"github.com/octohelm/unifs/pkg/filesystem/crypt"
+	"github.com/octohelm/unifs/pkg/filesystem/policy"
+	"github.com/octohelm/unifs/pkg/filesystem/quota"
+	"github.com/octohelm/unifs/pkg/filesystem/throttle"
 	"github.com/octohelm/unifs/pkg/fuse"
 	"github.com/octohelm/unifs/pkg/strfmt"
+	"github.com/octohelm/unifs/pkg/units"
 )
 
 func init() {
@@ -39,11 +48,59 @@ type Mounter struct {
 	Backend    strfmt.Endpoint `flag:"backend"`
 	Foreground bool            `flag:"foreground,omitzero"`
 	Delegate   bool            `flag:"delegate,omitzero"`
+	// Local dir to cache blocks of remote files
+	CacheDir string `flag:"cache-dir,omitzero"`
+	// Max size of cached blocks, cache enabled when not zero
+	CacheSize units.BinarySize `flag:"cache-size,omitzero"`
+	// Max total size of files, writes over it will fail with no space left
+	Quota units.BinarySize `flag:"quota,omitzero"`
+	// How long stat results cached like 5s, stat cache enabled when set
+	StatTTL string `flag:"stat-ttl,omitzero"`
+	// How long not exists results cached like 1s, defaults to 1s, 0s to disable
+	StatNegativeTTL string `flag:"stat-negative-ttl,omitzero"`
+
+	policy.Config
+
+	Throttle throttle.Config
+
+	Encrypt crypt.Config
+
+	Compress compress.Config
+}
+
+func (m *Mounter) statCacheOptions() ([]filesystem.StatCacheOption, error) {
+	ttl, err := time.ParseDuration(m.StatTTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stat-ttl: %w", err)
+	}
+
+	opts := []filesystem.StatCacheOption{
+		filesystem.WithStatTTL(ttl),
+	}
+
+	if m.StatNegativeTTL != "" {
+		negativeTTL, err := time.ParseDuration(m.StatNegativeTTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stat-negative-ttl: %w", err)
+		}
+		opts = append(opts, filesystem.WithNegativeStatTTL(negativeTTL))
+	}
+
+	return opts, nil
 }
 
 func (m *Mounter) Run(ctx context.Context) error {
 	if m.Delegate {
-		m2, err := mounter.NewMounter(ctx, m.Backend.String())
+		m2, err := mounter.NewMounter(ctx, m.Backend.String(),
+			mounter.WithCache(m.CacheDir, m.CacheSize),
+			mounter.WithQuota(m.Quota),
+			mounter.WithStatCache(m.StatTTL, m.StatNegativeTTL),
+			mounter.WithReadOnly(m.ReadOnly),
+			mounter.WithRules(m.Rules),
+			mounter.WithThrottle(m.Throttle),
+			mounter.WithEncrypt(m.Encrypt),
+			mounter.WithCompress(m.Compress),
+		)
 		if err != nil {
 			return err
 		}
@@ -75,11 +132,56 @@ func (m *Mounter) Run(ctx context.Context) error {
 		return err
 	}
 
+	// limits on backend, reads hit cache will not be limited
+	fsys := m.Throttle.Apply(b.FileSystem())
+
+	if m.CacheSize > 0 {
+		// each backend uses its own dir, blocks of same path and version from other backends should never be hit
+		c, err := blockcache.NewCache(blockcache.Dir(m.CacheDir, b.ID()), m.CacheSize, 0)
+		if err != nil {
+			return err
+		}
+		fsys = blockcache.Wrap(fsys, c)
+	}
+
+	// encrypt above cache, blocks cached locally are encrypted too
+	fsys, err := m.Encrypt.Apply(fsys)
+	if err != nil {
+		return err
+	}
+
+	// compress above encrypt, encrypted data could not be compressed
+	fsys, err = m.Compress.Apply(fsys)
+	if err != nil {
+		return err
+	}
+
+	if m.Quota > 0 {
+		fsys = quota.Wrap(fsys, int64(m.Quota))
+	}
+
+	// cache stat above compress and encrypt, which read files to stat
+	if m.StatTTL != "" {
+		opts, err := m.statCacheOptions()
+		if err != nil {
+			return err
+		}
+		fsys = filesystem.StatCache(fsys, opts...)
+	}
+
+	fsys, err = m.Config.Apply(fsys)
+	if err != nil {
+		return err
+	}
+
 	options := &fs.Options{}
 	options.Name = fmt.Sprintf("%s.fs", b.Backend.Scheme)
+	if m.ReadOnly {
+		options.Options = append(options.Options, "ro")
+	}
 	// options.Debug = true
 
-	rawFS := fs.NewNodeFS(fuse.FS(b.FileSystem()), options)
+	rawFS := fs.NewNodeFS(fuse.FS(fsys), options)
 
 	state, err := fusefuse.NewServer(rawFS, m.MountPoint, &options.MountOptions)
 	if err != nil {
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+
+	"github.com/innoai-tech/infra/pkg/cli"
+	"github.com/innoai-tech/infra/pkg/configuration"
+	"github.com/innoai-tech/infra/pkg/otel"
+	"github.com/octohelm/x/logr"
+
+	"github.com/octohelm/unifs/pkg/filesystem/api"
+	fssync "github.com/octohelm/unifs/pkg/filesystem/sync"
+	"github.com/octohelm/unifs/pkg/strfmt"
+)
+
+func init() {
+	cli.AddTo(App, &Sync{})
+}
+
+// Sync files from one backend to another
+type Sync struct {
+	cli.C
+	Otel otel.Otel
+
+	Syncer
+}
+
+var _ configuration.Runner = &Syncer{}
+
+type Syncer struct {
+	// Source backend
+	From strfmt.Endpoint `flag:"from"`
+	// Target backend
+	To strfmt.Endpoint `flag:"to"`
+	// Compare files by size, mtime or checksum
+	Compare string `flag:"compare,omitzero"`
+	// Only print changes without applying
+	DryRun bool `flag:"dry-run,omitzero"`
+	// Delete extraneous files from target, files not included or excluded are kept
+	Delete bool `flag:"delete,omitzero"`
+	// Only sync files matched these patterns, directories without matched files are not created
+	Include []string `flag:"include,omitzero"`
+	// Skip files matched these patterns, pattern ends with / only matches directories
+	Exclude []string `flag:"exclude,omitzero"`
+	// Count of files copied at the same time
+	Concurrency int `flag:"concurrency,omitzero"`
+}
+
+func (s *Syncer) SetDefaults() {
+	if s.Compare == "" {
+		s.Compare = string(fssync.CompareModTime)
+	}
+	if s.Concurrency == 0 {
+		s.Concurrency = 4
+	}
+}
+
+func (s *Syncer) Run(ctx context.Context) error {
+	compare, err := fssync.ParseCompare(s.Compare)
+	if err != nil {
+		return err
+	}
+
+	from := &api.FileSystemBackend{}
+	from.Backend = s.From
+	if err := from.Init(ctx); err != nil {
+		return err
+	}
+
+	to := &api.FileSystemBackend{}
+	to.Backend = s.To
+	if err := to.Init(ctx); err != nil {
+		return err
+	}
+
+	l := logr.FromContext(ctx)
+
+	syncer := fssync.New(
+		from.FileSystem(), to.FileSystem(),
+		fssync.WithCompare(compare),
+		fssync.WithDryRun(s.DryRun),
+		fssync.WithDelete(s.Delete),
+		fssync.WithInclude(s.Include...),
+		fssync.WithExclude(s.Exclude...),
+		fssync.WithConcurrency(s.Concurrency),
+		fssync.WithReporter(func(c fssync.Change) {
+			l.WithValues("action", c.Action, "size", c.Size, "dry-run", s.DryRun).Info(c.Path)
+		}),
+	)
+
+	return syncer.Sync(ctx)
+}
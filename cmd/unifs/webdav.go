@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"runtime"
 	"time"
@@ -13,7 +14,11 @@ import (
 	"github.com/innoai-tech/infra/pkg/otel"
 	"github.com/octohelm/x/logr"
 
+	"github.com/octohelm/unifs/pkg/filesystem"
 	"github.com/octohelm/unifs/pkg/filesystem/api"
+	"github.com/octohelm/unifs/pkg/filesystem/notify"
+	"github.com/octohelm/unifs/pkg/filesystem/policy"
+	"github.com/octohelm/unifs/pkg/filesystem/throttle"
 )
 
 func init() {
@@ -34,6 +39,11 @@ type WebDAVServer struct {
 	Addr string `flag:"addr,omitzero"`
 
 	api.FileSystemBackend
+	policy.Config
+
+	Throttle throttle.Config
+
+	Notify notify.Config
 
 	svc *http.Server `flag:"-"`
 }
@@ -45,15 +55,24 @@ func (s *WebDAVServer) SetDefaults() {
 }
 
 func (s *WebDAVServer) Serve(ctx context.Context) error {
+	backend := s.Throttle.Apply(s.FileSystem())
+
+	// events only emitted for changes allowed by policy
+	fsys, err := s.Config.Apply(s.Notify.Apply(ctx, backend))
+	if err != nil {
+		return err
+	}
+
 	h := &netwebdav.Handler{
-		FileSystem: s.FileSystem(),
-		LockSystem: netwebdav.NewMemLS(),
+		FileSystem: fsys,
+		// locks checked by policy, but stored in backend, so shared by replicas.
+		LockSystem: filesystem.NewLockSystem(fsys),
 	}
 
 	s.svc = &http.Server{
 		Addr:              s.Addr,
 		ReadHeaderTimeout: 10 * time.Second,
-		Handler:           h,
+		Handler:           withUser(withLockTokens(h)),
 	}
 
 	logr.FromContext(ctx).Info("serve on %s (%s/%s)", s.svc.Addr, runtime.GOOS, runtime.GOARCH)
@@ -61,6 +80,32 @@ func (s *WebDAVServer) Serve(ctx context.Context) error {
 	return s.svc.ListenAndServe()
 }
 
+// withUser injects basic auth user or client ip as user of request, and client ip as client of request.
+// basic auth user is not verified, so limits of throttle for each user work only when verified by reverse proxy.
+func withUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		client, _, _ := net.SplitHostPort(r.RemoteAddr)
+
+		user, _, ok := r.BasicAuth()
+		if !ok {
+			user = client
+		}
+
+		ctx := filesystem.ClientInjectContext(filesystem.UserInjectContext(r.Context(), user), client)
+		next.ServeHTTP(rw, r.WithContext(ctx))
+	})
+}
+
+// withLockTokens injects tokens submitted by If header, for backends which locks are enforced by server
+func withLockTokens(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if tokens := filesystem.LockTokensFromIf(r.Header.Get("If")); len(tokens) > 0 {
+			r = r.WithContext(filesystem.LockTokensInjectContext(r.Context(), tokens...))
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
 func (s *WebDAVServer) Shutdown(ctx context.Context) error {
 	return s.svc.Shutdown(ctx)
 }
@@ -21,6 +21,64 @@ func (v *CSIDriver) RuntimeDoc(names ...string) ([]string, bool) {
 	}, true
 }
 
+func (v *Find) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "Otel":
+			return []string{}, true
+		}
+		if doc, ok := runtimeDoc(&v.Finder, "", names...); ok {
+			return doc, ok
+		}
+
+		return nil, false
+	}
+	return []string{
+		"Find files in backend",
+	}, true
+}
+
+func (v *Finder) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "Path":
+			return []string{
+				"Root dir, or glob pattern of paths like /logs/2024-*/*.log",
+			}, true
+		case "Backend":
+			return []string{}, true
+		case "Name":
+			return []string{
+				"Only files which base name matched the pattern",
+			}, true
+		case "MinSize":
+			return []string{
+				"Only files not smaller than it",
+			}, true
+		case "MaxSize":
+			return []string{
+				"Only files not larger than it",
+			}, true
+		case "NewerThan":
+			return []string{
+				"Only files modified within the duration, like 24h",
+			}, true
+		case "OlderThan":
+			return []string{
+				"Only files modified before the duration, like 24h",
+			}, true
+		case "Long":
+			return []string{
+				"Print size and mtime before path",
+			}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{}, true
+}
+
 func (v *Ftp) RuntimeDoc(names ...string) ([]string, bool) {
 	if len(names) > 0 {
 		switch names[0] {
@@ -69,6 +127,94 @@ func (v *Mounter) RuntimeDoc(names ...string) ([]string, bool) {
 			return []string{}, true
 		case "Delegate":
 			return []string{}, true
+		case "CacheDir":
+			return []string{
+				"Local dir to cache blocks of remote files",
+			}, true
+		case "CacheSize":
+			return []string{
+				"Max size of cached blocks, cache enabled when not zero",
+			}, true
+		case "Quota":
+			return []string{
+				"Max total size of files, writes over it will fail with no space left",
+			}, true
+		case "StatTTL":
+			return []string{
+				"How long stat results cached like 5s, stat cache enabled when set",
+			}, true
+		case "StatNegativeTTL":
+			return []string{
+				"How long not exists results cached like 1s, defaults to 1s, 0s to disable",
+			}, true
+		case "Throttle":
+			return []string{}, true
+		case "Encrypt":
+			return []string{}, true
+		case "Compress":
+			return []string{}, true
+		}
+		if doc, ok := runtimeDoc(&v.Config, "", names...); ok {
+			return doc, ok
+		}
+
+		return nil, false
+	}
+	return []string{}, true
+}
+
+func (v *Sync) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "Otel":
+			return []string{}, true
+		}
+		if doc, ok := runtimeDoc(&v.Syncer, "", names...); ok {
+			return doc, ok
+		}
+
+		return nil, false
+	}
+	return []string{
+		"Sync files from one backend to another",
+	}, true
+}
+
+func (v *Syncer) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "From":
+			return []string{
+				"Source backend",
+			}, true
+		case "To":
+			return []string{
+				"Target backend",
+			}, true
+		case "Compare":
+			return []string{
+				"Compare files by size, mtime or checksum",
+			}, true
+		case "DryRun":
+			return []string{
+				"Only print changes without applying",
+			}, true
+		case "Delete":
+			return []string{
+				"Delete extraneous files from target, files not included or excluded are kept",
+			}, true
+		case "Include":
+			return []string{
+				"Only sync files matched these patterns, directories without matched files are not created",
+			}, true
+		case "Exclude":
+			return []string{
+				"Skip files matched these patterns, pattern ends with / only matches directories",
+			}, true
+		case "Concurrency":
+			return []string{
+				"Count of files copied at the same time",
+			}, true
 
 		}
 
@@ -99,10 +245,17 @@ func (v *WebDAVServer) RuntimeDoc(names ...string) ([]string, bool) {
 		switch names[0] {
 		case "Addr":
 			return []string{}, true
+		case "Throttle":
+			return []string{}, true
+		case "Notify":
+			return []string{}, true
 		}
 		if doc, ok := runtimeDoc(&v.FileSystemBackend, "", names...); ok {
 			return doc, ok
 		}
+		if doc, ok := runtimeDoc(&v.Config, "", names...); ok {
+			return doc, ok
+		}
 
 		return nil, false
 	}
@@ -13,13 +13,20 @@ import (
 )
 
 func From(fs filesystem.FileSystem) afero.Fs {
+	return WithContext(context.Background(), fs)
+}
+
+// WithContext creates afero.Fs which calls fs with ctx, since afero.Fs is without context
+func WithContext(ctx context.Context, fs filesystem.FileSystem) afero.Fs {
 	return &adaptor{
-		fs: fs,
+		ctx: ctx,
+		fs:  fs,
 	}
 }
 
 type adaptor struct {
-	fs filesystem.FileSystem
+	ctx context.Context
+	fs  filesystem.FileSystem
 }
 
 func (a *adaptor) Create(name string) (afero.File, error) {
@@ -27,11 +34,11 @@ func (a *adaptor) Create(name string) (afero.File, error) {
 }
 
 func (a *adaptor) Mkdir(name string, perm os.FileMode) error {
-	return a.fs.Mkdir(context.Background(), name, perm)
+	return a.fs.Mkdir(a.ctx, name, perm)
 }
 
 func (a *adaptor) MkdirAll(path string, perm os.FileMode) error {
-	return filesystem.MkdirAll(context.Background(), a.fs, path)
+	return filesystem.MkdirAll(a.ctx, a.fs, path)
 }
 
 func (a *adaptor) Open(name string) (afero.File, error) {
@@ -39,39 +46,41 @@ func (a *adaptor) Open(name string) (afero.File, error) {
 }
 
 func (a *adaptor) Remove(name string) error {
-	return a.fs.RemoveAll(context.Background(), name)
+	return a.fs.RemoveAll(a.ctx, name)
 }
 
 func (a *adaptor) RemoveAll(path string) error {
-	return a.fs.RemoveAll(context.Background(), path)
+	return a.fs.RemoveAll(a.ctx, path)
 }
 
 func (a *adaptor) Rename(oldname, newname string) error {
-	return a.fs.Rename(context.Background(), oldname, newname)
+	return a.fs.Rename(a.ctx, oldname, newname)
 }
 
 func (a *adaptor) Stat(name string) (os.FileInfo, error) {
-	return a.fs.Stat(context.Background(), name)
+	return a.fs.Stat(a.ctx, name)
 }
 
 func (a adaptor) Name() string {
 	return "unifs"
 }
 
-func (adaptor) Chmod(name string, mode os.FileMode) error {
-	return notImplemented("chmod", name)
+func (a *adaptor) Chmod(name string, mode os.FileMode) error {
+	return filesystem.SetMode(a.ctx, a.fs, name, mode)
 }
 
-func (adaptor) Chown(name string, uid, gid int) error {
-	return notImplemented("chown", name)
+// Chown by filesystem.Chowner, otherwise not supported, since files are owned by the user of backends
+func (a *adaptor) Chown(name string, uid, gid int) error {
+	return filesystem.Chown(a.ctx, a.fs, name, uid, gid)
 }
 
-func (adaptor) Chtimes(name string, atime time.Time, mtime time.Time) error {
-	return notImplemented("chtimes", name)
+// Chtimes changes mtime only, atime is not stored by backends
+func (a *adaptor) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return filesystem.SetModTime(a.ctx, a.fs, name, mtime)
 }
 
 func (a *adaptor) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
-	f, err := a.fs.OpenFile(context.Background(), name, flag, perm)
+	f, err := a.fs.OpenFile(a.ctx, name, flag, perm)
 	if err != nil {
 		return nil, err
 	}
@@ -87,6 +96,21 @@ func (a *adaptor) OpenFile(name string, flag int, perm os.FileMode) (afero.File,
 type file struct {
 	info fs.FileInfo
 	filesystem.File
+
+	aborted bool
+}
+
+// TransferError aborts file when transfer of ftp server failed, then the partial file will not be committed
+func (f *file) TransferError(err error) {
+	f.aborted = true
+	_ = filesystem.Abort(f.File, err)
+}
+
+func (f *file) Close() error {
+	if f.aborted {
+		return nil
+	}
+	return f.File.Close()
 }
 
 func (f *file) ReadAt(p []byte, off int64) (n int, err error) {
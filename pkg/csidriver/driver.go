@@ -19,6 +19,17 @@ import (
 const (
 	DefaultDriverName = "csi-driver.unifs.octohelm.tech"
 	backend           = "backend"
+
+	// volume parameters to enable block cache of mount
+	cacheDir  = "cacheDir"
+	cacheSize = "cacheSize"
+
+	// volume parameters to enable stat cache of mount, like 5s
+	statTTL         = "statTTL"
+	statNegativeTTL = "statNegativeTTL"
+
+	// volume context to limit total size of files, set from capacity when creating volume
+	capacity = "capacity"
 )
 
 var _ configuration.Server = &Driver{}
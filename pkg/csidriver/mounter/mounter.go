@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,14 +14,78 @@ import (
 	"k8s.io/utils/mount"
 
 	"github.com/octohelm/unifs/pkg/filesystem/api"
+	"github.com/octohelm/unifs/pkg/filesystem/compress"
+	"github.com/octohelm/unifs/pkg/filesystem/crypt"
+	"github.com/octohelm/unifs/pkg/filesystem/throttle"
 	"github.com/octohelm/unifs/pkg/strfmt"
+	"github.com/octohelm/unifs/pkg/units"
 )
 
 type Mounter interface {
 	Mount(mountPoint string) error
 }
 
-func NewMounter(ctx context.Context, backendStr string) (Mounter, error) {
+type Option func(m *mounter)
+
+// WithCache enables block cache of mount when size not zero
+func WithCache(dir string, size units.BinarySize) Option {
+	return func(m *mounter) {
+		m.CacheDir = dir
+		m.CacheSize = size
+	}
+}
+
+// WithStatCache caches stat results when ttl set, like 5s
+func WithStatCache(ttl string, negativeTTL string) Option {
+	return func(m *mounter) {
+		m.StatTTL = ttl
+		m.StatNegativeTTL = negativeTTL
+	}
+}
+
+// WithReadOnly mounts with all changes denied
+func WithReadOnly(readOnly bool) Option {
+	return func(m *mounter) {
+		m.ReadOnly = readOnly
+	}
+}
+
+// WithQuota limits total size of files when size not zero
+func WithQuota(size units.BinarySize) Option {
+	return func(m *mounter) {
+		m.Quota = size
+	}
+}
+
+// WithRules applies path rules in file when not empty
+func WithRules(file string) Option {
+	return func(m *mounter) {
+		m.Rules = file
+	}
+}
+
+// WithThrottle limits bandwidth and operations on backend
+func WithThrottle(c throttle.Config) Option {
+	return func(m *mounter) {
+		m.Throttle = c
+	}
+}
+
+// WithEncrypt encrypts files when key or key file set
+func WithEncrypt(c crypt.Config) Option {
+	return func(m *mounter) {
+		m.Encrypt = c
+	}
+}
+
+// WithCompress compresses files when algorithm set
+func WithCompress(c compress.Config) Option {
+	return func(m *mounter) {
+		m.Compress = c
+	}
+}
+
+func NewMounter(ctx context.Context, backendStr string, opts ...Option) (Mounter, error) {
 	backend, err := strfmt.ParseEndpoint(backendStr)
 	if err != nil {
 		return nil, err
@@ -34,13 +99,29 @@ func NewMounter(ctx context.Context, backendStr string) (Mounter, error) {
 		return nil, err
 	}
 
-	return &mounter{
+	m := &mounter{
 		Backend: b.Backend,
-	}, nil
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m, nil
 }
 
 type mounter struct {
-	Backend strfmt.Endpoint
+	Backend         strfmt.Endpoint
+	CacheDir        string
+	CacheSize       units.BinarySize
+	Quota           units.BinarySize
+	StatTTL         string
+	StatNegativeTTL string
+	ReadOnly        bool
+	Rules           string
+	Throttle        throttle.Config
+	Encrypt         crypt.Config
+	Compress        compress.Config
 }
 
 func (m *mounter) Mount(mountPoint string) error {
@@ -53,14 +134,10 @@ func (m *mounter) Mount(mountPoint string) error {
 		return err
 	}
 
-	args := []string{
-		"mount",
-		"--backend", m.Backend.String(),
-		mountPoint,
-	}
+	args, env := m.command(mountPoint)
 
 	cmd := exec.Command(p, args...)
-	cmd.Env = os.Environ()
+	cmd.Env = append(os.Environ(), env...)
 
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("FuseMount: %s: %w", append([]string{p}, args...), err)
@@ -69,6 +146,92 @@ func (m *mounter) Mount(mountPoint string) error {
 	return waitForMount(mountPoint, 10*time.Second)
 }
 
+// command returns args and env of mount command, all options should be forwarded,
+// otherwise the mount will be less restricted than required.
+func (m *mounter) command(mountPoint string) (args []string, env []string) {
+	args = []string{
+		"mount",
+		"--backend", m.Backend.String(),
+	}
+
+	if m.CacheSize > 0 {
+		args = append(args, "--cache-size", m.CacheSize.String())
+		if m.CacheDir != "" {
+			args = append(args, "--cache-dir", m.CacheDir)
+		}
+	}
+
+	if m.Quota > 0 {
+		args = append(args, "--quota", m.Quota.String())
+	}
+
+	if m.StatTTL != "" {
+		args = append(args, "--stat-ttl", m.StatTTL)
+		if m.StatNegativeTTL != "" {
+			args = append(args, "--stat-negative-ttl", m.StatNegativeTTL)
+		}
+	}
+
+	if m.ReadOnly {
+		args = append(args, "--read-only")
+	}
+
+	if m.Rules != "" {
+		args = append(args, "--rules", m.Rules)
+	}
+
+	for _, r := range []struct {
+		flag string
+		rate units.BinarySize
+	}{
+		{"--throttle-read-rate", m.Throttle.ReadRate},
+		{"--throttle-write-rate", m.Throttle.WriteRate},
+		{"--throttle-user-read-rate", m.Throttle.UserReadRate},
+		{"--throttle-user-write-rate", m.Throttle.UserWriteRate},
+		{"--throttle-client-read-rate", m.Throttle.ClientReadRate},
+		{"--throttle-client-write-rate", m.Throttle.ClientWriteRate},
+	} {
+		if r.rate > 0 {
+			args = append(args, r.flag, r.rate.String())
+		}
+	}
+
+	for _, r := range []struct {
+		flag string
+		rate int
+	}{
+		{"--throttle-op-rate", m.Throttle.OpRate},
+		{"--throttle-user-op-rate", m.Throttle.UserOpRate},
+		{"--throttle-client-op-rate", m.Throttle.ClientOpRate},
+	} {
+		if r.rate > 0 {
+			args = append(args, r.flag, strconv.Itoa(r.rate))
+		}
+	}
+
+	if m.Encrypt.Key != "" {
+		// key passed by env, to avoid exposed in args of process
+		env = append(env, "UNIFS_ENCRYPT_KEY="+m.Encrypt.Key)
+	}
+	if m.Encrypt.KeyFile != "" {
+		args = append(args, "--encrypt-key-file", m.Encrypt.KeyFile)
+	}
+	if m.Encrypt.Names {
+		args = append(args, "--encrypt-names")
+	}
+
+	if m.Compress.Algorithm != "" {
+		args = append(args, "--compress-algorithm", m.Compress.Algorithm)
+		for _, pattern := range m.Compress.Exclude {
+			args = append(args, "--compress-exclude", pattern)
+		}
+	}
+
+	args = append(args, mountPoint)
+
+	return args, env
+}
+
 func FuseUnmount(path string) error {
 	if _, err := os.Stat(path); err != nil {
 		if os.IsNotExist(err) {
@@ -0,0 +1,49 @@
+package mounter
+
+import (
+	"testing"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem/compress"
+	"github.com/octohelm/unifs/pkg/filesystem/crypt"
+	"github.com/octohelm/unifs/pkg/filesystem/throttle"
+	"github.com/octohelm/unifs/pkg/strfmt"
+	"github.com/octohelm/unifs/pkg/units"
+)
+
+func TestMounterCommand(t *testing.T) {
+	backend, err := strfmt.ParseEndpoint("file:///data")
+	testingx.Expect(t, err, testingx.Be[error](nil))
+
+	m := &mounter{Backend: *backend}
+
+	for _, opt := range []Option{
+		WithStatCache("5s", "0s"),
+		WithReadOnly(true),
+		WithRules("/etc/unifs/rules"),
+		WithThrottle(throttle.Config{ReadRate: 10 * units.MiB, ClientOpRate: 100}),
+		WithEncrypt(crypt.Config{Key: "secret", Names: true}),
+		WithCompress(compress.Config{Algorithm: "zstd", Exclude: []string{"*.gz"}}),
+	} {
+		opt(m)
+	}
+
+	args, env := m.command("/mnt")
+
+	testingx.Expect(t, args, testingx.Equal([]string{
+		"mount",
+		"--backend", "file:///data",
+		"--stat-ttl", "5s",
+		"--stat-negative-ttl", "0s",
+		"--read-only",
+		"--rules", "/etc/unifs/rules",
+		"--throttle-read-rate", "10Mi",
+		"--throttle-client-op-rate", "100",
+		"--encrypt-names",
+		"--compress-algorithm", "zstd",
+		"--compress-exclude", "*.gz",
+		"/mnt",
+	}))
+	testingx.Expect(t, env, testingx.Equal([]string{"UNIFS_ENCRYPT_KEY=secret"}))
+}
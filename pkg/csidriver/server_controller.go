@@ -3,6 +3,7 @@ package csidriver
 import (
 	"context"
 	"errors"
+	"strconv"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc/codes"
@@ -54,11 +55,20 @@ func (c *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVolu
 		return nil, err
 	}
 
+	volumeContext := req.GetParameters()
+	if v.size > 0 {
+		volumeContext = make(map[string]string, len(parameters)+1)
+		for k, p := range parameters {
+			volumeContext[k] = p
+		}
+		volumeContext[capacity] = strconv.FormatInt(v.size, 10)
+	}
+
 	return &csi.CreateVolumeResponse{
 		Volume: &csi.Volume{
 			VolumeId:      v.id,
 			CapacityBytes: v.size,
-			VolumeContext: req.GetParameters(),
+			VolumeContext: volumeContext,
 		},
 	}, nil
 }
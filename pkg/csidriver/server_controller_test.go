@@ -44,6 +44,38 @@ func TestController(t *testing.T) {
 					},
 				},
 			},
+			{
+				name: "with capacity",
+				req: &csi.CreateVolumeRequest{
+					Name: "volume-name",
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: 1 << 30,
+					},
+					VolumeCapabilities: []*csi.VolumeCapability{
+						{
+							AccessType: &csi.VolumeCapability_Mount{
+								Mount: &csi.VolumeCapability_MountVolume{},
+							},
+							AccessMode: &csi.VolumeCapability_AccessMode{
+								Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+							},
+						},
+					},
+					Secrets: map[string]string{
+						backend: "file:///tmp/local",
+					},
+					Parameters: map[string]string{},
+				},
+				resp: &csi.CreateVolumeResponse{
+					Volume: &csi.Volume{
+						VolumeId:      "file##tmp/local#volume-name",
+						CapacityBytes: 1 << 30,
+						VolumeContext: map[string]string{
+							capacity: "1073741824",
+						},
+					},
+				},
+			},
 		}
 
 		for _, c := range cases {
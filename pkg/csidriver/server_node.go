@@ -2,6 +2,11 @@ package csidriver
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc/codes"
@@ -10,6 +15,7 @@ import (
 	"github.com/octohelm/x/logr"
 
 	"github.com/octohelm/unifs/pkg/csidriver/mounter"
+	"github.com/octohelm/unifs/pkg/units"
 )
 
 type nodeServer struct {
@@ -67,7 +73,16 @@ func (n *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublish
 		return nil, status.Error(codes.InvalidArgument, "Missing backend in secret")
 	}
 
-	m, err := mounter.NewMounter(ctx, b)
+	opts, err := mountOptionsFromVolumeContext(volumeID, req.GetVolumeContext())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if req.GetReadonly() {
+		opts = append(opts, mounter.WithReadOnly(true))
+	}
+
+	m, err := mounter.NewMounter(ctx, b, opts...)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
@@ -79,6 +94,75 @@ func (n *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublish
 	return &csi.NodePublishVolumeResponse{}, nil
 }
 
+func mountOptionsFromVolumeContext(volumeID string, volumeContext map[string]string) ([]mounter.Option, error) {
+	opts := make([]mounter.Option, 0)
+
+	if s, ok := volumeContext[capacity]; ok && s != "" {
+		size, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", capacity, err)
+		}
+		if size > 0 {
+			opts = append(opts, mounter.WithQuota(units.BinarySize(size)))
+		}
+	}
+
+	if ttl := volumeContext[statTTL]; ttl != "" {
+		negativeTTL := volumeContext[statNegativeTTL]
+
+		for _, p := range []struct {
+			key string
+			ttl string
+		}{
+			{statTTL, ttl},
+			{statNegativeTTL, negativeTTL},
+		} {
+			if p.ttl == "" {
+				continue
+			}
+			if _, err := time.ParseDuration(p.ttl); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", p.key, err)
+			}
+		}
+
+		opts = append(opts, mounter.WithStatCache(ttl, negativeTTL))
+	}
+
+	size := units.BinarySize(0)
+
+	if s, ok := volumeContext[cacheSize]; ok {
+		if err := size.UnmarshalText([]byte(s)); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", cacheSize, err)
+		}
+	}
+
+	if size == 0 {
+		return opts, nil
+	}
+
+	dir, err := cacheDirOf(volumeID, volumeContext)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(opts, mounter.WithCache(dir, size)), nil
+}
+
+// cacheDirOf returns cache dir of the volume, each volume should use its own cache dir
+func cacheDirOf(volumeID string, volumeContext map[string]string) (string, error) {
+	vol, err := volumeFromID(volumeID)
+	if err != nil {
+		return "", err
+	}
+
+	root := volumeContext[cacheDir]
+	if root == "" {
+		root = filepath.Join(os.TempDir(), "unifs", "blockcache")
+	}
+
+	return filepath.Join(root, vol.uuid), nil
+}
+
 func (n *nodeServer) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpublishVolumeRequest) (resp *csi.NodeUnpublishVolumeResponse, err error) {
 	l := n.l.WithValues("NodeUnpublishVolume", req.GetVolumeId())
 	defer func() {
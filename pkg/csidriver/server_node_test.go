@@ -0,0 +1,85 @@
+package csidriver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	testingx "github.com/octohelm/x/testing"
+)
+
+func TestMountOptionsFromVolumeContext(t *testing.T) {
+	volumeID := "s3#host#base#volume-name"
+
+	t.Run("cache disabled without size", func(t *testing.T) {
+		opts, err := mountOptionsFromVolumeContext(volumeID, map[string]string{
+			cacheDir: "/var/cache/unifs",
+		})
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, len(opts), testingx.Be(0))
+	})
+
+	t.Run("cache enabled with size", func(t *testing.T) {
+		opts, err := mountOptionsFromVolumeContext(volumeID, map[string]string{
+			cacheDir:  "/var/cache/unifs",
+			cacheSize: "10Gi",
+		})
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, len(opts), testingx.Be(1))
+	})
+
+	t.Run("quota from capacity", func(t *testing.T) {
+		opts, err := mountOptionsFromVolumeContext(volumeID, map[string]string{
+			capacity:  "1073741824",
+			cacheSize: "10Gi",
+		})
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, len(opts), testingx.Be(2))
+	})
+
+	t.Run("stat cache with ttl", func(t *testing.T) {
+		opts, err := mountOptionsFromVolumeContext(volumeID, map[string]string{
+			statTTL:         "5s",
+			statNegativeTTL: "1s",
+		})
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, len(opts), testingx.Be(1))
+	})
+
+	t.Run("invalid stat ttl", func(t *testing.T) {
+		_, err := mountOptionsFromVolumeContext(volumeID, map[string]string{
+			statTTL: "x",
+		})
+		testingx.Expect(t, err, testingx.Not(testingx.Be[error](nil)))
+	})
+
+	t.Run("invalid capacity", func(t *testing.T) {
+		_, err := mountOptionsFromVolumeContext(volumeID, map[string]string{
+			capacity: "x",
+		})
+		testingx.Expect(t, err, testingx.Not(testingx.Be[error](nil)))
+	})
+
+	t.Run("invalid size", func(t *testing.T) {
+		_, err := mountOptionsFromVolumeContext(volumeID, map[string]string{
+			cacheSize: "x",
+		})
+		testingx.Expect(t, err, testingx.Not(testingx.Be[error](nil)))
+	})
+}
+
+func TestCacheDirOf(t *testing.T) {
+	t.Run("per volume under cache dir", func(t *testing.T) {
+		dir, err := cacheDirOf("s3#host#base#volume-name", map[string]string{
+			cacheDir: "/var/cache/unifs",
+		})
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, dir, testingx.Be("/var/cache/unifs/volume-name"))
+	})
+
+	t.Run("per volume under default dir", func(t *testing.T) {
+		dir, err := cacheDirOf("s3#host#base#volume-name", map[string]string{})
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, dir, testingx.Be(filepath.Join(os.TempDir(), "unifs", "blockcache", "volume-name")))
+	})
+}
@@ -7,10 +7,6 @@ import (
 	"strings"
 
 	"github.com/octohelm/unifs/pkg/filesystem"
-	"github.com/octohelm/unifs/pkg/filesystem/ftp"
-	"github.com/octohelm/unifs/pkg/filesystem/local"
-	"github.com/octohelm/unifs/pkg/filesystem/s3"
-	"github.com/octohelm/unifs/pkg/filesystem/webdav"
 	"github.com/octohelm/unifs/pkg/strfmt"
 )
 
@@ -25,12 +21,14 @@ type FileSystemBackend struct {
 	PathOverwrite string `flag:",omitzero"`
 	// Overwrite extra when not empty
 	ExtraOverwrite string `flag:",omitzero"`
+	// Mount more backends as <prefix>=<endpoint>, backend will be mounted at / when set
+	Mounts []string `flag:"mount,omitzero"`
 
 	fsi filesystem.FileSystem `flag:"-"`
 }
 
 func (m *FileSystemBackend) Disabled(ctx context.Context) bool {
-	return m.Backend.IsZero()
+	return m.Backend.IsZero() && len(m.Mounts) == 0
 }
 
 func (m *FileSystemBackend) FileSystem() filesystem.FileSystem {
@@ -42,6 +40,86 @@ func (m *FileSystemBackend) Init(ctx context.Context) error {
 		return nil
 	}
 
+	if len(m.Mounts) == 0 {
+		fsys, err := m.newFileSystem(ctx)
+		if err != nil {
+			return err
+		}
+		m.fsi = fsys
+		return nil
+	}
+
+	mux := filesystem.NewMux()
+
+	if !m.Backend.IsZero() {
+		fsys, err := m.newFileSystem(ctx)
+		if err != nil {
+			return err
+		}
+		if err := mux.Mount("/", fsys); err != nil {
+			return err
+		}
+	}
+
+	for _, mount := range m.Mounts {
+		prefix, e, ok := strings.Cut(mount, "=")
+		if !ok {
+			return fmt.Errorf("invalid mount %q, should be <prefix>=<endpoint>", mount)
+		}
+
+		endpoint, err := strfmt.ParseEndpoint(e)
+		if err != nil {
+			return err
+		}
+
+		fsys, err := NewFileSystem(ctx, *endpoint)
+		if err != nil {
+			return err
+		}
+
+		if err := mux.Mount(prefix, fsys); err != nil {
+			return err
+		}
+	}
+
+	m.fsi = mux
+	return nil
+}
+
+// ID identifies the backend by endpoints without passwords,
+// for things should not be shared between backends, like dirs of block cache.
+func (m *FileSystemBackend) ID() string {
+	ids := make([]string, 0, 1+len(m.Mounts))
+
+	if !m.Backend.IsZero() {
+		endpoint, _ := m.endpoint()
+		endpoint.Password = ""
+		ids = append(ids, endpoint.String())
+	}
+
+	for _, mount := range m.Mounts {
+		prefix, e, _ := strings.Cut(mount, "=")
+		if endpoint, err := strfmt.ParseEndpoint(e); err == nil {
+			endpoint.Password = ""
+			e = endpoint.String()
+		}
+		ids = append(ids, prefix+"="+e)
+	}
+
+	return strings.Join(ids, "\n")
+}
+
+func (m *FileSystemBackend) newFileSystem(ctx context.Context) (filesystem.FileSystem, error) {
+	endpoint, err := m.endpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFileSystem(ctx, endpoint)
+}
+
+// endpoint returns Backend with overwrites
+func (m *FileSystemBackend) endpoint() (strfmt.Endpoint, error) {
 	endpoint := m.Backend
 
 	if path := m.PathOverwrite; path != "" {
@@ -59,41 +137,12 @@ func (m *FileSystemBackend) Init(ctx context.Context) error {
 	if extra := m.ExtraOverwrite; extra != "" {
 		q, err := url.ParseQuery(extra)
 		if err != nil {
-			return err
+			return endpoint, err
 		}
 		endpoint.Extra = q
 	}
 
-	switch endpoint.Scheme {
-	case "s3":
-		conf := &s3.Config{Endpoint: endpoint}
-		fsys, err := conf.AsFileSystem(ctx)
-		if err != nil {
-			return err
-		}
-		m.fsi = fsys
-		return nil
-	case "ftp", "ftps":
-		m.fsi = ftp.NewFS(&ftp.Config{Endpoint: endpoint})
-		return nil
-	case "webdav":
-		conf := &webdav.Config{Endpoint: endpoint}
-		c, err := conf.Client(ctx)
-		if err != nil {
-			return err
-		}
-		m.fsi = webdav.NewFS(c)
-		return nil
-	case "file":
-		if endpoint.Hostname == "." && strings.HasPrefix(endpoint.Path, "/") {
-			m.fsi = local.NewFS(endpoint.Path[1:])
-			return nil
-		}
-		m.fsi = local.NewFS(endpoint.Path)
-		return nil
-	default:
-		return fmt.Errorf("unsupported %s", endpoint)
-	}
+	return endpoint, nil
 }
 
 func (m *FileSystemBackend) InjectContext(ctx context.Context) context.Context {
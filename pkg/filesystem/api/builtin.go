@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/ftp"
+	"github.com/octohelm/unifs/pkg/filesystem/local"
+	"github.com/octohelm/unifs/pkg/filesystem/s3"
+	"github.com/octohelm/unifs/pkg/filesystem/webdav"
+	"github.com/octohelm/unifs/pkg/strfmt"
+)
+
+func init() {
+	Register("s3", func(ctx context.Context, endpoint strfmt.Endpoint) (filesystem.FileSystem, error) {
+		conf := &s3.Config{Endpoint: endpoint}
+		return conf.AsFileSystem(ctx)
+	})
+
+	newFTP := func(ctx context.Context, endpoint strfmt.Endpoint) (filesystem.FileSystem, error) {
+		return ftp.NewFS(&ftp.Config{Endpoint: endpoint}), nil
+	}
+
+	Register("ftp", newFTP)
+	Register("ftps", newFTP)
+
+	Register("webdav", func(ctx context.Context, endpoint strfmt.Endpoint) (filesystem.FileSystem, error) {
+		conf := &webdav.Config{Endpoint: endpoint}
+		c, err := conf.Client(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return webdav.NewFS(c), nil
+	})
+
+	Register("file", func(ctx context.Context, endpoint strfmt.Endpoint) (filesystem.FileSystem, error) {
+		if endpoint.Hostname == "." && strings.HasPrefix(endpoint.Path, "/") {
+			return local.NewFS(endpoint.Path[1:]), nil
+		}
+		return local.NewFS(endpoint.Path), nil
+	})
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/compress"
+	"github.com/octohelm/unifs/pkg/filesystem/crypt"
+	fsotel "github.com/octohelm/unifs/pkg/filesystem/otel"
+	"github.com/octohelm/unifs/pkg/strfmt"
+)
+
+// Factory creates the FileSystem described by endpoint
+type Factory func(ctx context.Context, endpoint strfmt.Endpoint) (filesystem.FileSystem, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{}
+)
+
+// Register makes a backend available by endpoint scheme.
+// It panics if factory is nil or the scheme is already registered.
+func Register(scheme string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if factory == nil {
+		panic("unifs: Register factory is nil")
+	}
+
+	if _, dup := factories[scheme]; dup {
+		panic("unifs: Register called twice for scheme " + scheme)
+	}
+
+	factories[scheme] = factory
+}
+
+// Schemes returns sorted registered schemes
+func Schemes() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	schemes := make([]string, 0, len(factories))
+	for scheme := range factories {
+		schemes = append(schemes, scheme)
+	}
+	slices.Sort(schemes)
+	return schemes
+}
+
+// NewFileSystem creates the FileSystem by the factory registered for endpoint scheme.
+//
+// Operations of the created backend will be traced and measured,
+// before wrapped by retry, encryption and compression, so each attempt and bytes on wire are recorded.
+//
+// Idempotent operations will be retried on transient errors when endpoint with query param `retry=<max retries>`,
+// and `retryInterval` / `retryMaxInterval` to tune the backoff.
+//
+// Contents will be encrypted when endpoint with query param `encryptKeyFile=<path>` or `encryptKeyEnv=<env name>`,
+// and names encrypted too with `encryptNames=true`.
+//
+// Contents will be compressed when endpoint with query param `compress=zstd|gzip`,
+// and `compressExclude=<comma separated patterns>` to store files matched as it is.
+func NewFileSystem(ctx context.Context, endpoint strfmt.Endpoint) (filesystem.FileSystem, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[endpoint.Scheme]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported %s, available schemes: %v", endpoint.SecurityString(), Schemes())
+	}
+
+	fsys, err := factory(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	fsys = fsotel.Wrap(ctx, fsys, endpoint.Scheme)
+
+	fsys, err = withRetry(fsys, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	fsys, err = withEncryption(fsys, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// compress before encrypt, encrypted data could not be compressed
+	return withCompression(fsys, endpoint)
+}
+
+func withCompression(fsys filesystem.FileSystem, endpoint strfmt.Endpoint) (filesystem.FileSystem, error) {
+	c := &compress.Config{
+		Algorithm: endpoint.Extra.Get("compress"),
+	}
+
+	if exclude := endpoint.Extra.Get("compressExclude"); exclude != "" {
+		c.Exclude = strings.Split(exclude, ",")
+	}
+
+	return c.Apply(fsys)
+}
+
+func withEncryption(fsys filesystem.FileSystem, endpoint strfmt.Endpoint) (filesystem.FileSystem, error) {
+	keyFile, keyEnv := endpoint.Extra.Get("encryptKeyFile"), endpoint.Extra.Get("encryptKeyEnv")
+	if keyFile == "" && keyEnv == "" {
+		return fsys, nil
+	}
+
+	key, err := crypt.LoadKey(keyFile, keyEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptNames := false
+	if v := endpoint.Extra.Get("encryptNames"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryptNames: %w", err)
+		}
+		encryptNames = b
+	}
+
+	return crypt.Wrap(fsys, key, crypt.WithNameEncryption(encryptNames))
+}
+
+func withRetry(fsys filesystem.FileSystem, endpoint strfmt.Endpoint) (filesystem.FileSystem, error) {
+	r := endpoint.Extra.Get("retry")
+	if r == "" {
+		return fsys, nil
+	}
+
+	maxRetries, err := strconv.Atoi(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid retry: %w", err)
+	}
+
+	if maxRetries <= 0 {
+		return fsys, nil
+	}
+
+	var interval, maxInterval time.Duration
+
+	if t := endpoint.Extra.Get("retryInterval"); t != "" {
+		d, err := time.ParseDuration(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retryInterval: %w", err)
+		}
+		interval = d
+	}
+
+	if t := endpoint.Extra.Get("retryMaxInterval"); t != "" {
+		d, err := time.ParseDuration(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retryMaxInterval: %w", err)
+		}
+		maxInterval = d
+	}
+
+	return filesystem.Retry(
+		fsys,
+		filesystem.WithMaxRetries(maxRetries),
+		filesystem.WithRetryInterval(interval, maxInterval),
+	), nil
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/strfmt"
+)
+
+func TestRegister(t *testing.T) {
+	memfs := filesystem.NewMemFS()
+
+	Register("mem", func(ctx context.Context, endpoint strfmt.Endpoint) (filesystem.FileSystem, error) {
+		return memfs, nil
+	})
+
+	t.Run("builtin schemes registered", func(t *testing.T) {
+		testingx.Expect(t, Schemes(), testingx.Equal([]string{"file", "ftp", "ftps", "mem", "s3", "webdav"}))
+	})
+
+	t.Run("backend init with registered scheme", func(t *testing.T) {
+		e, err := strfmt.ParseEndpoint("mem://localhost")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		b := &FileSystemBackend{Backend: *e}
+		err = b.Init(context.Background())
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		err = filesystem.Write(context.Background(), memfs, "/registered.txt", []byte("1"))
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		_, err = b.FileSystem().Stat(context.Background(), "/registered.txt")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+	})
+
+	t.Run("backend init failed with unknown scheme", func(t *testing.T) {
+		e, err := strfmt.ParseEndpoint("unknown://localhost")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		b := &FileSystemBackend{Backend: *e}
+		err = b.Init(context.Background())
+		testingx.Expect(t, err, testingx.Not(testingx.BeNil[error]()))
+	})
+
+	t.Run("backend init failed with invalid retry", func(t *testing.T) {
+		e, err := strfmt.ParseEndpoint("mem://localhost?retry=x")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		b := &FileSystemBackend{Backend: *e}
+		err = b.Init(context.Background())
+		testingx.Expect(t, err, testingx.Not(testingx.BeNil[error]()))
+	})
+
+	t.Run("backend init with encryption", func(t *testing.T) {
+		t.Setenv("TEST_ENCRYPT_KEY", "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+
+		e, err := strfmt.ParseEndpoint("mem://localhost?encryptKeyEnv=TEST_ENCRYPT_KEY")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		b := &FileSystemBackend{Backend: *e}
+		err = b.Init(context.Background())
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		err = filesystem.Write(context.Background(), b.FileSystem(), "/encrypted.txt", []byte("1"))
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		info, err := memfs.Stat(context.Background(), "/encrypted.txt")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+		testingx.Expect(t, info.Size() > 1, testingx.Be(true))
+	})
+
+	t.Run("backend init failed with missing encryption key", func(t *testing.T) {
+		e, err := strfmt.ParseEndpoint("mem://localhost?encryptKeyEnv=TEST_ENCRYPT_KEY_MISSING")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		b := &FileSystemBackend{Backend: *e}
+		err = b.Init(context.Background())
+		testingx.Expect(t, err, testingx.Not(testingx.BeNil[error]()))
+	})
+
+	t.Run("backend init failed with unsupported compression", func(t *testing.T) {
+		e, err := strfmt.ParseEndpoint("mem://localhost?compress=lzma")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		b := &FileSystemBackend{Backend: *e}
+		err = b.Init(context.Background())
+		testingx.Expect(t, err, testingx.Not(testingx.BeNil[error]()))
+	})
+
+	t.Run("backend init with mounts", func(t *testing.T) {
+		e, err := strfmt.ParseEndpoint("mem://localhost")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		b := &FileSystemBackend{Backend: *e, Mounts: []string{"/a=mem://localhost", "/b/c=mem://localhost"}}
+		err = b.Init(context.Background())
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		mux, ok := b.FileSystem().(*filesystem.Mux)
+		testingx.Expect(t, ok, testingx.Be(true))
+		testingx.Expect(t, mux.Mounts(), testingx.Equal([]string{"/", "/a", "/b/c"}))
+	})
+
+	t.Run("backend init failed with invalid mount", func(t *testing.T) {
+		b := &FileSystemBackend{Mounts: []string{"mem://localhost"}}
+		err := b.Init(context.Background())
+		testingx.Expect(t, err, testingx.Not(testingx.BeNil[error]()))
+	})
+
+	t.Run("duplicate register should panic", func(t *testing.T) {
+		defer func() {
+			testingx.Expect(t, recover() != nil, testingx.Be(true))
+		}()
+
+		Register("mem", func(ctx context.Context, endpoint strfmt.Endpoint) (filesystem.FileSystem, error) {
+			return memfs, nil
+		})
+	})
+	t.Run("transient errors of backend retried", func(t *testing.T) {
+		flaky := &flakyFS{FileSystem: memfs}
+		flaky.failures.Store(2)
+
+		Register("flaky", func(ctx context.Context, endpoint strfmt.Endpoint) (filesystem.FileSystem, error) {
+			return flaky, nil
+		})
+
+		e, err := strfmt.ParseEndpoint("flaky://localhost?retry=3&retryInterval=1ms")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		fsys, err := NewFileSystem(context.Background(), *e)
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		_, err = fsys.Stat(context.Background(), "/")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+		testingx.Expect(t, flaky.failures.Load(), testingx.Be(int64(0)))
+	})
+}
+
+var errBusy = errors.New("busy")
+
+// flakyFS fails Stat with errBusy, which only transient to itself
+type flakyFS struct {
+	filesystem.FileSystem
+	failures atomic.Int64
+}
+
+func (f *flakyFS) IsTransientError(err error) bool {
+	return errors.Is(err, errBusy)
+}
+
+func (f *flakyFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	if f.failures.Add(-1) >= 0 {
+		return nil, errBusy
+	}
+	f.failures.Store(0)
+	return f.FileSystem.Stat(ctx, name)
+}
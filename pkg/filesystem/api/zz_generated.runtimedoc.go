@@ -27,6 +27,10 @@ func (v *FileSystemBackend) RuntimeDoc(names ...string) ([]string, bool) {
 			return []string{
 				"Overwrite extra when not empty",
 			}, true
+		case "Mounts":
+			return []string{
+				"Mount more backends as <prefix>=<endpoint>, backend will be mounted at / when set",
+			}, true
 
 		}
 
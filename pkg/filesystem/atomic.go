@@ -0,0 +1,157 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// O_ATOMIC could be used with flags of OpenFile for write,
+// then the file will be visible only after Close succeeded, and nothing left when write failed.
+//
+// only FileSystem with CapAtomicWrite supports it, OpenAtomic could be used for any FileSystem.
+const O_ATOMIC = 0x40000000
+
+const tempPrefix = ".unifs-tmp-"
+
+// TempName returns random name of temp file for name in the same dir, checked by IsTempName
+func TempName(name string) string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return path.Join(path.Dir(slashClean(name)), tempPrefix+hex.EncodeToString(b)+"-"+path.Base(name))
+}
+
+// IsTempName checks name is temp file of writing, which should be ignored by watching
+func IsTempName(name string) bool {
+	return strings.HasPrefix(path.Base(name), tempPrefix)
+}
+
+// WriteAtomic writes data to name, readers will see the old content or all of data, but never a part of it.
+func WriteAtomic(ctx context.Context, fsys FileSystem, name string, data []byte) error {
+	f, err := OpenAtomic(ctx, fsys, name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		// written should not be committed by Close
+		_ = Abort(f, err)
+		return err
+	}
+	return f.Close()
+}
+
+// OpenAtomic opens name for write with O_ATOMIC when fsys supports CapAtomicWrite,
+// otherwise by OpenByRename.
+func OpenAtomic(ctx context.Context, fsys FileSystem, name string, flag int, perm os.FileMode) (File, error) {
+	if HasCapabilities(fsys, CapAtomicWrite) {
+		return fsys.OpenFile(ctx, name, flag|O_ATOMIC, perm)
+	}
+	return OpenByRename(ctx, fsys, name, flag, perm)
+}
+
+// OpenByRename opens temp file in the same dir of name for write, which will be renamed to name when closed.
+// temp file will be removed when any write failed or renaming failed.
+// backends without atomic put could use it to support O_ATOMIC.
+//
+// O_EXCL is checked by Stat when opened, which is not exclusive,
+// file created by others before renamed will be replaced.
+func OpenByRename(ctx context.Context, fsys FileSystem, name string, flag int, perm os.FileMode) (File, error) {
+	flag &^= O_ATOMIC
+
+	// content of existing file should be copied, which is not atomic
+	if flag&os.O_APPEND != 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: fmt.Errorf("append atomically: %w", errors.ErrUnsupported)}
+	}
+
+	if flag&os.O_EXCL != 0 {
+		if _, err := fsys.Stat(ctx, name); err == nil {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+		}
+	}
+
+	tmp := TempName(name)
+
+	f, err := fsys.OpenFile(ctx, tmp, flag|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &renameFile{File: f, ctx: ctx, fsys: fsys, name: name, tmp: tmp}, nil
+}
+
+type renameFile struct {
+	File
+
+	ctx  context.Context
+	fsys FileSystem
+	name string
+	tmp  string
+	// the first error of writing
+	err error
+	// closed or aborted
+	done bool
+}
+
+func (f *renameFile) Write(p []byte) (int, error) {
+	if err := f.ctx.Err(); err != nil {
+		return 0, context.Cause(f.ctx)
+	}
+
+	n, err := f.File.Write(p)
+	if err != nil && f.err == nil {
+		f.err = err
+	}
+	return n, err
+}
+
+func (f *renameFile) Close() error {
+	if f.done {
+		return nil
+	}
+
+	// written should not be committed when canceled
+	if f.ctx.Err() != nil {
+		err := context.Cause(f.ctx)
+		_ = f.Abort(err)
+		return err
+	}
+
+	f.done = true
+
+	// renaming should not be canceled with ctx of open
+	ctx := context.WithoutCancel(f.ctx)
+
+	err := f.File.Close()
+	if err == nil {
+		err = f.err
+	}
+
+	if err == nil {
+		err = f.fsys.Rename(ctx, f.tmp, f.name)
+	}
+
+	if err != nil {
+		_ = f.fsys.RemoveAll(ctx, f.tmp)
+		return err
+	}
+
+	return nil
+}
+
+// Abort discards temp file, name is never touched
+func (f *renameFile) Abort(err error) error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+
+	// temp file may be committed when closed, but removed anyway
+	_ = Abort(f.File, err)
+
+	return f.fsys.RemoveAll(context.WithoutCancel(f.ctx), f.tmp)
+}
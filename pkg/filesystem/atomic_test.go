@@ -0,0 +1,82 @@
+package filesystem_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/testutil"
+)
+
+func TestAtomicWrite(t *testing.T) {
+	t.Run("by rename", func(t *testing.T) {
+		testutil.TestAtomicWrite(t, filesystem.NewMemFS())
+	})
+
+	t.Run("abort by rename", func(t *testing.T) {
+		testutil.TestAbort(t, filesystem.NewMemFS())
+	})
+
+	t.Run("append unsupported", func(t *testing.T) {
+		_, err := filesystem.OpenAtomic(context.Background(), filesystem.NewMemFS(), "/a.txt", os.O_WRONLY|os.O_CREATE|os.O_APPEND, os.ModePerm)
+		testingx.Expect(t, errors.Is(err, errors.ErrUnsupported), testingx.Be(true))
+	})
+
+	t.Run("abort native atomic when write failed", func(t *testing.T) {
+		ctx := context.Background()
+		fsys := &atomicWriteFS{FileSystem: filesystem.NewMemFS()}
+
+		err := filesystem.WriteAtomic(ctx, fsys, "/a.txt", []byte("a"))
+		testingx.Expect(t, errors.Is(err, errWriteFailed), testingx.Be(true))
+		testingx.Expect(t, fsys.aborted, testingx.Be(true))
+	})
+
+	t.Run("exclusive", func(t *testing.T) {
+		ctx := context.Background()
+		fsys := filesystem.NewMemFS()
+
+		err := filesystem.Write(ctx, fsys, "/a.txt", []byte("a"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = filesystem.OpenAtomic(ctx, fsys, "/a.txt", os.O_WRONLY|os.O_CREATE|os.O_EXCL, os.ModePerm)
+		testingx.Expect(t, errors.Is(err, os.ErrExist), testingx.Be(true))
+	})
+}
+
+var errWriteFailed = errors.New("write failed")
+
+// atomicWriteFS supports O_ATOMIC natively, and writes always fail
+type atomicWriteFS struct {
+	filesystem.FileSystem
+	aborted bool
+}
+
+func (fs *atomicWriteFS) Capabilities() filesystem.Capability {
+	return filesystem.CapAtomicWrite
+}
+
+func (fs *atomicWriteFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (filesystem.File, error) {
+	f, err := fs.FileSystem.OpenFile(ctx, name, flag&^filesystem.O_ATOMIC, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &failedWriteFile{File: f, fs: fs}, nil
+}
+
+type failedWriteFile struct {
+	filesystem.File
+	fs *atomicWriteFS
+}
+
+func (f *failedWriteFile) Write(p []byte) (int, error) {
+	return 0, errWriteFailed
+}
+
+func (f *failedWriteFile) Abort(err error) error {
+	f.fs.aborted = true
+	return f.File.Close()
+}
@@ -0,0 +1,72 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+// AttrSetter could be implemented by FileSystem to change attributes of files, like os.Chmod and os.Chtimes.
+// errors.ErrUnsupported could be returned when backend could not store the attribute.
+//
+// attributes are stored natively when possible, otherwise as metadata of files, like user metadata of s3,
+// and will be reported by Stat.
+type AttrSetter interface {
+	// SetMode changes permission bits of name, only mode.Perm() is used
+	SetMode(ctx context.Context, name string, mode os.FileMode) error
+	// SetModTime changes modification time of name
+	SetModTime(ctx context.Context, name string, mtime time.Time) error
+}
+
+// SetMode changes permission bits of name by AttrSetter
+func SetMode(ctx context.Context, fsys FileSystem, name string, mode os.FileMode) error {
+	if s, ok := fsys.(AttrSetter); ok {
+		if err := s.SetMode(ctx, name, mode); !errors.Is(err, errors.ErrUnsupported) {
+			return err
+		}
+	}
+	return &os.PathError{Op: "chmod", Path: name, Err: errors.ErrUnsupported}
+}
+
+// SetModTime changes modification time of name by AttrSetter
+func SetModTime(ctx context.Context, fsys FileSystem, name string, mtime time.Time) error {
+	if s, ok := fsys.(AttrSetter); ok {
+		if err := s.SetModTime(ctx, name, mtime); !errors.Is(err, errors.ErrUnsupported) {
+			return err
+		}
+	}
+	return &os.PathError{Op: "chtimes", Path: name, Err: errors.ErrUnsupported}
+}
+
+// Chowner could be implemented by FileSystem to change owner of files, like os.Lchown.
+// links are not followed, uid or gid -1 keeps the current one.
+type Chowner interface {
+	Chown(ctx context.Context, name string, uid, gid int) error
+}
+
+// Chown changes owner of name by Chowner
+func Chown(ctx context.Context, fsys FileSystem, name string, uid, gid int) error {
+	if c, ok := fsys.(Chowner); ok {
+		if err := c.Chown(ctx, name, uid, gid); !errors.Is(err, errors.ErrUnsupported) {
+			return err
+		}
+	}
+	return &os.PathError{Op: "chown", Path: name, Err: errors.ErrUnsupported}
+}
+
+type modTimeCtx struct{}
+
+// ModTimeInjectContext sets modification time of files written with ctx, like rsync -t,
+// stored when content committed, by X-OC-Mtime header of PUT for webdav and in metadata for s3,
+// other backends set it after written.
+func ModTimeInjectContext(ctx context.Context, mtime time.Time) context.Context {
+	return context.WithValue(ctx, modTimeCtx{}, mtime)
+}
+
+func ModTimeFromContext(ctx context.Context) (time.Time, bool) {
+	if v, ok := ctx.Value(modTimeCtx{}).(time.Time); ok {
+		return v, true
+	}
+	return time.Time{}, false
+}
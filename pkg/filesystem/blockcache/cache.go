@@ -0,0 +1,277 @@
+package blockcache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/octohelm/unifs/pkg/units"
+)
+
+const (
+	DefaultBlockSize = 1 * units.MiB
+	DefaultMaxSize   = 1 * units.GiB
+)
+
+// Cache stores fixed-size blocks of remote files on local disk,
+// with least recently used blocks evicted when total size over MaxSize.
+//
+// blocks stored as <dir>/<hash of each segment of path>/<hash of version>-<index>,
+// version is ETag or mtime with size, so changed files will never hit old blocks.
+// blocks of files under the same dir share the prefix, so they could be dropped together.
+type Cache struct {
+	dir       string
+	maxSize   int64
+	blockSize int64
+
+	mu    sync.Mutex
+	size  int64
+	lru   *list.List
+	items map[string]*list.Element
+}
+
+type block struct {
+	key  string
+	size int64
+}
+
+// Dir returns dir of Cache under root for the backend identified by id, root defaults to $TMPDIR/unifs/blockcache.
+//
+// each backend should use its own dir, since blocks are keyed by path and version only,
+// and Cache only accounts and drops blocks in its own dir.
+func Dir(root string, id string) string {
+	if root == "" {
+		root = filepath.Join(os.TempDir(), "unifs", "blockcache")
+	}
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(root, hex.EncodeToString(sum[:8]))
+}
+
+// NewCache creates Cache in dir, and blocks already in dir will be reused.
+// maxSize and blockSize use defaults when zero.
+func NewCache(dir string, maxSize units.BinarySize, blockSize units.BinarySize) (*Cache, error) {
+	if maxSize == 0 {
+		maxSize = DefaultMaxSize
+	}
+	if blockSize == 0 {
+		blockSize = DefaultBlockSize
+	}
+
+	c := &Cache{
+		dir:       dir,
+		maxSize:   int64(maxSize),
+		blockSize: int64(blockSize),
+		lru:       list.New(),
+		items:     map[string]*list.Element{},
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Cache) BlockSize() int64 {
+	return c.blockSize
+}
+
+// load blocks exists in dir, older modified ones are treated as less recently used.
+func (c *Cache) load() error {
+	type entry struct {
+		key  string
+		info fs.FileInfo
+	}
+
+	entries := make([]entry, 0)
+
+	err := filepath.WalkDir(c.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		// incomplete block
+		if strings.HasSuffix(p, ".tmp") {
+			_ = os.Remove(p)
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		key, err := filepath.Rel(c.dir, p)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{key: filepath.ToSlash(key), info: info})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].info.ModTime().Before(entries[j].info.ModTime())
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, e := range entries {
+		c.add(e.key, e.info.Size())
+	}
+	c.evict()
+
+	return nil
+}
+
+func (c *Cache) blockKey(name string, version string, idx int64) string {
+	return fmt.Sprintf("%s/%s-%d", pathKey(name), hash(version), idx)
+}
+
+// Get returns cached block data, or nil when not cached.
+func (c *Cache) Get(name string, version string, idx int64) []byte {
+	key := c.blockKey(name, version, idx)
+
+	c.mu.Lock()
+	e, ok := c.items[key]
+	if ok {
+		c.lru.MoveToFront(e)
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(c.dir, filepath.FromSlash(key)))
+	if err != nil {
+		c.mu.Lock()
+		c.remove(key)
+		c.mu.Unlock()
+		return nil
+	}
+	return data
+}
+
+// Put stores block data.
+func (c *Cache) Put(name string, version string, idx int64, data []byte) error {
+	key := c.blockKey(name, version, idx)
+	filename := filepath.Join(c.dir, filepath.FromSlash(key))
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+
+	// write to temp file first, to avoid reading incomplete block
+	tmp := fmt.Sprintf("%s.%d.tmp", filename, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.add(key, int64(len(data)))
+	c.evict()
+
+	return nil
+}
+
+// Invalidate drops all cached blocks of name, and of files under name when it is a dir.
+func (c *Cache) Invalidate(name string) {
+	dir := pathKey(name)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.items {
+		if dir == "" || strings.HasPrefix(key, dir+"/") {
+			c.remove(key)
+		}
+	}
+
+	if dir == "" {
+		entries, _ := os.ReadDir(c.dir)
+		for _, e := range entries {
+			_ = os.RemoveAll(filepath.Join(c.dir, e.Name()))
+		}
+		return
+	}
+
+	_ = os.RemoveAll(filepath.Join(c.dir, filepath.FromSlash(dir)))
+}
+
+func (c *Cache) add(key string, size int64) {
+	if e, ok := c.items[key]; ok {
+		c.size -= e.Value.(*block).size
+		e.Value.(*block).size = size
+		c.size += size
+		c.lru.MoveToFront(e)
+		return
+	}
+
+	c.items[key] = c.lru.PushFront(&block{key: key, size: size})
+	c.size += size
+}
+
+func (c *Cache) remove(key string) {
+	e, ok := c.items[key]
+	if !ok {
+		return
+	}
+
+	c.lru.Remove(e)
+	delete(c.items, key)
+	c.size -= e.Value.(*block).size
+
+	_ = os.Remove(filepath.Join(c.dir, filepath.FromSlash(key)))
+}
+
+func (c *Cache) evict() {
+	for c.size > c.maxSize {
+		e := c.lru.Back()
+		if e == nil {
+			return
+		}
+		c.remove(e.Value.(*block).key)
+	}
+}
+
+// pathKey returns hashes of each segment of name joined by /, empty for root
+func pathKey(name string) string {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "" {
+		return ""
+	}
+
+	segments := strings.Split(name, "/")
+	for i, s := range segments {
+		segments[i] = hash(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+func hash(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:16])
+}
@@ -0,0 +1,263 @@
+package blockcache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+
+	"golang.org/x/net/webdav"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+// Wrap caches blocks of files opened for read only.
+// Writes, removes, renames, copies and links go through fsys directly, and drop cached blocks of the changed path.
+// optional interfaces of fsys are forwarded, attributes changes need no drop, since blocks are keyed by version.
+func Wrap(fsys filesystem.FileSystem, cache *Cache) filesystem.FileSystem {
+	return &cachedFS{Forwarder: filesystem.Forwarder{FileSystem: fsys}, cache: cache}
+}
+
+type cachedFS struct {
+	filesystem.Forwarder
+
+	cache *Cache
+}
+
+func (c *cachedFS) Capabilities() filesystem.Capability {
+	return filesystem.CapabilitiesOf(c.FileSystem) | filesystem.CapReaderAt
+}
+
+func (c *cachedFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (filesystem.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		// blocks of new version will not hit old ones, just drop to release space
+		c.cache.Invalidate(name)
+		return c.FileSystem.OpenFile(ctx, name, flag, perm)
+	}
+
+	info, err := c.FileSystem.Stat(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return c.FileSystem.OpenFile(ctx, name, flag, perm)
+	}
+
+	return &file{
+		ctx:     ctx,
+		fs:      c.FileSystem,
+		cache:   c.cache,
+		name:    name,
+		flag:    flag,
+		info:    info,
+		version: versionOf(ctx, info),
+	}, nil
+}
+
+func (c *cachedFS) RemoveAll(ctx context.Context, name string) error {
+	c.cache.Invalidate(name)
+	return c.FileSystem.RemoveAll(ctx, name)
+}
+
+func (c *cachedFS) Rename(ctx context.Context, oldName, newName string) error {
+	c.cache.Invalidate(oldName)
+	c.cache.Invalidate(newName)
+	return c.FileSystem.Rename(ctx, oldName, newName)
+}
+
+func (c *cachedFS) Copy(ctx context.Context, oldName, newName string, recursive bool) error {
+	c.cache.Invalidate(newName)
+	return c.Forwarder.Copy(ctx, oldName, newName, recursive)
+}
+
+func (c *cachedFS) Symlink(ctx context.Context, target string, name string) error {
+	c.cache.Invalidate(name)
+	return c.Forwarder.Symlink(ctx, target, name)
+}
+
+func versionOf(ctx context.Context, info filesystem.FileInfo) string {
+	if e, ok := info.(webdav.ETager); ok {
+		if etag, err := e.ETag(ctx); err == nil && etag != "" {
+			return etag
+		}
+	}
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+}
+
+type file struct {
+	ctx     context.Context
+	fs      filesystem.FileSystem
+	cache   *Cache
+	name    string
+	flag    int
+	info    filesystem.FileInfo
+	version string
+
+	mu sync.Mutex
+	// offset for Read and Seek
+	offset int64
+	// underlying file opened when some block missed
+	src       filesystem.File
+	srcOffset int64
+}
+
+func (f *file) Name() string {
+	return f.name
+}
+
+func (f *file) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *file) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: os.ErrInvalid}
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "write", Path: f.name, Err: os.ErrPermission}
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	offset := f.offset
+	f.mu.Unlock()
+
+	n, err := f.ReadAt(p, offset)
+
+	f.mu.Lock()
+	f.offset = offset + int64(n)
+	f.mu.Unlock()
+
+	if n > 0 && err == io.EOF {
+		return n, nil
+	}
+	return n, err
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.Size()
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: os.ErrInvalid}
+	}
+
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: os.ErrInvalid}
+	}
+
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	size := f.info.Size()
+	blockSize := f.cache.BlockSize()
+
+	n := 0
+
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= size {
+			return n, io.EOF
+		}
+
+		idx := pos / blockSize
+
+		data, err := f.block(idx)
+		if err != nil {
+			return n, err
+		}
+
+		start := pos - idx*blockSize
+		if start >= int64(len(data)) {
+			// remote file changed to be shorter
+			return n, io.EOF
+		}
+
+		n += copy(p[n:], data[start:])
+	}
+
+	return n, nil
+}
+
+func (f *file) block(idx int64) ([]byte, error) {
+	if data := f.cache.Get(f.name, f.version, idx); data != nil {
+		return data, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	blockSize := f.cache.BlockSize()
+	off := idx * blockSize
+
+	length := f.info.Size() - off
+	if length > blockSize {
+		length = blockSize
+	}
+
+	data := make([]byte, length)
+
+	n, err := f.readSource(data, off)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	data = data[:n]
+
+	// only cache complete block,
+	// and failed to cache should not break reading
+	if int64(n) == length {
+		_ = f.cache.Put(f.name, f.version, idx, data)
+	}
+
+	return data, nil
+}
+
+func (f *file) readSource(p []byte, off int64) (int, error) {
+	if f.src == nil {
+		src, err := f.fs.OpenFile(f.ctx, f.name, f.flag, 0)
+		if err != nil {
+			return 0, err
+		}
+		f.src = src
+		f.srcOffset = 0
+	}
+
+	if r, ok := f.src.(io.ReaderAt); ok {
+		return r.ReadAt(p, off)
+	}
+
+	if off != f.srcOffset {
+		if _, err := f.src.Seek(off, io.SeekStart); err != nil {
+			return 0, err
+		}
+		f.srcOffset = off
+	}
+
+	n, err := io.ReadFull(f.src, p)
+	f.srcOffset += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (f *file) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.src != nil {
+		return f.src.Close()
+	}
+	return nil
+}
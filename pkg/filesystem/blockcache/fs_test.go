@@ -0,0 +1,228 @@
+package blockcache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/compress"
+	"github.com/octohelm/unifs/pkg/filesystem/crypt"
+	"github.com/octohelm/unifs/pkg/filesystem/local"
+	"github.com/octohelm/unifs/pkg/filesystem/otel"
+	"github.com/octohelm/unifs/pkg/filesystem/testutil"
+	"github.com/octohelm/unifs/pkg/units"
+)
+
+func TestBlockCache(t *testing.T) {
+	t.Run("Simple", func(t *testing.T) {
+		c, err := NewCache(t.TempDir(), 0, 4*units.KiB)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		testutil.TestSimpleFS(t, Wrap(filesystem.NewMemFS(), c))
+	})
+
+	t.Run("Symlink", func(t *testing.T) {
+		c, err := NewCache(t.TempDir(), 0, 4*units.KiB)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		testutil.TestSymlink(t, Wrap(local.NewFS(t.TempDir()), c))
+	})
+
+	t.Run("SetAttr", func(t *testing.T) {
+		c, err := NewCache(t.TempDir(), 0, 4*units.KiB)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		testutil.TestSetAttr(t, Wrap(local.NewFS(t.TempDir()), c))
+	})
+
+	t.Run("forward optional interfaces", func(t *testing.T) {
+		c, err := NewCache(t.TempDir(), 0, 4*units.KiB)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		fsys := Wrap(local.NewFS(t.TempDir()), c)
+
+		_, ok := fsys.(filesystem.Locker)
+		testingx.Expect(t, ok, testingx.Be(true))
+		_, ok = fsys.(filesystem.Hasher)
+		testingx.Expect(t, ok, testingx.Be(true))
+		_, ok = fsys.(filesystem.Watcher)
+		testingx.Expect(t, ok, testingx.Be(true))
+		_, ok = fsys.(filesystem.PrefixLister)
+		testingx.Expect(t, ok, testingx.Be(true))
+		_, ok = fsys.(filesystem.Copier)
+		testingx.Expect(t, ok, testingx.Be(true))
+
+		info, err := filesystem.Lock(context.Background(), fsys, "/1.txt", filesystem.LockOptions{})
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, info.Name, testingx.Be("/1.txt"))
+	})
+
+	t.Run("stacked over wrappers without ReaderAt", func(t *testing.T) {
+		ctx := context.Background()
+		key := bytes.Repeat([]byte("k"), 32)
+
+		data := bytes.Repeat([]byte("0123456789"), 10000)
+
+		for name, wrap := range map[string]func(fsys filesystem.FileSystem) filesystem.FileSystem{
+			"retry": func(fsys filesystem.FileSystem) filesystem.FileSystem {
+				return filesystem.Retry(fsys)
+			},
+			"otel": func(fsys filesystem.FileSystem) filesystem.FileSystem {
+				return otel.Wrap(ctx, fsys, "mem")
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				for _, cached := range []bool{false, true} {
+					fsys := wrap(filesystem.NewMemFS())
+
+					if cached {
+						c, err := NewCache(t.TempDir(), 0, 4*units.KiB)
+						testingx.Expect(t, err, testingx.Be[error](nil))
+						fsys = Wrap(fsys, c)
+					}
+
+					fsys, err := crypt.Wrap(fsys, key)
+					testingx.Expect(t, err, testingx.Be[error](nil))
+
+					fsys = compress.Wrap(fsys, compress.WithAlgorithm(compress.Zstd))
+
+					err = filesystem.Write(ctx, fsys, "/data.txt", data)
+					testingx.Expect(t, err, testingx.Be[error](nil))
+
+					info, err := fsys.Stat(ctx, "/data.txt")
+					testingx.Expect(t, err, testingx.Be[error](nil))
+					testingx.Expect(t, info.Size(), testingx.Be(int64(len(data))))
+
+					f, err := filesystem.Open(ctx, fsys, "/data.txt")
+					testingx.Expect(t, err, testingx.Be[error](nil))
+
+					all, err := io.ReadAll(f)
+					_ = f.Close()
+					testingx.Expect(t, err, testingx.Be[error](nil))
+					testingx.Expect(t, all, testingx.Equal(data))
+				}
+			})
+		}
+	})
+
+	t.Run("read through cache", func(t *testing.T) {
+		ctx := context.Background()
+		dir := t.TempDir()
+
+		c, err := NewCache(dir, 8*units.KiB, 1*units.KiB)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		source := filesystem.NewMemFS()
+		fsys := Wrap(source, c)
+
+		data := bytes.Repeat([]byte("0123456789"), 1000)
+		err = filesystem.Write(ctx, fsys, "/data.txt", data)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		f, err := fsys.OpenFile(ctx, "/data.txt", os.O_RDONLY, 0)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		p := make([]byte, 100)
+		n, err := f.(io.ReaderAt).ReadAt(p, 1000)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, string(p[:n]), testingx.Be(string(data[1000:1100])))
+
+		all, err := io.ReadAll(f)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, all, testingx.Equal(data))
+		_ = f.Close()
+
+		t.Run("should evict when over max size", func(t *testing.T) {
+			testingx.Expect(t, c.size <= 8*1024, testingx.Be(true))
+			testingx.Expect(t, c.lru.Len(), testingx.Be(8))
+		})
+
+		t.Run("should reuse blocks in dir", func(t *testing.T) {
+			c2, err := NewCache(dir, 8*units.KiB, 1*units.KiB)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, c2.lru.Len(), testingx.Be(8))
+		})
+
+		t.Run("should read new content after written", func(t *testing.T) {
+			err = filesystem.Write(ctx, fsys, "/data.txt", []byte("new"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			f, err := fsys.OpenFile(ctx, "/data.txt", os.O_RDONLY, 0)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			defer f.Close()
+
+			all, err := io.ReadAll(f)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, string(all), testingx.Be("new"))
+		})
+	})
+
+	t.Run("backends share one root", func(t *testing.T) {
+		ctx := context.Background()
+		root := t.TempDir()
+		mtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		read := func(t *testing.T, id string, content string) string {
+			dir := t.TempDir()
+
+			err := os.WriteFile(filepath.Join(dir, "data.txt"), []byte(content), 0o644)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			// same path, size and mtime, blocks keyed by them only
+			err = os.Chtimes(filepath.Join(dir, "data.txt"), mtime, mtime)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			c, err := NewCache(Dir(root, id), 0, 1*units.KiB)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			f, err := filesystem.Open(ctx, Wrap(local.NewFS(dir), c), "/data.txt")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			defer f.Close()
+
+			data, err := io.ReadAll(f)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			return string(data)
+		}
+
+		testingx.Expect(t, read(t, "a", "aaaa"), testingx.Be("aaaa"))
+		testingx.Expect(t, read(t, "b", "bbbb"), testingx.Be("bbbb"))
+		testingx.Expect(t, Dir(root, "a") == Dir(root, "b"), testingx.Be(false))
+	})
+
+	t.Run("drop blocks of files under dir", func(t *testing.T) {
+		ctx := context.Background()
+
+		c, err := NewCache(t.TempDir(), 0, 1*units.KiB)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		fsys := Wrap(filesystem.NewMemFS(), c)
+
+		err = filesystem.MkdirAll(ctx, fsys, "/dir/sub")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		for _, name := range []string{"/dir/a.txt", "/dir/sub/b.txt", "/other.txt"} {
+			err := filesystem.Write(ctx, fsys, name, []byte(name))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			f, err := filesystem.Open(ctx, fsys, name)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			_, err = io.ReadAll(f)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			_ = f.Close()
+		}
+		testingx.Expect(t, c.lru.Len(), testingx.Be(3))
+
+		err = fsys.Rename(ctx, "/dir", "/moved")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, c.lru.Len(), testingx.Be(1))
+
+		c.Invalidate("/")
+		testingx.Expect(t, c.lru.Len(), testingx.Be(0))
+	})
+}
@@ -0,0 +1,83 @@
+package filesystem
+
+import (
+	"strings"
+)
+
+// Capability flags optional behaviours of a FileSystem
+type Capability uint64
+
+const (
+	// CapAppend supports OpenFile with os.O_APPEND
+	CapAppend Capability = 1 << iota
+	// CapRandomAccessWrite supports Seek before Write or io.WriterAt on opened files
+	CapRandomAccessWrite
+	// CapAtomicRename supports Rename without copy
+	CapAtomicRename
+	// CapServerSideCopy supports Copy without streaming content through the client
+	CapServerSideCopy
+	// CapReaderAt supports io.ReaderAt on files opened for read
+	CapReaderAt
+	// CapSetModTime supports changing modification time
+	CapSetModTime
+	// CapSetMode supports changing permission bits
+	CapSetMode
+	// CapTruncate supports FileTruncator on opened files
+	CapTruncate
+	// CapAtomicWrite supports OpenFile with O_ATOMIC
+	CapAtomicWrite
+	// CapSymlink supports Linker to create symbolic links
+	CapSymlink
+	// CapChown supports Chowner to change owner
+	CapChown
+)
+
+var capabilityNames = []struct {
+	c    Capability
+	name string
+}{
+	{CapAppend, "append"},
+	{CapRandomAccessWrite, "random-access-write"},
+	{CapAtomicRename, "atomic-rename"},
+	{CapServerSideCopy, "server-side-copy"},
+	{CapReaderAt, "reader-at"},
+	{CapSetModTime, "set-mod-time"},
+	{CapSetMode, "set-mode"},
+	{CapTruncate, "truncate"},
+	{CapAtomicWrite, "atomic-write"},
+	{CapSymlink, "symlink"},
+	{CapChown, "chown"},
+}
+
+func (c Capability) Has(caps Capability) bool {
+	return c&caps == caps
+}
+
+func (c Capability) String() string {
+	names := make([]string, 0, len(capabilityNames))
+	for _, n := range capabilityNames {
+		if c.Has(n.c) {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Capabilities could be implemented by FileSystem to report what it supports
+type Capabilities interface {
+	Capabilities() Capability
+}
+
+// CapabilitiesOf returns capabilities of fsys.
+// FileSystem without Capabilities is treated as supporting nothing optional.
+func CapabilitiesOf(fsys FileSystem) Capability {
+	if c, ok := fsys.(Capabilities); ok {
+		return c.Capabilities()
+	}
+	return 0
+}
+
+// HasCapabilities checks fsys supports all of caps
+func HasCapabilities(fsys FileSystem, caps Capability) bool {
+	return CapabilitiesOf(fsys).Has(caps)
+}
@@ -0,0 +1,34 @@
+package compress
+
+import (
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+type Config struct {
+	// Compress files written by zstd or gzip, compression enabled when set
+	Algorithm string `flag:"algorithm,omitzero"`
+	// Patterns of file names to store as it is, defaults to common compressed formats like *.gz, *.zip, *.jpg
+	Exclude []string `flag:"exclude,omitzero"`
+}
+
+// Apply wraps fsys with compression, fsys returned directly when no algorithm set
+func (c *Config) Apply(fsys filesystem.FileSystem) (filesystem.FileSystem, error) {
+	if c.Algorithm == "" {
+		return fsys, nil
+	}
+
+	algorithm, err := ParseAlgorithm(c.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []Option{
+		WithAlgorithm(algorithm),
+	}
+
+	if len(c.Exclude) > 0 {
+		opts = append(opts, WithExclude(c.Exclude...))
+	}
+
+	return Wrap(fsys, opts...), nil
+}
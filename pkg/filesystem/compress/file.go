@@ -0,0 +1,326 @@
+package compress
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+type reader struct {
+	filesystem.File
+
+	name string
+
+	inited     bool
+	compressed bool
+	algorithm  Algorithm
+	// size of underlying file
+	size int64
+	// uncompressed size, -1 means unknown
+	plainSize int64
+
+	dec io.ReadCloser
+	// offset of decompressed stream
+	pos int64
+	// offset to read
+	offset int64
+}
+
+func (r *reader) init() error {
+	if r.inited {
+		return nil
+	}
+	r.inited = true
+	r.plainSize = -1
+
+	info, err := r.File.Stat()
+	if err != nil {
+		return err
+	}
+	r.size = info.Size()
+
+	if r.size < int64(headerSize+footerSize) {
+		return nil
+	}
+
+	h := make([]byte, headerSize)
+	n, err := io.ReadFull(r.File, h)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+
+	if a, ok := parseHeader(h[:n]); ok {
+		r.compressed = true
+		r.algorithm = a
+		r.pos = -1
+		return nil
+	}
+
+	// not compressed, read as it is
+	_, err = r.File.Seek(0, io.SeekStart)
+	return err
+}
+
+// reset restarts decompression from the beginning
+func (r *reader) reset() error {
+	if r.dec != nil {
+		_ = r.dec.Close()
+		r.dec = nil
+	}
+
+	if _, err := r.File.Seek(int64(headerSize), io.SeekStart); err != nil {
+		return err
+	}
+
+	dec, err := r.algorithm.newReader(io.LimitReader(r.File, r.size-int64(headerSize+footerSize)))
+	if err != nil {
+		return &os.PathError{Op: "read", Path: r.name, Err: err}
+	}
+
+	r.dec = dec
+	r.pos = 0
+	return nil
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if err := r.init(); err != nil {
+		return 0, err
+	}
+
+	if !r.compressed {
+		return r.File.Read(p)
+	}
+
+	if r.dec == nil || r.offset < r.pos {
+		if err := r.reset(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.offset > r.pos {
+		n, err := io.CopyN(io.Discard, r.dec, r.offset-r.pos)
+		r.pos += n
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.dec.Read(p)
+	r.pos += int64(n)
+	r.offset = r.pos
+	return n, err
+}
+
+func (r *reader) Seek(offset int64, whence int) (int64, error) {
+	if err := r.init(); err != nil {
+		return 0, err
+	}
+
+	if !r.compressed {
+		return r.File.Seek(offset, whence)
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		size, err := r.uncompressedSize()
+		if err != nil {
+			return 0, err
+		}
+		offset += size
+	default:
+		return 0, &os.PathError{Op: "seek", Path: r.name, Err: os.ErrInvalid}
+	}
+
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: r.name, Err: os.ErrInvalid}
+	}
+
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *reader) uncompressedSize() (int64, error) {
+	if r.plainSize >= 0 {
+		return r.plainSize, nil
+	}
+
+	size, ok, err := readFooter(r.File, r.size)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, &os.PathError{Op: "read", Path: r.name, Err: errors.New("invalid compressed file")}
+	}
+
+	// underlying offset moved, restart when next read
+	if r.dec != nil {
+		_ = r.dec.Close()
+		r.dec = nil
+	}
+
+	r.plainSize = size
+	return size, nil
+}
+
+func (r *reader) Stat() (os.FileInfo, error) {
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+
+	info, err := r.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.compressed {
+		return info, nil
+	}
+
+	size, err := r.uncompressedSize()
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileInfo{FileInfo: info, size: size}, nil
+}
+
+func (r *reader) Close() error {
+	if r.dec != nil {
+		_ = r.dec.Close()
+	}
+	return r.File.Close()
+}
+
+// writer writes header before first write, and footer when closing
+type writer struct {
+	filesystem.File
+
+	name      string
+	algorithm Algorithm
+
+	// open opens File with O_TRUNC for existing file on first write, info is the stat of it before opened
+	open func() (filesystem.File, error)
+	info os.FileInfo
+
+	enc     io.WriteCloser
+	written int64
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.File == nil {
+		f, err := w.open()
+		if err != nil {
+			return 0, err
+		}
+		w.File = f
+	}
+
+	if w.enc == nil {
+		if _, err := w.File.Write(header(w.algorithm)); err != nil {
+			return 0, err
+		}
+
+		enc, err := w.algorithm.newWriter(w.File)
+		if err != nil {
+			return 0, err
+		}
+		w.enc = enc
+	}
+
+	n, err := w.enc.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// Seek only supports current position, since compressed stream could not be changed
+func (w *writer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent, io.SeekEnd:
+		offset += w.written
+	}
+
+	if offset != w.written {
+		return 0, &os.PathError{Op: "seek", Path: w.name, Err: errors.ErrUnsupported}
+	}
+	return offset, nil
+}
+
+func (w *writer) Read(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "read", Path: w.name, Err: errors.ErrUnsupported}
+}
+
+func (w *writer) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: w.name, Err: errors.ErrUnsupported}
+}
+
+func (w *writer) Stat() (os.FileInfo, error) {
+	if w.File == nil {
+		return w.info, nil
+	}
+
+	info, err := w.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{FileInfo: info, size: w.written}, nil
+}
+
+// Sync syncs compressed data flushed only, the rest will be written when closing
+func (w *writer) Sync() error {
+	if w.File == nil {
+		return nil
+	}
+	if s, ok := w.File.(filesystem.FileSyncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+func (w *writer) Close() error {
+	// existing file keeps its content when nothing written
+	if w.File == nil {
+		return nil
+	}
+
+	// empty file keeps empty
+	if w.enc != nil {
+		if err := w.enc.Close(); err != nil {
+			_ = w.File.Close()
+			return err
+		}
+
+		if _, err := w.File.Write(footer(w.written)); err != nil {
+			_ = w.File.Close()
+			return err
+		}
+	}
+	return w.File.Close()
+}
+
+// Abort drops data buffered by encoder
+func (w *writer) Abort(err error) error {
+	if w.File == nil {
+		return nil
+	}
+
+	a, ok := w.File.(filesystem.FileAborter)
+	if !ok {
+		return &os.PathError{Op: "abort", Path: w.name, Err: errors.ErrUnsupported}
+	}
+
+	if err := a.Abort(err); err != nil {
+		return err
+	}
+
+	// release encoder, flushing to aborted file fails anyway
+	if w.enc != nil {
+		_ = w.enc.Close()
+	}
+	return nil
+}
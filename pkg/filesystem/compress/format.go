@@ -0,0 +1,111 @@
+package compress
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressed file layout:
+//
+//	header: magic (8 bytes) | algorithm (1 byte)
+//	body:   compressed stream
+//	footer: uncompressed size (8 bytes, big endian) | magic (8 bytes)
+//
+// footer written after the whole stream, so could be written without seeking back,
+// and uncompressed size could be read by one range read.
+// files without magic will be read as it is.
+const (
+	magic      = "UNIFSZ\x00\x01"
+	headerSize = len(magic) + 1
+	footerSize = 8 + len(magic)
+)
+
+// Algorithm of compression
+type Algorithm string
+
+const (
+	Zstd Algorithm = "zstd"
+	Gzip Algorithm = "gzip"
+)
+
+var algorithms = []Algorithm{Zstd, Gzip}
+
+func ParseAlgorithm(s string) (Algorithm, error) {
+	for _, a := range algorithms {
+		if string(a) == s {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported compression algorithm %q, should be one of %v", s, algorithms)
+}
+
+func (a Algorithm) id() byte {
+	switch a {
+	case Gzip:
+		return 2
+	default:
+		return 1
+	}
+}
+
+func algorithmOf(id byte) (Algorithm, bool) {
+	switch id {
+	case 1:
+		return Zstd, true
+	case 2:
+		return Gzip, true
+	}
+	return "", false
+}
+
+func (a Algorithm) newWriter(w io.Writer) (io.WriteCloser, error) {
+	switch a {
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	default:
+		return zstd.NewWriter(w)
+	}
+}
+
+func (a Algorithm) newReader(r io.Reader) (io.ReadCloser, error) {
+	switch a {
+	case Gzip:
+		return gzip.NewReader(r)
+	default:
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	}
+}
+
+func header(a Algorithm) []byte {
+	return append([]byte(magic), a.id())
+}
+
+// parseHeader returns algorithm of compressed file, false when not compressed
+func parseHeader(h []byte) (Algorithm, bool) {
+	if len(h) != headerSize || string(h[:len(magic)]) != magic {
+		return "", false
+	}
+	return algorithmOf(h[len(magic)])
+}
+
+func footer(size int64) []byte {
+	f := make([]byte, 8, footerSize)
+	binary.BigEndian.PutUint64(f, uint64(size))
+	return append(f, magic...)
+}
+
+// parseFooter returns uncompressed size, false when not compressed
+func parseFooter(f []byte) (int64, bool) {
+	if len(f) != footerSize || string(f[8:]) != magic {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(f[:8])), true
+}
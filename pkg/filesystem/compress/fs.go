@@ -0,0 +1,357 @@
+package compress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/webdav"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+// DefaultExclude patterns of already compressed formats
+var DefaultExclude = []string{
+	"*.gz", "*.tgz", "*.zst", "*.bz2", "*.xz", "*.lz4", "*.br", "*.zip", "*.7z", "*.rar",
+	"*.jpg", "*.jpeg", "*.png", "*.gif", "*.webp",
+	"*.mp3", "*.mp4", "*.mkv", "*.mov", "*.webm",
+}
+
+type Option func(c *compressFS)
+
+// WithAlgorithm sets algorithm for writing, zstd by default.
+// files compressed by any supported algorithm could be read.
+func WithAlgorithm(algorithm Algorithm) Option {
+	return func(c *compressFS) {
+		c.algorithm = algorithm
+	}
+}
+
+// WithExclude sets patterns of base names to write as it is, matched case-insensitively, DefaultExclude by default
+func WithExclude(patterns ...string) Option {
+	return func(c *compressFS) {
+		c.exclude = patterns
+	}
+}
+
+// Wrap compresses contents of files written to fsys, and decompresses contents read.
+//
+// uncompressed size recorded in footer, so Stat reports uncompressed sizes, but costs one more read of each file,
+// which cached until size or mtime of the file changed.
+// files could only be written from the beginning, append and random access write are not supported,
+// seek backward of files read will restart decompression from the beginning.
+func Wrap(fsys filesystem.FileSystem, opts ...Option) filesystem.FileSystem {
+	c := &compressFS{
+		Forwarder: filesystem.Forwarder{FileSystem: fsys},
+		algorithm: Zstd,
+		exclude:   DefaultExclude,
+		sizes:     map[string]*sizeEntry{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type compressFS struct {
+	filesystem.Forwarder
+
+	algorithm Algorithm
+	exclude   []string
+
+	mu    sync.Mutex
+	sizes map[string]*sizeEntry
+}
+
+func (c *compressFS) Capabilities() filesystem.Capability {
+	return filesystem.CapabilitiesOf(c.FileSystem) &^ (filesystem.CapAppend | filesystem.CapRandomAccessWrite | filesystem.CapTruncate | filesystem.CapReaderAt)
+}
+
+func (c *compressFS) excluded(name string) bool {
+	base := strings.ToLower(path.Base(name))
+	for _, pattern := range c.exclude {
+		if ok, _ := path.Match(strings.ToLower(pattern), base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *compressFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (filesystem.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		c.forgetSize(name)
+
+		if c.excluded(name) {
+			return c.FileSystem.OpenFile(ctx, name, flag, perm)
+		}
+
+		if flag&os.O_APPEND != 0 {
+			return nil, &os.PathError{Op: "open", Path: name, Err: errors.ErrUnsupported}
+		}
+
+		// always rewrite whole file, since compressed stream could not be changed partially
+		flag = (flag &^ os.O_RDWR) | os.O_WRONLY
+
+		if flag&os.O_TRUNC == 0 {
+			// existing file keeps its content until written
+			if info, err := c.Stat(ctx, name); err == nil && !info.IsDir() && info.Size() > 0 {
+				return &writer{name: name, algorithm: c.algorithm, info: info, open: func() (filesystem.File, error) {
+					return c.FileSystem.OpenFile(ctx, name, flag|os.O_TRUNC, perm)
+				}}, nil
+			}
+		}
+
+		f, err := c.FileSystem.OpenFile(ctx, name, flag|os.O_TRUNC, perm)
+		if err != nil {
+			return nil, err
+		}
+
+		return &writer{File: f, name: name, algorithm: c.algorithm}, nil
+	}
+
+	f, err := c.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return &dir{File: f, ctx: ctx, c: c, name: name}, nil
+	}
+
+	// always detect, files could be renamed from names not excluded
+	return &reader{File: f, name: name}, nil
+}
+
+// Hash streams uncompressed content, since hashes of backend are of compressed content
+func (c *compressFS) Hash(ctx context.Context, name string, algo filesystem.HashAlgorithm) (string, error) {
+	return "", errors.ErrUnsupported
+}
+
+// ListPrefix forwards with uncompressed sizes, which costs one more read of each file not cached as Stat
+func (c *compressFS) ListPrefix(ctx context.Context, prefix string) iter.Seq2[*filesystem.FileEntry, error] {
+	return func(yield func(*filesystem.FileEntry, error) bool) {
+		for e, err := range c.Forwarder.ListPrefix(ctx, prefix) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			info, err := c.statInfo(ctx, e.Path, e.FileInfo)
+			if err != nil {
+				// removed after listed
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				yield(nil, err)
+				return
+			}
+
+			if !yield(&filesystem.FileEntry{Path: e.Path, FileInfo: info}, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (c *compressFS) Lstat(ctx context.Context, name string) (os.FileInfo, error) {
+	info, err := c.Forwarder.Lstat(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return c.statInfo(ctx, name, info)
+}
+
+func (c *compressFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	info, err := c.FileSystem.Stat(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return c.statInfo(ctx, name, info)
+}
+
+// statInfo returns info with uncompressed size
+func (c *compressFS) statInfo(ctx context.Context, name string, info os.FileInfo) (os.FileInfo, error) {
+	if info.IsDir() || filesystem.IsSymlink(info) || c.excluded(name) || info.Size() < int64(headerSize+footerSize) {
+		return info, nil
+	}
+
+	if size, ok := c.cachedSize(ctx, name, info); ok {
+		if size < 0 {
+			return info, nil
+		}
+		return &fileInfo{FileInfo: info, size: size}, nil
+	}
+
+	f, err := c.FileSystem.OpenFile(ctx, name, os.O_RDONLY, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	size, ok, err := readFooter(f, info.Size())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		c.cacheSize(ctx, name, info, -1)
+		return info, nil
+	}
+
+	c.cacheSize(ctx, name, info, size)
+
+	return &fileInfo{FileInfo: info, size: size}, nil
+}
+
+// maxCachedSizes limits entries of cached sizes, about 100 bytes each
+const maxCachedSizes = 100000
+
+// sizeEntry of uncompressed size, which is -1 when not compressed
+type sizeEntry struct {
+	size    int64
+	version string
+}
+
+// versionOf identifies content of info, by ETag when provided,
+// since mtime could be of seconds or minutes for backends like ftp.
+func versionOf(ctx context.Context, info os.FileInfo) string {
+	v := fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+	if etag := filesystem.ETagOf(ctx, info); etag != "" {
+		v += "-" + etag
+	}
+	return v
+}
+
+func (c *compressFS) cachedSize(ctx context.Context, name string, info os.FileInfo) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.sizes[name]
+	if !ok || e.version != versionOf(ctx, info) {
+		return 0, false
+	}
+	return e.size, true
+}
+
+func (c *compressFS) cacheSize(ctx context.Context, name string, info os.FileInfo, size int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.sizes[name]; !ok && len(c.sizes) >= maxCachedSizes {
+		// drop any one, entries of files changed could not be known
+		for n := range c.sizes {
+			delete(c.sizes, n)
+			break
+		}
+	}
+
+	c.sizes[name] = &sizeEntry{size: size, version: versionOf(ctx, info)}
+}
+
+func (c *compressFS) forgetSize(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.sizes, name)
+}
+
+func readFooter(f filesystem.File, size int64) (int64, bool, error) {
+	if size < int64(headerSize+footerSize) {
+		return 0, false, nil
+	}
+
+	buf := make([]byte, footerSize)
+
+	if r, ok := f.(io.ReaderAt); ok {
+		if _, err := r.ReadAt(buf, size-int64(footerSize)); err != nil && !errors.Is(err, io.EOF) {
+			return 0, false, err
+		}
+	} else {
+		if _, err := f.Seek(size-int64(footerSize), io.SeekStart); err != nil {
+			return 0, false, err
+		}
+		if _, err := io.ReadFull(f, buf); err != nil {
+			return 0, false, err
+		}
+	}
+
+	s, ok := parseFooter(buf)
+	return s, ok, nil
+}
+
+type fileInfo struct {
+	os.FileInfo
+	size int64
+}
+
+func (i *fileInfo) UserMetadata(ctx context.Context) (filesystem.UserMetadata, error) {
+	if p, ok := i.FileInfo.(filesystem.UserMetadataProvider); ok {
+		return p.UserMetadata(ctx)
+	}
+	return nil, errors.ErrUnsupported
+}
+
+// ETag forwards ETag of compressed content, which still changes with uncompressed content
+func (i *fileInfo) ETag(ctx context.Context) (string, error) {
+	if e, ok := i.FileInfo.(webdav.ETager); ok {
+		return e.ETag(ctx)
+	}
+	return "", webdav.ErrNotImplemented
+}
+
+func (i *fileInfo) ContentType(ctx context.Context) (string, error) {
+	if c, ok := i.FileInfo.(webdav.ContentTyper); ok {
+		return c.ContentType(ctx)
+	}
+	return "", webdav.ErrNotImplemented
+}
+
+func (i *fileInfo) Size() int64 {
+	return i.size
+}
+
+type dir struct {
+	filesystem.File
+
+	ctx  context.Context
+	c    *compressFS
+	name string
+}
+
+func (d *dir) Readdir(count int) ([]os.FileInfo, error) {
+	for {
+		infos, err := d.File.Readdir(count)
+
+		list := make([]os.FileInfo, 0, len(infos))
+		for _, info := range infos {
+			fi, e := d.c.statInfo(d.ctx, path.Join(d.name, info.Name()), info)
+			if e != nil {
+				// removed after listed
+				if errors.Is(e, fs.ErrNotExist) {
+					continue
+				}
+				return list, e
+			}
+			list = append(list, fi)
+		}
+
+		// all removed, read more to avoid returning empty list before the end
+		if count > 0 && len(list) == 0 && len(infos) > 0 && err == nil {
+			continue
+		}
+
+		return list, err
+	}
+}
@@ -0,0 +1,211 @@
+package compress
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/local"
+	"github.com/octohelm/unifs/pkg/filesystem/testutil"
+)
+
+func TestCompress(t *testing.T) {
+	for _, algorithm := range algorithms {
+		t.Run("Simple "+string(algorithm), func(t *testing.T) {
+			testutil.TestSimpleFS(t, Wrap(filesystem.NewMemFS(), WithAlgorithm(algorithm)))
+		})
+	}
+
+	t.Run("Simple on local", func(t *testing.T) {
+		testutil.TestSimpleFS(t, Wrap(local.NewFS(t.TempDir())))
+	})
+
+	ctx := context.Background()
+
+	data := []byte(strings.Repeat("2006-01-02 15:04:05 INFO something happened\n", 10000))
+
+	read := func(t *testing.T, fsys filesystem.FileSystem, name string) []byte {
+		f, err := filesystem.Open(ctx, fsys, name)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		defer f.Close()
+
+		d, err := io.ReadAll(f)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		return d
+	}
+
+	t.Run("compressed with uncompressed size", func(t *testing.T) {
+		memfs := filesystem.NewMemFS()
+		fsys := Wrap(memfs, WithAlgorithm(Gzip))
+
+		err := filesystem.Write(ctx, fsys, "/app.log", data)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		raw, err := memfs.Stat(ctx, "/app.log")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, raw.Size() < int64(len(data))/10, testingx.Be(true))
+
+		info, err := fsys.Stat(ctx, "/app.log")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, info.Size(), testingx.Be(int64(len(data))))
+
+		entries, err := filesystem.ReadDir(ctx, fsys, "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		entryInfo, _ := entries[0].Info()
+		testingx.Expect(t, entryInfo.Size(), testingx.Be(int64(len(data))))
+
+		testingx.Expect(t, bytes.Equal(read(t, fsys, "/app.log"), data), testingx.Be(true))
+	})
+
+	t.Run("list prefix with uncompressed sizes", func(t *testing.T) {
+		mux := filesystem.NewMux()
+		_ = mux.Mount("/", filesystem.NewMemFS())
+		fsys := Wrap(mux)
+
+		_ = filesystem.MkdirAll(ctx, fsys, "/logs")
+		_ = filesystem.Write(ctx, fsys, "/logs/app.log", data)
+
+		for e, err := range filesystem.ListPrefix(ctx, fsys, "/logs/") {
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, e.Path, testingx.Be("/logs/app.log"))
+			testingx.Expect(t, e.Size(), testingx.Be(int64(len(data))))
+		}
+	})
+
+	t.Run("uncompressed sizes cached until changed", func(t *testing.T) {
+		source := &openCountingFS{Forwarder: filesystem.Forwarder{FileSystem: filesystem.NewMemFS()}}
+		fsys := Wrap(source)
+
+		_ = filesystem.Write(ctx, fsys, "/app.log", data)
+
+		_, err := filesystem.ReadDir(ctx, fsys, "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		n := source.count.Load()
+
+		for range 2 {
+			entries, err := filesystem.ReadDir(ctx, fsys, "/")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			entryInfo, _ := entries[0].Info()
+			testingx.Expect(t, entryInfo.Size(), testingx.Be(int64(len(data))))
+
+			info, err := fsys.Stat(ctx, "/app.log")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, info.Size(), testingx.Be(int64(len(data))))
+		}
+
+		// only opened for listing
+		testingx.Expect(t, source.count.Load(), testingx.Be(n+2))
+
+		t.Run("changed by others", func(t *testing.T) {
+			_ = filesystem.Write(ctx, Wrap(source.FileSystem), "/app.log", data[:100])
+
+			info, err := fsys.Stat(ctx, "/app.log")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, info.Size(), testingx.Be(int64(100)))
+		})
+	})
+
+	t.Run("excluded as it is", func(t *testing.T) {
+		memfs := filesystem.NewMemFS()
+		fsys := Wrap(memfs)
+
+		err := filesystem.Write(ctx, fsys, "/app.log.GZ", data)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		testingx.Expect(t, bytes.Equal(read(t, memfs, "/app.log.GZ"), data), testingx.Be(true))
+	})
+
+	t.Run("not compressed read as it is", func(t *testing.T) {
+		memfs := filesystem.NewMemFS()
+		_ = filesystem.Write(ctx, memfs, "/app.log", data)
+
+		fsys := Wrap(memfs)
+
+		info, err := fsys.Stat(ctx, "/app.log")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, info.Size(), testingx.Be(int64(len(data))))
+		testingx.Expect(t, bytes.Equal(read(t, fsys, "/app.log"), data), testingx.Be(true))
+	})
+
+	t.Run("seek", func(t *testing.T) {
+		fsys := Wrap(filesystem.NewMemFS())
+		_ = filesystem.Write(ctx, fsys, "/app.log", data)
+
+		f, err := filesystem.Open(ctx, fsys, "/app.log")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		defer f.Close()
+
+		for _, off := range []int64{1000, 100, 200000} {
+			_, err = f.Seek(off, io.SeekStart)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			p := make([]byte, 50)
+			_, err = io.ReadFull(f, p)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, string(p), testingx.Be(string(data[off:off+50])))
+		}
+
+		end, err := f.Seek(0, io.SeekEnd)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, end, testingx.Be(int64(len(data))))
+	})
+
+	t.Run("open existing for write", func(t *testing.T) {
+		fsys := Wrap(filesystem.NewMemFS())
+		_ = filesystem.Write(ctx, fsys, "/app.log", data)
+
+		t.Run("should keep content when nothing written", func(t *testing.T) {
+			f, err := fsys.OpenFile(ctx, "/app.log", os.O_WRONLY, os.ModePerm)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			info, err := f.Stat()
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, info.Size(), testingx.Be(int64(len(data))))
+
+			err = f.Close()
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			testingx.Expect(t, bytes.Equal(read(t, fsys, "/app.log"), data), testingx.Be(true))
+		})
+
+		t.Run("should rewrite whole file when written", func(t *testing.T) {
+			f, err := fsys.OpenFile(ctx, "/app.log", os.O_RDWR, os.ModePerm)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			_, err = f.Write([]byte("new"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			err = f.Close()
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			testingx.Expect(t, string(read(t, fsys, "/app.log")), testingx.Be("new"))
+		})
+	})
+
+	t.Run("append unsupported", func(t *testing.T) {
+		fsys := Wrap(filesystem.NewMemFS())
+
+		_, err := fsys.OpenFile(ctx, "/app.log", os.O_WRONLY|os.O_CREATE|os.O_APPEND, os.ModePerm)
+		testingx.Expect(t, errors.Is(err, errors.ErrUnsupported), testingx.Be(true))
+	})
+}
+
+// openCountingFS counts OpenFile calls
+type openCountingFS struct {
+	filesystem.Forwarder
+	count atomic.Int64
+}
+
+func (fs *openCountingFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (filesystem.File, error) {
+	fs.count.Add(1)
+	return fs.FileSystem.OpenFile(ctx, name, flag, perm)
+}
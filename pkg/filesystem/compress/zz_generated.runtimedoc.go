@@ -0,0 +1,48 @@
+/*
+Package compress GENERATED BY gengo:runtimedoc
+DON'T EDIT THIS FILE
+*/
+package compress
+
+func (*Algorithm) RuntimeDoc(names ...string) ([]string, bool) {
+	return []string{
+		"Algorithm of compression",
+	}, true
+}
+
+func (v *Config) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "Algorithm":
+			return []string{
+				"Compress files written by zstd or gzip, compression enabled when set",
+			}, true
+		case "Exclude":
+			return []string{
+				"Patterns of file names to store as it is, defaults to common compressed formats like *.gz, *.zip, *.jpg",
+			}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{}, true
+}
+
+// nolint:deadcode,unused
+func runtimeDoc(v any, prefix string, names ...string) ([]string, bool) {
+	if c, ok := v.(interface {
+		RuntimeDoc(names ...string) ([]string, bool)
+	}); ok {
+		doc, ok := c.RuntimeDoc(names...)
+		if ok {
+			if prefix != "" && len(doc) > 0 {
+				doc[0] = prefix + doc[0]
+				return doc, true
+			}
+
+			return doc, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,140 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// Copier could be implemented by FileSystem to copy without streaming content through the client.
+//
+// newName will be replaced if exists.
+// when oldName is a directory, only the directory itself is created unless recursive.
+// errors.ErrUnsupported could be returned to fall back to streaming copy.
+type Copier interface {
+	Copy(ctx context.Context, oldName, newName string, recursive bool) error
+}
+
+// Copy copies src of srcFS to dst of dstFS.
+// Copier of dstFS will be used when srcFS and dstFS are the same one,
+// otherwise content will be streamed.
+func Copy(ctx context.Context, srcFS FileSystem, src string, dstFS FileSystem, dst string, recursive bool) error {
+	if isSameFileSystem(srcFS, dstFS) {
+		src, dst = path.Clean(src), path.Clean(dst)
+
+		if src == dst {
+			return nil
+		}
+
+		//  /x could not cp to its child path like /x/a/b/x
+		if src == "/" || strings.HasPrefix(dst, src+"/") {
+			return &os.LinkError{
+				Op:  "copy",
+				Old: src,
+				New: dst,
+				Err: os.ErrInvalid,
+			}
+		}
+
+		if c, ok := dstFS.(Copier); ok {
+			if err := c.Copy(ctx, src, dst, recursive); !errors.Is(err, errors.ErrUnsupported) {
+				return err
+			}
+		}
+	}
+
+	info, err := srcFS.Stat(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	// files replaced by atomic rename of copyFile, to keep dst until all copied
+	if dstInfo, err := dstFS.Stat(ctx, dst); err == nil && (info.IsDir() || dstInfo.IsDir()) {
+		if err := dstFS.RemoveAll(ctx, dst); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return copyFiles(ctx, srcFS, src, info, dstFS, dst, recursive)
+}
+
+func copyFiles(ctx context.Context, srcFS FileSystem, src string, info FileInfo, dstFS FileSystem, dst string, recursive bool) error {
+	if !info.IsDir() {
+		return copyFile(ctx, srcFS, src, info, dstFS, dst)
+	}
+
+	if err := dstFS.Mkdir(ctx, dst, permOf(info, os.ModePerm)); err != nil {
+		return err
+	}
+
+	if !recursive {
+		return nil
+	}
+
+	children, err := readDirInfos(ctx, srcFS, src)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range children {
+		if err := copyFiles(ctx, srcFS, path.Join(src, c.Name()), c, dstFS, path.Join(dst, c.Name()), recursive); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyFile(ctx context.Context, srcFS FileSystem, src string, info FileInfo, dstFS FileSystem, dst string) error {
+	srcFile, err := Open(ctx, srcFS, src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	// dst only visible when all copied
+	dstFile, err := OpenAtomic(ctx, dstFS, dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, permOf(info, 0o666))
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dstFile, srcFile); err != nil {
+		_ = Abort(dstFile, err)
+		return &fs.PathError{Op: "copy", Path: dst, Err: err}
+	}
+
+	return dstFile.Close()
+}
+
+func readDirInfos(ctx context.Context, fsys FileSystem, name string) ([]FileInfo, error) {
+	f, err := fsys.OpenFile(ctx, name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return f.Readdir(-1)
+}
+
+// permOf returns perm of info, or fallback when backend not provide it.
+func permOf(info FileInfo, fallback os.FileMode) os.FileMode {
+	if perm := info.Mode() & os.ModePerm; perm != 0 {
+		return perm
+	}
+	return fallback
+}
+
+// isSameFileSystem compares identity of a and b, backends wrapped are not compared,
+// since names or contents could be changed by wrappers, like crypt,
+// so different wrappers of the same backend will be copied by streaming.
+func isSameFileSystem(a, b FileSystem) bool {
+	if t := reflect.TypeOf(a); t == nil || t != reflect.TypeOf(b) || !t.Comparable() {
+		return false
+	}
+	return a == b
+}
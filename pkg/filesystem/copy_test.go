@@ -0,0 +1,102 @@
+package filesystem_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/local"
+)
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+
+	src := filesystem.NewMemFS()
+	dst := local.NewFS(t.TempDir())
+
+	err := filesystem.MkdirAll(ctx, src, "/a/b")
+	testingx.Expect(t, err, testingx.Be[error](nil))
+	err = filesystem.Write(ctx, src, "/a/b/c.txt", []byte("c"))
+	testingx.Expect(t, err, testingx.Be[error](nil))
+
+	t.Run("streaming copy between file systems", func(t *testing.T) {
+		err := filesystem.Copy(ctx, src, "/a", dst, "/x", true)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		testingx.Expect(t, readFile(t, dst, "/x/b/c.txt"), testingx.Be("c"))
+	})
+
+	t.Run("native copy in same file system", func(t *testing.T) {
+		err := filesystem.Copy(ctx, dst, "/x", dst, "/y", true)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		testingx.Expect(t, readFile(t, dst, "/y/b/c.txt"), testingx.Be("c"))
+	})
+
+	t.Run("copy to child path should failed", func(t *testing.T) {
+		err := filesystem.Copy(ctx, dst, "/x", dst, "/x/b/x", true)
+		testingx.Expect(t, err, testingx.Not(testingx.Be[error](nil)))
+	})
+
+	t.Run("existing file kept when streaming copy failed", func(t *testing.T) {
+		err := filesystem.Write(ctx, dst, "/kept.txt", []byte("old"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		err = filesystem.Copy(ctx, &readFailedFS{FileSystem: src}, "/a/b/c.txt", dst, "/kept.txt", false)
+		testingx.Expect(t, errors.Is(err, errReadFailed), testingx.Be(true))
+
+		testingx.Expect(t, readFile(t, dst, "/kept.txt"), testingx.Be("old"))
+	})
+
+	t.Run("dir replaced by file", func(t *testing.T) {
+		err := filesystem.Copy(ctx, src, "/a/b/c.txt", dst, "/x", false)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		testingx.Expect(t, readFile(t, dst, "/x"), testingx.Be("c"))
+	})
+
+	t.Run("file replaced by dir", func(t *testing.T) {
+		err := filesystem.Copy(ctx, src, "/a", dst, "/x", true)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		testingx.Expect(t, readFile(t, dst, "/x/b/c.txt"), testingx.Be("c"))
+	})
+}
+
+var errReadFailed = errors.New("read failed")
+
+// readFailedFS fails reads of files
+type readFailedFS struct {
+	filesystem.FileSystem
+}
+
+func (fs *readFailedFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (filesystem.File, error) {
+	f, err := fs.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &readFailedFile{File: f}, nil
+}
+
+type readFailedFile struct {
+	filesystem.File
+}
+
+func (f *readFailedFile) Read(p []byte) (int, error) {
+	return 0, errReadFailed
+}
+
+func readFile(t testing.TB, fsys filesystem.FileSystem, name string) string {
+	f, err := filesystem.Open(context.Background(), fsys, name)
+	testingx.Expect(t, err, testingx.Be[error](nil))
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	testingx.Expect(t, err, testingx.Be[error](nil))
+	return string(data)
+}
@@ -0,0 +1,39 @@
+package crypt
+
+import (
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+type Config struct {
+	// Key of AES-256, 32 bytes in hex or base64, encryption enabled when key or key file set
+	Key string `flag:"key,omitzero,secret"`
+	// File of key
+	KeyFile string `flag:"key-file,omitzero"`
+	// Encrypt names of files and directories too
+	Names bool `flag:"names,omitzero"`
+}
+
+// Apply wraps fsys with encryption, fsys returned directly when no key set
+func (c *Config) Apply(fsys filesystem.FileSystem) (filesystem.FileSystem, error) {
+	if c.Key == "" && c.KeyFile == "" {
+		return fsys, nil
+	}
+
+	var key []byte
+
+	if c.KeyFile != "" {
+		k, err := LoadKey(c.KeyFile, "")
+		if err != nil {
+			return nil, err
+		}
+		key = k
+	} else {
+		k, err := ParseKey([]byte(c.Key))
+		if err != nil {
+			return nil, err
+		}
+		key = k
+	}
+
+	return Wrap(fsys, key, WithNameEncryption(c.Names))
+}
@@ -0,0 +1,398 @@
+package crypt
+
+import (
+	"crypto/cipher"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+type reader struct {
+	filesystem.File
+
+	c    *cryptFS
+	name string
+
+	once    sync.Once
+	aead    cipher.AEAD
+	initErr error
+	// index of the last chunk
+	lastIdx int64
+
+	// plaintext offset
+	offset int64
+	// offset of underlying file
+	pos int64
+
+	chunkIdx int64
+	chunk    []byte
+	sealed   []byte
+}
+
+func (r *reader) init() error {
+	r.once.Do(func() {
+		r.chunkIdx = -1
+
+		header := make([]byte, headerSize)
+
+		var n int
+		var err error
+
+		if ra, ok := r.File.(io.ReaderAt); ok {
+			n, err = ra.ReadAt(header, 0)
+		} else {
+			n, err = r.readFull(0, header)
+		}
+
+		if n != headerSize {
+			if err != nil && !errors.Is(err, io.EOF) {
+				r.initErr = err
+				return
+			}
+			r.initErr = &os.PathError{Op: "read", Path: r.name, Err: ErrInvalidFormat}
+			return
+		}
+
+		aead, err := contentCipher(r.c.key, header)
+		if err != nil {
+			r.initErr = &os.PathError{Op: "read", Path: r.name, Err: err}
+			return
+		}
+
+		info, err := r.File.Stat()
+		if err != nil {
+			r.initErr = err
+			return
+		}
+
+		// file ends without the last chunk
+		r.lastIdx = lastChunk(info.Size())
+		if r.lastIdx < 0 {
+			r.initErr = &os.PathError{Op: "read", Path: r.name, Err: ErrInvalidFormat}
+			return
+		}
+
+		r.aead = aead
+	})
+
+	return r.initErr
+}
+
+// readFull reads from off of underlying file, seek only when not continuous
+func (r *reader) readFull(off int64, p []byte) (int, error) {
+	if off != r.pos {
+		if _, err := r.File.Seek(off, io.SeekStart); err != nil {
+			return 0, err
+		}
+		r.pos = off
+	}
+
+	n, err := io.ReadFull(r.File, p)
+	r.pos += int64(n)
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (r *reader) open(dst []byte, idx int64, sealed []byte) ([]byte, error) {
+	// only the chunk of empty file could be empty
+	if len(sealed) < tagSize || (len(sealed) == tagSize && idx != 0) {
+		return nil, &os.PathError{Op: "read", Path: r.name, Err: ErrInvalidFormat}
+	}
+
+	plain, err := r.aead.Open(dst, chunkNonce(r.aead, idx, idx == r.lastIdx), sealed, nil)
+	if err != nil {
+		return nil, &os.PathError{Op: "read", Path: r.name, Err: ErrInvalidFormat}
+	}
+	return plain, nil
+}
+
+func (r *reader) loadChunk(idx int64) error {
+	if r.sealed == nil {
+		r.sealed = make([]byte, sealedChunkSize)
+	}
+
+	n, err := r.readFull(chunkOffset(idx), r.sealed)
+	if n == 0 {
+		if err == nil {
+			err = io.EOF
+		}
+		return err
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	chunk, err := r.open(r.chunk[:0], idx, r.sealed[:n])
+	if err != nil {
+		return err
+	}
+
+	r.chunk = chunk
+	r.chunkIdx = idx
+	return nil
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if err := r.init(); err != nil {
+		return 0, err
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	idx := r.offset / ChunkSize
+	if idx != r.chunkIdx {
+		if err := r.loadChunk(idx); err != nil {
+			return 0, err
+		}
+	}
+
+	within := int(r.offset % ChunkSize)
+	if within >= len(r.chunk) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, r.chunk[within:])
+	r.offset += int64(n)
+	return n, nil
+}
+
+// readerAt decrypts chunks read at offset of underlying file
+type readerAt struct {
+	*reader
+	ra io.ReaderAt
+}
+
+func (r *readerAt) ReadAt(p []byte, off int64) (int, error) {
+	ra := r.ra
+
+	if off < 0 {
+		return 0, &os.PathError{Op: "readat", Path: r.name, Err: os.ErrInvalid}
+	}
+
+	if err := r.init(); err != nil {
+		return 0, err
+	}
+
+	sealed := make([]byte, sealedChunkSize)
+	chunk := make([]byte, 0, ChunkSize)
+
+	read := 0
+
+	for read < len(p) {
+		idx := off / ChunkSize
+
+		n, err := ra.ReadAt(sealed, chunkOffset(idx))
+		if n == 0 {
+			if err == nil || errors.Is(err, io.EOF) {
+				return read, io.EOF
+			}
+			return read, err
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return read, err
+		}
+
+		plain, err := r.open(chunk[:0], idx, sealed[:n])
+		if err != nil {
+			return read, err
+		}
+
+		within := int(off % ChunkSize)
+		if within >= len(plain) {
+			return read, io.EOF
+		}
+
+		copied := copy(p[read:], plain[within:])
+		read += copied
+		off += int64(copied)
+
+		// last chunk
+		if len(plain) < ChunkSize && read < len(p) {
+			return read, io.EOF
+		}
+	}
+
+	return read, nil
+}
+
+func (r *reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		info, err := r.File.Stat()
+		if err != nil {
+			return 0, err
+		}
+		offset += PlainSize(info.Size())
+	default:
+		return 0, &os.PathError{Op: "seek", Path: r.name, Err: os.ErrInvalid}
+	}
+
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: r.name, Err: os.ErrInvalid}
+	}
+
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *reader) Stat() (os.FileInfo, error) {
+	info, err := r.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{FileInfo: info, name: path.Base(r.name)}, nil
+}
+
+// writer seals chunk once filled and more written, and the last chunk when closing,
+// header and the last chunk are always written, even nothing written.
+type writer struct {
+	filesystem.File
+
+	c    *cryptFS
+	name string
+
+	aead    cipher.AEAD
+	buf     []byte
+	idx     int64
+	written int64
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if err := w.writeHeader(); err != nil {
+		return 0, err
+	}
+
+	written := 0
+
+	for len(p) > 0 {
+		// full chunk is not the last one when more written
+		if len(w.buf) == ChunkSize {
+			if err := w.flush(false); err != nil {
+				return written, err
+			}
+		}
+
+		n := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+
+		written += n
+		w.written += int64(n)
+	}
+
+	return written, nil
+}
+
+func (w *writer) writeHeader() error {
+	if w.aead != nil {
+		return nil
+	}
+
+	header, err := newHeader()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.File.Write(header); err != nil {
+		return err
+	}
+
+	aead, err := contentCipher(w.c.key, header)
+	if err != nil {
+		return err
+	}
+
+	w.aead = aead
+	w.buf = make([]byte, 0, ChunkSize)
+	return nil
+}
+
+// flush seals buffered as chunk, the last chunk is sealed even empty
+func (w *writer) flush(last bool) error {
+	if len(w.buf) == 0 && !last {
+		return nil
+	}
+
+	sealed := w.aead.Seal(nil, chunkNonce(w.aead, w.idx, last), w.buf, nil)
+	if _, err := w.File.Write(sealed); err != nil {
+		return err
+	}
+
+	w.idx++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Seek only supports current position, since chunks written could not be changed
+func (w *writer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent, io.SeekEnd:
+		offset += w.written
+	}
+
+	if offset != w.written {
+		return 0, &os.PathError{Op: "seek", Path: w.name, Err: errors.ErrUnsupported}
+	}
+	return offset, nil
+}
+
+func (w *writer) Read(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "read", Path: w.name, Err: errors.ErrUnsupported}
+}
+
+func (w *writer) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: w.name, Err: errors.ErrUnsupported}
+}
+
+func (w *writer) Stat() (os.FileInfo, error) {
+	info, err := w.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{FileInfo: info, name: path.Base(w.name)}, nil
+}
+
+// Sync syncs sealed chunks only, the last chunk will be sealed when closing
+func (w *writer) Sync() error {
+	if s, ok := w.File.(filesystem.FileSyncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+func (w *writer) Close() error {
+	if err := w.writeHeader(); err != nil {
+		_ = w.File.Close()
+		return err
+	}
+	if err := w.flush(true); err != nil {
+		_ = w.File.Close()
+		return err
+	}
+	return w.File.Close()
+}
+
+// Abort drops the last chunk not sealed
+func (w *writer) Abort(err error) error {
+	if a, ok := w.File.(filesystem.FileAborter); ok {
+		return a.Abort(err)
+	}
+	return &os.PathError{Op: "abort", Path: w.name, Err: errors.ErrUnsupported}
+}
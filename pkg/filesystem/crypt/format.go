@@ -0,0 +1,119 @@
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// Encrypted file layout:
+//
+//	header: magic (8 bytes) | salt (24 bytes)
+//	chunks: AES-256-GCM sealed chunk (up to ChunkSize bytes plaintext + 16 bytes tag) ...
+//
+// key of each file derived from master key and salt by HKDF,
+// so nonce of chunk could be its index, and files could be copied or renamed as it is.
+// like STREAM, nonce of the last chunk is flagged, so files truncated at chunk boundary could be detected.
+// an empty file is the header and a sealed empty last chunk, so files truncated to empty could be detected too.
+const (
+	ChunkSize = 64 * 1024
+
+	magic      = "UNIFSC\x00\x01"
+	saltSize   = 24
+	headerSize = len(magic) + saltSize
+	tagSize    = 16
+
+	sealedChunkSize = ChunkSize + tagSize
+)
+
+var ErrInvalidFormat = errors.New("invalid encrypted file")
+
+// PlainSize returns plaintext size of encrypted file
+func PlainSize(size int64) int64 {
+	if size <= int64(headerSize) {
+		return 0
+	}
+
+	body := size - int64(headerSize)
+	full := body / sealedChunkSize
+	rest := body % sealedChunkSize
+
+	plain := full * ChunkSize
+	if rest > tagSize {
+		plain += rest - tagSize
+	}
+	return plain
+}
+
+// EncryptedSize returns encrypted file size of plaintext size
+func EncryptedSize(size int64) int64 {
+	if size <= 0 {
+		return int64(headerSize) + tagSize
+	}
+
+	full := size / ChunkSize
+	rest := size % ChunkSize
+
+	encrypted := int64(headerSize) + full*sealedChunkSize
+	if rest > 0 {
+		encrypted += rest + tagSize
+	}
+	return encrypted
+}
+
+func newHeader() ([]byte, error) {
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	if _, err := rand.Read(header[len(magic):]); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+func contentCipher(masterKey []byte, header []byte) (cipher.AEAD, error) {
+	if len(header) != headerSize || string(header[:len(magic)]) != magic {
+		return nil, ErrInvalidFormat
+	}
+
+	key, err := hkdf.Key(sha256.New, masterKey, header[len(magic):], "unifs content", KeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	return newGCM(key)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce returns index | last flag (1 byte) as nonce of chunk
+func chunkNonce(aead cipher.AEAD, idx int64, last bool) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-9:], uint64(idx))
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+// lastChunk returns index of the last chunk of encrypted file size, -1 when no chunks
+func lastChunk(size int64) int64 {
+	body := size - int64(headerSize)
+	if body <= 0 {
+		return -1
+	}
+	return (body - 1) / sealedChunkSize
+}
+
+func chunkOffset(idx int64) int64 {
+	return int64(headerSize) + idx*sealedChunkSize
+}
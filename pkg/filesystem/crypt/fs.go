@@ -0,0 +1,387 @@
+package crypt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"os"
+	"path"
+	"time"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+type Option func(c *cryptFS)
+
+// WithNameEncryption encrypts names of files and directories too
+func WithNameEncryption(enabled bool) Option {
+	return func(c *cryptFS) {
+		c.encryptNames = enabled
+	}
+}
+
+// Wrap encrypts contents of files written to fsys by key, and decrypts contents read.
+//
+// contents are sealed in chunks, so seek and read at still work, and sizes of Stat are plaintext sizes.
+// files could only be written from the beginning, append and random access write are not supported,
+// and existing non-empty files should be opened for write with O_TRUNC.
+func Wrap(fsys filesystem.FileSystem, key []byte, opts ...Option) (filesystem.FileSystem, error) {
+	if len(key) != KeySize {
+		return nil, errors.New("key should be 32 bytes")
+	}
+
+	c := &cryptFS{fs: fsys, key: key}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.encryptNames {
+		names, err := newNameCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		c.names = names
+	}
+
+	return c, nil
+}
+
+type cryptFS struct {
+	fs           filesystem.FileSystem
+	key          []byte
+	encryptNames bool
+	names        *nameCipher
+}
+
+func (c *cryptFS) Capabilities() filesystem.Capability {
+	return filesystem.CapabilitiesOf(c.fs) &^ (filesystem.CapAppend | filesystem.CapRandomAccessWrite | filesystem.CapTruncate)
+}
+
+// realPath returns path in fsys
+func (c *cryptFS) realPath(name string) string {
+	name = slashClean(name)
+	if c.names == nil {
+		return name
+	}
+	return c.names.encryptPath(name)
+}
+
+// plainName returns plaintext name of entry in fsys, false when not encrypted by the key
+func (c *cryptFS) plainName(name string) (string, bool) {
+	if c.names == nil || name == "/" {
+		return name, true
+	}
+	n, err := c.names.decryptName(name)
+	if err != nil {
+		return "", false
+	}
+	return n, true
+}
+
+// plainPath returns plaintext path of path in fsys, false when not encrypted by the key
+func (c *cryptFS) plainPath(p string) (string, bool) {
+	if c.names == nil {
+		return p, true
+	}
+	return c.names.decryptPath(p)
+}
+
+func (c *cryptFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return c.fs.Mkdir(ctx, c.realPath(name), perm)
+}
+
+func (c *cryptFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (filesystem.File, error) {
+	name = slashClean(name)
+
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		if flag&os.O_APPEND != 0 {
+			return nil, &os.PathError{Op: "open", Path: name, Err: errors.ErrUnsupported}
+		}
+
+		// always rewrite whole file, since each file sealed with its own key
+		flag = (flag &^ os.O_RDWR) | os.O_WRONLY
+
+		// content of existing file could not be kept when rewritten, so it should be truncated explicitly
+		if flag&os.O_TRUNC == 0 {
+			if info, err := c.Stat(ctx, name); err == nil && !info.IsDir() && info.Size() > 0 {
+				return nil, &os.PathError{Op: "open", Path: name, Err: errors.ErrUnsupported}
+			}
+		}
+
+		f, err := c.fs.OpenFile(ctx, c.realPath(name), flag|os.O_TRUNC, perm)
+		if err != nil {
+			return nil, err
+		}
+
+		return &writer{File: f, c: c, name: name}, nil
+	}
+
+	f, err := c.fs.OpenFile(ctx, c.realPath(name), flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return &dir{File: f, c: c, name: name}, nil
+	}
+
+	r := &reader{File: f, c: c, name: name}
+	if ra, ok := f.(io.ReaderAt); ok {
+		return filesystem.WithOptional(r, filesystem.OptionalFile{ReaderAt: &readerAt{reader: r, ra: ra}}), nil
+	}
+	return r, nil
+}
+
+func (c *cryptFS) RemoveAll(ctx context.Context, name string) error {
+	return c.fs.RemoveAll(ctx, c.realPath(name))
+}
+
+func (c *cryptFS) Rename(ctx context.Context, oldName, newName string) error {
+	return c.fs.Rename(ctx, c.realPath(oldName), c.realPath(newName))
+}
+
+func (c *cryptFS) Copy(ctx context.Context, oldName, newName string, recursive bool) error {
+	// key of file is not bound to its path, so could copy as it is
+	return filesystem.Copy(ctx, c.fs, c.realPath(oldName), c.fs, c.realPath(newName), recursive)
+}
+
+// Lock by path in fsys, so locks are shared with clients without the key
+func (c *cryptFS) Lock(ctx context.Context, name string, opts filesystem.LockOptions) (*filesystem.LockInfo, error) {
+	l, ok := c.fs.(filesystem.Locker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	info, err := l.Lock(ctx, c.realPath(name), opts)
+	if err != nil {
+		return nil, err
+	}
+	info.Name = slashClean(name)
+	return info, nil
+}
+
+func (c *cryptFS) RefreshLock(ctx context.Context, name string, token string, ttl time.Duration) (*filesystem.LockInfo, error) {
+	l, ok := c.fs.(filesystem.Locker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	info, err := l.RefreshLock(ctx, c.realPath(name), token, ttl)
+	if err != nil {
+		return nil, err
+	}
+	info.Name = slashClean(name)
+	return info, nil
+}
+
+func (c *cryptFS) Unlock(ctx context.Context, name string, token string) error {
+	l, ok := c.fs.(filesystem.Locker)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	return l.Unlock(ctx, c.realPath(name), token)
+}
+
+func (c *cryptFS) Locks(ctx context.Context, name string) ([]*filesystem.LockInfo, error) {
+	l, ok := c.fs.(filesystem.Locker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	locks, err := l.Locks(ctx, c.realPath(name))
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range locks {
+		info.Name = slashClean(name)
+	}
+	return locks, nil
+}
+
+// ListPrefix forwards only when names not encrypted,
+// since prefix could not be encrypted by segments, and encrypted paths are not in lexical order of plain paths.
+// errors.ErrUnsupported returned otherwise to fall back to walking.
+func (c *cryptFS) ListPrefix(ctx context.Context, prefix string) iter.Seq2[*filesystem.FileEntry, error] {
+	return func(yield func(*filesystem.FileEntry, error) bool) {
+		l, ok := c.fs.(filesystem.PrefixLister)
+		if !ok || c.names != nil {
+			yield(nil, errors.ErrUnsupported)
+			return
+		}
+
+		for e, err := range l.ListPrefix(ctx, prefix) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(&filesystem.FileEntry{Path: e.Path, FileInfo: &fileInfo{FileInfo: e.FileInfo, name: path.Base(e.Path)}}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Watch forwards events with plain paths, events of entries not encrypted by the key are skipped
+func (c *cryptFS) Watch(ctx context.Context, name string) iter.Seq2[*filesystem.Event, error] {
+	return func(yield func(*filesystem.Event, error) bool) {
+		w, ok := c.fs.(filesystem.Watcher)
+		if !ok {
+			yield(nil, errors.ErrUnsupported)
+			return
+		}
+
+		for e, err := range w.Watch(ctx, c.realPath(name)) {
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+
+			p, ok := c.plainPath(e.Path)
+			if !ok {
+				// moved out to entry not encrypted by the key
+				if oldPath, ok := c.plainPath(e.OldPath); ok && e.OldPath != "" {
+					if !yield(&filesystem.Event{Op: filesystem.EventDelete, Path: oldPath, User: e.User}, nil) {
+						return
+					}
+				}
+				continue
+			}
+
+			event := &filesystem.Event{Op: e.Op, Path: p, User: e.User}
+			if e.OldPath != "" {
+				// moved in from entry not encrypted by the key
+				if oldPath, ok := c.plainPath(e.OldPath); ok {
+					event.OldPath = oldPath
+				} else {
+					event.Op = filesystem.EventCreate
+				}
+			}
+
+			if !yield(event, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Symlink is not supported, since targets could not be stored without leaking plain names
+func (c *cryptFS) Symlink(ctx context.Context, target string, name string) error {
+	return errors.ErrUnsupported
+}
+
+func (c *cryptFS) Readlink(ctx context.Context, name string) (string, error) {
+	return "", errors.ErrUnsupported
+}
+
+func (c *cryptFS) Lstat(ctx context.Context, name string) (os.FileInfo, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// SetMode forwards to underlying file, since attributes are not encrypted
+func (c *cryptFS) SetMode(ctx context.Context, name string, mode os.FileMode) error {
+	s, ok := c.fs.(filesystem.AttrSetter)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	return s.SetMode(ctx, c.realPath(name), mode)
+}
+
+func (c *cryptFS) SetModTime(ctx context.Context, name string, mtime time.Time) error {
+	s, ok := c.fs.(filesystem.AttrSetter)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	return s.SetModTime(ctx, c.realPath(name), mtime)
+}
+
+func (c *cryptFS) Chown(ctx context.Context, name string, uid, gid int) error {
+	o, ok := c.fs.(filesystem.Chowner)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	return o.Chown(ctx, c.realPath(name), uid, gid)
+}
+
+func (c *cryptFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	name = slashClean(name)
+
+	info, err := c.fs.Stat(ctx, c.realPath(name))
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileInfo{FileInfo: info, name: path.Base(name)}, nil
+}
+
+type fileInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (i *fileInfo) Name() string {
+	return i.name
+}
+
+// UserMetadata forwards user metadata of underlying file, which is not encrypted
+func (i *fileInfo) UserMetadata(ctx context.Context) (filesystem.UserMetadata, error) {
+	if p, ok := i.FileInfo.(filesystem.UserMetadataProvider); ok {
+		return p.UserMetadata(ctx)
+	}
+	return nil, errors.ErrUnsupported
+}
+
+func (i *fileInfo) Size() int64 {
+	if i.IsDir() {
+		return i.FileInfo.Size()
+	}
+	return PlainSize(i.FileInfo.Size())
+}
+
+type dir struct {
+	filesystem.File
+
+	c    *cryptFS
+	name string
+}
+
+func (d *dir) Stat() (os.FileInfo, error) {
+	info, err := d.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{FileInfo: info, name: path.Base(d.name)}, nil
+}
+
+func (d *dir) Readdir(count int) ([]os.FileInfo, error) {
+	for {
+		infos, err := d.File.Readdir(count)
+
+		list := make([]os.FileInfo, 0, len(infos))
+		for _, info := range infos {
+			// skip entries not encrypted by the key
+			if name, ok := d.c.plainName(info.Name()); ok {
+				list = append(list, &fileInfo{FileInfo: info, name: name})
+			}
+		}
+
+		// all skipped, read more to avoid returning empty list before the end
+		if count > 0 && len(list) == 0 && len(infos) > 0 && err == nil {
+			continue
+		}
+
+		return list, err
+	}
+}
+
+func slashClean(name string) string {
+	if name == "" || name[0] != '/' {
+		name = "/" + name
+	}
+	return path.Clean(name)
+}
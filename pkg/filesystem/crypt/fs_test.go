@@ -0,0 +1,280 @@
+package crypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/local"
+	"github.com/octohelm/unifs/pkg/filesystem/testutil"
+)
+
+func TestCrypt(t *testing.T) {
+	key := make([]byte, KeySize)
+	_, _ = rand.Read(key)
+
+	t.Run("Simple", func(t *testing.T) {
+		fsys, err := Wrap(filesystem.NewMemFS(), key)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testutil.TestSimpleFS(t, fsys)
+	})
+
+	t.Run("Simple on local", func(t *testing.T) {
+		fsys, err := Wrap(local.NewFS(t.TempDir()), key)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testutil.TestSimpleFS(t, fsys)
+	})
+
+	t.Run("Simple with name encryption", func(t *testing.T) {
+		fsys, err := Wrap(filesystem.NewMemFS(), key, WithNameEncryption(true))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testutil.TestSimpleFS(t, fsys)
+	})
+
+	ctx := context.Background()
+
+	data := make([]byte, 3*ChunkSize+100)
+	_, _ = rand.Read(data)
+
+	read := func(t *testing.T, fsys filesystem.FileSystem, name string) []byte {
+		f, err := filesystem.Open(ctx, fsys, name)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		defer f.Close()
+
+		d, err := io.ReadAll(f)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		return d
+	}
+
+	t.Run("encrypted at rest", func(t *testing.T) {
+		memfs := filesystem.NewMemFS()
+		fsys, _ := Wrap(memfs, key, WithNameEncryption(true))
+
+		_ = filesystem.MkdirAll(ctx, fsys, "/a")
+		err := filesystem.Write(ctx, fsys, "/a/1.bin", data)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		testingx.Expect(t, bytes.Equal(read(t, fsys, "/a/1.bin"), data), testingx.Be(true))
+
+		info, err := fsys.Stat(ctx, "/a/1.bin")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, info.Name(), testingx.Be("1.bin"))
+		testingx.Expect(t, info.Size(), testingx.Be(int64(len(data))))
+
+		entries, err := filesystem.ReadDir(ctx, memfs, "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, len(entries), testingx.Be(1))
+		testingx.Expect(t, entries[0].Name() != "a", testingx.Be(true))
+
+		raw, err := memfs.Stat(ctx, "/"+entries[0].Name())
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, raw.IsDir(), testingx.Be(true))
+	})
+
+	t.Run("seek and read at", func(t *testing.T) {
+		fsys, _ := Wrap(local.NewFS(t.TempDir()), key)
+
+		_ = filesystem.Write(ctx, fsys, "/1.bin", data)
+
+		f, err := filesystem.Open(ctx, fsys, "/1.bin")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		defer f.Close()
+
+		off := int64(2*ChunkSize - 10)
+
+		_, err = f.Seek(off, io.SeekStart)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		p := make([]byte, 20)
+		_, err = io.ReadFull(f, p)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, bytes.Equal(p, data[off:off+20]), testingx.Be(true))
+
+		end, err := f.Seek(0, io.SeekEnd)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, end, testingx.Be(int64(len(data))))
+
+		p = make([]byte, 200)
+		n, err := f.(io.ReaderAt).ReadAt(p, int64(len(data)-100))
+		testingx.Expect(t, errors.Is(err, io.EOF), testingx.Be(true))
+		testingx.Expect(t, bytes.Equal(p[:n], data[len(data)-100:]), testingx.Be(true))
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		memfs := filesystem.NewMemFS()
+		fsys, _ := Wrap(memfs, key)
+
+		_ = filesystem.Write(ctx, fsys, "/1.bin", data)
+
+		raw := read(t, memfs, "/1.bin")
+		raw[len(raw)-1] ^= 0xff
+		_ = filesystem.Write(ctx, memfs, "/1.bin", raw)
+
+		f, _ := filesystem.Open(ctx, fsys, "/1.bin")
+		defer f.Close()
+
+		_, err := io.ReadAll(f)
+		testingx.Expect(t, errors.Is(err, ErrInvalidFormat), testingx.Be(true))
+	})
+
+	t.Run("truncated at chunk boundary", func(t *testing.T) {
+		localfs := local.NewFS(t.TempDir())
+		fsys, _ := Wrap(localfs, key)
+
+		_ = filesystem.Write(ctx, fsys, "/1.bin", data)
+		raw := read(t, localfs, "/1.bin")
+
+		for _, size := range []int64{EncryptedSize(2 * ChunkSize), EncryptedSize(ChunkSize), int64(headerSize)} {
+			_ = filesystem.Write(ctx, localfs, "/1.bin", raw[:size])
+
+			f, err := filesystem.Open(ctx, fsys, "/1.bin")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			_, err = io.ReadAll(f)
+			testingx.Expect(t, errors.Is(err, ErrInvalidFormat), testingx.Be(true))
+
+			// chunks before the last one could be read as it is, the last one fails
+			_, err = f.(io.ReaderAt).ReadAt(make([]byte, 10), max(PlainSize(size)-10, 0))
+			testingx.Expect(t, errors.Is(err, ErrInvalidFormat), testingx.Be(true))
+
+			_ = f.Close()
+		}
+	})
+
+	t.Run("full chunks", func(t *testing.T) {
+		fsys, _ := Wrap(filesystem.NewMemFS(), key)
+
+		_ = filesystem.Write(ctx, fsys, "/1.bin", data[:2*ChunkSize])
+		testingx.Expect(t, bytes.Equal(read(t, fsys, "/1.bin"), data[:2*ChunkSize]), testingx.Be(true))
+	})
+
+	t.Run("open existing for write", func(t *testing.T) {
+		fsys, _ := Wrap(filesystem.NewMemFS(), key)
+		_ = filesystem.Write(ctx, fsys, "/1.bin", data)
+
+		t.Run("should fail without truncating", func(t *testing.T) {
+			_, err := fsys.OpenFile(ctx, "/1.bin", os.O_WRONLY, os.ModePerm)
+			testingx.Expect(t, errors.Is(err, errors.ErrUnsupported), testingx.Be(true))
+
+			testingx.Expect(t, bytes.Equal(read(t, fsys, "/1.bin"), data), testingx.Be(true))
+		})
+
+		t.Run("should rewrite whole file when truncated", func(t *testing.T) {
+			f, err := fsys.OpenFile(ctx, "/1.bin", os.O_RDWR|os.O_TRUNC, os.ModePerm)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			_, err = f.Write([]byte("new"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			err = f.Close()
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			testingx.Expect(t, string(read(t, fsys, "/1.bin")), testingx.Be("new"))
+		})
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		memfs := filesystem.NewMemFS()
+		fsys, _ := Wrap(memfs, key)
+
+		f, err := fsys.OpenFile(ctx, "/1.bin", os.O_WRONLY|os.O_CREATE, os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, f.Close(), testingx.Be[error](nil))
+
+		info, err := fsys.Stat(ctx, "/1.bin")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, info.Size(), testingx.Be(int64(0)))
+
+		raw, err := memfs.Stat(ctx, "/1.bin")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, raw.Size(), testingx.Be(EncryptedSize(0)))
+
+		testingx.Expect(t, len(read(t, fsys, "/1.bin")), testingx.Be(0))
+
+		t.Run("should fail when truncated to empty", func(t *testing.T) {
+			_ = filesystem.Write(ctx, fsys, "/2.bin", data)
+			_ = filesystem.Write(ctx, memfs, "/2.bin", nil)
+
+			f, err := filesystem.Open(ctx, fsys, "/2.bin")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			defer f.Close()
+
+			_, err = io.ReadAll(f)
+			testingx.Expect(t, errors.Is(err, ErrInvalidFormat), testingx.Be(true))
+		})
+	})
+
+	t.Run("append unsupported", func(t *testing.T) {
+		fsys, _ := Wrap(filesystem.NewMemFS(), key)
+
+		_, err := fsys.OpenFile(ctx, "/1.bin", os.O_WRONLY|os.O_CREATE|os.O_APPEND, os.ModePerm)
+		testingx.Expect(t, errors.Is(err, errors.ErrUnsupported), testingx.Be(true))
+	})
+
+	t.Run("list prefix with plain sizes", func(t *testing.T) {
+		mux := filesystem.NewMux()
+		_ = mux.Mount("/", filesystem.NewMemFS())
+		fsys, _ := Wrap(mux, key)
+
+		_ = filesystem.MkdirAll(ctx, fsys, "/logs")
+		_ = filesystem.Write(ctx, fsys, "/logs/1.log", data)
+
+		for e, err := range filesystem.ListPrefix(ctx, fsys, "/logs/") {
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, e.Path, testingx.Be("/logs/1.log"))
+			testingx.Expect(t, e.Size(), testingx.Be(int64(len(data))))
+		}
+	})
+
+	t.Run("watch with plain paths", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		fsys, _ := Wrap(local.NewFS(t.TempDir()), key, WithNameEncryption(true))
+		_ = filesystem.MkdirAll(ctx, fsys, "/in")
+
+		events := make(chan *filesystem.Event, 16)
+
+		go func() {
+			for e, err := range filesystem.Watch(ctx, fsys, "/in", filesystem.WatchInterval(10*time.Millisecond)) {
+				if err == nil {
+					events <- e
+				}
+			}
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+
+		_ = filesystem.Write(ctx, fsys, "/in/a.txt", []byte("1"))
+
+		select {
+		case e := <-events:
+			testingx.Expect(t, e.Path, testingx.Be("/in/a.txt"))
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+	})
+
+	t.Run("sizes", func(t *testing.T) {
+		for _, size := range []int64{0, 1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 10 * ChunkSize} {
+			testingx.Expect(t, PlainSize(EncryptedSize(size)), testingx.Be(size))
+		}
+	})
+}
+
+func TestParseKey(t *testing.T) {
+	key, err := ParseKey([]byte("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f\n"))
+	testingx.Expect(t, err, testingx.Be[error](nil))
+	testingx.Expect(t, key[31], testingx.Be[byte](0x1f))
+
+	_, err = ParseKey([]byte("short"))
+	testingx.Expect(t, err, testingx.Not(testingx.Be[error](nil)))
+}
@@ -0,0 +1,57 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// KeySize of AES-256
+const KeySize = 32
+
+// ParseKey parses key as raw 32 bytes, 64 hex chars or base64 of 32 bytes.
+// leading and trailing spaces will be trimmed for hex and base64.
+func ParseKey(data []byte) ([]byte, error) {
+	if len(data) == KeySize {
+		return data, nil
+	}
+
+	text := bytes.TrimSpace(data)
+
+	if len(text) == hex.EncodedLen(KeySize) {
+		key := make([]byte, KeySize)
+		if _, err := hex.Decode(key, text); err == nil {
+			return key, nil
+		}
+	}
+
+	key, err := base64.StdEncoding.DecodeString(string(text))
+	if err == nil && len(key) == KeySize {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("invalid key, should be %d bytes, or hex or base64 encoded", KeySize)
+}
+
+// LoadKey loads key from file or env var named env, file first
+func LoadKey(file string, env string) ([]byte, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		return ParseKey(data)
+	}
+
+	if env != "" {
+		v, ok := os.LookupEnv(env)
+		if !ok || v == "" {
+			return nil, fmt.Errorf("missing key in env %s", env)
+		}
+		return ParseKey([]byte(v))
+	}
+
+	return nil, fmt.Errorf("missing key, key file or env should be set")
+}
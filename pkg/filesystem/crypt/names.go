@@ -0,0 +1,96 @@
+package crypt
+
+import (
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// nameCipher encrypts each segment of path deterministically,
+// nonce is the HMAC of plaintext name (like SIV), so same name always be same encrypted name for lookup.
+// encrypted name is base64url of nonce | sealed name, which 28 bytes plus 1/3 longer than plaintext.
+type nameCipher struct {
+	aead   cipher.AEAD
+	macKey []byte
+}
+
+func newNameCipher(masterKey []byte) (*nameCipher, error) {
+	key, err := hkdf.Key(sha256.New, masterKey, nil, "unifs name", KeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	macKey, err := hkdf.Key(sha256.New, masterKey, nil, "unifs name nonce", KeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &nameCipher{aead: aead, macKey: macKey}, nil
+}
+
+func (c *nameCipher) nonce(name string) []byte {
+	mac := hmac.New(sha256.New, c.macKey)
+	mac.Write([]byte(name))
+	return mac.Sum(nil)[:c.aead.NonceSize()]
+}
+
+func (c *nameCipher) encryptName(name string) string {
+	nonce := c.nonce(name)
+	return base64.RawURLEncoding.EncodeToString(c.aead.Seal(nonce, nonce, []byte(name), nil))
+}
+
+func (c *nameCipher) decryptName(encrypted string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) < c.aead.NonceSize() {
+		return "", errors.New("invalid encrypted name")
+	}
+
+	nonce, sealed := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+
+	name, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(name), nil
+}
+
+func (c *nameCipher) encryptPath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		if s == "" || s == "." || s == ".." {
+			continue
+		}
+		segments[i] = c.encryptName(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// decryptPath decrypts each segment of path, false when any segment not encrypted by the key
+func (c *nameCipher) decryptPath(p string) (string, bool) {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		if s == "" {
+			continue
+		}
+		n, err := c.decryptName(s)
+		if err != nil {
+			return "", false
+		}
+		segments[i] = n
+	}
+	return strings.Join(segments, "/"), true
+}
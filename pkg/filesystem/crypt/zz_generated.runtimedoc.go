@@ -0,0 +1,46 @@
+/*
+Package crypt GENERATED BY gengo:runtimedoc
+DON'T EDIT THIS FILE
+*/
+package crypt
+
+func (v *Config) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "Key":
+			return []string{
+				"Key of AES-256, 32 bytes in hex or base64, encryption enabled when key or key file set",
+			}, true
+		case "KeyFile":
+			return []string{
+				"File of key",
+			}, true
+		case "Names":
+			return []string{
+				"Encrypt names of files and directories too",
+			}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{}, true
+}
+
+// nolint:deadcode,unused
+func runtimeDoc(v any, prefix string, names ...string) ([]string, bool) {
+	if c, ok := v.(interface {
+		RuntimeDoc(names ...string) ([]string, bool)
+	}); ok {
+		doc, ok := c.RuntimeDoc(names...)
+		if ok {
+			if prefix != "" && len(doc) > 0 {
+				doc[0] = prefix + doc[0]
+				return doc, true
+			}
+
+			return doc, true
+		}
+	}
+	return nil, false
+}
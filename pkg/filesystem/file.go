@@ -1,5 +1,12 @@
 package filesystem
 
+import (
+	"errors"
+)
+
+// ErrAborted is the cause of aborting when nil given
+var ErrAborted = errors.New("aborted")
+
 type FileTruncator interface {
 	Truncate(size int64) error
 }
@@ -7,3 +14,27 @@ type FileTruncator interface {
 type FileSyncer interface {
 	Sync() error
 }
+
+// FileAborter could be implemented by File opened for write,
+// to discard written instead of committing, like aborting multipart upload of s3.
+// errors.ErrUnsupported could be returned to fall back to Close.
+type FileAborter interface {
+	// Abort discards written with err as cause and releases the file, Close after aborted is a no-op
+	Abort(err error) error
+}
+
+// Abort discards written of f when FileAborter implemented,
+// otherwise f will be closed, and written may be committed.
+//
+// files opened with O_ATOMIC always leave nothing when aborted.
+func Abort(f File, err error) error {
+	if err == nil {
+		err = ErrAborted
+	}
+	if a, ok := f.(FileAborter); ok {
+		if err := a.Abort(err); !errors.Is(err, errors.ErrUnsupported) {
+			return err
+		}
+	}
+	return f.Close()
+}
@@ -0,0 +1,110 @@
+package filesystem
+
+import (
+	"errors"
+	"io"
+)
+
+// OptionalFile holds optional interfaces of File, which consumers check by type assertion,
+// and fall back to Seek with Read or Write, and Readdir when not implemented.
+type OptionalFile struct {
+	ReaderAt    io.ReaderAt
+	WriterAt    io.WriterAt
+	DirIterator DirIterator
+}
+
+// OptionalOf returns optional interfaces implemented by f
+func OptionalOf(f File) OptionalFile {
+	o := OptionalFile{}
+	if r, ok := f.(io.ReaderAt); ok {
+		o.ReaderAt = r
+	}
+	if w, ok := f.(io.WriterAt); ok {
+		o.WriterAt = w
+	}
+	if it, ok := f.(DirIterator); ok {
+		o.DirIterator = it
+	}
+	return o
+}
+
+// WithOptional returns f with interfaces of o which are not nil.
+//
+// wrappers of File should only set ones implemented by the underlying File, like by OptionalOf,
+// so consumers could fall back when not implemented.
+// FileSyncer, FileTruncator and FileAborter of f are kept.
+func WithOptional(f File, o OptionalFile) File {
+	ff := fileForwarder{File: f}
+
+	switch r, w, it := o.ReaderAt, o.WriterAt, o.DirIterator; {
+	case r != nil && w != nil && it != nil:
+		return &struct {
+			fileForwarder
+			io.ReaderAt
+			io.WriterAt
+			DirIterator
+		}{ff, r, w, it}
+	case r != nil && w != nil:
+		return &struct {
+			fileForwarder
+			io.ReaderAt
+			io.WriterAt
+		}{ff, r, w}
+	case r != nil && it != nil:
+		return &struct {
+			fileForwarder
+			io.ReaderAt
+			DirIterator
+		}{ff, r, it}
+	case w != nil && it != nil:
+		return &struct {
+			fileForwarder
+			io.WriterAt
+			DirIterator
+		}{ff, w, it}
+	case r != nil:
+		return &struct {
+			fileForwarder
+			io.ReaderAt
+		}{ff, r}
+	case w != nil:
+		return &struct {
+			fileForwarder
+			io.WriterAt
+		}{ff, w}
+	case it != nil:
+		return &struct {
+			fileForwarder
+			DirIterator
+		}{ff, it}
+	}
+
+	return f
+}
+
+// fileForwarder forwards FileSyncer, FileTruncator and FileAborter to File,
+// errors.ErrUnsupported returned when File not implemented, like Forwarder.
+type fileForwarder struct {
+	File
+}
+
+func (f fileForwarder) Sync() error {
+	if s, ok := f.File.(FileSyncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+func (f fileForwarder) Truncate(size int64) error {
+	if t, ok := f.File.(FileTruncator); ok {
+		return t.Truncate(size)
+	}
+	return errors.ErrUnsupported
+}
+
+func (f fileForwarder) Abort(err error) error {
+	if a, ok := f.File.(FileAborter); ok {
+		return a.Abort(err)
+	}
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,64 @@
+package filesystem_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/local"
+)
+
+func TestWithOptional(t *testing.T) {
+	ctx := context.Background()
+
+	fsys := local.NewFS(t.TempDir())
+
+	err := filesystem.Write(ctx, fsys, "/1.txt", []byte("0123456789"))
+	testingx.Expect(t, err, testingx.Be[error](nil))
+
+	f, err := fsys.OpenFile(ctx, "/1.txt", os.O_RDWR, os.ModePerm)
+	testingx.Expect(t, err, testingx.Be[error](nil))
+	defer f.Close()
+
+	t.Run("only set ones exposed", func(t *testing.T) {
+		wrapped := filesystem.WithOptional(&syncedFile{File: f}, filesystem.OptionalFile{ReaderAt: filesystem.OptionalOf(f).ReaderAt})
+
+		_, ok := wrapped.(io.WriterAt)
+		testingx.Expect(t, ok, testingx.Be(false))
+		_, ok = wrapped.(filesystem.DirIterator)
+		testingx.Expect(t, ok, testingx.Be(false))
+
+		p := make([]byte, 3)
+		_, err := wrapped.(io.ReaderAt).ReadAt(p, 2)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, string(p), testingx.Be("234"))
+	})
+
+	t.Run("methods of wrapper kept", func(t *testing.T) {
+		s := &syncedFile{File: f}
+		wrapped := filesystem.WithOptional(s, filesystem.OptionalOf(f))
+
+		err := wrapped.(filesystem.FileSyncer).Sync()
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, s.synced, testingx.Be(true))
+	})
+
+	t.Run("returned directly without optional", func(t *testing.T) {
+		s := &syncedFile{File: f}
+		testingx.Expect(t, filesystem.WithOptional(s, filesystem.OptionalFile{}), testingx.Be[filesystem.File](s))
+	})
+}
+
+type syncedFile struct {
+	filesystem.File
+	synced bool
+}
+
+func (f *syncedFile) Sync() error {
+	f.synced = true
+	return nil
+}
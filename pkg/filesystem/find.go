@@ -0,0 +1,299 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"iter"
+	"path"
+	"strings"
+	"time"
+)
+
+// FileEntry of file listed with full path
+type FileEntry struct {
+	// Path of file, starts with /
+	Path string
+	FileInfo
+}
+
+// PrefixLister could be implemented by FileSystem which could list files under all sub dirs at once,
+// like ListObjects of s3 with recursive.
+// errors.ErrUnsupported could be yielded first to fall back to walking.
+//
+// prefix is not required to be a dir, "/logs/2024-" matches both "/logs/2024-01/a.log" and "/logs/2024-02.log".
+// only files will be listed, in lexical order of paths.
+type PrefixLister interface {
+	ListPrefix(ctx context.Context, prefix string) iter.Seq2[*FileEntry, error]
+}
+
+// ListPrefix iterates files which paths starts with prefix, stops at the first error.
+// PrefixLister will be used when implemented, otherwise walk from the dir of prefix.
+func ListPrefix(ctx context.Context, fsys FileSystem, prefix string) iter.Seq2[*FileEntry, error] {
+	return listPrefix(ctx, fsys, prefix, nil)
+}
+
+func listPrefix(ctx context.Context, fsys FileSystem, prefix string, skipDir func(dir string) bool) iter.Seq2[*FileEntry, error] {
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+
+	return func(yield func(*FileEntry, error) bool) {
+		if l, ok := fsys.(PrefixLister); ok {
+			listed := false
+			for e, err := range l.ListPrefix(ctx, prefix) {
+				// wrappers return errors.ErrUnsupported when wrapped FileSystem not implements PrefixLister
+				if !listed && errors.Is(err, errors.ErrUnsupported) {
+					break
+				}
+				listed = true
+				if !yield(e, err) || err != nil {
+					return
+				}
+			}
+			if listed {
+				return
+			}
+		}
+
+		root := path.Dir(prefix)
+		if strings.HasSuffix(prefix, "/") {
+			root = path.Clean(prefix)
+		}
+
+		stopped := false
+
+		err := WalkDir(ctx, fsys, root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// nothing matched when root not exists
+				if p == root && errors.Is(err, fs.ErrNotExist) {
+					return SkipAll
+				}
+				return err
+			}
+
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				if p == root {
+					return nil
+				}
+				// only walk into dirs under prefix, or parents of prefix
+				if !(strings.HasPrefix(p+"/", prefix) || strings.HasPrefix(prefix, p+"/")) {
+					return SkipDir
+				}
+				if skipDir != nil && skipDir(p) {
+					return SkipDir
+				}
+				return nil
+			}
+
+			if !strings.HasPrefix(p, prefix) {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			if !yield(&FileEntry{Path: p, FileInfo: info}, nil) {
+				stopped = true
+				return SkipAll
+			}
+			return nil
+		})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
+
+// Glob returns paths of files matched pattern, in the syntax of path.Match, so * not matches /.
+// directories are not included.
+//
+// files will be listed by ListPrefix with the longest literal prefix of pattern,
+// and dirs could not contain matches will be skipped when walking.
+func Glob(ctx context.Context, fsys FileSystem, pattern string) ([]string, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		pattern = "/" + pattern
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	var matches []string
+
+	for e, err := range listPrefix(ctx, fsys, literalPrefix(pattern), globSkipDir(pattern)) {
+		if err != nil {
+			return nil, err
+		}
+		if ok, _ := path.Match(pattern, e.Path); ok {
+			matches = append(matches, e.Path)
+		}
+	}
+
+	return matches, nil
+}
+
+// globSkipDir returns func to check dir could not contain files matched pattern
+func globSkipDir(pattern string) func(dir string) bool {
+	segments := strings.Split(pattern, "/")
+
+	return func(dir string) bool {
+		parts := strings.Split(dir, "/")
+		// files in dir have more segments than pattern
+		if len(parts) >= len(segments) {
+			return true
+		}
+		for i := range parts {
+			if ok, _ := path.Match(segments[i], parts[i]); !ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// literalPrefix returns part of pattern before the first meta char
+func literalPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+type FindOption func(f *finder)
+
+// FindName matches base name of files with pattern in the syntax of path.Match
+func FindName(pattern string) FindOption {
+	return func(f *finder) {
+		f.name = pattern
+	}
+}
+
+// FindPath matches full path of files with pattern in the syntax of path.Match,
+// listing will start from the longest literal prefix of pattern like Glob
+func FindPath(pattern string) FindOption {
+	return func(f *finder) {
+		if !strings.HasPrefix(pattern, "/") {
+			pattern = "/" + pattern
+		}
+		f.path = pattern
+	}
+}
+
+// FindMinSize matches files with size >= size
+func FindMinSize(size int64) FindOption {
+	return func(f *finder) {
+		f.minSize = &size
+	}
+}
+
+// FindMaxSize matches files with size <= size
+func FindMaxSize(size int64) FindOption {
+	return func(f *finder) {
+		f.maxSize = &size
+	}
+}
+
+// FindNewerThan matches files modified after t
+func FindNewerThan(t time.Time) FindOption {
+	return func(f *finder) {
+		f.newerThan = t
+	}
+}
+
+// FindOlderThan matches files modified before t
+func FindOlderThan(t time.Time) FindOption {
+	return func(f *finder) {
+		f.olderThan = t
+	}
+}
+
+type finder struct {
+	path      string
+	name      string
+	minSize   *int64
+	maxSize   *int64
+	newerThan time.Time
+	olderThan time.Time
+}
+
+func (f *finder) match(e *FileEntry) bool {
+	if f.path != "" {
+		if ok, _ := path.Match(f.path, e.Path); !ok {
+			return false
+		}
+	}
+	if f.name != "" {
+		if ok, _ := path.Match(f.name, path.Base(e.Path)); !ok {
+			return false
+		}
+	}
+	if f.minSize != nil && e.Size() < *f.minSize {
+		return false
+	}
+	if f.maxSize != nil && e.Size() > *f.maxSize {
+		return false
+	}
+	if !f.newerThan.IsZero() && !e.ModTime().After(f.newerThan) {
+		return false
+	}
+	if !f.olderThan.IsZero() && !e.ModTime().Before(f.olderThan) {
+		return false
+	}
+	return true
+}
+
+// Find iterates files under root matched all options, stops at the first error.
+// files will be listed by ListPrefix with root or the literal prefix of FindPath as prefix.
+func Find(ctx context.Context, fsys FileSystem, root string, opts ...FindOption) iter.Seq2[*FileEntry, error] {
+	f := &finder{}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	prefix := path.Clean("/" + root)
+	if prefix != "/" {
+		prefix += "/"
+	}
+
+	return func(yield func(*FileEntry, error) bool) {
+		var skipDir func(dir string) bool
+
+		for _, pattern := range []string{f.path, f.name} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+
+		if f.path != "" {
+			skipDir = globSkipDir(f.path)
+
+			if p := literalPrefix(f.path); strings.HasPrefix(p, prefix) {
+				prefix = p
+			} else if !strings.HasPrefix(prefix, p) {
+				// no files under root could be matched
+				return
+			}
+		}
+
+		for e, err := range listPrefix(ctx, fsys, prefix, skipDir) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !f.match(e) {
+				continue
+			}
+			if !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package filesystem_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+func TestFind(t *testing.T) {
+	ctx := context.Background()
+
+	fsys := filesystem.NewMemFS()
+
+	for name, data := range map[string]string{
+		"/logs/2024-01/a.log":  "1",
+		"/logs/2024-01/b.txt":  "22",
+		"/logs/2024-02.log":    "333",
+		"/logs/2025-01/c.log":  "4444",
+		"/data/x/y/z/d.log":    "55555",
+		"/data/x/y/z/e.log.gz": "666666",
+	} {
+		testingx.Expect(t, filesystem.MkdirAll(ctx, fsys, filepath.Dir(name)), testingx.BeNil[error]())
+		testingx.Expect(t, filesystem.Write(ctx, fsys, name, []byte(data)), testingx.BeNil[error]())
+	}
+
+	t.Run("list prefix", func(t *testing.T) {
+		paths := make([]string, 0)
+		for e, err := range filesystem.ListPrefix(ctx, fsys, "/logs/2024-") {
+			testingx.Expect(t, err, testingx.BeNil[error]())
+			paths = append(paths, e.Path)
+		}
+		testingx.Expect(t, paths, testingx.Equal([]string{
+			"/logs/2024-01/a.log",
+			"/logs/2024-01/b.txt",
+			"/logs/2024-02.log",
+		}))
+	})
+
+	t.Run("glob", func(t *testing.T) {
+		matches, err := filesystem.Glob(ctx, fsys, "/logs/*/*.log")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+		testingx.Expect(t, matches, testingx.Equal([]string{
+			"/logs/2024-01/a.log",
+			"/logs/2025-01/c.log",
+		}))
+
+		matches, err = filesystem.Glob(ctx, filesystem.Sub(fsys, "/data"), "x/*/z/*.log")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+		testingx.Expect(t, matches, testingx.Equal([]string{
+			"/x/y/z/d.log",
+		}))
+
+		matches, err = filesystem.Glob(ctx, fsys, "/not-exists/*")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+		testingx.Expect(t, len(matches), testingx.Be(0))
+
+		_, err = filesystem.Glob(ctx, fsys, "/logs/[")
+		testingx.Expect(t, err, testingx.Not(testingx.BeNil[error]()))
+	})
+
+	t.Run("find", func(t *testing.T) {
+		paths := make([]string, 0)
+		for e, err := range filesystem.Find(ctx, fsys, "/",
+			filesystem.FindName("*.log"),
+			filesystem.FindMinSize(2),
+			filesystem.FindMaxSize(5),
+			filesystem.FindNewerThan(time.Now().Add(-time.Hour)),
+		) {
+			testingx.Expect(t, err, testingx.BeNil[error]())
+			paths = append(paths, e.Path)
+		}
+		testingx.Expect(t, paths, testingx.Equal([]string{
+			"/data/x/y/z/d.log",
+			"/logs/2024-02.log",
+			"/logs/2025-01/c.log",
+		}))
+
+		paths = make([]string, 0)
+		for e, err := range filesystem.Find(ctx, fsys, "/logs", filesystem.FindPath("/logs/20*-01/*")) {
+			testingx.Expect(t, err, testingx.BeNil[error]())
+			paths = append(paths, e.Path)
+		}
+		testingx.Expect(t, paths, testingx.Equal([]string{
+			"/logs/2024-01/a.log",
+			"/logs/2024-01/b.txt",
+			"/logs/2025-01/c.log",
+		}))
+
+		count := 0
+		for _, err := range filesystem.Find(ctx, fsys, "/logs", filesystem.FindOlderThan(time.Now().Add(-time.Hour))) {
+			testingx.Expect(t, err, testingx.BeNil[error]())
+			count++
+		}
+		testingx.Expect(t, count, testingx.Be(0))
+	})
+
+	t.Run("find in mux", func(t *testing.T) {
+		mux := filesystem.NewMux()
+		_ = mux.Mount("/a", filesystem.Sub(fsys, "/logs"))
+		_ = mux.Mount("/a/b", filesystem.Sub(fsys, "/data"))
+
+		paths := make([]string, 0)
+		for e, err := range filesystem.Find(ctx, mux, "/a", filesystem.FindName("*.log")) {
+			testingx.Expect(t, err, testingx.BeNil[error]())
+			paths = append(paths, e.Path)
+		}
+		testingx.Expect(t, paths, testingx.Equal([]string{
+			"/a/2024-01/a.log",
+			"/a/2024-02.log",
+			"/a/2025-01/c.log",
+			"/a/b/x/y/z/d.log",
+		}))
+
+		matches, err := filesystem.Glob(ctx, mux, "/a/2025-01/*")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+		testingx.Expect(t, matches, testingx.Equal([]string{"/a/2025-01/c.log"}))
+	})
+}
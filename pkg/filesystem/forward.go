@@ -0,0 +1,147 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"os"
+	"time"
+)
+
+var _ interface {
+	Capabilities
+	Copier
+	Hasher
+	PrefixLister
+	Watcher
+	Locker
+	Linker
+	AttrSetter
+	Chowner
+	TransientErrorClassifier
+} = Forwarder{}
+
+// Forwarder implements optional interfaces by forwarding to FileSystem,
+// errors.ErrUnsupported returned when FileSystem not implemented, then package helpers fall back.
+//
+// wrappers could embed it to forward all optional interfaces, and only override ones should be intercepted.
+// methods of FileSystem are promoted too, wrappers changing names or contents should override all of them.
+type Forwarder struct {
+	FileSystem
+}
+
+// IsTransientError forwards to FileSystem, so Retry could classify errors of backend through wrappers
+func (f Forwarder) IsTransientError(err error) bool {
+	if c, ok := f.FileSystem.(TransientErrorClassifier); ok {
+		return c.IsTransientError(err)
+	}
+	return false
+}
+
+func (f Forwarder) Capabilities() Capability {
+	return CapabilitiesOf(f.FileSystem)
+}
+
+// Copy copies by Copier of FileSystem only, when not implemented,
+// Copy of package will stream content through the wrapper.
+func (f Forwarder) Copy(ctx context.Context, oldName, newName string, recursive bool) error {
+	if c, ok := f.FileSystem.(Copier); ok {
+		return c.Copy(ctx, oldName, newName, recursive)
+	}
+	return errors.ErrUnsupported
+}
+
+func (f Forwarder) Hash(ctx context.Context, name string, algo HashAlgorithm) (string, error) {
+	if h, ok := f.FileSystem.(Hasher); ok {
+		return h.Hash(ctx, name, algo)
+	}
+	return "", errors.ErrUnsupported
+}
+
+func (f Forwarder) ListPrefix(ctx context.Context, prefix string) iter.Seq2[*FileEntry, error] {
+	if l, ok := f.FileSystem.(PrefixLister); ok {
+		return l.ListPrefix(ctx, prefix)
+	}
+	return func(yield func(*FileEntry, error) bool) {
+		yield(nil, errors.ErrUnsupported)
+	}
+}
+
+func (f Forwarder) Watch(ctx context.Context, name string) iter.Seq2[*Event, error] {
+	if w, ok := f.FileSystem.(Watcher); ok {
+		return w.Watch(ctx, name)
+	}
+	return func(yield func(*Event, error) bool) {
+		yield(nil, errors.ErrUnsupported)
+	}
+}
+
+func (f Forwarder) Lock(ctx context.Context, name string, opts LockOptions) (*LockInfo, error) {
+	if l, ok := f.FileSystem.(Locker); ok {
+		return l.Lock(ctx, name, opts)
+	}
+	return nil, errors.ErrUnsupported
+}
+
+func (f Forwarder) RefreshLock(ctx context.Context, name string, token string, ttl time.Duration) (*LockInfo, error) {
+	if l, ok := f.FileSystem.(Locker); ok {
+		return l.RefreshLock(ctx, name, token, ttl)
+	}
+	return nil, errors.ErrUnsupported
+}
+
+func (f Forwarder) Unlock(ctx context.Context, name string, token string) error {
+	if l, ok := f.FileSystem.(Locker); ok {
+		return l.Unlock(ctx, name, token)
+	}
+	return errors.ErrUnsupported
+}
+
+func (f Forwarder) Locks(ctx context.Context, name string) ([]*LockInfo, error) {
+	if l, ok := f.FileSystem.(Locker); ok {
+		return l.Locks(ctx, name)
+	}
+	return nil, errors.ErrUnsupported
+}
+
+func (f Forwarder) Symlink(ctx context.Context, target string, name string) error {
+	if l, ok := f.FileSystem.(Linker); ok {
+		return l.Symlink(ctx, target, name)
+	}
+	return errors.ErrUnsupported
+}
+
+func (f Forwarder) Readlink(ctx context.Context, name string) (string, error) {
+	if l, ok := f.FileSystem.(Linker); ok {
+		return l.Readlink(ctx, name)
+	}
+	return "", errors.ErrUnsupported
+}
+
+func (f Forwarder) Lstat(ctx context.Context, name string) (os.FileInfo, error) {
+	if l, ok := f.FileSystem.(Linker); ok {
+		return l.Lstat(ctx, name)
+	}
+	return nil, errors.ErrUnsupported
+}
+
+func (f Forwarder) SetMode(ctx context.Context, name string, mode os.FileMode) error {
+	if s, ok := f.FileSystem.(AttrSetter); ok {
+		return s.SetMode(ctx, name, mode)
+	}
+	return errors.ErrUnsupported
+}
+
+func (f Forwarder) SetModTime(ctx context.Context, name string, mtime time.Time) error {
+	if s, ok := f.FileSystem.(AttrSetter); ok {
+		return s.SetModTime(ctx, name, mtime)
+	}
+	return errors.ErrUnsupported
+}
+
+func (f Forwarder) Chown(ctx context.Context, name string, uid, gid int) error {
+	if c, ok := f.FileSystem.(Chowner); ok {
+		return c.Chown(ctx, name, uid, gid)
+	}
+	return errors.ErrUnsupported
+}
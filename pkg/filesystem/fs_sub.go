@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"io/fs"
+	"iter"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"golang.org/x/net/webdav"
 )
@@ -28,6 +30,10 @@ type subFS struct {
 	dir    string
 }
 
+func (f *subFS) Capabilities() Capability {
+	return CapabilitiesOf(f.source)
+}
+
 func (f *subFS) shorten(name string) (rel string, ok bool) {
 	if name == f.dir {
 		return ".", true
@@ -99,6 +105,263 @@ func (f *subFS) Rename(ctx context.Context, oldName, newName string) error {
 	return f.fixErr(f.source.Rename(ctx, oldFullName, newFullName))
 }
 
+func (f *subFS) Copy(ctx context.Context, oldName, newName string, recursive bool) error {
+	c, ok := f.source.(Copier)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	oldFullName, err := f.fullName("copy", oldName)
+	if err != nil {
+		return err
+	}
+	newFullName, err := f.fullName("copy", newName)
+	if err != nil {
+		return err
+	}
+	return f.fixErr(c.Copy(ctx, oldFullName, newFullName, recursive))
+}
+
+func (f *subFS) Hash(ctx context.Context, name string, algo HashAlgorithm) (string, error) {
+	h, ok := f.source.(Hasher)
+	if !ok {
+		return "", errors.ErrUnsupported
+	}
+	fullName, err := f.fullName("hash", name)
+	if err != nil {
+		return "", err
+	}
+	sum, err := h.Hash(ctx, fullName, algo)
+	return sum, f.fixErr(err)
+}
+
+func (f *subFS) ListPrefix(ctx context.Context, prefix string) iter.Seq2[*FileEntry, error] {
+	return func(yield func(*FileEntry, error) bool) {
+		l, ok := f.source.(PrefixLister)
+		if !ok {
+			yield(nil, errors.ErrUnsupported)
+			return
+		}
+
+		fullPrefix, err := f.fullName("list", strings.TrimSuffix(prefix, "/"))
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if strings.HasSuffix(prefix, "/") || fullPrefix == f.dir {
+			fullPrefix += "/"
+		}
+
+		for e, err := range l.ListPrefix(ctx, fullPrefix) {
+			if err != nil {
+				yield(nil, f.fixErr(err))
+				return
+			}
+			rel, ok := f.shorten(e.Path)
+			if !ok {
+				continue
+			}
+			if !yield(&FileEntry{Path: "/" + rel, FileInfo: e.FileInfo}, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (f *subFS) Watch(ctx context.Context, name string) iter.Seq2[*Event, error] {
+	return func(yield func(*Event, error) bool) {
+		w, ok := f.source.(Watcher)
+		if !ok {
+			yield(nil, errors.ErrUnsupported)
+			return
+		}
+
+		fullName, err := f.fullName("watch", name)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for e, err := range w.Watch(ctx, fullName) {
+			if err != nil {
+				if !yield(nil, f.fixErr(err)) {
+					return
+				}
+				continue
+			}
+
+			rel, ok := f.shorten(e.Path)
+			if !ok {
+				continue
+			}
+
+			shortened := &Event{Op: e.Op, Path: "/" + rel, User: e.User}
+			if e.OldPath != "" {
+				// moved in from outside
+				if oldRel, ok := f.shorten(e.OldPath); ok {
+					shortened.OldPath = "/" + oldRel
+				} else {
+					shortened.Op = EventCreate
+				}
+			}
+
+			if !yield(shortened, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (f *subFS) Lock(ctx context.Context, name string, opts LockOptions) (*LockInfo, error) {
+	l, ok := f.source.(Locker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	fullName, err := f.fullName("lock", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := l.Lock(ctx, fullName, opts)
+	if err != nil {
+		return nil, f.fixErr(err)
+	}
+	return f.shortenLock(info), nil
+}
+
+func (f *subFS) RefreshLock(ctx context.Context, name string, token string, ttl time.Duration) (*LockInfo, error) {
+	l, ok := f.source.(Locker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	fullName, err := f.fullName("refresh", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := l.RefreshLock(ctx, fullName, token, ttl)
+	if err != nil {
+		return nil, f.fixErr(err)
+	}
+	return f.shortenLock(info), nil
+}
+
+func (f *subFS) Unlock(ctx context.Context, name string, token string) error {
+	l, ok := f.source.(Locker)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	fullName, err := f.fullName("unlock", name)
+	if err != nil {
+		return err
+	}
+	return f.fixErr(l.Unlock(ctx, fullName, token))
+}
+
+func (f *subFS) Locks(ctx context.Context, name string) ([]*LockInfo, error) {
+	l, ok := f.source.(Locker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	fullName, err := f.fullName("locks", name)
+	if err != nil {
+		return nil, err
+	}
+	locks, err := l.Locks(ctx, fullName)
+	if err != nil {
+		return nil, f.fixErr(err)
+	}
+	for i := range locks {
+		locks[i] = f.shortenLock(locks[i])
+	}
+	return locks, nil
+}
+
+func (f *subFS) Symlink(ctx context.Context, target string, name string) error {
+	l, ok := f.source.(Linker)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	fullName, err := f.fullName("symlink", name)
+	if err != nil {
+		return err
+	}
+	return f.fixErr(l.Symlink(ctx, target, fullName))
+}
+
+func (f *subFS) Readlink(ctx context.Context, name string) (string, error) {
+	l, ok := f.source.(Linker)
+	if !ok {
+		return "", errors.ErrUnsupported
+	}
+	fullName, err := f.fullName("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	target, err := l.Readlink(ctx, fullName)
+	if err != nil {
+		return "", f.fixErr(err)
+	}
+	return target, nil
+}
+
+func (f *subFS) Lstat(ctx context.Context, name string) (os.FileInfo, error) {
+	l, ok := f.source.(Linker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	fullName, err := f.fullName("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := l.Lstat(ctx, fullName)
+	if err != nil {
+		return nil, f.fixErr(err)
+	}
+	return info, nil
+}
+
+func (f *subFS) SetMode(ctx context.Context, name string, mode os.FileMode) error {
+	s, ok := f.source.(AttrSetter)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	fullName, err := f.fullName("chmod", name)
+	if err != nil {
+		return err
+	}
+	return f.fixErr(s.SetMode(ctx, fullName, mode))
+}
+
+func (f *subFS) SetModTime(ctx context.Context, name string, mtime time.Time) error {
+	s, ok := f.source.(AttrSetter)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	fullName, err := f.fullName("chtimes", name)
+	if err != nil {
+		return err
+	}
+	return f.fixErr(s.SetModTime(ctx, fullName, mtime))
+}
+
+func (f *subFS) Chown(ctx context.Context, name string, uid, gid int) error {
+	c, ok := f.source.(Chowner)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	fullName, err := f.fullName("chown", name)
+	if err != nil {
+		return err
+	}
+	return f.fixErr(c.Chown(ctx, fullName, uid, gid))
+}
+
+func (f *subFS) shortenLock(info *LockInfo) *LockInfo {
+	shortened := *info
+	if rel, ok := f.shorten(info.Name); ok {
+		shortened.Name = path.Join("/", rel)
+	}
+	return &shortened
+}
+
 func (f *subFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
 	fullName, err := f.fullName("stat", name)
 	if err != nil {
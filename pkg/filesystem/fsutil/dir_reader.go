@@ -0,0 +1,73 @@
+package fsutil
+
+import (
+	"io"
+	"io/fs"
+	"iter"
+)
+
+// NewDirReader creates DirReader to list entries of seq page by page, seq will be started at first Readdir
+func NewDirReader(seq iter.Seq2[fs.FileInfo, error]) *DirReader {
+	return &DirReader{seq: seq}
+}
+
+// DirReader implements Readdir with same semantics of os.File
+type DirReader struct {
+	seq  iter.Seq2[fs.FileInfo, error]
+	next func() (fs.FileInfo, error, bool)
+	stop func()
+	done bool
+}
+
+// Readdir returns at most count entries, io.EOF when no more entries if count > 0,
+// otherwise returns all remaining entries.
+func (r *DirReader) Readdir(count int) ([]fs.FileInfo, error) {
+	if r.next == nil {
+		r.next, r.stop = iter.Pull2(r.seq)
+	}
+
+	var list []fs.FileInfo
+	if count > 0 {
+		list = make([]fs.FileInfo, 0, min(count, 1024))
+	}
+
+	for !r.done && (count <= 0 || len(list) < count) {
+		info, err, ok := r.next()
+		if !ok {
+			r.done = true
+			break
+		}
+		if err != nil {
+			return list, err
+		}
+		list = append(list, info)
+	}
+
+	if count > 0 && len(list) == 0 {
+		return nil, io.EOF
+	}
+
+	return list, nil
+}
+
+// Close stops the seq when not finished
+func (r *DirReader) Close() {
+	if r.stop != nil {
+		r.stop()
+	}
+}
+
+// DirEntries converts seq of FileInfo to DirEntry
+func DirEntries(seq iter.Seq2[fs.FileInfo, error]) iter.Seq2[fs.DirEntry, error] {
+	return func(yield func(fs.DirEntry, error) bool) {
+		for info, err := range seq {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(fs.FileInfoToDirEntry(info), nil) {
+				return
+			}
+		}
+	}
+}
@@ -1,12 +1,53 @@
 package fsutil
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"io/fs"
 	"time"
 
+	"golang.org/x/net/webdav"
+
 	"github.com/octohelm/unifs/pkg/filesystem"
 )
 
+type FileInfoOption func(fi *fileInfo)
+
+// WithETag sets ETag without quotes
+func WithETag(etag string) FileInfoOption {
+	return func(fi *fileInfo) {
+		fi.etag = etag
+	}
+}
+
+func WithContentType(contentType string) FileInfoOption {
+	return func(fi *fileInfo) {
+		fi.contentType = contentType
+	}
+}
+
+// WithUserMetadata sets user metadata, empty metadata should not be nil when loaded
+func WithUserMetadata(meta filesystem.UserMetadata) FileInfoOption {
+	return func(fi *fileInfo) {
+		fi.userMetadata = meta
+	}
+}
+
+// WithSymlink marks as symbolic link, for links emulated by backends
+func WithSymlink() FileInfoOption {
+	return func(fi *fileInfo) {
+		fi.symlink = true
+	}
+}
+
+// WithMode sets permission bits, for mode stored by backends
+func WithMode(mode fs.FileMode) FileInfoOption {
+	return func(fi *fileInfo) {
+		fi.mode = mode.Perm()
+	}
+}
+
 func NewDirFileInfo(name string) filesystem.FileInfo {
 	return &fileInfo{
 		name:      name,
@@ -15,19 +56,29 @@ func NewDirFileInfo(name string) filesystem.FileInfo {
 	}
 }
 
-func NewFileInfo(name string, size int64, modTime time.Time) filesystem.FileInfo {
-	return &fileInfo{
+// NewFileInfo creates FileInfo, which implements webdav.ETager, webdav.ContentTyper and filesystem.UserMetadataProvider
+func NewFileInfo(name string, size int64, modTime time.Time, opts ...FileInfoOption) filesystem.FileInfo {
+	fi := &fileInfo{
 		name:    name,
 		modTime: modTime,
 		size:    size,
 	}
+	for _, opt := range opts {
+		opt(fi)
+	}
+	return fi
 }
 
 type fileInfo struct {
-	directory bool
-	name      string
-	modTime   time.Time
-	size      int64
+	directory    bool
+	symlink      bool
+	mode         fs.FileMode
+	name         string
+	modTime      time.Time
+	size         int64
+	etag         string
+	contentType  string
+	userMetadata filesystem.UserMetadata
 }
 
 func (fi *fileInfo) Name() string {
@@ -40,9 +91,12 @@ func (fi *fileInfo) Size() int64 {
 
 func (fi *fileInfo) Mode() fs.FileMode {
 	if fi.directory {
-		return 0o755
+		return cmp.Or(fi.mode, 0o755)
 	}
-	return 0o664
+	if fi.symlink {
+		return fs.ModeSymlink | 0o777
+	}
+	return cmp.Or(fi.mode, 0o664)
 }
 
 func (fi *fileInfo) ModTime() time.Time {
@@ -56,3 +110,27 @@ func (fi *fileInfo) IsDir() bool {
 func (fi *fileInfo) Sys() any {
 	return nil
 }
+
+// ETag returns quoted ETag, webdav.ErrNotImplemented when not set
+func (fi *fileInfo) ETag(ctx context.Context) (string, error) {
+	if fi.etag == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	return `"` + fi.etag + `"`, nil
+}
+
+// ContentType returns content type, webdav.ErrNotImplemented when not set
+func (fi *fileInfo) ContentType(ctx context.Context) (string, error) {
+	if fi.contentType == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	return fi.contentType, nil
+}
+
+// UserMetadata returns user metadata, errors.ErrUnsupported when not loaded
+func (fi *fileInfo) UserMetadata(ctx context.Context) (filesystem.UserMetadata, error) {
+	if fi.userMetadata == nil {
+		return nil, errors.ErrUnsupported
+	}
+	return fi.userMetadata, nil
+}
@@ -0,0 +1,67 @@
+package ftp
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"os"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+var _ filesystem.AttrSetter = &fs{}
+
+// SetMode changes mode by SITE CHMOD, supported by most servers of unix.
+// errors.ErrUnsupported returned when server not support it.
+func (f *fs) SetMode(ctx context.Context, name string, mode os.FileMode) error {
+	p, err := f.c.pool()
+	if err != nil {
+		return err
+	}
+
+	if err := p.Chmod(ctx, name, mode); err != nil {
+		if errors.Is(err, errors.ErrUnsupported) {
+			return &os.PathError{Op: "chmod", Path: name, Err: err}
+		}
+		return normalizeError("chmod", name, err)
+	}
+	return nil
+}
+
+// SetModTime changes mtime by MFMT, or MDTM with time for servers allowed.
+// errors.ErrUnsupported returned when server not support them.
+func (f *fs) SetModTime(ctx context.Context, name string, mtime time.Time) error {
+	c, err := f.c.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := c.SetTime(name, mtime); err != nil {
+		if errors.Is(err, errors.ErrUnsupported) {
+			return &os.PathError{Op: "chtimes", Path: name, Err: err}
+		}
+		return normalizeError("chtimes", name, err)
+	}
+	return nil
+}
+
+// Chmod sends SITE CHMOD <mode> <name>
+func (p *Pool) Chmod(ctx context.Context, name string, mode os.FileMode) error {
+	return p.control(ctx, func(c *textproto.Conn) error {
+		code, msg, err := cmdWithCode(c, "SITE CHMOD %04o %s", mode.Perm(), quote(name))
+		if err != nil {
+			return err
+		}
+		switch code {
+		case ftp.StatusCommandOK:
+			return nil
+		case ftp.StatusBadCommand, ftp.StatusBadArguments, ftp.StatusNotImplemented, ftp.StatusNotImplementedParameter, ftp.StatusCommandNotImplemented:
+			return errors.ErrUnsupported
+		}
+		return &textproto.Error{Code: code, Msg: msg}
+	})
+}
@@ -32,6 +32,10 @@ type Conn interface {
 
 	RetrFrom(path string, offset uint64) (*ftp.Response, error)
 	StorFrom(path string, reader io.Reader, offset uint64) error
+	Append(path string, reader io.Reader) error
+
+	// SetTime sets mtime by MFMT, errors.ErrUnsupported returned when server not support it
+	SetTime(path string, t time.Time) error
 }
 
 type Pool struct {
@@ -170,6 +174,17 @@ func (c *conn) StorFrom(path string, reader io.Reader, offset uint64) error {
 	return c.conn.StorFrom(path, reader, offset)
 }
 
+func (c *conn) Append(path string, reader io.Reader) error {
+	return c.conn.Append(path, reader)
+}
+
+func (c *conn) SetTime(path string, t time.Time) error {
+	if !c.conn.IsSetTimeSupported() {
+		return errors.ErrUnsupported
+	}
+	return c.conn.SetTime(path, t)
+}
+
 func (c *conn) RetrFrom(path string, offset uint64) (*ftp.Response, error) {
 	return c.conn.RetrFrom(path, offset)
 }
@@ -42,6 +42,14 @@ func (c *Config) BasePath() string {
 }
 
 func (c *Config) Conn(ctx context.Context, args ...any) (Conn, error) {
+	p, err := c.pool()
+	if err != nil {
+		return nil, err
+	}
+	return p.Conn(ctx, args...)
+}
+
+func (c *Config) pool() (*Pool, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -95,5 +103,5 @@ func (c *Config) Conn(ctx context.Context, args ...any) (Conn, error) {
 		c.p = p
 	}
 
-	return c.p.Conn(ctx, args...)
+	return c.p, nil
 }
@@ -34,3 +34,15 @@ func normalizeError(op string, path string, err error, values ...any) error {
 		Err:  err,
 	}
 }
+
+// IsTransientError checks err is reply of service not available or data connection broken
+func (f *fs) IsTransientError(err error) bool {
+	tpErr := &textproto.Error{}
+	if errors.As(err, &tpErr) {
+		switch tpErr.Code {
+		case ftp.StatusNotAvailable, ftp.StatusCanNotOpenDataConnection, ftp.StatusTransfertAborted:
+			return true
+		}
+	}
+	return false
+}
@@ -1,14 +1,21 @@
 package ftp
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"io"
+	iofs "io/fs"
+	"iter"
 	"os"
 	"sync"
 	"time"
 
 	"github.com/jlaffaye/ftp"
 	"golang.org/x/sync/errgroup"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/fsutil"
 )
 
 type file struct {
@@ -18,22 +25,57 @@ type file struct {
 	client Client
 	flag   int
 	offset uint64
+	// not exists before opened
+	created bool
 
 	readCloser  io.ReadCloser
-	writeCloser io.WriteCloser
+	writeCloser *writeCloser
 	err         error
+	// stops aborting when ctx canceled
+	stop    func() bool
+	aborted bool
 
 	once sync.Once
+
+	dirReader *fsutil.DirReader
 }
 
 func (f *file) Close() error {
+	if f.dirReader != nil {
+		f.dirReader.Close()
+	}
+
+	if f.aborted {
+		return nil
+	}
+
+	// written should not be committed when canceled
+	if f.writable() && f.ctx.Err() != nil {
+		err := context.Cause(f.ctx)
+		_ = f.Abort(err)
+		return normalizeError("write", f.entry.Name, err)
+	}
+
+	// like os, file should be created or truncated even nothing written
+	if f.writeCloser == nil && f.err == nil && f.writable() && (f.created || f.flag&os.O_TRUNC != 0) {
+		if _, err := f.Write(nil); err != nil {
+			return err
+		}
+	}
+
 	eg := &errgroup.Group{}
 
 	if f.writeCloser != nil {
+		f.stop()
+
 		eg.Go(func() error {
 			err := f.writeCloser.Close()
 			f.writeCloser = nil
-			return err
+			if err != nil {
+				return err
+			}
+			// error of STOR, only known after all written
+			return f.err
 		})
 	}
 
@@ -108,11 +150,23 @@ func (c *readCloser) Close() error {
 	return eg.Wait()
 }
 
+func (f *file) writable() bool {
+	return f.flag&os.O_WRONLY != 0 || f.flag&os.O_RDWR != 0
+}
+
 func (f *file) Write(p []byte) (n int, err error) {
-	if !(f.flag&os.O_WRONLY != 0 || f.flag&os.O_RDWR != 0) {
+	if !f.writable() {
 		return 0, normalizeError("write", f.entry.Name, os.ErrPermission)
 	}
 
+	if f.aborted {
+		return 0, normalizeError("write", f.entry.Name, os.ErrClosed)
+	}
+
+	if err := f.ctx.Err(); err != nil {
+		return 0, normalizeError("write", f.entry.Name, context.Cause(f.ctx))
+	}
+
 	f.once.Do(func() {
 		conn, err := f.client.Conn(f.ctx, "write", f.entry.Name)
 		if err != nil {
@@ -122,9 +176,11 @@ func (f *file) Write(p []byte) (n int, err error) {
 
 		r, w := io.Pipe()
 
-		ww := &writeCloser{WriteCloser: w}
+		ww := &writeCloser{PipeWriter: w}
 		ww.wg.Add(1)
 
+		body := &bodyReader{PipeReader: r}
+
 		go func() {
 			defer func() {
 				_ = conn.Close()
@@ -132,11 +188,36 @@ func (f *file) Write(p []byte) (n int, err error) {
 				ww.wg.Done()
 			}()
 
-			if err := conn.StorFrom(f.entry.Name, r, f.offset); err != nil {
+			if f.flag&os.O_APPEND != 0 {
+				if err := conn.Append(f.entry.Name, body); err != nil {
+					f.err = normalizeError("write", f.entry.Name, err)
+				}
+				return
+			}
+
+			if err := conn.StorFrom(f.entry.Name, body, f.offset); err != nil {
 				f.err = normalizeError("write", f.entry.Name, err)
+
+				// data connection closed early when aborted, partial file stored by server should be deleted.
+				// not for resuming, which content before offset not written by this file.
+				if body.failed && f.offset == 0 {
+					_ = conn.Delete(f.entry.Name)
+				}
+				return
+			}
+
+			if mtime, ok := filesystem.ModTimeFromContext(f.ctx); ok {
+				if err := conn.SetTime(f.entry.Name, mtime); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+					f.err = normalizeError("chtimes", f.entry.Name, err)
+				}
 			}
 		}()
 
+		// data transfer failed when canceled
+		f.stop = context.AfterFunc(f.ctx, func() {
+			_ = w.CloseWithError(context.Cause(f.ctx))
+		})
+
 		f.writeCloser = ww
 	})
 
@@ -149,42 +230,88 @@ func (f *file) Write(p []byte) (n int, err error) {
 
 type writeCloser struct {
 	wg sync.WaitGroup
-	io.WriteCloser
+	*io.PipeWriter
 }
 
 func (c *writeCloser) Close() error {
-	err := c.WriteCloser.Close()
+	err := c.PipeWriter.Close()
 	c.wg.Wait()
 	return err
 }
 
-func (f *file) Readdir(count int) ([]os.FileInfo, error) {
-	conn, err := f.client.Conn(f.ctx)
-	if err != nil {
-		return nil, normalizeError("write", f.entry.Name, err)
+// bodyReader records body failed by writer, to tell from errors of server
+type bodyReader struct {
+	*io.PipeReader
+	failed bool
+}
+
+func (r *bodyReader) Read(p []byte) (int, error) {
+	n, err := r.PipeReader.Read(p)
+	if err != nil && err != io.EOF {
+		r.failed = true
+	}
+	return n, err
+}
+
+// Abort fails the data transfer, and the partial file will be deleted.
+// ABOR is not exposed by the client, but closing data connection early works the same for servers.
+func (f *file) Abort(err error) error {
+	if f.aborted {
+		return nil
 	}
-	defer conn.Close()
+	f.aborted = true
 
-	entries, err := conn.List(f.entry.Name)
-	if err != nil {
-		return nil, normalizeError("readdir", f.entry.Name, err)
+	if f.writeCloser != nil {
+		f.stop()
+		_ = f.writeCloser.CloseWithError(cmp.Or(err, filesystem.ErrAborted))
+		f.writeCloser.wg.Wait()
+		f.writeCloser = nil
 	}
 
-	n := len(entries)
-	if count > 0 {
-		n = 0
+	if f.readCloser != nil {
+		err := f.readCloser.Close()
+		f.readCloser = nil
+		return err
 	}
 
-	list := make([]os.FileInfo, n)
+	return nil
+}
 
-	for i := 0; i < n; i++ {
-		list[i] = &entry{
-			name:  entries[i].Name,
-			entry: entries[i],
-		}
+func (f *file) Readdir(count int) ([]os.FileInfo, error) {
+	if f.dirReader == nil {
+		f.dirReader = fsutil.NewDirReader(f.infos(f.ctx))
 	}
+	return f.dirReader.Readdir(count)
+}
 
-	return list, nil
+func (f *file) ReadDirIter(ctx context.Context) iter.Seq2[iofs.DirEntry, error] {
+	return fsutil.DirEntries(f.infos(ctx))
+}
+
+// infos lists entries by MLSD or LIST,
+// entries are buffered by github.com/jlaffaye/ftp before returned.
+func (f *file) infos(ctx context.Context) iter.Seq2[os.FileInfo, error] {
+	return func(yield func(os.FileInfo, error) bool) {
+		conn, err := f.client.Conn(ctx)
+		if err != nil {
+			yield(nil, normalizeError("readdir", f.entry.Name, err))
+			return
+		}
+
+		entries, err := conn.List(f.entry.Name)
+		// release conn before yielding, since entries are all loaded
+		_ = conn.Close()
+		if err != nil {
+			yield(nil, normalizeError("readdir", f.entry.Name, err))
+			return
+		}
+
+		for _, e := range entries {
+			if !yield(&entry{name: e.Name, entry: e}, nil) {
+				return
+			}
+		}
+	}
 }
 
 func (f *file) Stat() (os.FileInfo, error) {
@@ -211,6 +338,9 @@ func (f *entry) Mode() os.FileMode {
 	if f.IsDir() {
 		return os.ModeDir
 	}
+	if f.entry.Type == ftp.EntryTypeLink {
+		return os.ModeSymlink | os.ModePerm
+	}
 	return os.ModePerm
 }
 
@@ -24,11 +24,19 @@ type fs struct {
 	c *Config
 }
 
+func (f *fs) Capabilities() filesystem.Capability {
+	return filesystem.CapAppend | filesystem.CapAtomicRename | filesystem.CapAtomicWrite | filesystem.CapSetModTime | filesystem.CapSetMode
+}
+
 func (f *fs) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
 	if name == "" {
 		name = "."
 	}
 
+	if flag&filesystem.O_ATOMIC != 0 {
+		return filesystem.OpenByRename(ctx, f, name, flag, perm)
+	}
+
 	c, err := f.c.Conn(ctx)
 	if err != nil {
 		return nil, normalizeError("openfile", name, err)
@@ -59,7 +67,9 @@ func (f *fs) OpenFile(ctx context.Context, name string, flag int, perm os.FileMo
 		}
 	}
 
-	if ftpEntry == nil {
+	created := ftpEntry == nil
+
+	if created {
 		if !createWhenNotExists {
 			return nil, normalizeError("openfile", name, os.ErrNotExist)
 		}
@@ -80,10 +90,11 @@ func (f *fs) OpenFile(ctx context.Context, name string, flag int, perm os.FileMo
 	ftpEntry.Name = name
 
 	return &file{
-		ctx:    ctx,
-		client: f.c,
-		entry:  ftpEntry,
-		flag:   flag,
+		ctx:     ctx,
+		client:  f.c,
+		entry:   ftpEntry,
+		flag:    flag,
+		created: created && ftpEntry.Type == ftp.EntryTypeFile,
 	}, nil
 }
 
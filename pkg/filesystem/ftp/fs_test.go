@@ -2,12 +2,17 @@ package ftp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
+	"path"
+	"syscall"
 	"testing"
 	"time"
 
+	testingx "github.com/octohelm/x/testing"
+
 	"github.com/octohelm/unifs/pkg/filesystem"
 	"github.com/octohelm/unifs/pkg/filesystem/local"
 	"github.com/octohelm/unifs/pkg/filesystem/testutil"
@@ -85,5 +90,79 @@ func TestFTPFS(t *testing.T) {
 			testutil.TestFullFS(t, NewFS(c))
 			fmt.Println(c.p.count)
 		})
+
+		t.Run("AtomicWrite", func(t *testing.T) {
+			testutil.TestAtomicWrite(t, NewFS(c))
+		})
+
+		t.Run("Abort", func(t *testing.T) {
+			testutil.TestAbort(t, NewFS(c))
+		})
+
+		t.Run("Hash", func(t *testing.T) {
+			ctx := context.Background()
+			fsys := NewFS(c)
+
+			err := filesystem.Write(ctx, fsys, "hello.txt", []byte("hello"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			sum, err := fsys.(filesystem.Hasher).Hash(ctx, "hello.txt", filesystem.HashSHA256)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, sum, testingx.Be("2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"))
+
+			_, err = fsys.(filesystem.Hasher).Hash(ctx, "hello.txt", filesystem.HashCRC32C)
+			testingx.Expect(t, errors.Is(err, errors.ErrUnsupported), testingx.Be(true))
+		})
+
+		t.Run("Symlink", func(t *testing.T) {
+			ctx := context.Background()
+			fsys := NewFS(c)
+
+			err := filesystem.Write(ctx, fsys, "target.txt", []byte("hello"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			// SITE SYMLINK not served, links could escape root of local
+			err = filesystem.Symlink(ctx, fsys, "target.txt", "link")
+			testingx.Expect(t, errors.Is(err, errors.ErrUnsupported), testingx.Be(true))
+
+			err = os.Symlink("target.txt", path.Join(dir, "link"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			// type of MLSD entries not includes links
+			info, err := filesystem.Lstat(ctx, fsys, "link")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, info.Name(), testingx.Be("link"))
+
+			_, err = filesystem.Readlink(ctx, fsys, "target.txt")
+			testingx.Expect(t, errors.Is(err, syscall.EINVAL), testingx.Be(true))
+		})
+
+		t.Run("SetAttr", func(t *testing.T) {
+			ctx := context.Background()
+			fsys := NewFS(c)
+
+			mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+			err := filesystem.Write(ctx, fsys, "attrs.txt", []byte("hello"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			err = filesystem.SetModTime(ctx, fsys, "attrs.txt", mtime)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			err = filesystem.SetMode(ctx, fsys, "attrs.txt", 0o600)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			info, err := os.Stat(path.Join(dir, "attrs.txt"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, info.ModTime().Equal(mtime), testingx.Be(true))
+			testingx.Expect(t, info.Mode().Perm(), testingx.Be(os.FileMode(0o600)))
+
+			err = filesystem.Write(filesystem.ModTimeInjectContext(ctx, mtime), fsys, "injected.txt", []byte("hello"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			info, err = fsys.Stat(ctx, "injected.txt")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, info.ModTime().Equal(mtime), testingx.Be(true))
+		})
 	})
 }
@@ -0,0 +1,182 @@
+package ftp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+// names of HASH algorithms, https://datatracker.ietf.org/doc/html/draft-bryan-ftpext-hash-02
+var hashAlgorithms = map[filesystem.HashAlgorithm]string{
+	filesystem.HashMD5:    "MD5",
+	filesystem.HashSHA1:   "SHA-1",
+	filesystem.HashSHA256: "SHA-256",
+	filesystem.HashSHA512: "SHA-512",
+	filesystem.HashCRC32:  "CRC32",
+}
+
+func (f *fs) Hash(ctx context.Context, name string, algo filesystem.HashAlgorithm) (string, error) {
+	a, ok := hashAlgorithms[algo]
+	if !ok {
+		return "", errors.ErrUnsupported
+	}
+
+	p, err := f.c.pool()
+	if err != nil {
+		return "", err
+	}
+
+	sum, err := p.Hash(ctx, name, a)
+	if err != nil {
+		if errors.Is(err, errors.ErrUnsupported) {
+			return "", err
+		}
+		return "", normalizeError("hash", name, err)
+	}
+	return sum, nil
+}
+
+// Hash requests hash of file by HASH command.
+// errors.ErrUnsupported returned when server not support HASH or the algorithm.
+func (p *Pool) Hash(ctx context.Context, name string, algo string) (string, error) {
+	var sum string
+
+	err := p.control(ctx, func(c *textproto.Conn) error {
+		// not check FEAT, since some servers list algorithms without HASH,
+		// servers without HASH or the algorithm will reject OPTS HASH
+		if _, err := cmd(c, ftp.StatusCommandOK, "OPTS HASH %s", algo); err != nil {
+			return errors.ErrUnsupported
+		}
+
+		msg, err := cmd(c, ftp.StatusFile, "HASH %s", quote(name))
+		if err != nil {
+			return err
+		}
+
+		// <algo> <start>-<end> <hash> <path>
+		for _, line := range strings.Split(msg, "\n") {
+			if parts := strings.Fields(line); len(parts) >= 3 && parts[0] == algo {
+				sum = strings.ToLower(parts[2])
+				return nil
+			}
+		}
+
+		return fmt.Errorf("invalid HASH response: %s", msg)
+	})
+
+	return sum, err
+}
+
+// control creates a logged in control connection for commands not supported by github.com/jlaffaye/ftp,
+// like HASH and SITE commands.
+func (p *Pool) control(ctx context.Context, fn func(c *textproto.Conn) error) error {
+	connectTimeout := time.Second * 5
+	if p.ConnectTimeout > 0 {
+		connectTimeout = p.ConnectTimeout
+	}
+
+	dialer := &net.Dialer{Timeout: connectTimeout}
+
+	var nc net.Conn
+	var err error
+
+	if p.TLSConfig != nil && !p.ExplicitTLS {
+		nc, err = (&tls.Dialer{NetDialer: dialer, Config: p.TLSConfig}).DialContext(ctx, "tcp", p.Addr)
+	} else {
+		nc, err = dialer.DialContext(ctx, "tcp", p.Addr)
+	}
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = nc.SetDeadline(deadline)
+	}
+
+	c := textproto.NewConn(nc)
+
+	if _, _, err := c.ReadResponse(ftp.StatusReady); err != nil {
+		return err
+	}
+
+	if p.TLSConfig != nil && p.ExplicitTLS {
+		if _, err := cmd(c, ftp.StatusAuthOK, "AUTH TLS"); err != nil {
+			return err
+		}
+
+		tlsConfig := p.TLSConfig.Clone()
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName, _, _ = net.SplitHostPort(p.Addr)
+		}
+
+		nc = tls.Client(nc, tlsConfig)
+		c = textproto.NewConn(nc)
+	}
+
+	user, pass := "anonymous", "anonymous"
+	if p.Auth != nil {
+		user = p.Auth.Username()
+		pass, _ = p.Auth.Password()
+	}
+
+	code, _, err := cmdWithCode(c, "USER %s", user)
+	if err != nil {
+		return err
+	}
+	switch code {
+	case ftp.StatusLoggedIn:
+	case ftp.StatusUserOK:
+		if _, err := cmd(c, ftp.StatusLoggedIn, "PASS %s", pass); err != nil {
+			return err
+		}
+	default:
+		return &textproto.Error{Code: code, Msg: "login failed"}
+	}
+
+	defer func() {
+		_, _ = cmd(c, ftp.StatusClosing, "QUIT")
+	}()
+
+	return fn(c)
+}
+
+// quote quotes name with spaces as argument of commands
+func quote(name string) string {
+	if strings.Contains(name, " ") {
+		return `"` + name + `"`
+	}
+	return name
+}
+
+func cmd(c *textproto.Conn, expectCode int, format string, args ...any) (string, error) {
+	id, err := c.Cmd(format, args...)
+	if err != nil {
+		return "", err
+	}
+	c.StartResponse(id)
+	defer c.EndResponse(id)
+
+	_, msg, err := c.ReadResponse(expectCode)
+	return msg, err
+}
+
+func cmdWithCode(c *textproto.Conn, format string, args ...any) (int, string, error) {
+	id, err := c.Cmd(format, args...)
+	if err != nil {
+		return 0, "", err
+	}
+	c.StartResponse(id)
+	defer c.EndResponse(id)
+
+	return c.ReadResponse(0)
+}
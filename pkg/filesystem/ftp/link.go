@@ -0,0 +1,85 @@
+package ftp
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/jlaffaye/ftp"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+var _ filesystem.Linker = &fs{}
+
+// Symlink creates link by SITE SYMLINK, supported by servers like proftpd with mod_site_misc.
+// errors.ErrUnsupported returned when server not support it.
+func (f *fs) Symlink(ctx context.Context, target string, name string) error {
+	p, err := f.c.pool()
+	if err != nil {
+		return err
+	}
+
+	if err := p.Symlink(ctx, target, name); err != nil {
+		if errors.Is(err, errors.ErrUnsupported) {
+			return &os.LinkError{Op: "symlink", Old: target, New: name, Err: err}
+		}
+		return normalizeError("symlink", name, err, "to", target)
+	}
+	return nil
+}
+
+// Readlink finds target from entries listed of parent dir,
+// only known when server lists in form of `ls -l`, since type of MLSD not includes target.
+func (f *fs) Readlink(ctx context.Context, name string) (string, error) {
+	c, err := f.c.Conn(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+
+	entries, err := c.List(path.Dir(name))
+	if err != nil {
+		return "", normalizeError("readlink", name, err)
+	}
+
+	for _, e := range entries {
+		if e.Name != path.Base(name) {
+			continue
+		}
+		if e.Type != ftp.EntryTypeLink {
+			return "", &os.PathError{Op: "readlink", Path: name, Err: syscall.EINVAL}
+		}
+		if e.Target == "" {
+			return "", &os.PathError{Op: "readlink", Path: name, Err: errors.ErrUnsupported}
+		}
+		return e.Target, nil
+	}
+
+	return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrNotExist}
+}
+
+// Lstat same as Stat, entries of links are not followed by servers
+func (f *fs) Lstat(ctx context.Context, name string) (os.FileInfo, error) {
+	return f.Stat(ctx, name)
+}
+
+// Symlink sends SITE SYMLINK <target> <name>
+func (p *Pool) Symlink(ctx context.Context, target string, name string) error {
+	return p.control(ctx, func(c *textproto.Conn) error {
+		code, msg, err := cmdWithCode(c, "SITE SYMLINK %s %s", target, name)
+		if err != nil {
+			return err
+		}
+		switch code {
+		case ftp.StatusCommandOK:
+			return nil
+		case ftp.StatusBadCommand, ftp.StatusBadArguments, ftp.StatusNotImplemented, ftp.StatusNotImplementedParameter, ftp.StatusCommandNotImplemented:
+			return errors.ErrUnsupported
+		}
+		return &textproto.Error{Code: code, Msg: msg}
+	})
+}
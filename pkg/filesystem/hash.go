@@ -0,0 +1,121 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"mime"
+	"path"
+
+	"golang.org/x/net/webdav"
+)
+
+// HashAlgorithm of content hash
+type HashAlgorithm string
+
+const (
+	HashMD5    HashAlgorithm = "md5"
+	HashSHA1   HashAlgorithm = "sha1"
+	HashSHA256 HashAlgorithm = "sha256"
+	HashSHA512 HashAlgorithm = "sha512"
+	HashCRC32  HashAlgorithm = "crc32"
+	HashCRC32C HashAlgorithm = "crc32c"
+)
+
+var HashAlgorithms = []HashAlgorithm{HashMD5, HashSHA1, HashSHA256, HashSHA512, HashCRC32, HashCRC32C}
+
+func ParseHashAlgorithm(s string) (HashAlgorithm, error) {
+	for _, a := range HashAlgorithms {
+		if string(a) == s {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported hash algorithm %q, should be one of %v", s, HashAlgorithms)
+}
+
+func (a HashAlgorithm) New() (hash.Hash, error) {
+	switch a {
+	case HashMD5:
+		return md5.New(), nil
+	case HashSHA1:
+		return sha1.New(), nil
+	case HashSHA256:
+		return sha256.New(), nil
+	case HashSHA512:
+		return sha512.New(), nil
+	case HashCRC32:
+		return crc32.NewIEEE(), nil
+	case HashCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	}
+	return nil, fmt.Errorf("unsupported hash algorithm %q: %w", a, errors.ErrUnsupported)
+}
+
+// Hasher could be implemented by FileSystem to return hash of file without streaming content through the client.
+//
+// hash should be lower case hex.
+// errors.ErrUnsupported could be returned to fall back to streaming hash.
+type Hasher interface {
+	Hash(ctx context.Context, name string, algo HashAlgorithm) (string, error)
+}
+
+// Hash returns lower case hex hash of file content.
+// Hasher of fsys will be used when implemented, otherwise content will be streamed.
+func Hash(ctx context.Context, fsys FileSystem, name string, algo HashAlgorithm) (string, error) {
+	if h, ok := fsys.(Hasher); ok {
+		if sum, err := h.Hash(ctx, name, algo); !errors.Is(err, errors.ErrUnsupported) {
+			return sum, err
+		}
+	}
+
+	return StreamHash(ctx, fsys, name, algo)
+}
+
+// StreamHash returns lower case hex hash by reading the whole content
+func StreamHash(ctx context.Context, fsys FileSystem, name string, algo HashAlgorithm) (string, error) {
+	h, err := algo.New()
+	if err != nil {
+		return "", err
+	}
+
+	f, err := Open(ctx, fsys, name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", &fs.PathError{Op: "hash", Path: name, Err: err}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ETagOf returns ETag of info quoted as http header, empty when not provided by backend
+func ETagOf(ctx context.Context, info FileInfo) string {
+	if e, ok := info.(webdav.ETager); ok {
+		if etag, err := e.ETag(ctx); err == nil {
+			return etag
+		}
+	}
+	return ""
+}
+
+// ContentTypeOf returns content type of info, or detected by extension of name when not provided by backend
+func ContentTypeOf(ctx context.Context, info FileInfo) string {
+	if c, ok := info.(webdav.ContentTyper); ok {
+		if contentType, err := c.ContentType(ctx); err == nil && contentType != "" {
+			return contentType
+		}
+	}
+	return mime.TypeByExtension(path.Ext(info.Name()))
+}
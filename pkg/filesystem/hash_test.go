@@ -0,0 +1,38 @@
+package filesystem_test
+
+import (
+	"context"
+	"testing"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+func TestHash(t *testing.T) {
+	ctx := context.Background()
+
+	fsys := filesystem.NewMemFS()
+	_ = filesystem.Write(ctx, fsys, "/hello.json", []byte("hello"))
+
+	t.Run("stream hash as fallback", func(t *testing.T) {
+		sum, err := filesystem.Hash(ctx, fsys, "/hello.json", filesystem.HashSHA256)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, sum, testingx.Be("2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"))
+
+		sum, err = filesystem.Hash(ctx, filesystem.Sub(fsys, "/"), "hello.json", filesystem.HashMD5)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, sum, testingx.Be("5d41402abc4b2a76b9719d911017c592"))
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		_, err := filesystem.ParseHashAlgorithm("sha3")
+		testingx.Expect(t, err, testingx.Not(testingx.Be[error](nil)))
+	})
+
+	t.Run("content type by extension when not provided", func(t *testing.T) {
+		info, err := fsys.Stat(ctx, "/hello.json")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, filesystem.ContentTypeOf(ctx, info), testingx.Be("application/json"))
+	})
+}
@@ -0,0 +1,57 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+// Linker could be implemented by FileSystem to create and read symbolic links.
+// errors.ErrUnsupported could be returned when backend could not store links.
+//
+// links are never followed by FileSystem, OpenFile and Stat of a link work on the link itself for emulated backends,
+// resolving targets is left to clients, like kernel through fuse.
+type Linker interface {
+	// Symlink creates name as symbolic link to target, like os.Symlink, target is not required to exist
+	Symlink(ctx context.Context, target string, name string) error
+	// Readlink returns target of symbolic link name, like os.Readlink
+	Readlink(ctx context.Context, name string) (string, error)
+	// Lstat returns FileInfo of name with os.ModeSymlink when name is a symbolic link
+	Lstat(ctx context.Context, name string) (os.FileInfo, error)
+}
+
+// Symlink creates name as symbolic link to target by Linker
+func Symlink(ctx context.Context, fsys FileSystem, target string, name string) error {
+	if l, ok := fsys.(Linker); ok {
+		if err := l.Symlink(ctx, target, name); !errors.Is(err, errors.ErrUnsupported) {
+			return err
+		}
+	}
+	return &os.LinkError{Op: "symlink", Old: target, New: name, Err: errors.ErrUnsupported}
+}
+
+// Readlink returns target of symbolic link name by Linker
+func Readlink(ctx context.Context, fsys FileSystem, name string) (string, error) {
+	if l, ok := fsys.(Linker); ok {
+		if target, err := l.Readlink(ctx, name); !errors.Is(err, errors.ErrUnsupported) {
+			return target, err
+		}
+	}
+	return "", &os.PathError{Op: "readlink", Path: name, Err: errors.ErrUnsupported}
+}
+
+// Lstat returns FileInfo of name without following symbolic link.
+// Linker will be used when implemented, otherwise Stat, since no links could exist.
+func Lstat(ctx context.Context, fsys FileSystem, name string) (os.FileInfo, error) {
+	if l, ok := fsys.(Linker); ok {
+		if info, err := l.Lstat(ctx, name); !errors.Is(err, errors.ErrUnsupported) {
+			return info, err
+		}
+	}
+	return fsys.Stat(ctx, name)
+}
+
+// IsSymlink checks info is of a symbolic link
+func IsSymlink(info os.FileInfo) bool {
+	return info != nil && info.Mode()&os.ModeSymlink != 0
+}
@@ -0,0 +1,44 @@
+package local
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+var _ filesystem.AttrSetter = &fs{}
+var _ filesystem.Chowner = &fs{}
+
+func (fsys *fs) SetMode(ctx context.Context, name string, mode os.FileMode) error {
+	if err := fsys.confine("chmod", name, true); err != nil {
+		return err
+	}
+	if err := os.Chmod(fsys.resolve(name), mode.Perm()); err != nil {
+		return &os.PathError{Op: "chmod", Path: name, Err: unwrap(err)}
+	}
+	return nil
+}
+
+// SetModTime changes mtime only, atime is kept
+func (fsys *fs) SetModTime(ctx context.Context, name string, mtime time.Time) error {
+	if err := fsys.confine("chtimes", name, true); err != nil {
+		return err
+	}
+	if err := os.Chtimes(fsys.resolve(name), time.Time{}, mtime); err != nil {
+		return &os.PathError{Op: "chtimes", Path: name, Err: unwrap(err)}
+	}
+	return nil
+}
+
+// Chown changes owner of link itself, same as os.Lchown
+func (fsys *fs) Chown(ctx context.Context, name string, uid, gid int) error {
+	if err := fsys.confine("chown", name, false); err != nil {
+		return err
+	}
+	if err := os.Lchown(fsys.resolve(name), uid, gid); err != nil {
+		return &os.PathError{Op: "chown", Path: name, Err: unwrap(err)}
+	}
+	return nil
+}
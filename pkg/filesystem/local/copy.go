@@ -0,0 +1,109 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+func (fsys *fs) Copy(ctx context.Context, oldName, newName string, recursive bool) error {
+	src, dst := fsys.resolve(oldName), fsys.resolve(newName)
+	if src == "" || dst == "" {
+		return &os.PathError{Op: "copy", Path: newName, Err: os.ErrNotExist}
+	}
+
+	if err := fsys.confine("copy", oldName, true); err != nil {
+		return err
+	}
+	if err := fsys.confine("copy", newName, false); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(dst); err == nil {
+		if err := fsys.RemoveAll(ctx, newName); err != nil {
+			return err
+		}
+	}
+
+	if err := fsys.copyPath(ctx, src, dst, info, recursive); err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		// sidecars in dir copied with dir
+		if err := copyFile(fsys.resolve(sidecarName(oldName)), fsys.resolve(sidecarName(newName)), 0o644); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (fsys *fs) copyPath(ctx context.Context, src string, dst string, info os.FileInfo, recursive bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// links under dir will be followed
+	if info.Mode()&os.ModeSymlink != 0 && !fsys.within(src, true) {
+		return &os.PathError{Op: "copy", Path: filepath.Base(src), Err: os.ErrPermission}
+	}
+
+	if !info.IsDir() {
+		return copyFile(src, dst, info.Mode().Perm())
+	}
+
+	if err := os.Mkdir(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	if !recursive {
+		return nil
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		i, err := e.Info()
+		if err != nil {
+			return err
+		}
+		if err := fsys.copyPath(ctx, filepath.Join(src, e.Name()), filepath.Join(dst, e.Name()), i, recursive); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyFile(src string, dst string, perm os.FileMode) error {
+	s, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	d, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	// *os.File.ReadFrom uses copy_file_range when supported,
+	// content will be copied in kernel.
+	if _, err := io.Copy(d, s); err != nil {
+		_ = d.Close()
+		return err
+	}
+
+	return d.Close()
+}
@@ -1,11 +1,153 @@
 package local
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
 	"golang.org/x/net/webdav"
 
 	"github.com/octohelm/unifs/pkg/filesystem"
 )
 
 func NewFS(prefix string) filesystem.FileSystem {
-	return webdav.Dir(prefix)
+	return &fs{Dir: webdav.Dir(prefix)}
+}
+
+type fs struct {
+	webdav.Dir
+}
+
+func (fsys *fs) Capabilities() filesystem.Capability {
+	return filesystem.CapAppend |
+		filesystem.CapRandomAccessWrite |
+		filesystem.CapAtomicRename |
+		filesystem.CapServerSideCopy |
+		filesystem.CapReaderAt |
+		filesystem.CapTruncate |
+		filesystem.CapAtomicWrite |
+		filesystem.CapSymlink |
+		filesystem.CapSetModTime |
+		filesystem.CapSetMode |
+		filesystem.CapChown
+}
+
+func (fsys *fs) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if flag&filesystem.O_ATOMIC != 0 {
+		return filesystem.OpenByRename(ctx, fsys, name, flag, perm)
+	}
+
+	if err := fsys.confine("open", name, true); err != nil {
+		return nil, err
+	}
+
+	write := flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0
+
+	userMetadata, err := filesystem.UserMetadataFromContext(ctx).Normalize()
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	f, created, err := fsys.openFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	// metadata replaced when truncated
+	if write && (len(userMetadata) > 0 || flag&os.O_TRUNC != 0) {
+		if err := fsys.writeUserMetadata(name, userMetadata); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+
+	if osFile, ok := f.(*os.File); ok {
+		lf := &file{File: osFile, fsys: fsys, name: name, created: created}
+		if write {
+			lf.modTime, _ = filesystem.ModTimeFromContext(ctx)
+		}
+		return lf, nil
+	}
+	return f, nil
+}
+
+// openFile opens with O_EXCL first when O_CREATE, to know file created or not
+func (fsys *fs) openFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, bool, error) {
+	if flag&os.O_CREATE == 0 {
+		f, err := fsys.Dir.OpenFile(ctx, name, flag, perm)
+		return f, false, err
+	}
+
+	f, err := fsys.Dir.OpenFile(ctx, name, flag|os.O_EXCL, perm)
+	if err == nil {
+		return f, true, nil
+	}
+	if flag&os.O_EXCL != 0 || !errors.Is(err, os.ErrExist) {
+		return nil, false, err
+	}
+
+	f, err = fsys.Dir.OpenFile(ctx, name, flag, perm)
+	return f, false, err
+}
+
+func (fsys *fs) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	if err := fsys.confine("mkdir", name, false); err != nil {
+		return err
+	}
+	return fsys.Dir.Mkdir(ctx, name, perm)
+}
+
+func (fsys *fs) RemoveAll(ctx context.Context, name string) error {
+	if err := fsys.confine("removeall", name, false); err != nil {
+		return err
+	}
+	if err := fsys.Dir.RemoveAll(ctx, name); err != nil {
+		return err
+	}
+	return fsys.removeUserMetadata(name)
+}
+
+func (fsys *fs) Rename(ctx context.Context, oldName, newName string) error {
+	for _, name := range []string{oldName, newName} {
+		if err := fsys.confine("rename", name, false); err != nil {
+			return err
+		}
+	}
+	if err := fsys.Dir.Rename(ctx, oldName, newName); err != nil {
+		return err
+	}
+	return fsys.moveUserMetadata(oldName, newName)
+}
+
+func (fsys *fs) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	if err := fsys.confine("stat", name, true); err != nil {
+		return nil, err
+	}
+	info, err := fsys.Dir.Stat(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return fsys.fileInfo(name, info), nil
+}
+
+// resolve same as webdav.Dir
+func (fsys *fs) resolve(name string) string {
+	if filepath.Separator != '/' && strings.IndexRune(name, filepath.Separator) >= 0 {
+		return ""
+	}
+	dir := string(fsys.Dir)
+	if dir == "" {
+		dir = "."
+	}
+	return filepath.Join(dir, filepath.FromSlash(slashClean(name)))
+}
+
+func slashClean(name string) string {
+	if name == "" || name[0] != '/' {
+		name = "/" + name
+	}
+	return path.Clean(name)
 }
@@ -1,8 +1,16 @@
 package local
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
 	"github.com/octohelm/unifs/pkg/filesystem/testutil"
 )
 
@@ -14,4 +22,194 @@ func TestLocalFS(t *testing.T) {
 	t.Run("Full", func(t *testing.T) {
 		testutil.TestFullFS(t, NewFS(t.TempDir()))
 	})
+
+	t.Run("AtomicWrite", func(t *testing.T) {
+		testutil.TestAtomicWrite(t, NewFS(t.TempDir()))
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		testutil.TestAbort(t, NewFS(t.TempDir()))
+	})
+
+	t.Run("Symlink", func(t *testing.T) {
+		testutil.TestSymlink(t, NewFS(t.TempDir()))
+	})
+
+	t.Run("SetAttr", func(t *testing.T) {
+		testutil.TestSetAttr(t, NewFS(t.TempDir()))
+	})
+
+	t.Run("Symlink confined", func(t *testing.T) {
+		ctx := context.Background()
+		root := t.TempDir()
+		prefix := filepath.Join(root, "prefix")
+		outside := filepath.Join(root, "outside")
+
+		for _, dir := range []string{filepath.Join(prefix, "d"), outside} {
+			err := os.MkdirAll(dir, os.ModePerm)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+		}
+		err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		fsys := NewFS(prefix)
+
+		for _, target := range []string{outside, "../outside/secret.txt", "d/../../outside"} {
+			err := filesystem.Symlink(ctx, fsys, target, "/x")
+			testingx.Expect(t, errors.Is(err, os.ErrPermission), testingx.Be(true))
+		}
+
+		// created by others
+		err = os.Symlink(outside, filepath.Join(prefix, "external"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		// moved out
+		err = filesystem.Symlink(ctx, fsys, "../outside/secret.txt", "/d/moved")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		err = fsys.Rename(ctx, "/d/moved", "/moved")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		// chained
+		err = filesystem.Symlink(ctx, fsys, ".", "/self")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		err = filesystem.Symlink(ctx, fsys, "self/../outside", "/chained")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = fsys.OpenFile(ctx, "/moved", os.O_WRONLY|os.O_CREATE, os.ModePerm)
+		testingx.Expect(t, errors.Is(err, os.ErrPermission), testingx.Be(true))
+
+		for _, name := range []string{"/external/secret.txt", "/moved", "/chained/secret.txt"} {
+			_, err := fsys.Stat(ctx, name)
+			testingx.Expect(t, errors.Is(err, os.ErrPermission), testingx.Be(true))
+
+			_, err = filesystem.Open(ctx, fsys, name)
+			testingx.Expect(t, errors.Is(err, os.ErrPermission), testingx.Be(true))
+
+			// link itself could be removed
+			err = fsys.RemoveAll(ctx, name)
+			testingx.Expect(t, errors.Is(err, os.ErrPermission) || name == "/moved", testingx.Be(true))
+		}
+
+		err = fsys.Mkdir(ctx, "/external/d", os.ModePerm)
+		testingx.Expect(t, errors.Is(err, os.ErrPermission), testingx.Be(true))
+
+		data, err := os.ReadFile(filepath.Join(outside, "secret.txt"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, string(data), testingx.Be("secret"))
+
+		err = filesystem.Symlink(ctx, fsys, "d", "/link")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		err = filesystem.Write(ctx, fsys, "/link/1.txt", []byte("1"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+	})
+
+	t.Run("AbortCreated", func(t *testing.T) {
+		ctx := context.Background()
+		fsys := NewFS(t.TempDir())
+
+		err := filesystem.Write(ctx, fsys, "/existing.txt", []byte("old"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		for _, name := range []string{"/created.txt", "/existing.txt"} {
+			f, err := fsys.OpenFile(ctx, name, os.O_WRONLY|os.O_CREATE, os.ModePerm)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			err = filesystem.Abort(f, nil)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+		}
+
+		_, err = fsys.Stat(ctx, "/created.txt")
+		testingx.Expect(t, os.IsNotExist(err), testingx.Be(true))
+
+		// written to existing file could not be discarded without O_ATOMIC, but file kept
+		_, err = fsys.Stat(ctx, "/existing.txt")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+	})
+
+	t.Run("UserMetadata", func(t *testing.T) {
+		ctx := context.Background()
+		fsys := NewFS(t.TempDir())
+
+		metaOf := func(t *testing.T, name string) filesystem.UserMetadata {
+			info, err := fsys.Stat(ctx, name)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			return filesystem.UserMetadataOf(ctx, info)
+		}
+
+		err := filesystem.Write(
+			filesystem.UserMetadataInjectContext(ctx, filesystem.UserMetadata{"Producer": "ci", "commit-id": "abc"}),
+			fsys, "/artifact.tgz", []byte("1"),
+		)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, metaOf(t, "/artifact.tgz"), testingx.Equal(filesystem.UserMetadata{"producer": "ci", "commit-id": "abc"}))
+
+		entries, err := filesystem.ReadDir(ctx, fsys, "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, len(entries), testingx.Be(1))
+
+		err = fsys.Rename(ctx, "/artifact.tgz", "/renamed.tgz")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, metaOf(t, "/renamed.tgz").Get("producer"), testingx.Be("ci"))
+
+		err = fsys.(filesystem.Copier).Copy(ctx, "/renamed.tgz", "/copied.tgz", false)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, metaOf(t, "/copied.tgz").Get("commit-id"), testingx.Be("abc"))
+
+		err = filesystem.Write(ctx, fsys, "/copied.tgz", []byte("2"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, metaOf(t, "/copied.tgz"), testingx.Equal(filesystem.UserMetadata{}))
+
+		err = filesystem.Write(
+			filesystem.UserMetadataInjectContext(ctx, filesystem.UserMetadata{"commit id": "abc"}),
+			fsys, "/invalid.tgz", []byte("1"),
+		)
+		testingx.Expect(t, err, testingx.Not(testingx.Be[error](nil)))
+	})
+
+	t.Run("Watch", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		fsys := NewFS(t.TempDir())
+		_ = filesystem.MkdirAll(ctx, fsys, "/in/sub")
+
+		events := make(chan *filesystem.Event, 16)
+
+		go func() {
+			for e, err := range filesystem.Watch(ctx, fsys, "/in", filesystem.WatchInterval(10*time.Millisecond)) {
+				if err == nil {
+					events <- e
+				}
+			}
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+
+		next := func(t *testing.T) filesystem.Event {
+			select {
+			case e := <-events:
+				return *e
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout")
+			}
+			return filesystem.Event{}
+		}
+
+		_ = filesystem.Write(ctx, fsys, "/in/sub/a.txt", []byte("1"))
+		testingx.Expect(t, next(t), testingx.Equal(filesystem.Event{Op: filesystem.EventCreate, Path: "/in/sub/a.txt"}))
+		testingx.Expect(t, next(t), testingx.Equal(filesystem.Event{Op: filesystem.EventModify, Path: "/in/sub/a.txt"}))
+
+		_ = fsys.Rename(ctx, "/in/sub/a.txt", "/in/b.txt")
+		testingx.Expect(t, next(t), testingx.Equal(filesystem.Event{Op: filesystem.EventRename, Path: "/in/b.txt", OldPath: "/in/sub/a.txt"}))
+
+		_ = filesystem.MkdirAll(ctx, fsys, "/in/new")
+		_ = filesystem.Write(ctx, fsys, "/in/new/c.txt", []byte("1"))
+		e := next(t)
+		testingx.Expect(t, e.Path, testingx.Be("/in/new/c.txt"))
+
+		_ = fsys.RemoveAll(ctx, "/in/b.txt")
+		for e.Op != filesystem.EventDelete {
+			e = next(t)
+		}
+		testingx.Expect(t, e, testingx.Equal(filesystem.Event{Op: filesystem.EventDelete, Path: "/in/b.txt"}))
+	})
 }
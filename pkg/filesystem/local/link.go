@@ -0,0 +1,134 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+var _ filesystem.Linker = &fs{}
+
+// Symlink creates native symbolic link, absolute target or target out of the prefix is rejected.
+//
+// links could still be resolved out of the prefix, like moved by Rename, chained by other links or created by others,
+// so links are checked when followed too, see confine.
+func (fsys *fs) Symlink(ctx context.Context, target string, name string) error {
+	if escapes(name, target) {
+		return &os.LinkError{Op: "symlink", Old: target, New: name, Err: os.ErrPermission}
+	}
+	if err := fsys.confine("symlink", name, false); err != nil {
+		return err
+	}
+	if err := os.Symlink(target, fsys.resolve(name)); err != nil {
+		return &os.LinkError{Op: "symlink", Old: target, New: name, Err: unwrap(err)}
+	}
+	return nil
+}
+
+func (fsys *fs) Readlink(ctx context.Context, name string) (string, error) {
+	if err := fsys.confine("readlink", name, false); err != nil {
+		return "", err
+	}
+	target, err := os.Readlink(fsys.resolve(name))
+	if err != nil {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: unwrap(err)}
+	}
+	return target, nil
+}
+
+func (fsys *fs) Lstat(ctx context.Context, name string) (os.FileInfo, error) {
+	if err := fsys.confine("lstat", name, false); err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(fsys.resolve(name))
+	if err != nil {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: unwrap(err)}
+	}
+	return fsys.fileInfo(name, info), nil
+}
+
+// escapes checks target of link name is absolute or out of the prefix
+func escapes(name string, target string) bool {
+	if path.IsAbs(target) || filepath.IsAbs(target) {
+		return true
+	}
+	p := path.Join(strings.TrimPrefix(path.Dir(slashClean(name)), "/"), target)
+	return p == ".." || strings.HasPrefix(p, "../")
+}
+
+// max links followed, same as linux
+const maxFollowedLinks = 40
+
+// confine checks links of name resolved within the prefix, fs.ErrPermission returned when not.
+// links of parent dirs are always followed, and link of name only followed when follow.
+func (fsys *fs) confine(op string, name string, follow bool) error {
+	p := fsys.resolve(name)
+	if p == "" || fsys.within(p, follow) {
+		return nil
+	}
+	return &os.PathError{Op: op, Path: name, Err: os.ErrPermission}
+}
+
+func (fsys *fs) within(p string, follow bool) bool {
+	root, err := filepath.EvalSymlinks(fsys.resolve("/"))
+	if err != nil {
+		// prefix not exists, nothing could be resolved
+		return true
+	}
+
+	contains := func(p string) bool {
+		rel, err := filepath.Rel(root, p)
+		return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	}
+
+	for range maxFollowedLinks {
+		if follow {
+			if real, err := filepath.EvalSymlinks(p); err == nil {
+				return contains(real)
+			}
+		}
+
+		if p == fsys.resolve("/") {
+			return true
+		}
+
+		dir, err := filepath.EvalSymlinks(filepath.Dir(p))
+		if err != nil {
+			// parent not resolved, the op will fail too
+			return true
+		}
+		if !contains(dir) {
+			return false
+		}
+		if !follow {
+			return true
+		}
+
+		// dangling link will be created in its target when followed
+		target, err := os.Readlink(p)
+		if err != nil {
+			return true
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(dir, target)
+		}
+		p = target
+	}
+
+	return false
+}
+
+// unwrap returns syscall error of os errors, to hide resolved paths
+func unwrap(err error) error {
+	switch x := err.(type) {
+	case *os.PathError:
+		return x.Err
+	case *os.LinkError:
+		return x.Err
+	}
+	return err
+}
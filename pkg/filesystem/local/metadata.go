@@ -0,0 +1,196 @@
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	iofs "io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+// user metadata of file stored in hidden sidecar file `.<name>.unifs-meta.json` of same dir,
+// sidecar files will be hidden from Readdir, and moved, copied or removed with the file.
+const sidecarSuffix = ".unifs-meta.json"
+
+func sidecarName(name string) string {
+	dir, base := path.Split(slashClean(name))
+	return path.Join(dir, "."+base+sidecarSuffix)
+}
+
+func isSidecar(base string) bool {
+	return strings.HasPrefix(base, ".") && strings.HasSuffix(base, sidecarSuffix)
+}
+
+func (fsys *fs) readUserMetadata(name string) (filesystem.UserMetadata, error) {
+	data, err := os.ReadFile(fsys.resolve(sidecarName(name)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return filesystem.UserMetadata{}, nil
+		}
+		return nil, err
+	}
+
+	meta := filesystem.UserMetadata{}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, &os.PathError{Op: "read", Path: sidecarName(name), Err: err}
+	}
+	return meta, nil
+}
+
+// writeUserMetadata writes sidecar file, or removes it when meta is empty
+func (fsys *fs) writeUserMetadata(name string, meta filesystem.UserMetadata) error {
+	if len(meta) == 0 {
+		return fsys.removeUserMetadata(name)
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fsys.resolve(sidecarName(name)), data, 0o644)
+}
+
+func (fsys *fs) removeUserMetadata(name string) error {
+	if err := os.Remove(fsys.resolve(sidecarName(name))); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// moveUserMetadata moves sidecar of oldName to newName, or removes sidecar of replaced newName
+func (fsys *fs) moveUserMetadata(oldName, newName string) error {
+	err := os.Rename(fsys.resolve(sidecarName(oldName)), fsys.resolve(sidecarName(newName)))
+	if errors.Is(err, os.ErrNotExist) {
+		return fsys.removeUserMetadata(newName)
+	}
+	return err
+}
+
+type fileInfo struct {
+	os.FileInfo
+
+	fsys *fs
+	name string
+}
+
+// UserMetadata reads sidecar file lazily
+func (i *fileInfo) UserMetadata(ctx context.Context) (filesystem.UserMetadata, error) {
+	return i.fsys.readUserMetadata(i.name)
+}
+
+type file struct {
+	*os.File
+
+	fsys *fs
+	name string
+	// not exists before opened
+	created bool
+	aborted bool
+	// set after closed, when injected by filesystem.ModTimeInjectContext
+	modTime time.Time
+}
+
+func (f *file) Close() error {
+	if f.aborted {
+		return nil
+	}
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	if !f.modTime.IsZero() {
+		return f.fsys.SetModTime(context.Background(), f.name, f.modTime)
+	}
+	return nil
+}
+
+// Abort removes file created by this open.
+// written to existing file could not be discarded, use O_ATOMIC instead, which removes the temp file.
+func (f *file) Abort(err error) error {
+	if f.aborted {
+		return nil
+	}
+	f.aborted = true
+
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+
+	if f.created {
+		return f.fsys.RemoveAll(context.Background(), f.name)
+	}
+	return nil
+}
+
+func (f *file) Stat() (os.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return f.fsys.fileInfo(f.name, info), nil
+}
+
+// Readdir hides sidecar files
+func (f *file) Readdir(count int) ([]os.FileInfo, error) {
+	for {
+		infos, err := f.File.Readdir(count)
+
+		filtered := make([]os.FileInfo, 0, len(infos))
+		for _, info := range infos {
+			if isSidecar(info.Name()) {
+				continue
+			}
+			filtered = append(filtered, f.fsys.fileInfo(path.Join(f.name, info.Name()), info))
+		}
+
+		// all listed are sidecars, continue to keep returning at least one when count > 0
+		if len(filtered) > 0 || err != nil || count <= 0 {
+			return filtered, err
+		}
+	}
+}
+
+// ReadDir hides sidecar files
+func (f *file) ReadDir(count int) ([]iofs.DirEntry, error) {
+	for {
+		entries, err := f.File.ReadDir(count)
+
+		filtered := make([]iofs.DirEntry, 0, len(entries))
+		for _, e := range entries {
+			if isSidecar(e.Name()) {
+				continue
+			}
+			filtered = append(filtered, &dirEntry{DirEntry: e, fsys: f.fsys, name: path.Join(f.name, e.Name())})
+		}
+
+		if len(filtered) > 0 || err != nil || count <= 0 {
+			return filtered, err
+		}
+	}
+}
+
+type dirEntry struct {
+	iofs.DirEntry
+
+	fsys *fs
+	name string
+}
+
+func (e *dirEntry) Info() (iofs.FileInfo, error) {
+	info, err := e.DirEntry.Info()
+	if err != nil {
+		return nil, err
+	}
+	return e.fsys.fileInfo(e.name, info), nil
+}
+
+func (fsys *fs) fileInfo(name string, info os.FileInfo) os.FileInfo {
+	if info.IsDir() {
+		return info
+	}
+	return &fileInfo{FileInfo: info, fsys: fsys, name: slashClean(name)}
+}
@@ -0,0 +1,268 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+var _ filesystem.Watcher = &fs{}
+
+const inotifyMask = syscall.IN_CREATE |
+	syscall.IN_CLOSE_WRITE |
+	syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO |
+	syscall.IN_ONLYDIR
+
+// Watch files under dir name by inotify, sub dirs created will be watched too.
+// like polling, only events of files will be yielded, a modify event yielded when file closed after written.
+// files of dir moved out are not tracked, so no delete events for them.
+func (fsys *fs) Watch(ctx context.Context, name string) iter.Seq2[*filesystem.Event, error] {
+	return func(yield func(*filesystem.Event, error) bool) {
+		if err := fsys.confine("watch", name, true); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+		if err != nil {
+			yield(nil, os.NewSyscallError("inotify_init1", err))
+			return
+		}
+
+		// non-blocking fd will be added to runtime poller, then Read could be interrupted by Close
+		f := os.NewFile(uintptr(fd), "inotify")
+		defer f.Close()
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		go func() {
+			<-ctx.Done()
+			_ = f.Close()
+		}()
+
+		w := &inotify{fd: fd, fsys: fsys, dirs: map[int]string{}}
+
+		if _, err := w.add(slashClean(name)); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		buf := make([]byte, 64*1024)
+
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				if ctx.Err() != nil || errors.Is(err, os.ErrClosed) {
+					return
+				}
+				yield(nil, err)
+				return
+			}
+
+			for _, e := range w.parse(buf[:n]) {
+				if !yield(e, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+type inotify struct {
+	fd   int
+	fsys *fs
+	dirs map[int]string
+}
+
+// add watches dir and all its sub dirs, returns files under them
+func (w *inotify) add(dir string) ([]string, error) {
+	wd, err := syscall.InotifyAddWatch(w.fd, w.fsys.resolve(dir), inotifyMask)
+	if err != nil {
+		return nil, &os.PathError{Op: "watch", Path: dir, Err: err}
+	}
+	w.dirs[wd] = dir
+
+	entries, err := os.ReadDir(w.fsys.resolve(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(entries))
+
+	for _, e := range entries {
+		p := path.Join(dir, e.Name())
+
+		if e.IsDir() {
+			sub, err := w.add(p)
+			if err != nil {
+				// removed before watched
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				return nil, err
+			}
+			files = append(files, sub...)
+			continue
+		}
+
+		if !isSidecar(e.Name()) && !filesystem.IsTempName(e.Name()) {
+			files = append(files, p)
+		}
+	}
+
+	return files, nil
+}
+
+// move updates paths of watched dirs when dir renamed, returns files under it
+func (w *inotify) move(oldDir string, newDir string) []string {
+	for wd, d := range w.dirs {
+		if d == oldDir || strings.HasPrefix(d, oldDir+"/") {
+			w.dirs[wd] = newDir + strings.TrimPrefix(d, oldDir)
+		}
+	}
+
+	files := make([]string, 0)
+	_ = walkFiles(w.fsys, newDir, func(p string) {
+		files = append(files, p)
+	})
+	return files
+}
+
+// forget stops watching dir and its sub dirs moved out
+func (w *inotify) forget(dir string) {
+	for wd, d := range w.dirs {
+		if d == dir || strings.HasPrefix(d, dir+"/") {
+			_, _ = syscall.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.dirs, wd)
+		}
+	}
+}
+
+func (w *inotify) parse(buf []byte) []*filesystem.Event {
+	events := make([]*filesystem.Event, 0)
+
+	type movedFrom struct {
+		path  string
+		isDir bool
+	}
+
+	moved := map[uint32]movedFrom{}
+	movedCookies := make([]uint32, 0)
+
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(buf); {
+		raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		nameStart := offset + syscall.SizeofInotifyEvent
+		offset = nameStart + int(raw.Len)
+
+		if raw.Mask&syscall.IN_IGNORED != 0 {
+			delete(w.dirs, int(raw.Wd))
+			continue
+		}
+
+		dir, ok := w.dirs[int(raw.Wd)]
+		if !ok || raw.Len == 0 {
+			continue
+		}
+
+		name := strings.TrimRight(string(buf[nameStart:offset]), "\x00")
+		if isSidecar(name) || filesystem.IsTempName(name) {
+			continue
+		}
+
+		p := path.Join(dir, name)
+		isDir := raw.Mask&syscall.IN_ISDIR != 0
+
+		switch {
+		case raw.Mask&syscall.IN_CREATE != 0:
+			if isDir {
+				files, _ := w.add(p)
+				for _, f := range files {
+					events = append(events, &filesystem.Event{Op: filesystem.EventCreate, Path: f})
+				}
+				continue
+			}
+			events = append(events, &filesystem.Event{Op: filesystem.EventCreate, Path: p})
+		case raw.Mask&syscall.IN_CLOSE_WRITE != 0:
+			events = append(events, &filesystem.Event{Op: filesystem.EventModify, Path: p})
+		case raw.Mask&syscall.IN_DELETE != 0:
+			if !isDir {
+				events = append(events, &filesystem.Event{Op: filesystem.EventDelete, Path: p})
+			}
+		case raw.Mask&syscall.IN_MOVED_FROM != 0:
+			moved[raw.Cookie] = movedFrom{path: p, isDir: isDir}
+			movedCookies = append(movedCookies, raw.Cookie)
+		case raw.Mask&syscall.IN_MOVED_TO != 0:
+			from, ok := moved[raw.Cookie]
+			if ok {
+				delete(moved, raw.Cookie)
+			}
+
+			if !isDir {
+				if ok {
+					events = append(events, &filesystem.Event{Op: filesystem.EventRename, Path: p, OldPath: from.path})
+				} else {
+					events = append(events, &filesystem.Event{Op: filesystem.EventCreate, Path: p})
+				}
+				continue
+			}
+
+			if ok {
+				for _, f := range w.move(from.path, p) {
+					events = append(events, &filesystem.Event{Op: filesystem.EventRename, Path: f, OldPath: from.path + strings.TrimPrefix(f, p)})
+				}
+				continue
+			}
+
+			files, _ := w.add(p)
+			for _, f := range files {
+				events = append(events, &filesystem.Event{Op: filesystem.EventCreate, Path: f})
+			}
+		}
+	}
+
+	// moved out of watched dirs
+	for _, cookie := range movedCookies {
+		from, ok := moved[cookie]
+		if !ok {
+			continue
+		}
+		if from.isDir {
+			w.forget(from.path)
+			continue
+		}
+		events = append(events, &filesystem.Event{Op: filesystem.EventDelete, Path: from.path})
+	}
+
+	return events
+}
+
+// walkFiles walks files under dir
+func walkFiles(fsys *fs, dir string, fn func(p string)) error {
+	entries, err := os.ReadDir(fsys.resolve(dir))
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		p := path.Join(dir, e.Name())
+		if e.IsDir() {
+			if err := walkFiles(fsys, p, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if !isSidecar(e.Name()) && !filesystem.IsTempName(e.Name()) {
+			fn(p)
+		}
+	}
+	return nil
+}
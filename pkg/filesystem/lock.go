@@ -0,0 +1,148 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var (
+	// ErrLocked returned when lock conflicted with locks held by others
+	ErrLocked = errors.New("locked")
+	// ErrLockNotFound returned when lock of token expired or unlocked
+	ErrLockNotFound = errors.New("lock not found")
+)
+
+const DefaultLockTTL = 30 * time.Second
+
+type LockOptions struct {
+	// Shared locks could be held at the same time, but not with an exclusive one
+	Shared bool
+	// Recursive lock covers all files under name, like depth infinity of webdav.
+	// only stored with lock, conflicts of parents and children are checked by LockSystem.
+	Recursive bool
+	// Lock expires when not refreshed in TTL, DefaultLockTTL when zero
+	TTL time.Duration
+	// Owner of lock for display, like user name
+	Owner string
+}
+
+// LockInfo of lock held
+type LockInfo struct {
+	Token     string    `json:"token"`
+	Name      string    `json:"name"`
+	Shared    bool      `json:"shared,omitempty"`
+	Recursive bool      `json:"recursive,omitempty"`
+	Owner     string    `json:"owner,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (l *LockInfo) Expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// ConflictsWith checks lock could not be held with a new lock
+func (l *LockInfo) ConflictsWith(shared bool) bool {
+	return !(l.Shared && shared)
+}
+
+// Locker could be implemented by FileSystem to lock files by backend, like LOCK of webdav.
+// errors.ErrUnsupported could be returned to fall back to lock files.
+type Locker interface {
+	// Lock acquires lock of name, ErrLocked returned when conflicted
+	Lock(ctx context.Context, name string, opts LockOptions) (*LockInfo, error)
+	// RefreshLock extends lock of token to expire after ttl from now, ErrLockNotFound returned when expired or unlocked
+	RefreshLock(ctx context.Context, name string, token string, ttl time.Duration) (*LockInfo, error)
+	// Unlock releases lock of token, ErrLockNotFound returned when expired or unlocked
+	Unlock(ctx context.Context, name string, token string) error
+	// Locks lists locks held of name, not includes locks of parents
+	Locks(ctx context.Context, name string) ([]*LockInfo, error)
+}
+
+// Lock acquires lock of name.
+// Locker will be used when implemented, otherwise lock files stored under /.unifs-locks of fsys.
+func Lock(ctx context.Context, fsys FileSystem, name string, opts LockOptions) (*LockInfo, error) {
+	if l, ok := fsys.(Locker); ok {
+		if info, err := l.Lock(ctx, name, opts); !errors.Is(err, errors.ErrUnsupported) {
+			return info, err
+		}
+	}
+	return (&fileLocker{fs: fsys}).Lock(ctx, name, opts)
+}
+
+// RefreshLock extends lock of token to expire after ttl from now
+func RefreshLock(ctx context.Context, fsys FileSystem, name string, token string, ttl time.Duration) (*LockInfo, error) {
+	if l, ok := fsys.(Locker); ok {
+		if info, err := l.RefreshLock(ctx, name, token, ttl); !errors.Is(err, errors.ErrUnsupported) {
+			return info, err
+		}
+	}
+	return (&fileLocker{fs: fsys}).RefreshLock(ctx, name, token, ttl)
+}
+
+// Unlock releases lock of token
+func Unlock(ctx context.Context, fsys FileSystem, name string, token string) error {
+	if l, ok := fsys.(Locker); ok {
+		if err := l.Unlock(ctx, name, token); !errors.Is(err, errors.ErrUnsupported) {
+			return err
+		}
+	}
+	return (&fileLocker{fs: fsys}).Unlock(ctx, name, token)
+}
+
+// Locks lists locks held of name
+func Locks(ctx context.Context, fsys FileSystem, name string) ([]*LockInfo, error) {
+	if l, ok := fsys.(Locker); ok {
+		if locks, err := l.Locks(ctx, name); !errors.Is(err, errors.ErrUnsupported) {
+			return locks, err
+		}
+	}
+	return (&fileLocker{fs: fsys}).Locks(ctx, name)
+}
+
+// NewLockToken generates token of lock in form of opaquelocktoken:<uuid>, which could be used as lock token of webdav
+func NewLockToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("opaquelocktoken:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// NewLockInfo creates LockInfo with new token for lock acquired now
+func NewLockInfo(name string, opts LockOptions) *LockInfo {
+	return &LockInfo{
+		Token:     NewLockToken(),
+		Name:      slashClean(name),
+		Shared:    opts.Shared,
+		Recursive: opts.Recursive,
+		Owner:     opts.Owner,
+		ExpiresAt: time.Now().Add(opts.LockTTL()),
+	}
+}
+
+// LockTTL returns ttl of opts, DefaultLockTTL when not set
+func (opts LockOptions) LockTTL() time.Duration {
+	if opts.TTL > 0 {
+		return opts.TTL
+	}
+	return DefaultLockTTL
+}
+
+type lockTokensCtx struct{}
+
+// LockTokensInjectContext sets tokens of locks held for requests with ctx,
+// needed by backends which locks are enforced by server, like webdav.
+func LockTokensInjectContext(ctx context.Context, tokens ...string) context.Context {
+	return context.WithValue(ctx, lockTokensCtx{}, slices.Concat(LockTokensFromContext(ctx), tokens))
+}
+
+func LockTokensFromContext(ctx context.Context) []string {
+	if v, ok := ctx.Value(lockTokensCtx{}).([]string); ok {
+		return v
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+// lock files stored as /.unifs-locks/<name>/<id>.lock, one file for each holder.
+// since creating files is not atomic on all backends,
+// holders will be checked again after written, and all conflicted will back off.
+const lockDir = "/.unifs-locks"
+
+// IsLockName checks name is stored lock files or dir of them, which should be hidden from clients
+func IsLockName(name string) bool {
+	name = slashClean(name)
+	return name == lockDir || strings.HasPrefix(name, lockDir+"/")
+}
+
+type fileLocker struct {
+	fs FileSystem
+}
+
+func (l *fileLocker) dir(name string) string {
+	return path.Join(lockDir, slashClean(name))
+}
+
+func (l *fileLocker) file(name string, token string) string {
+	return path.Join(l.dir(name), strings.TrimPrefix(token, "opaquelocktoken:")+".lock")
+}
+
+func (l *fileLocker) Lock(ctx context.Context, name string, opts LockOptions) (*LockInfo, error) {
+	held, err := l.held(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, h := range held {
+		if h.ConflictsWith(opts.Shared) {
+			return nil, &fs.PathError{Op: "lock", Path: name, Err: ErrLocked}
+		}
+	}
+
+	info := NewLockInfo(name, opts)
+
+	if err := MkdirAll(ctx, l.fs, l.dir(name)); err != nil {
+		return nil, err
+	}
+
+	if err := l.write(ctx, info); err != nil {
+		return nil, err
+	}
+
+	held, err = l.held(ctx, name)
+	if err != nil {
+		_ = l.fs.RemoveAll(ctx, l.file(name, info.Token))
+		return nil, err
+	}
+
+	for _, h := range held {
+		if h.Token != info.Token && h.ConflictsWith(info.Shared) {
+			_ = l.fs.RemoveAll(ctx, l.file(name, info.Token))
+			return nil, &fs.PathError{Op: "lock", Path: name, Err: ErrLocked}
+		}
+	}
+
+	return info, nil
+}
+
+func (l *fileLocker) RefreshLock(ctx context.Context, name string, token string, ttl time.Duration) (*LockInfo, error) {
+	info, err := l.read(ctx, l.file(name, token))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &fs.PathError{Op: "refresh", Path: name, Err: ErrLockNotFound}
+		}
+		return nil, err
+	}
+
+	if info.Expired(time.Now()) {
+		_ = l.fs.RemoveAll(ctx, l.file(name, token))
+		return nil, &fs.PathError{Op: "refresh", Path: name, Err: ErrLockNotFound}
+	}
+
+	info.ExpiresAt = time.Now().Add(LockOptions{TTL: ttl}.LockTTL())
+
+	if err := l.write(ctx, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (l *fileLocker) Unlock(ctx context.Context, name string, token string) error {
+	info, err := l.read(ctx, l.file(name, token))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &fs.PathError{Op: "unlock", Path: name, Err: ErrLockNotFound}
+		}
+		return err
+	}
+
+	if err := l.fs.RemoveAll(ctx, l.file(name, token)); err != nil {
+		return err
+	}
+
+	if info.Expired(time.Now()) {
+		return &fs.PathError{Op: "unlock", Path: name, Err: ErrLockNotFound}
+	}
+	return nil
+}
+
+func (l *fileLocker) Locks(ctx context.Context, name string) ([]*LockInfo, error) {
+	return l.held(ctx, name)
+}
+
+// held lists locks not expired, expired will be removed
+func (l *fileLocker) held(ctx context.Context, name string) ([]*LockInfo, error) {
+	entries, err := ReadDir(ctx, l.fs, l.dir(name))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	held := make([]*LockInfo, 0, len(entries))
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".lock") {
+			continue
+		}
+
+		p := path.Join(l.dir(name), e.Name())
+
+		info, err := l.read(ctx, p)
+		if err != nil {
+			// unlocked by others
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+
+		if info.Expired(now) {
+			_ = l.fs.RemoveAll(ctx, p)
+			continue
+		}
+
+		held = append(held, info)
+	}
+
+	return held, nil
+}
+
+func (l *fileLocker) read(ctx context.Context, name string) (*LockInfo, error) {
+	f, err := Open(ctx, l.fs, name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &LockInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return info, nil
+}
+
+func (l *fileLocker) write(ctx context.Context, info *LockInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	// never read partial written
+	return WriteAtomic(ctx, l.fs, l.file(info.Name, info.Token), data)
+}
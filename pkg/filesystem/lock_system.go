@@ -0,0 +1,231 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+// NewLockSystem creates webdav.LockSystem storing locks by Lock of fsys,
+// so servers with same backend could share locks.
+//
+// tokens are in form of <token of lock>?<escaped root>, so lock could be found by token only.
+// only locks of name and its parents are checked, locks under dir are not checked when locking dir with depth infinity.
+func NewLockSystem(fsys FileSystem) webdav.LockSystem {
+	return &lockSystem{fs: fsys}
+}
+
+// max ttl of lock, for locks requested with infinite timeout
+const maxLockTTL = 24 * time.Hour
+
+type lockSystem struct {
+	fs FileSystem
+}
+
+func (ls *lockSystem) Confirm(now time.Time, name0, name1 string, conditions ...webdav.Condition) (func(), error) {
+	ctx := context.Background()
+
+	for _, name := range []string{name0, name1} {
+		if name == "" {
+			continue
+		}
+
+		covering, err := ls.covering(ctx, slashClean(name))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(covering) > 0 && !matched(covering, conditions) {
+			return nil, webdav.ErrConfirmationFailed
+		}
+	}
+
+	// nothing held, locks checked again by others will not be blocked
+	return func() {}, nil
+}
+
+func (ls *lockSystem) Create(now time.Time, details webdav.LockDetails) (string, error) {
+	ctx := context.Background()
+
+	root := slashClean(details.Root)
+
+	for p := root; p != "/"; {
+		p = path.Dir(p)
+
+		locks, err := Locks(ctx, ls.fs, p)
+		if err != nil {
+			return "", err
+		}
+		for _, l := range locks {
+			if l.Recursive {
+				return "", webdav.ErrLocked
+			}
+		}
+	}
+
+	ttl := details.Duration
+	if ttl <= 0 || ttl > maxLockTTL {
+		ttl = maxLockTTL
+	}
+
+	info, err := Lock(ctx, ls.fs, root, LockOptions{
+		Recursive: !details.ZeroDepth,
+		TTL:       ttl,
+		Owner:     details.OwnerXML,
+	})
+	if err != nil {
+		if errors.Is(err, ErrLocked) {
+			return "", webdav.ErrLocked
+		}
+		return "", err
+	}
+
+	return encodeLockToken(info.Token, root), nil
+}
+
+func (ls *lockSystem) Refresh(now time.Time, token string, duration time.Duration) (webdav.LockDetails, error) {
+	raw, root, ok := decodeLockToken(token)
+	if !ok {
+		return webdav.LockDetails{}, webdav.ErrNoSuchLock
+	}
+
+	if duration <= 0 || duration > maxLockTTL {
+		duration = maxLockTTL
+	}
+
+	info, err := RefreshLock(context.Background(), ls.fs, root, raw, duration)
+	if err != nil {
+		if errors.Is(err, ErrLockNotFound) {
+			return webdav.LockDetails{}, webdav.ErrNoSuchLock
+		}
+		return webdav.LockDetails{}, err
+	}
+
+	return webdav.LockDetails{
+		Root:      info.Name,
+		Duration:  duration,
+		OwnerXML:  info.Owner,
+		ZeroDepth: !info.Recursive,
+	}, nil
+}
+
+func (ls *lockSystem) Unlock(now time.Time, token string) error {
+	raw, root, ok := decodeLockToken(token)
+	if !ok {
+		return webdav.ErrNoSuchLock
+	}
+
+	if err := Unlock(context.Background(), ls.fs, root, raw); err != nil {
+		if errors.Is(err, ErrLockNotFound) {
+			return webdav.ErrNoSuchLock
+		}
+		return err
+	}
+	return nil
+}
+
+// covering returns locks of name and recursive locks of its parents
+func (ls *lockSystem) covering(ctx context.Context, name string) ([]*LockInfo, error) {
+	covering, err := Locks(ctx, ls.fs, name)
+	if err != nil {
+		return nil, err
+	}
+
+	for p := name; p != "/"; {
+		p = path.Dir(p)
+
+		locks, err := Locks(ctx, ls.fs, p)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range locks {
+			if l.Recursive {
+				covering = append(covering, l)
+			}
+		}
+	}
+
+	return covering, nil
+}
+
+func matched(locks []*LockInfo, conditions []webdav.Condition) bool {
+	for _, c := range conditions {
+		if c.Not || c.Token == "" {
+			continue
+		}
+
+		raw, _, ok := decodeLockToken(c.Token)
+		if !ok {
+			continue
+		}
+
+		for _, l := range locks {
+			if l.Token == raw {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LockTokensFromIf returns tokens of locks from If header of webdav request, like `(<token1>) </a> (<token2>)`.
+// tokens created by LockSystem will be decoded to tokens of Locker.
+func LockTokensFromIf(header string) []string {
+	tokens := make([]string, 0)
+
+	for header != "" {
+		start := strings.Index(header, "(")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(header[start:], ")")
+		if end < 0 {
+			break
+		}
+
+		list := header[start+1 : start+end]
+		header = header[start+end+1:]
+
+		for list != "" {
+			i := strings.Index(list, "<")
+			if i < 0 {
+				break
+			}
+			j := strings.Index(list[i:], ">")
+			if j < 0 {
+				break
+			}
+
+			token := list[i+1 : i+j]
+			list = list[i+j+1:]
+
+			if raw, _, ok := decodeLockToken(token); ok {
+				token = raw
+			}
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+func encodeLockToken(token string, root string) string {
+	return token + "?" + url.PathEscape(root)
+}
+
+func decodeLockToken(token string) (raw string, root string, ok bool) {
+	i := strings.LastIndex(token, "?")
+	if i < 0 {
+		return "", "", false
+	}
+	root, err := url.PathUnescape(token[i+1:])
+	if err != nil {
+		return "", "", false
+	}
+	return token[:i], root, true
+}
@@ -0,0 +1,149 @@
+package filesystem_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/net/webdav"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+
+	fsys := filesystem.NewMemFS()
+
+	t.Run("exclusive", func(t *testing.T) {
+		l, err := filesystem.Lock(ctx, fsys, "/a.txt", filesystem.LockOptions{Owner: "a"})
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		_, err = filesystem.Lock(ctx, fsys, "/a.txt", filesystem.LockOptions{Shared: true})
+		testingx.Expect(t, errors.Is(err, filesystem.ErrLocked), testingx.Be(true))
+
+		locks, err := filesystem.Locks(ctx, fsys, "/a.txt")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+		testingx.Expect(t, len(locks), testingx.Be(1))
+		testingx.Expect(t, locks[0].Owner, testingx.Be("a"))
+
+		testingx.Expect(t, filesystem.Unlock(ctx, fsys, "/a.txt", l.Token), testingx.BeNil[error]())
+
+		err = filesystem.Unlock(ctx, fsys, "/a.txt", l.Token)
+		testingx.Expect(t, errors.Is(err, filesystem.ErrLockNotFound), testingx.Be(true))
+
+		l, err = filesystem.Lock(ctx, fsys, "/a.txt", filesystem.LockOptions{})
+		testingx.Expect(t, err, testingx.BeNil[error]())
+		testingx.Expect(t, filesystem.Unlock(ctx, fsys, "/a.txt", l.Token), testingx.BeNil[error]())
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		l1, err := filesystem.Lock(ctx, fsys, "/b.txt", filesystem.LockOptions{Shared: true})
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		l2, err := filesystem.Lock(ctx, fsys, "/b.txt", filesystem.LockOptions{Shared: true})
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		_, err = filesystem.Lock(ctx, fsys, "/b.txt", filesystem.LockOptions{})
+		testingx.Expect(t, errors.Is(err, filesystem.ErrLocked), testingx.Be(true))
+
+		testingx.Expect(t, filesystem.Unlock(ctx, fsys, "/b.txt", l1.Token), testingx.BeNil[error]())
+		testingx.Expect(t, filesystem.Unlock(ctx, fsys, "/b.txt", l2.Token), testingx.BeNil[error]())
+
+		locks, err := filesystem.Locks(ctx, fsys, "/b.txt")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+		testingx.Expect(t, len(locks), testingx.Be(0))
+	})
+
+	t.Run("expire and refresh", func(t *testing.T) {
+		l, err := filesystem.Lock(ctx, fsys, "/c.txt", filesystem.LockOptions{TTL: 100 * time.Millisecond})
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		time.Sleep(50 * time.Millisecond)
+
+		_, err = filesystem.RefreshLock(ctx, fsys, "/c.txt", l.Token, 200*time.Millisecond)
+		testingx.Expect(t, err, testingx.BeNil[error]())
+
+		time.Sleep(100 * time.Millisecond)
+
+		_, err = filesystem.Lock(ctx, fsys, "/c.txt", filesystem.LockOptions{})
+		testingx.Expect(t, errors.Is(err, filesystem.ErrLocked), testingx.Be(true))
+
+		time.Sleep(150 * time.Millisecond)
+
+		_, err = filesystem.RefreshLock(ctx, fsys, "/c.txt", l.Token, time.Second)
+		testingx.Expect(t, errors.Is(err, filesystem.ErrLockNotFound), testingx.Be(true))
+
+		_, err = filesystem.Lock(ctx, fsys, "/c.txt", filesystem.LockOptions{})
+		testingx.Expect(t, err, testingx.BeNil[error]())
+	})
+
+	t.Run("sub", func(t *testing.T) {
+		testingx.Expect(t, filesystem.MkdirAll(ctx, fsys, "/sub"), testingx.BeNil[error]())
+
+		sub := filesystem.Sub(fsys, "/sub")
+
+		l, err := filesystem.Lock(ctx, sub, "/d.txt", filesystem.LockOptions{})
+		testingx.Expect(t, err, testingx.BeNil[error]())
+		testingx.Expect(t, l.Name, testingx.Be("/d.txt"))
+
+		locks, err := filesystem.Locks(ctx, sub, "/d.txt")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+		testingx.Expect(t, len(locks), testingx.Be(1))
+	})
+}
+
+func TestLockSystem(t *testing.T) {
+	fsys := filesystem.NewMemFS()
+
+	// replicas share locks by the same backend
+	ls1 := filesystem.NewLockSystem(fsys)
+	ls2 := filesystem.NewLockSystem(fsys)
+
+	now := time.Now()
+
+	token, err := ls1.Create(now, webdav.LockDetails{Root: "/dir", Duration: time.Minute})
+	testingx.Expect(t, err, testingx.BeNil[error]())
+
+	t.Run("children of locked dir could not be locked", func(t *testing.T) {
+		_, err := ls2.Create(now, webdav.LockDetails{Root: "/dir/a.txt", Duration: time.Minute, ZeroDepth: true})
+		testingx.Expect(t, err, testingx.Be(webdav.ErrLocked))
+	})
+
+	t.Run("confirm requires token", func(t *testing.T) {
+		_, err := ls2.Confirm(now, "/dir/a.txt", "")
+		testingx.Expect(t, err, testingx.Be(webdav.ErrConfirmationFailed))
+
+		release, err := ls2.Confirm(now, "/dir/a.txt", "", webdav.Condition{Token: token})
+		testingx.Expect(t, err, testingx.BeNil[error]())
+		release()
+
+		release, err = ls2.Confirm(now, "/other.txt", "")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+		release()
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		details, err := ls2.Refresh(now, token, 2*time.Minute)
+		testingx.Expect(t, err, testingx.BeNil[error]())
+		testingx.Expect(t, details.Root, testingx.Be("/dir"))
+		testingx.Expect(t, details.ZeroDepth, testingx.Be(false))
+	})
+
+	t.Run("unlock", func(t *testing.T) {
+		testingx.Expect(t, ls2.Unlock(now, token), testingx.BeNil[error]())
+		testingx.Expect(t, ls1.Unlock(now, token), testingx.Be(webdav.ErrNoSuchLock))
+
+		release, err := ls1.Confirm(now, "/dir/a.txt", "")
+		testingx.Expect(t, err, testingx.BeNil[error]())
+		release()
+	})
+
+	t.Run("tokens from if header", func(t *testing.T) {
+		tokens := filesystem.LockTokensFromIf(`</dir> (<opaquelocktoken:1?%2Fdir> ["etag"]) (Not <opaquelocktoken:2>)`)
+		testingx.Expect(t, tokens, testingx.Equal([]string{"opaquelocktoken:1", "opaquelocktoken:2"}))
+	})
+}
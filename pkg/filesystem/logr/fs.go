@@ -2,7 +2,10 @@ package logr
 
 import (
 	"context"
+	"errors"
+	"iter"
 	"os"
+	"time"
 
 	"github.com/octohelm/x/logr"
 
@@ -10,11 +13,12 @@ import (
 )
 
 func Wrap(fsys filesystem.FileSystem, logger logr.Logger) filesystem.FileSystem {
-	return &fs{fs: fsys, logger: logger}
+	return &fs{Forwarder: filesystem.Forwarder{FileSystem: fsys}, logger: logger}
 }
 
 type fs struct {
-	fs     filesystem.FileSystem
+	filesystem.Forwarder
+
 	logger logr.Logger
 }
 
@@ -35,29 +39,180 @@ func (f *fs) done(err error, op string, path string, values ...any) {
 func (f *fs) Mkdir(ctx context.Context, name string, perm os.FileMode) (err error) {
 	defer f.done(err, "mkdir", name)
 
-	return f.fs.Mkdir(ctx, name, perm)
+	return f.FileSystem.Mkdir(ctx, name, perm)
 }
 
 func (f *fs) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (file filesystem.File, err error) {
 	defer f.done(err, "openfile", name)
 
-	return f.fs.OpenFile(ctx, name, flag, perm)
+	return f.FileSystem.OpenFile(ctx, name, flag, perm)
 }
 
 func (f *fs) RemoveAll(ctx context.Context, name string) (err error) {
 	defer f.done(err, "removeall", name)
 
-	return f.fs.RemoveAll(ctx, name)
+	return f.FileSystem.RemoveAll(ctx, name)
 }
 
 func (f *fs) Rename(ctx context.Context, oldName, newName string) (err error) {
 	defer f.done(err, "rename", newName, "from", oldName)
 
-	return f.fs.Rename(ctx, oldName, newName)
+	return f.FileSystem.Rename(ctx, oldName, newName)
+}
+
+func (f *fs) Copy(ctx context.Context, oldName, newName string, recursive bool) (err error) {
+	defer f.done(err, "copy", newName, "from", oldName, "recursive", recursive)
+
+	return filesystem.Copy(ctx, f.FileSystem, oldName, f.FileSystem, newName, recursive)
+}
+
+func (f *fs) Hash(ctx context.Context, name string, algo filesystem.HashAlgorithm) (sum string, err error) {
+	h, ok := f.FileSystem.(filesystem.Hasher)
+	if !ok {
+		return "", errors.ErrUnsupported
+	}
+
+	defer f.done(err, "hash", name, "algo", algo)
+
+	return h.Hash(ctx, name, algo)
+}
+
+func (f *fs) ListPrefix(ctx context.Context, prefix string) iter.Seq2[*filesystem.FileEntry, error] {
+	return func(yield func(*filesystem.FileEntry, error) bool) {
+		l, ok := f.FileSystem.(filesystem.PrefixLister)
+		if !ok {
+			yield(nil, errors.ErrUnsupported)
+			return
+		}
+
+		var err error
+		defer func() { f.done(err, "list", prefix) }()
+
+		for e, e1 := range l.ListPrefix(ctx, prefix) {
+			if e1 != nil {
+				err = e1
+				yield(nil, err)
+				return
+			}
+			if !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (f *fs) Lock(ctx context.Context, name string, opts filesystem.LockOptions) (info *filesystem.LockInfo, err error) {
+	l, ok := f.FileSystem.(filesystem.Locker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+
+	defer f.done(err, "lock", name, "shared", opts.Shared)
+
+	return l.Lock(ctx, name, opts)
+}
+
+func (f *fs) RefreshLock(ctx context.Context, name string, token string, ttl time.Duration) (info *filesystem.LockInfo, err error) {
+	l, ok := f.FileSystem.(filesystem.Locker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+
+	defer f.done(err, "refreshlock", name)
+
+	return l.RefreshLock(ctx, name, token, ttl)
+}
+
+func (f *fs) Unlock(ctx context.Context, name string, token string) (err error) {
+	l, ok := f.FileSystem.(filesystem.Locker)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+
+	defer f.done(err, "unlock", name)
+
+	return l.Unlock(ctx, name, token)
+}
+
+func (f *fs) Locks(ctx context.Context, name string) (locks []*filesystem.LockInfo, err error) {
+	l, ok := f.FileSystem.(filesystem.Locker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+
+	defer f.done(err, "locks", name)
+
+	return l.Locks(ctx, name)
+}
+
+func (f *fs) Symlink(ctx context.Context, target string, name string) (err error) {
+	l, ok := f.FileSystem.(filesystem.Linker)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+
+	defer f.done(err, "symlink", name, "target", target)
+
+	return l.Symlink(ctx, target, name)
+}
+
+func (f *fs) Readlink(ctx context.Context, name string) (target string, err error) {
+	l, ok := f.FileSystem.(filesystem.Linker)
+	if !ok {
+		return "", errors.ErrUnsupported
+	}
+
+	defer f.done(err, "readlink", name)
+
+	return l.Readlink(ctx, name)
+}
+
+func (f *fs) Lstat(ctx context.Context, name string) (info os.FileInfo, err error) {
+	l, ok := f.FileSystem.(filesystem.Linker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+
+	defer f.done(err, "lstat", name)
+
+	return l.Lstat(ctx, name)
+}
+
+func (f *fs) SetMode(ctx context.Context, name string, mode os.FileMode) (err error) {
+	s, ok := f.FileSystem.(filesystem.AttrSetter)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+
+	defer f.done(err, "chmod", name, "mode", mode.Perm().String())
+
+	return s.SetMode(ctx, name, mode)
+}
+
+func (f *fs) SetModTime(ctx context.Context, name string, mtime time.Time) (err error) {
+	s, ok := f.FileSystem.(filesystem.AttrSetter)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+
+	defer f.done(err, "chtimes", name, "mtime", mtime)
+
+	return s.SetModTime(ctx, name, mtime)
+}
+
+func (f *fs) Chown(ctx context.Context, name string, uid, gid int) (err error) {
+	c, ok := f.FileSystem.(filesystem.Chowner)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+
+	defer f.done(err, "chown", name, "uid", uid, "gid", gid)
+
+	return c.Chown(ctx, name, uid, gid)
 }
 
 func (f *fs) Stat(ctx context.Context, name string) (info os.FileInfo, err error) {
 	defer f.done(err, "stat", name)
 
-	return f.fs.Stat(ctx, name)
+	return f.FileSystem.Stat(ctx, name)
 }
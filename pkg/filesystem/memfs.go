@@ -5,5 +5,13 @@ import (
 )
 
 func NewMemFS() FileSystem {
-	return webdav.NewMemFS()
+	return &memFS{FileSystem: webdav.NewMemFS()}
+}
+
+type memFS struct {
+	webdav.FileSystem
+}
+
+func (memFS) Capabilities() Capability {
+	return CapRandomAccessWrite | CapAtomicRename
 }
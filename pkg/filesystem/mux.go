@@ -0,0 +1,635 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// NewMux creates an empty mount table
+func NewMux() *Mux {
+	return &Mux{
+		mounts: map[string]FileSystem{},
+	}
+}
+
+// Mux presents FileSystems mounted at different prefixes as one FileSystem.
+//
+// Parent directories of mount points are virtual and read-only, unless some FileSystem mounted at '/'.
+// Rename across mount points falls back to copy then delete.
+type Mux struct {
+	mu     sync.RWMutex
+	mounts map[string]FileSystem
+}
+
+// Mount fsys at prefix
+func (m *Mux) Mount(prefix string, fsys FileSystem) error {
+	prefix = slashClean(prefix)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.mounts[prefix]; ok {
+		return fmt.Errorf("%s already mounted", prefix)
+	}
+	m.mounts[prefix] = fsys
+	return nil
+}
+
+// Mounts returns sorted mount points
+func (m *Mux) Mounts() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	prefixes := make([]string, 0, len(m.mounts))
+	for p := range m.mounts {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+func (m *Mux) Capabilities() Capability {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.mounts) == 0 {
+		return 0
+	}
+
+	caps := ^Capability(0)
+	for _, fsys := range m.mounts {
+		caps &= CapabilitiesOf(fsys)
+	}
+
+	if len(m.mounts) > 1 {
+		caps &^= CapAtomicRename
+	}
+
+	return caps
+}
+
+// resolve returns the FileSystem with longest mount point matched, and name relative to it.
+func (m *Mux) resolve(name string) (prefix string, fsys FileSystem, rel string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for p := name; ; p = path.Dir(p) {
+		if f, ok := m.mounts[p]; ok {
+			rel = strings.TrimPrefix(name, p)
+			if p == "/" {
+				rel = name
+			}
+			if rel == "" {
+				rel = "/"
+			}
+			return p, f, rel
+		}
+		if p == "/" {
+			return "", nil, ""
+		}
+	}
+}
+
+// children returns names of mount points or their parents directly under dir
+func (m *Mux) children(dir string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	prefix := dir + "/"
+	if dir == "/" {
+		prefix = "/"
+	}
+
+	names := map[string]bool{}
+
+	for p := range m.mounts {
+		if p != "/" && strings.HasPrefix(p, prefix) {
+			names[strings.SplitN(strings.TrimPrefix(p, prefix), "/", 2)[0]] = true
+		}
+	}
+
+	list := make([]string, 0, len(names))
+	for n := range names {
+		list = append(list, n)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func (m *Mux) isVirtualDir(name string) bool {
+	return name == "/" || len(m.children(name)) > 0
+}
+
+// isMountPoint checks name is mount point or virtual directory, which could not be changed
+func (m *Mux) isMountPoint(name string) bool {
+	if m.isVirtualDir(name) {
+		return true
+	}
+	prefix, fsys, _ := m.resolve(name)
+	return fsys != nil && prefix == name
+}
+
+func (m *Mux) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	name = slashClean(name)
+
+	if m.isMountPoint(name) {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+
+	_, fsys, rel := m.resolve(name)
+	if fsys == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrPermission}
+	}
+	return fsys.Mkdir(ctx, rel, perm)
+}
+
+func (m *Mux) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (File, error) {
+	name = slashClean(name)
+
+	virtual := m.isVirtualDir(name)
+
+	if virtual && flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+	}
+
+	prefix, fsys, rel := m.resolve(name)
+
+	if fsys != nil {
+		f, err := fsys.OpenFile(ctx, rel, flag, perm)
+		if err != nil {
+			if !(virtual && os.IsNotExist(err)) {
+				return nil, err
+			}
+		} else {
+			if !virtual && prefix != name {
+				return f, nil
+			}
+
+			info, err := f.Stat()
+			if err != nil {
+				_ = f.Close()
+				return nil, err
+			}
+
+			if !info.IsDir() {
+				return f, nil
+			}
+
+			if prefix == name {
+				info = &muxFileInfo{FileInfo: info, name: path.Base(name)}
+			}
+
+			return &muxDir{ctx: ctx, m: m, name: name, info: info, File: f}, nil
+		}
+	}
+
+	if virtual {
+		return &muxDir{ctx: ctx, m: m, name: name, info: newMuxDirInfo(name)}, nil
+	}
+
+	return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+}
+
+func (m *Mux) RemoveAll(ctx context.Context, name string) error {
+	name = slashClean(name)
+
+	if m.isMountPoint(name) {
+		return &os.PathError{Op: "removeall", Path: name, Err: os.ErrPermission}
+	}
+
+	_, fsys, rel := m.resolve(name)
+	if fsys == nil {
+		return nil
+	}
+	return fsys.RemoveAll(ctx, rel)
+}
+
+func (m *Mux) Rename(ctx context.Context, oldName, newName string) error {
+	oldName, newName = slashClean(oldName), slashClean(newName)
+
+	if m.isMountPoint(oldName) || m.isMountPoint(newName) {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: os.ErrPermission}
+	}
+
+	oldPrefix, oldFS, oldRel := m.resolve(oldName)
+	newPrefix, newFS, newRel := m.resolve(newName)
+
+	if oldFS == nil || newFS == nil {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: os.ErrPermission}
+	}
+
+	if oldPrefix == newPrefix {
+		return oldFS.Rename(ctx, oldRel, newRel)
+	}
+
+	if err := Copy(ctx, oldFS, oldRel, newFS, newRel, true); err != nil {
+		return err
+	}
+	return oldFS.RemoveAll(ctx, oldRel)
+}
+
+func (m *Mux) Copy(ctx context.Context, oldName, newName string, recursive bool) error {
+	oldName, newName = slashClean(oldName), slashClean(newName)
+
+	if m.isMountPoint(newName) {
+		return &os.LinkError{Op: "copy", Old: oldName, New: newName, Err: os.ErrPermission}
+	}
+
+	_, oldFS, oldRel := m.resolve(oldName)
+	_, newFS, newRel := m.resolve(newName)
+
+	if oldFS == nil || newFS == nil {
+		return &os.LinkError{Op: "copy", Old: oldName, New: newName, Err: os.ErrPermission}
+	}
+
+	return Copy(ctx, oldFS, oldRel, newFS, newRel, recursive)
+}
+
+func (m *Mux) Hash(ctx context.Context, name string, algo HashAlgorithm) (string, error) {
+	_, fsys, rel := m.resolve(slashClean(name))
+	if fsys == nil {
+		return "", &os.PathError{Op: "hash", Path: name, Err: os.ErrNotExist}
+	}
+	return Hash(ctx, fsys, rel, algo)
+}
+
+// ListPrefix lists by the FileSystem mounted when all matches inside single mount point,
+// otherwise errors.ErrUnsupported to fall back to walking.
+func (m *Mux) ListPrefix(ctx context.Context, prefix string) iter.Seq2[*FileEntry, error] {
+	return func(yield func(*FileEntry, error) bool) {
+		dir := path.Dir(prefix)
+		if strings.HasSuffix(prefix, "/") {
+			dir = slashClean(prefix)
+		}
+
+		mountPoint, fsys, _ := m.resolve(dir)
+		if fsys == nil || m.hasMountsUnder(mountPoint, prefix) {
+			yield(nil, errors.ErrUnsupported)
+			return
+		}
+
+		rel := prefix
+		if mountPoint != "/" {
+			rel = strings.TrimPrefix(prefix, mountPoint)
+		}
+
+		for e, err := range ListPrefix(ctx, fsys, rel) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if mountPoint != "/" {
+				e = &FileEntry{Path: mountPoint + e.Path, FileInfo: e.FileInfo}
+			}
+			if !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Watch forwards to the FileSystem mounted when no other mount points under name,
+// otherwise errors.ErrUnsupported to fall back to polling.
+func (m *Mux) Watch(ctx context.Context, name string) iter.Seq2[*Event, error] {
+	return func(yield func(*Event, error) bool) {
+		name = slashClean(name)
+
+		prefix := name
+		if prefix != "/" {
+			prefix += "/"
+		}
+
+		mountPoint, fsys, rel := m.resolve(name)
+		w, ok := fsys.(Watcher)
+		if !ok || m.hasMountsUnder(mountPoint, prefix) {
+			yield(nil, errors.ErrUnsupported)
+			return
+		}
+
+		join := func(p string) string {
+			if p == "" || mountPoint == "/" {
+				return p
+			}
+			return mountPoint + p
+		}
+
+		for e, err := range w.Watch(ctx, rel) {
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			if !yield(&Event{Op: e.Op, Path: join(e.Path), OldPath: join(e.OldPath), User: e.User}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Lock by the FileSystem mounted, virtual dirs could not be locked
+func (m *Mux) Lock(ctx context.Context, name string, opts LockOptions) (*LockInfo, error) {
+	mountPoint, fsys, rel := m.resolve(slashClean(name))
+	if fsys == nil {
+		return nil, &os.PathError{Op: "lock", Path: name, Err: os.ErrPermission}
+	}
+	info, err := Lock(ctx, fsys, rel, opts)
+	if err != nil {
+		return nil, err
+	}
+	info.Name = path.Join(mountPoint, info.Name)
+	return info, nil
+}
+
+func (m *Mux) RefreshLock(ctx context.Context, name string, token string, ttl time.Duration) (*LockInfo, error) {
+	mountPoint, fsys, rel := m.resolve(slashClean(name))
+	if fsys == nil {
+		return nil, &os.PathError{Op: "refresh", Path: name, Err: ErrLockNotFound}
+	}
+	info, err := RefreshLock(ctx, fsys, rel, token, ttl)
+	if err != nil {
+		return nil, err
+	}
+	info.Name = path.Join(mountPoint, info.Name)
+	return info, nil
+}
+
+func (m *Mux) Unlock(ctx context.Context, name string, token string) error {
+	_, fsys, rel := m.resolve(slashClean(name))
+	if fsys == nil {
+		return &os.PathError{Op: "unlock", Path: name, Err: ErrLockNotFound}
+	}
+	return Unlock(ctx, fsys, rel, token)
+}
+
+// Locks returns nothing for virtual dirs
+func (m *Mux) Locks(ctx context.Context, name string) ([]*LockInfo, error) {
+	mountPoint, fsys, rel := m.resolve(slashClean(name))
+	if fsys == nil {
+		return nil, nil
+	}
+	locks, err := Locks(ctx, fsys, rel)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range locks {
+		info.Name = path.Join(mountPoint, info.Name)
+	}
+	return locks, nil
+}
+
+func (m *Mux) Symlink(ctx context.Context, target string, name string) error {
+	_, fsys, rel := m.resolve(slashClean(name))
+	if fsys == nil {
+		return &os.LinkError{Op: "symlink", Old: target, New: name, Err: os.ErrPermission}
+	}
+	return Symlink(ctx, fsys, target, rel)
+}
+
+func (m *Mux) Readlink(ctx context.Context, name string) (string, error) {
+	_, fsys, rel := m.resolve(slashClean(name))
+	if fsys == nil {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: syscall.EINVAL}
+	}
+	return Readlink(ctx, fsys, rel)
+}
+
+// Lstat same as Stat for mount points and virtual dirs, which are never links
+func (m *Mux) Lstat(ctx context.Context, name string) (os.FileInfo, error) {
+	name = slashClean(name)
+
+	prefix, fsys, rel := m.resolve(name)
+	if fsys == nil || prefix == name {
+		return m.Stat(ctx, name)
+	}
+	return Lstat(ctx, fsys, rel)
+}
+
+func (m *Mux) SetMode(ctx context.Context, name string, mode os.FileMode) error {
+	_, fsys, rel := m.resolve(slashClean(name))
+	if fsys == nil {
+		return &os.PathError{Op: "chmod", Path: name, Err: os.ErrPermission}
+	}
+	return SetMode(ctx, fsys, rel, mode)
+}
+
+func (m *Mux) SetModTime(ctx context.Context, name string, mtime time.Time) error {
+	_, fsys, rel := m.resolve(slashClean(name))
+	if fsys == nil {
+		return &os.PathError{Op: "chtimes", Path: name, Err: os.ErrPermission}
+	}
+	return SetModTime(ctx, fsys, rel, mtime)
+}
+
+func (m *Mux) Chown(ctx context.Context, name string, uid, gid int) error {
+	_, fsys, rel := m.resolve(slashClean(name))
+	if fsys == nil {
+		return &os.PathError{Op: "chown", Path: name, Err: os.ErrPermission}
+	}
+	return Chown(ctx, fsys, rel, uid, gid)
+}
+
+// hasMountsUnder checks any other mount point under mountPoint may contain paths with prefix
+func (m *Mux) hasMountsUnder(mountPoint string, prefix string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for p := range m.mounts {
+		if p == mountPoint {
+			continue
+		}
+		if strings.HasPrefix(p, prefix) || strings.HasPrefix(prefix, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Mux) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	name = slashClean(name)
+
+	virtual := m.isVirtualDir(name)
+
+	prefix, fsys, rel := m.resolve(name)
+
+	if fsys != nil {
+		info, err := fsys.Stat(ctx, rel)
+		if err == nil {
+			if prefix == name {
+				return &muxFileInfo{FileInfo: info, name: path.Base(name)}, nil
+			}
+			return info, nil
+		}
+		if !(virtual && os.IsNotExist(err)) {
+			return nil, err
+		}
+	}
+
+	if virtual {
+		return newMuxDirInfo(name), nil
+	}
+
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+func newMuxDirInfo(name string) FileInfo {
+	return &muxFileInfo{name: path.Base(name)}
+}
+
+// muxFileInfo renames root of mounted FileSystem as its mount point,
+// or presents virtual directory when FileInfo is nil.
+type muxFileInfo struct {
+	FileInfo
+	name string
+}
+
+func (i *muxFileInfo) Name() string {
+	return i.name
+}
+
+func (i *muxFileInfo) Size() int64 {
+	if i.FileInfo != nil {
+		return i.FileInfo.Size()
+	}
+	return 0
+}
+
+func (i *muxFileInfo) Mode() fs.FileMode {
+	if i.FileInfo != nil {
+		return i.FileInfo.Mode()
+	}
+	return fs.ModeDir | 0o555
+}
+
+func (i *muxFileInfo) ModTime() time.Time {
+	if i.FileInfo != nil {
+		return i.FileInfo.ModTime()
+	}
+	return time.Unix(0, 0)
+}
+
+func (i *muxFileInfo) IsDir() bool {
+	if i.FileInfo != nil {
+		return i.FileInfo.IsDir()
+	}
+	return true
+}
+
+func (i *muxFileInfo) Sys() any {
+	if i.FileInfo != nil {
+		return i.FileInfo.Sys()
+	}
+	return nil
+}
+
+// muxDir lists entries of File with mount points under it
+type muxDir struct {
+	File
+	ctx  context.Context
+	m    *Mux
+	name string
+	info FileInfo
+
+	entries []FileInfo
+	loaded  bool
+	pos     int
+}
+
+func (d *muxDir) Name() string {
+	return d.name
+}
+
+func (d *muxDir) Stat() (os.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *muxDir) Readdir(n int) ([]os.FileInfo, error) {
+	if !d.loaded {
+		entries, err := d.list()
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.loaded = true
+	}
+
+	remain := d.entries[d.pos:]
+
+	if n <= 0 {
+		d.pos = len(d.entries)
+		return remain, nil
+	}
+
+	if len(remain) == 0 {
+		return nil, io.EOF
+	}
+
+	if n > len(remain) {
+		n = len(remain)
+	}
+	d.pos += n
+	return remain[:n], nil
+}
+
+func (d *muxDir) list() ([]FileInfo, error) {
+	entries := map[string]FileInfo{}
+
+	if d.File != nil {
+		infos, err := d.File.Readdir(-1)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			entries[info.Name()] = info
+		}
+	}
+
+	for _, n := range d.m.children(d.name) {
+		info, err := d.m.Stat(d.ctx, path.Join(d.name, n))
+		if err != nil {
+			return nil, err
+		}
+		entries[n] = info
+	}
+
+	list := make([]FileInfo, 0, len(entries))
+	for _, info := range entries {
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list, nil
+}
+
+func (d *muxDir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: syscall.EISDIR}
+}
+
+func (d *muxDir) Write(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "write", Path: d.name, Err: syscall.EISDIR}
+}
+
+func (d *muxDir) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekStart {
+		d.pos = 0
+		return 0, nil
+	}
+	return 0, &fs.PathError{Op: "seek", Path: d.name, Err: os.ErrInvalid}
+}
+
+func (d *muxDir) Close() error {
+	if d.File != nil {
+		return d.File.Close()
+	}
+	return nil
+}
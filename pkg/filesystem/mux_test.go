@@ -0,0 +1,81 @@
+package filesystem_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/testutil"
+)
+
+func TestMux(t *testing.T) {
+	newMux := func(t *testing.T, prefixes ...string) *filesystem.Mux {
+		m := filesystem.NewMux()
+		for _, p := range prefixes {
+			err := m.Mount(p, filesystem.NewMemFS())
+			testingx.Expect(t, err, testingx.Be[error](nil))
+		}
+		return m
+	}
+
+	t.Run("Simple", func(t *testing.T) {
+		testutil.TestSimpleFS(t, newMux(t, "/"))
+	})
+
+	t.Run("Full", func(t *testing.T) {
+		testutil.TestFullFS(t, newMux(t, "/"))
+	})
+
+	t.Run("mounts", func(t *testing.T) {
+		ctx := context.Background()
+
+		m := newMux(t, "/datasets", "/inbox", "/a/b")
+
+		t.Run("duplicated mount should failed", func(t *testing.T) {
+			err := m.Mount("/inbox/", filesystem.NewMemFS())
+			testingx.Expect(t, err, testingx.Not(testingx.Be[error](nil)))
+		})
+
+		t.Run("root lists mount points", func(t *testing.T) {
+			entries, err := filesystem.ReadDir(ctx, m, "/")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			names := make([]string, 0)
+			for _, e := range entries {
+				testingx.Expect(t, e.IsDir(), testingx.Be(true))
+				names = append(names, e.Name())
+			}
+			testingx.Expect(t, names, testingx.Equal([]string{"a", "datasets", "inbox"}))
+		})
+
+		t.Run("virtual dir is read-only", func(t *testing.T) {
+			err := filesystem.Write(ctx, m, "/x.txt", []byte("x"))
+			testingx.Expect(t, err, testingx.Not(testingx.Be[error](nil)))
+
+			err = m.RemoveAll(ctx, "/inbox")
+			testingx.Expect(t, err, testingx.Not(testingx.Be[error](nil)))
+		})
+
+		t.Run("write into mounted", func(t *testing.T) {
+			err := filesystem.Write(ctx, m, "/inbox/1.txt", []byte("1"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			entries, err := filesystem.ReadDir(ctx, m, "/inbox")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, len(entries), testingx.Be(1))
+		})
+
+		t.Run("rename across mount points", func(t *testing.T) {
+			err := m.Rename(ctx, "/inbox/1.txt", "/a/b/1.txt")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			_, err = m.Stat(ctx, "/inbox/1.txt")
+			testingx.Expect(t, os.IsNotExist(err), testingx.Be(true))
+
+			testingx.Expect(t, readFile(t, m, "/a/b/1.txt"), testingx.Be("1"))
+		})
+	})
+}
@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/octohelm/x/logr"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+type Config struct {
+	// Url to POST events of changes as json, like {"op":"create","path":"/a.txt","user":"partner"}
+	Webhook string `flag:"webhook,omitzero"`
+}
+
+// Apply wraps fsys to post events to webhook until ctx done, fsys returned directly when no webhook set
+func (c *Config) Apply(ctx context.Context, fsys filesystem.FileSystem) filesystem.FileSystem {
+	if c.Webhook == "" {
+		return fsys
+	}
+
+	n := Wrap(fsys).(*notifyFS)
+
+	// subscribe before returned, to not miss any events
+	s := n.subscribe("/")
+
+	go func() {
+		defer n.unsubscribe(s)
+
+		l := logr.FromContext(ctx)
+
+		for e, err := range s.events(ctx) {
+			if err != nil {
+				l.Error(fmt.Errorf("notify failed: %w", err))
+				continue
+			}
+			if err := post(ctx, c.Webhook, e); err != nil {
+				l.WithValues("op", e.Op, "path", e.Path).Error(fmt.Errorf("notify failed: %w", err))
+			}
+		}
+	}()
+
+	return n
+}
+
+const maxPostAttempts = 3
+
+// events posted one by one to keep the order,
+// so slow webhook should time out, to not hold all events after.
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+func post(ctx context.Context, webhook string, e *filesystem.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = postOnce(ctx, webhook, data)
+		if err == nil || attempt+1 >= maxPostAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Second << attempt):
+		}
+	}
+}
+
+func postOnce(ctx context.Context, webhook string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%s responded %s", webhook, resp.Status)
+	}
+	return nil
+}
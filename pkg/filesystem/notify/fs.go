@@ -0,0 +1,336 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+// Wrap emits events of changes made through fsys, which could be watched by filesystem.Watch of the returned FileSystem.
+// changes not made through it, like by other clients of the backend, will not be notified.
+//
+// create or modify event emitted when file written closed, and events of files under dir emitted when dir removed or renamed.
+// changes of attributes emit no events, which not watched by inotify either.
+func Wrap(fsys filesystem.FileSystem) filesystem.FileSystem {
+	return &notifyFS{
+		Forwarder:   filesystem.Forwarder{FileSystem: fsys},
+		subscribers: map[*subscriber]struct{}{},
+	}
+}
+
+type notifyFS struct {
+	filesystem.Forwarder
+
+	mu          sync.RWMutex
+	subscribers map[*subscriber]struct{}
+}
+
+func (n *notifyFS) emit(ctx context.Context, op filesystem.EventOp, name string, oldName string) {
+	// temp files of atomic writing are invisible, file created when renamed
+	if filesystem.IsTempName(name) || filesystem.IsLockName(name) {
+		return
+	}
+	if oldName != "" && filesystem.IsTempName(oldName) {
+		op = filesystem.EventCreate
+		oldName = ""
+	}
+
+	e := &filesystem.Event{
+		Op:      op,
+		Path:    slashClean(name),
+		OldPath: oldName,
+		User:    filesystem.UserFromContext(ctx),
+	}
+	if oldName != "" {
+		e.OldPath = slashClean(oldName)
+	}
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	for s := range n.subscribers {
+		if filesystem.Contains(s.name, e.Path) || (e.OldPath != "" && filesystem.Contains(s.name, e.OldPath)) {
+			s.push(e)
+		}
+	}
+}
+
+func (n *notifyFS) subscribe(name string) *subscriber {
+	s := &subscriber{name: slashClean(name), limit: maxQueuedEvents, ready: make(chan struct{}, 1)}
+
+	n.mu.Lock()
+	n.subscribers[s] = struct{}{}
+	n.mu.Unlock()
+
+	return s
+}
+
+func (n *notifyFS) unsubscribe(s *subscriber) {
+	n.mu.Lock()
+	delete(n.subscribers, s)
+	n.mu.Unlock()
+}
+
+func (n *notifyFS) Watch(ctx context.Context, name string) iter.Seq2[*filesystem.Event, error] {
+	return func(yield func(*filesystem.Event, error) bool) {
+		s := n.subscribe(name)
+		defer n.unsubscribe(s)
+
+		for e, err := range s.events(ctx) {
+			if !yield(e, err) {
+				return
+			}
+		}
+	}
+}
+
+// files lists files under dir before changed, nil when name is file
+func (n *notifyFS) files(ctx context.Context, name string) ([]string, error) {
+	info, err := n.FileSystem.Stat(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, nil
+	}
+
+	files := make([]string, 0)
+	for e, err := range filesystem.ListPrefix(ctx, n.FileSystem, slashClean(name)+"/") {
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, e.Path)
+	}
+	return files, nil
+}
+
+func (n *notifyFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return n.FileSystem.Mkdir(ctx, name, perm)
+}
+
+func (n *notifyFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (filesystem.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) == 0 {
+		return n.FileSystem.OpenFile(ctx, name, flag, perm)
+	}
+
+	existed := true
+	if flag&os.O_CREATE != 0 {
+		if _, err := n.FileSystem.Stat(ctx, name); err != nil {
+			existed = false
+		}
+	}
+
+	f, err := n.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &file{
+		File:    f,
+		ctx:     ctx,
+		n:       n,
+		name:    name,
+		existed: existed,
+		dirty:   flag&(os.O_CREATE|os.O_TRUNC) != 0,
+	}, nil
+}
+
+func (n *notifyFS) RemoveAll(ctx context.Context, name string) error {
+	files, err := n.files(ctx, name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return n.FileSystem.RemoveAll(ctx, name)
+		}
+		return err
+	}
+
+	if err := n.FileSystem.RemoveAll(ctx, name); err != nil {
+		return err
+	}
+
+	if files == nil {
+		n.emit(ctx, filesystem.EventDelete, name, "")
+	}
+	for _, f := range files {
+		n.emit(ctx, filesystem.EventDelete, f, "")
+	}
+	return nil
+}
+
+func (n *notifyFS) Rename(ctx context.Context, oldName, newName string) error {
+	files, err := n.files(ctx, oldName)
+	if err != nil {
+		return err
+	}
+
+	if err := n.FileSystem.Rename(ctx, oldName, newName); err != nil {
+		return err
+	}
+
+	if files == nil {
+		n.emit(ctx, filesystem.EventRename, newName, oldName)
+	}
+	for _, f := range files {
+		n.emit(ctx, filesystem.EventRename, path.Join(newName, f[len(slashClean(oldName)):]), f)
+	}
+	return nil
+}
+
+func (n *notifyFS) Copy(ctx context.Context, oldName, newName string, recursive bool) error {
+	if err := filesystem.Copy(ctx, n.FileSystem, oldName, n.FileSystem, newName, recursive); err != nil {
+		return err
+	}
+
+	files, err := n.files(ctx, newName)
+	if err != nil {
+		return err
+	}
+
+	if files == nil {
+		n.emit(ctx, filesystem.EventCreate, newName, "")
+	}
+	for _, f := range files {
+		n.emit(ctx, filesystem.EventCreate, f, "")
+	}
+	return nil
+}
+
+func (n *notifyFS) Symlink(ctx context.Context, target string, name string) error {
+	if err := n.Forwarder.Symlink(ctx, target, name); err != nil {
+		return err
+	}
+	n.emit(ctx, filesystem.EventCreate, name, "")
+	return nil
+}
+
+func (n *notifyFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	return n.FileSystem.Stat(ctx, name)
+}
+
+type file struct {
+	filesystem.File
+
+	ctx     context.Context
+	n       *notifyFS
+	name    string
+	existed bool
+	dirty   bool
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	f.dirty = true
+	return f.File.Write(p)
+}
+
+func (f *file) Close() error {
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+
+	if f.dirty {
+		if f.existed {
+			f.n.emit(f.ctx, filesystem.EventModify, f.name, "")
+		} else {
+			f.n.emit(f.ctx, filesystem.EventCreate, f.name, "")
+		}
+	}
+	return nil
+}
+
+func (f *file) Sync() error {
+	if s, ok := f.File.(filesystem.FileSyncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+func (f *file) Truncate(size int64) error {
+	if t, ok := f.File.(filesystem.FileTruncator); ok {
+		f.dirty = true
+		return t.Truncate(size)
+	}
+	return &os.PathError{Op: "truncate", Path: f.name, Err: errors.ErrUnsupported}
+}
+
+// Abort emits nothing, since nothing changed
+func (f *file) Abort(err error) error {
+	if a, ok := f.File.(filesystem.FileAborter); ok {
+		return a.Abort(err)
+	}
+	return &os.PathError{Op: "abort", Path: f.name, Err: errors.ErrUnsupported}
+}
+
+// maxQueuedEvents limits events queued for each subscriber
+const maxQueuedEvents = 1024
+
+// ErrEventsDropped returned by watching when events dropped, since consumed slower than changed
+var ErrEventsDropped = errors.New("events dropped")
+
+// subscriber queues events, to not block writes by slow watchers
+type subscriber struct {
+	name  string
+	limit int
+
+	mu      sync.Mutex
+	queue   []*filesystem.Event
+	dropped int
+	ready   chan struct{}
+}
+
+// push queues e, the oldest dropped when queue full, to not block changes or grow without limit
+func (s *subscriber) push(e *filesystem.Event) {
+	s.mu.Lock()
+	if len(s.queue) >= s.limit {
+		s.queue = s.queue[1:]
+		s.dropped++
+	}
+	s.queue = append(s.queue, e)
+	s.mu.Unlock()
+
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscriber) events(ctx context.Context) iter.Seq2[*filesystem.Event, error] {
+	return func(yield func(*filesystem.Event, error) bool) {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.ready:
+			}
+
+			s.mu.Lock()
+			queue, dropped := s.queue, s.dropped
+			s.queue, s.dropped = nil, 0
+			s.mu.Unlock()
+
+			if dropped > 0 {
+				if !yield(nil, fmt.Errorf("%d %w", dropped, ErrEventsDropped)) {
+					return
+				}
+			}
+
+			for _, e := range queue {
+				if !yield(e, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func slashClean(name string) string {
+	if name == "" || name[0] != '/' {
+		name = "/" + name
+	}
+	return path.Clean(name)
+}
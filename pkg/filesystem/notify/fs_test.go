@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/testutil"
+)
+
+func TestNotify(t *testing.T) {
+	t.Run("Simple", func(t *testing.T) {
+		testutil.TestSimpleFS(t, Wrap(filesystem.NewMemFS()))
+	})
+
+	t.Run("events of changes", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		fsys := Wrap(filesystem.NewMemFS())
+		_ = filesystem.MkdirAll(ctx, fsys, "/in/sub")
+		_ = filesystem.Write(ctx, fsys, "/outside.txt", []byte("1"))
+
+		events := make(chan *filesystem.Event, 16)
+		watching := make(chan struct{})
+
+		go func() {
+			close(watching)
+			for e, err := range filesystem.Watch(ctx, fsys, "/in") {
+				if err == nil {
+					events <- e
+				}
+			}
+		}()
+
+		<-watching
+		time.Sleep(10 * time.Millisecond)
+
+		next := func(t *testing.T) filesystem.Event {
+			select {
+			case e := <-events:
+				return *e
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout")
+			}
+			return filesystem.Event{}
+		}
+
+		userCtx := filesystem.UserInjectContext(ctx, "partner")
+
+		_ = filesystem.Write(userCtx, fsys, "/in/sub/a.txt", []byte("1"))
+		testingx.Expect(t, next(t), testingx.Equal(filesystem.Event{Op: filesystem.EventCreate, Path: "/in/sub/a.txt", User: "partner"}))
+
+		_ = filesystem.Write(ctx, fsys, "/outside.txt", []byte("2"))
+		_ = filesystem.Write(ctx, fsys, "/in/sub/a.txt", []byte("2"))
+		testingx.Expect(t, next(t), testingx.Equal(filesystem.Event{Op: filesystem.EventModify, Path: "/in/sub/a.txt"}))
+
+		_ = fsys.Rename(ctx, "/in/sub", "/in/moved")
+		testingx.Expect(t, next(t), testingx.Equal(filesystem.Event{Op: filesystem.EventRename, Path: "/in/moved/a.txt", OldPath: "/in/sub/a.txt"}))
+
+		_ = fsys.RemoveAll(ctx, "/in/moved")
+		testingx.Expect(t, next(t), testingx.Equal(filesystem.Event{Op: filesystem.EventDelete, Path: "/in/moved/a.txt"}))
+	})
+
+	t.Run("post to webhook", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		received := make(chan filesystem.Event, 1)
+
+		svc := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			e := filesystem.Event{}
+			_ = json.NewDecoder(r.Body).Decode(&e)
+			received <- e
+		}))
+		defer svc.Close()
+
+		c := &Config{Webhook: svc.URL}
+		fsys := c.Apply(ctx, filesystem.NewMemFS())
+
+		_ = filesystem.Write(ctx, fsys, "/a.txt", []byte("1"))
+
+		select {
+		case e := <-received:
+			testingx.Expect(t, e, testingx.Equal(filesystem.Event{Op: filesystem.EventCreate, Path: "/a.txt"}))
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+	})
+
+	t.Run("drop oldest events when queue full", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		s := &subscriber{name: "/", limit: 2, ready: make(chan struct{}, 1)}
+
+		for _, name := range []string{"/1.txt", "/2.txt", "/3.txt"} {
+			s.push(&filesystem.Event{Op: filesystem.EventCreate, Path: name})
+		}
+
+		paths := make([]string, 0)
+		for e, err := range s.events(ctx) {
+			if err != nil {
+				testingx.Expect(t, errors.Is(err, ErrEventsDropped), testingx.Be(true))
+				paths = append(paths, "dropped")
+				continue
+			}
+			paths = append(paths, e.Path)
+			if len(paths) == 3 {
+				break
+			}
+		}
+
+		testingx.Expect(t, paths, testingx.Equal([]string{"dropped", "/2.txt", "/3.txt"}))
+	})
+}
@@ -0,0 +1,38 @@
+/*
+Package notify GENERATED BY gengo:runtimedoc
+DON'T EDIT THIS FILE
+*/
+package notify
+
+func (v *Config) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "Webhook":
+			return []string{
+				"Url to POST events of changes as json, like {\"op\":\"create\",\"path\":\"/a.txt\",\"user\":\"partner\"}",
+			}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{}, true
+}
+
+// nolint:deadcode,unused
+func runtimeDoc(v any, prefix string, names ...string) ([]string, bool) {
+	if c, ok := v.(interface {
+		RuntimeDoc(names ...string) ([]string, bool)
+	}); ok {
+		doc, ok := c.RuntimeDoc(names...)
+		if ok {
+			if prefix != "" && len(doc) > 0 {
+				doc[0] = prefix + doc[0]
+				return doc, true
+			}
+
+			return doc, true
+		}
+	}
+	return nil, false
+}
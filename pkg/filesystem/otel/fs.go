@@ -0,0 +1,406 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/innoai-tech/infra/pkg/otel/metric"
+	"github.com/octohelm/x/logr"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+var (
+	opDuration = metric.NewFloat64Histogram(
+		"unifs.fs.operation.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of filesystem operations"),
+	)
+	opErrors = metric.NewInt64Counter(
+		"unifs.fs.operation.errors",
+		metric.WithDescription("Count of failed filesystem operations"),
+	)
+	transferred = metric.NewInt64Counter(
+		"unifs.fs.transferred",
+		metric.WithUnit("By"),
+		metric.WithDescription("Bytes read or written"),
+	)
+)
+
+// Wrap creates span and records metrics for each operation of fsys and files opened through it,
+// tagged with backend scheme and op.
+//
+// Tracer and meter providers are taken from ctx,
+// so calls with context without them (like from ftp server or fuse) will be collected too.
+func Wrap(ctx context.Context, fsys filesystem.FileSystem, scheme string) filesystem.FileSystem {
+	return &fs{Forwarder: filesystem.Forwarder{FileSystem: fsys}, ctx: ctx, scheme: scheme}
+}
+
+type fs struct {
+	filesystem.Forwarder
+
+	ctx    context.Context
+	scheme string
+}
+
+// start span for op, and returns func to end the span with result
+func (f *fs) start(ctx context.Context, op string, name string, attrs ...attribute.KeyValue) (context.Context, func(err error, n int64)) {
+	ctx = withValues(ctx, f.ctx)
+	ctx, l := logr.FromContext(ctx).Start(ctx, "fs."+op)
+
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		attribute.String("fs.scheme", f.scheme),
+		attribute.String("fs.path", name),
+	)
+	span.SetAttributes(attrs...)
+
+	startedAt := time.Now()
+
+	return ctx, func(err error, n int64) {
+		defer l.End()
+
+		tags := otelmetric.WithAttributes(
+			attribute.String("scheme", f.scheme),
+			attribute.String("op", op),
+		)
+
+		opDuration.Record(ctx, time.Since(startedAt).Seconds(), tags)
+
+		if n > 0 {
+			span.SetAttributes(attribute.Int64("fs.bytes", n))
+			transferred.Add(ctx, n, tags)
+		}
+
+		if err != nil && !errors.Is(err, io.EOF) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			opErrors.Add(ctx, 1, tags)
+		}
+	}
+}
+
+func (f *fs) Mkdir(ctx context.Context, name string, perm os.FileMode) (err error) {
+	ctx, done := f.start(ctx, "mkdir", name)
+	defer func() { done(err, 0) }()
+
+	return f.FileSystem.Mkdir(ctx, name, perm)
+}
+
+func (f *fs) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (file filesystem.File, err error) {
+	c, done := f.start(ctx, "openfile", name, attribute.Int("fs.flag", flag))
+	defer func() { done(err, 0) }()
+
+	file, err = f.FileSystem.OpenFile(c, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return withOptional(&tracedFile{File: file, ctx: ctx, fs: f, name: name}), nil
+}
+
+func (f *fs) RemoveAll(ctx context.Context, name string) (err error) {
+	ctx, done := f.start(ctx, "removeall", name)
+	defer func() { done(err, 0) }()
+
+	return f.FileSystem.RemoveAll(ctx, name)
+}
+
+func (f *fs) Rename(ctx context.Context, oldName, newName string) (err error) {
+	ctx, done := f.start(ctx, "rename", newName, attribute.String("fs.from", oldName))
+	defer func() { done(err, 0) }()
+
+	return f.FileSystem.Rename(ctx, oldName, newName)
+}
+
+func (f *fs) Copy(ctx context.Context, oldName, newName string, recursive bool) (err error) {
+	ctx, done := f.start(ctx, "copy", newName, attribute.String("fs.from", oldName), attribute.Bool("fs.recursive", recursive))
+	defer func() { done(err, 0) }()
+
+	return filesystem.Copy(ctx, f.FileSystem, oldName, f.FileSystem, newName, recursive)
+}
+
+func (f *fs) Hash(ctx context.Context, name string, algo filesystem.HashAlgorithm) (sum string, err error) {
+	h, ok := f.FileSystem.(filesystem.Hasher)
+	if !ok {
+		return "", errors.ErrUnsupported
+	}
+
+	ctx, done := f.start(ctx, "hash", name, attribute.String("fs.hash.algo", string(algo)))
+	defer func() { done(err, 0) }()
+
+	return h.Hash(ctx, name, algo)
+}
+
+func (f *fs) ListPrefix(ctx context.Context, prefix string) iter.Seq2[*filesystem.FileEntry, error] {
+	return func(yield func(*filesystem.FileEntry, error) bool) {
+		l, ok := f.FileSystem.(filesystem.PrefixLister)
+		if !ok {
+			yield(nil, errors.ErrUnsupported)
+			return
+		}
+
+		ctx, done := f.start(ctx, "list", prefix)
+
+		var err error
+		defer func() { done(err, 0) }()
+
+		for e, e1 := range l.ListPrefix(ctx, prefix) {
+			if e1 != nil {
+				err = e1
+				yield(nil, err)
+				return
+			}
+			if !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (f *fs) Lock(ctx context.Context, name string, opts filesystem.LockOptions) (info *filesystem.LockInfo, err error) {
+	l, ok := f.FileSystem.(filesystem.Locker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+
+	ctx, done := f.start(ctx, "lock", name, attribute.Bool("fs.lock.shared", opts.Shared))
+	defer func() { done(err, 0) }()
+
+	return l.Lock(ctx, name, opts)
+}
+
+func (f *fs) RefreshLock(ctx context.Context, name string, token string, ttl time.Duration) (info *filesystem.LockInfo, err error) {
+	l, ok := f.FileSystem.(filesystem.Locker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+
+	ctx, done := f.start(ctx, "refresh_lock", name)
+	defer func() { done(err, 0) }()
+
+	return l.RefreshLock(ctx, name, token, ttl)
+}
+
+func (f *fs) Unlock(ctx context.Context, name string, token string) (err error) {
+	l, ok := f.FileSystem.(filesystem.Locker)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+
+	ctx, done := f.start(ctx, "unlock", name)
+	defer func() { done(err, 0) }()
+
+	return l.Unlock(ctx, name, token)
+}
+
+func (f *fs) Locks(ctx context.Context, name string) (locks []*filesystem.LockInfo, err error) {
+	l, ok := f.FileSystem.(filesystem.Locker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+
+	ctx, done := f.start(ctx, "locks", name)
+	defer func() { done(err, 0) }()
+
+	return l.Locks(ctx, name)
+}
+
+func (f *fs) Symlink(ctx context.Context, target string, name string) (err error) {
+	l, ok := f.FileSystem.(filesystem.Linker)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+
+	ctx, done := f.start(ctx, "symlink", name, attribute.String("fs.link.target", target))
+	defer func() { done(err, 0) }()
+
+	return l.Symlink(ctx, target, name)
+}
+
+func (f *fs) Readlink(ctx context.Context, name string) (target string, err error) {
+	l, ok := f.FileSystem.(filesystem.Linker)
+	if !ok {
+		return "", errors.ErrUnsupported
+	}
+
+	ctx, done := f.start(ctx, "readlink", name)
+	defer func() { done(err, 0) }()
+
+	return l.Readlink(ctx, name)
+}
+
+func (f *fs) Lstat(ctx context.Context, name string) (info os.FileInfo, err error) {
+	l, ok := f.FileSystem.(filesystem.Linker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+
+	ctx, done := f.start(ctx, "lstat", name)
+	defer func() { done(err, 0) }()
+
+	return l.Lstat(ctx, name)
+}
+
+func (f *fs) SetMode(ctx context.Context, name string, mode os.FileMode) (err error) {
+	s, ok := f.FileSystem.(filesystem.AttrSetter)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+
+	ctx, done := f.start(ctx, "chmod", name, attribute.String("fs.mode", mode.Perm().String()))
+	defer func() { done(err, 0) }()
+
+	return s.SetMode(ctx, name, mode)
+}
+
+func (f *fs) SetModTime(ctx context.Context, name string, mtime time.Time) (err error) {
+	s, ok := f.FileSystem.(filesystem.AttrSetter)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+
+	ctx, done := f.start(ctx, "chtimes", name, attribute.String("fs.mtime", mtime.Format(time.RFC3339)))
+	defer func() { done(err, 0) }()
+
+	return s.SetModTime(ctx, name, mtime)
+}
+
+func (f *fs) Chown(ctx context.Context, name string, uid, gid int) (err error) {
+	c, ok := f.FileSystem.(filesystem.Chowner)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+
+	ctx, done := f.start(ctx, "chown", name, attribute.Int("fs.uid", uid), attribute.Int("fs.gid", gid))
+	defer func() { done(err, 0) }()
+
+	return c.Chown(ctx, name, uid, gid)
+}
+
+func (f *fs) Stat(ctx context.Context, name string) (info os.FileInfo, err error) {
+	ctx, done := f.start(ctx, "stat", name)
+	defer func() { done(err, 0) }()
+
+	return f.FileSystem.Stat(ctx, name)
+}
+
+type tracedFile struct {
+	filesystem.File
+
+	ctx  context.Context
+	fs   *fs
+	name string
+}
+
+func (f *tracedFile) Read(p []byte) (n int, err error) {
+	_, done := f.fs.start(f.ctx, "read", f.name)
+	defer func() { done(err, int64(n)) }()
+
+	return f.File.Read(p)
+}
+
+func (f *tracedFile) Write(p []byte) (n int, err error) {
+	_, done := f.fs.start(f.ctx, "write", f.name)
+	defer func() { done(err, int64(n)) }()
+
+	return f.File.Write(p)
+}
+
+func (f *tracedFile) Close() (err error) {
+	_, done := f.fs.start(f.ctx, "close", f.name)
+	defer func() { done(err, 0) }()
+
+	return f.File.Close()
+}
+
+func (f *tracedFile) Sync() error {
+	if s, ok := f.File.(filesystem.FileSyncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+func (f *tracedFile) Truncate(size int64) error {
+	if t, ok := f.File.(filesystem.FileTruncator); ok {
+		return t.Truncate(size)
+	}
+	return &os.PathError{Op: "truncate", Path: f.name, Err: errors.ErrUnsupported}
+}
+
+func (f *tracedFile) Abort(cause error) (err error) {
+	a, ok := f.File.(filesystem.FileAborter)
+	if !ok {
+		return &os.PathError{Op: "abort", Path: f.name, Err: errors.ErrUnsupported}
+	}
+
+	_, done := f.fs.start(f.ctx, "abort", f.name)
+	defer func() { done(err, 0) }()
+
+	return a.Abort(cause)
+}
+
+// withOptional traces io.ReaderAt and io.WriterAt of file, only when implemented
+func withOptional(f *tracedFile) filesystem.File {
+	o := filesystem.OptionalOf(f.File)
+	if o.ReaderAt != nil {
+		o.ReaderAt = tracedReaderAt{f: f, r: o.ReaderAt}
+	}
+	if o.WriterAt != nil {
+		o.WriterAt = tracedWriterAt{f: f, w: o.WriterAt}
+	}
+	return filesystem.WithOptional(f, o)
+}
+
+type tracedReaderAt struct {
+	f *tracedFile
+	r io.ReaderAt
+}
+
+func (a tracedReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	_, done := a.f.fs.start(a.f.ctx, "read", a.f.name, attribute.Int64("fs.offset", off))
+	defer func() { done(err, int64(n)) }()
+
+	return a.r.ReadAt(p, off)
+}
+
+type tracedWriterAt struct {
+	f *tracedFile
+	w io.WriterAt
+}
+
+func (a tracedWriterAt) WriteAt(p []byte, off int64) (n int, err error) {
+	_, done := a.f.fs.start(a.f.ctx, "write", a.f.name, attribute.Int64("fs.offset", off))
+	defer func() { done(err, int64(n)) }()
+
+	return a.w.WriteAt(p, off)
+}
+
+// withValues uses values of base when not found in ctx
+func withValues(ctx context.Context, base context.Context) context.Context {
+	if base == nil {
+		return ctx
+	}
+	return &valuesContext{Context: ctx, base: base}
+}
+
+type valuesContext struct {
+	context.Context
+	base context.Context
+}
+
+func (c *valuesContext) Value(key any) any {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.base.Value(key)
+}
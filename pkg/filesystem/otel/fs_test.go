@@ -0,0 +1,70 @@
+package otel
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/local"
+	"github.com/octohelm/unifs/pkg/filesystem/testutil"
+)
+
+func TestFS(t *testing.T) {
+	t.Run("Simple", func(t *testing.T) {
+		testutil.TestSimpleFS(t, Wrap(context.Background(), filesystem.NewMemFS(), "mem"))
+	})
+
+	t.Run("Full", func(t *testing.T) {
+		testutil.TestFullFS(t, Wrap(context.Background(), filesystem.NewMemFS(), "mem"))
+	})
+
+	t.Run("optional interfaces only when implemented", func(t *testing.T) {
+		ctx := context.Background()
+
+		for _, c := range []struct {
+			fsys        filesystem.FileSystem
+			readerAt    bool
+			writerAt    bool
+			dirIterator bool
+		}{
+			{fsys: filesystem.NewMemFS()},
+			{fsys: local.NewFS(t.TempDir()), readerAt: true, writerAt: true},
+			{fsys: testutil.DirIterFS(filesystem.NewMemFS()), dirIterator: true},
+		} {
+			fsys := Wrap(ctx, c.fsys, "test")
+
+			err := filesystem.Write(ctx, fsys, "/a.txt", []byte("hello"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			f, err := fsys.OpenFile(ctx, "/a.txt", os.O_RDWR, os.ModePerm)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			_, ok := f.(io.ReaderAt)
+			testingx.Expect(t, ok, testingx.Be(c.readerAt))
+			w, ok := f.(io.WriterAt)
+			testingx.Expect(t, ok, testingx.Be(c.writerAt))
+			if ok {
+				_, err := w.WriteAt([]byte("H"), 0)
+				testingx.Expect(t, err, testingx.Be[error](nil))
+			}
+			_ = f.Close()
+
+			d, err := filesystem.Open(ctx, fsys, "/")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			_, ok = d.(filesystem.DirIterator)
+			testingx.Expect(t, ok, testingx.Be(c.dirIterator))
+			_ = d.Close()
+
+			names := make([]string, 0)
+			for e, err := range filesystem.ReadDirIter(ctx, fsys, "/") {
+				testingx.Expect(t, err, testingx.Be[error](nil))
+				names = append(names, e.Name())
+			}
+			testingx.Expect(t, names, testingx.Equal([]string{"a.txt"}))
+		}
+	})
+}
@@ -0,0 +1,690 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// whiteoutPrefix marks file in lower layer as deleted, same as aufs and OCI image layers.
+const whiteoutPrefix = ".wh."
+
+// Overlay composes lower and upper as one FileSystem.
+//
+// Reads fall through to lower when not found in upper, and lower will never be changed.
+// Writes go to upper, with parent directories copied up on demand.
+// Deletions of entries in lower are recorded as whiteout files `.wh.<name>` in upper,
+// entry in upper always takes precedence over its whiteout,
+// so that a directory recreated after deleted will not merge the old one in lower.
+func Overlay(lower, upper FileSystem) FileSystem {
+	return &overlayFS{
+		lower: lower,
+		upper: upper,
+	}
+}
+
+type overlayFS struct {
+	lower FileSystem
+	upper FileSystem
+}
+
+func (o *overlayFS) Capabilities() Capability {
+	upper := CapabilitiesOf(o.upper)
+	lower := CapabilitiesOf(o.lower)
+
+	return upper&(CapAppend|CapRandomAccessWrite|CapTruncate|CapAtomicWrite|CapSymlink|CapSetModTime|CapSetMode|CapChown) | upper&lower&CapReaderAt
+}
+
+func (o *overlayFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	name = slashClean(name)
+
+	if isWhiteout(name) {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrPermission}
+	}
+
+	if _, err := o.Stat(ctx, name); err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+
+	if err := o.copyUpParent(ctx, "mkdir", name); err != nil {
+		return err
+	}
+
+	return o.upper.Mkdir(ctx, name, perm)
+}
+
+func (o *overlayFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (File, error) {
+	name = slashClean(name)
+
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) == 0 {
+		info, err := o.Stat(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if info.IsDir() {
+			return &overlayDir{ctx: ctx, fs: o, name: name, info: info}, nil
+		}
+
+		if o.inUpper(ctx, name) {
+			return o.upper.OpenFile(ctx, name, flag, perm)
+		}
+		return o.lower.OpenFile(ctx, name, flag, perm)
+	}
+
+	if isWhiteout(name) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrPermission}
+	}
+
+	info, err := o.Stat(ctx, name)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		if flag&os.O_CREATE == 0 {
+			return nil, err
+		}
+	} else {
+		if flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0 {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+		}
+		if info.IsDir() {
+			return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+		}
+	}
+
+	if err := o.copyUpParent(ctx, "open", name); err != nil {
+		return nil, err
+	}
+
+	// file only in lower need to copy up, unless it will be truncated
+	if info != nil && flag&os.O_TRUNC == 0 && !o.inUpper(ctx, name) {
+		if err := o.copyUpFile(ctx, name, info); err != nil {
+			return nil, err
+		}
+	}
+
+	return o.upper.OpenFile(ctx, name, flag, perm)
+}
+
+func (o *overlayFS) RemoveAll(ctx context.Context, name string) error {
+	name = slashClean(name)
+
+	if name == "/" || isWhiteout(name) {
+		return &os.PathError{Op: "removeall", Path: name, Err: os.ErrPermission}
+	}
+
+	inLower := o.inLower(ctx, name)
+
+	if err := o.upper.RemoveAll(ctx, name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if inLower {
+		if err := o.copyUpDir(ctx, path.Dir(name)); err != nil {
+			return err
+		}
+		return Write(ctx, o.upper, whiteout(name), nil)
+	}
+
+	return nil
+}
+
+func (o *overlayFS) Rename(ctx context.Context, oldName, newName string) error {
+	oldName, newName = slashClean(oldName), slashClean(newName)
+
+	if oldName == newName {
+		return nil
+	}
+
+	//  /x could not mv to its child path like /x/a/b/x
+	if oldName == "/" || strings.HasPrefix(newName, oldName+"/") || isWhiteout(oldName) || isWhiteout(newName) {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: os.ErrPermission}
+	}
+
+	if _, err := o.Stat(ctx, oldName); err != nil {
+		return err
+	}
+
+	if err := o.copyUpParent(ctx, "rename", newName); err != nil {
+		return err
+	}
+
+	// lower is read-only, so copy it up then delete
+	if o.inLower(ctx, oldName) {
+		if err := Copy(ctx, o, oldName, o, newName, true); err != nil {
+			return err
+		}
+		return o.RemoveAll(ctx, oldName)
+	}
+
+	// hide entry in lower, to avoid merging into the renamed directory
+	if o.inLower(ctx, newName) {
+		if err := Write(ctx, o.upper, whiteout(newName), nil); err != nil {
+			return err
+		}
+	}
+
+	return o.upper.Rename(ctx, oldName, newName)
+}
+
+// Copy by Copier of upper when oldName only in upper,
+// otherwise errors.ErrUnsupported to stream merged entries to upper.
+func (o *overlayFS) Copy(ctx context.Context, oldName, newName string, recursive bool) error {
+	oldName, newName = slashClean(oldName), slashClean(newName)
+
+	if isWhiteout(oldName) || isWhiteout(newName) {
+		return &os.LinkError{Op: "copy", Old: oldName, New: newName, Err: os.ErrPermission}
+	}
+
+	if !o.inUpper(ctx, oldName) || o.inLower(ctx, oldName) {
+		return errors.ErrUnsupported
+	}
+
+	if err := o.copyUpParent(ctx, "copy", newName); err != nil {
+		return err
+	}
+
+	// hide entry in lower, to avoid merging into the copied directory
+	if o.inLower(ctx, newName) {
+		if err := o.RemoveAll(ctx, newName); err != nil {
+			return err
+		}
+	}
+
+	return Copy(ctx, o.upper, oldName, o.upper, newName, recursive)
+}
+
+func (o *overlayFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	name = slashClean(name)
+
+	if isWhiteout(name) {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+
+	if info, err := o.upper.Stat(ctx, name); err == nil {
+		return info, nil
+	}
+
+	if !o.lowerVisible(ctx, name) {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+
+	return o.lower.Stat(ctx, name)
+}
+
+func (o *overlayFS) Hash(ctx context.Context, name string, algo HashAlgorithm) (string, error) {
+	name = slashClean(name)
+
+	if o.inUpper(ctx, name) {
+		return Hash(ctx, o.upper, name, algo)
+	}
+
+	if !o.lowerVisible(ctx, name) {
+		return "", &os.PathError{Op: "hash", Path: name, Err: os.ErrNotExist}
+	}
+
+	return Hash(ctx, o.lower, name, algo)
+}
+
+// ListPrefix merges sorted files listed from upper and lower, files of lower hidden by upper are skipped
+func (o *overlayFS) ListPrefix(ctx context.Context, prefix string) iter.Seq2[*FileEntry, error] {
+	return func(yield func(*FileEntry, error) bool) {
+		nextUpper, stopUpper := iter.Pull2(ListPrefix(ctx, o.upper, prefix))
+		defer stopUpper()
+
+		nextLower, stopLower := iter.Pull2(ListPrefix(ctx, o.lower, prefix))
+		defer stopLower()
+
+		next := func(next func() (*FileEntry, error, bool), skip func(e *FileEntry) bool) (*FileEntry, error) {
+			for {
+				e, err, ok := next()
+				if !ok || err != nil {
+					return nil, err
+				}
+				if !skip(e) {
+					return e, nil
+				}
+			}
+		}
+
+		visible := map[string]bool{}
+		// names of entries in dirs of upper, listed once for each dir of lower entries
+		upperNames := map[string]map[string]bool{}
+
+		skipUpper := func(e *FileEntry) bool {
+			return isWhiteout(e.Path)
+		}
+
+		skipLower := func(e *FileEntry) bool {
+			dir := path.Dir(e.Path)
+			if !o.lowerDirVisible(ctx, dir, visible) {
+				return true
+			}
+
+			names, ok := upperNames[dir]
+			if !ok {
+				names = map[string]bool{}
+				for d, err := range ReadDirIter(ctx, o.upper, dir) {
+					if err != nil {
+						break
+					}
+					names[d.Name()] = true
+				}
+				upperNames[dir] = names
+			}
+
+			// hidden by file, dir or whiteout in upper
+			name := path.Base(e.Path)
+			return names[name] || names[whiteoutPrefix+name]
+		}
+
+		u, err := next(nextUpper, skipUpper)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		l, err := next(nextLower, skipLower)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for u != nil || l != nil {
+			e := u
+			if u == nil || (l != nil && l.Path < u.Path) {
+				e = l
+			}
+
+			if !yield(e, nil) {
+				return
+			}
+
+			if e == u {
+				u, err = next(nextUpper, skipUpper)
+			} else {
+				l, err = next(nextLower, skipLower)
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+	}
+}
+
+// Watch forwards events of upper, since lower will never be changed by overlay.
+// whiteout created will be treated as deletion of the entry hidden.
+func (o *overlayFS) Watch(ctx context.Context, name string) iter.Seq2[*Event, error] {
+	return func(yield func(*Event, error) bool) {
+		w, ok := o.upper.(Watcher)
+		if !ok {
+			yield(nil, errors.ErrUnsupported)
+			return
+		}
+
+		for e, err := range w.Watch(ctx, name) {
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+
+			if isWhiteout(e.Path) {
+				if e.Op != EventCreate {
+					continue
+				}
+				e = &Event{Op: EventDelete, Path: path.Join(path.Dir(e.Path), strings.TrimPrefix(path.Base(e.Path), whiteoutPrefix)), User: e.User}
+			}
+
+			if !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Lock by upper, since all writes go to upper
+func (o *overlayFS) Lock(ctx context.Context, name string, opts LockOptions) (*LockInfo, error) {
+	return Lock(ctx, o.upper, name, opts)
+}
+
+func (o *overlayFS) RefreshLock(ctx context.Context, name string, token string, ttl time.Duration) (*LockInfo, error) {
+	return RefreshLock(ctx, o.upper, name, token, ttl)
+}
+
+func (o *overlayFS) Unlock(ctx context.Context, name string, token string) error {
+	return Unlock(ctx, o.upper, name, token)
+}
+
+func (o *overlayFS) Locks(ctx context.Context, name string) ([]*LockInfo, error) {
+	return Locks(ctx, o.upper, name)
+}
+
+// Symlink creates link in upper
+func (o *overlayFS) Symlink(ctx context.Context, target string, name string) error {
+	name = slashClean(name)
+
+	if isWhiteout(name) {
+		return &os.LinkError{Op: "symlink", Old: target, New: name, Err: os.ErrPermission}
+	}
+
+	if _, err := o.Lstat(ctx, name); err == nil {
+		return &os.LinkError{Op: "symlink", Old: target, New: name, Err: os.ErrExist}
+	}
+
+	if err := o.copyUpParent(ctx, "symlink", name); err != nil {
+		return err
+	}
+
+	return Symlink(ctx, o.upper, target, name)
+}
+
+func (o *overlayFS) Readlink(ctx context.Context, name string) (string, error) {
+	name = slashClean(name)
+
+	if _, err := Lstat(ctx, o.upper, name); err == nil {
+		return Readlink(ctx, o.upper, name)
+	}
+
+	if isWhiteout(name) || !o.lowerVisible(ctx, name) {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrNotExist}
+	}
+
+	return Readlink(ctx, o.lower, name)
+}
+
+func (o *overlayFS) Lstat(ctx context.Context, name string) (os.FileInfo, error) {
+	name = slashClean(name)
+
+	if isWhiteout(name) {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+	}
+
+	if info, err := Lstat(ctx, o.upper, name); err == nil {
+		return info, nil
+	}
+
+	if !o.lowerVisible(ctx, name) {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+	}
+
+	return Lstat(ctx, o.lower, name)
+}
+
+// SetMode changes mode in upper, file only in lower will be copied up first
+func (o *overlayFS) SetMode(ctx context.Context, name string, mode os.FileMode) error {
+	name = slashClean(name)
+
+	if err := o.copyUp(ctx, "chmod", name); err != nil {
+		return err
+	}
+
+	return SetMode(ctx, o.upper, name, mode)
+}
+
+// SetModTime changes mtime in upper, file only in lower will be copied up first
+func (o *overlayFS) SetModTime(ctx context.Context, name string, mtime time.Time) error {
+	name = slashClean(name)
+
+	if err := o.copyUp(ctx, "chtimes", name); err != nil {
+		return err
+	}
+
+	return SetModTime(ctx, o.upper, name, mtime)
+}
+
+// Chown changes owner in upper, file only in lower will be copied up first
+func (o *overlayFS) Chown(ctx context.Context, name string, uid, gid int) error {
+	name = slashClean(name)
+
+	if err := o.copyUp(ctx, "chown", name); err != nil {
+		return err
+	}
+
+	return Chown(ctx, o.upper, name, uid, gid)
+}
+
+func (o *overlayFS) inUpper(ctx context.Context, name string) bool {
+	_, err := o.upper.Stat(ctx, name)
+	return err == nil
+}
+
+// inLower checks name exists in lower and not hidden
+func (o *overlayFS) inLower(ctx context.Context, name string) bool {
+	if !o.lowerVisible(ctx, name) {
+		return false
+	}
+	_, err := o.lower.Stat(ctx, name)
+	return err == nil
+}
+
+// lowerVisible checks name and its parents are not whiteout in upper
+func (o *overlayFS) lowerVisible(ctx context.Context, name string) bool {
+	for p := name; p != "/"; p = path.Dir(p) {
+		if o.inUpper(ctx, whiteout(p)) {
+			return false
+		}
+	}
+	return true
+}
+
+// lowerDirVisible checks entries of lower under dir could be visible,
+// which needs dir and its parents not whiteout, and not files in upper.
+func (o *overlayFS) lowerDirVisible(ctx context.Context, dir string, visible map[string]bool) bool {
+	if dir == "/" {
+		return true
+	}
+
+	if v, ok := visible[dir]; ok {
+		return v
+	}
+
+	v := o.lowerDirVisible(ctx, path.Dir(dir), visible) && !o.inUpper(ctx, whiteout(dir))
+	if v {
+		if info, err := o.upper.Stat(ctx, dir); err == nil && !info.IsDir() {
+			v = false
+		}
+	}
+
+	visible[dir] = v
+	return v
+}
+
+// copyUpParent makes sure parent dir of name exists, and copy it up to upper
+func (o *overlayFS) copyUpParent(ctx context.Context, op string, name string) error {
+	parent := path.Dir(name)
+
+	info, err := o.Stat(ctx, parent)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return &os.PathError{Op: op, Path: name, Err: syscall.ENOTDIR}
+	}
+
+	return o.copyUpDir(ctx, parent)
+}
+
+// copyUp copies file or dir only in lower up to upper
+func (o *overlayFS) copyUp(ctx context.Context, op string, name string) error {
+	if isWhiteout(name) {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+
+	info, err := o.Stat(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return o.copyUpDir(ctx, name)
+	}
+
+	if o.inUpper(ctx, name) {
+		return nil
+	}
+
+	if err := o.copyUpParent(ctx, op, name); err != nil {
+		return err
+	}
+
+	return o.copyUpFile(ctx, name, info)
+}
+
+func (o *overlayFS) copyUpDir(ctx context.Context, dir string) error {
+	if dir == "/" || o.inUpper(ctx, dir) {
+		return nil
+	}
+
+	if err := o.copyUpDir(ctx, path.Dir(dir)); err != nil {
+		return err
+	}
+
+	info, err := o.lower.Stat(ctx, dir)
+	if err != nil {
+		return err
+	}
+
+	return o.upper.Mkdir(ctx, dir, permOf(info, os.ModePerm))
+}
+
+func (o *overlayFS) copyUpFile(ctx context.Context, name string, info FileInfo) error {
+	return copyFile(ctx, o.lower, name, info, o.upper, name)
+}
+
+func whiteout(name string) string {
+	return path.Join(path.Dir(name), whiteoutPrefix+path.Base(name))
+}
+
+func isWhiteout(name string) bool {
+	return strings.HasPrefix(path.Base(name), whiteoutPrefix)
+}
+
+func slashClean(name string) string {
+	if name == "" || name[0] != '/' {
+		name = "/" + name
+	}
+	return path.Clean(name)
+}
+
+// overlayDir lists entries merged from upper and lower
+type overlayDir struct {
+	ctx  context.Context
+	fs   *overlayFS
+	name string
+	info FileInfo
+
+	entries []FileInfo
+	loaded  bool
+	pos     int
+}
+
+func (d *overlayDir) Name() string {
+	return d.name
+}
+
+func (d *overlayDir) Stat() (os.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *overlayDir) Readdir(n int) ([]os.FileInfo, error) {
+	if !d.loaded {
+		entries, err := d.list()
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.loaded = true
+	}
+
+	remain := d.entries[d.pos:]
+
+	if n <= 0 {
+		d.pos = len(d.entries)
+		return remain, nil
+	}
+
+	if len(remain) == 0 {
+		return nil, io.EOF
+	}
+
+	if n > len(remain) {
+		n = len(remain)
+	}
+	d.pos += n
+	return remain[:n], nil
+}
+
+func (d *overlayDir) list() ([]FileInfo, error) {
+	entries := map[string]FileInfo{}
+	whiteouts := map[string]bool{}
+
+	if d.fs.inUpper(d.ctx, d.name) {
+		infos, err := readDirInfos(d.ctx, d.fs.upper, d.name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, info := range infos {
+			if n := info.Name(); strings.HasPrefix(n, whiteoutPrefix) {
+				whiteouts[strings.TrimPrefix(n, whiteoutPrefix)] = true
+			} else {
+				entries[n] = info
+			}
+		}
+	}
+
+	if d.fs.inLower(d.ctx, d.name) {
+		infos, err := readDirInfos(d.ctx, d.fs.lower, d.name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, info := range infos {
+			n := info.Name()
+			if _, ok := entries[n]; ok || whiteouts[n] {
+				continue
+			}
+			entries[n] = info
+		}
+	}
+
+	list := make([]FileInfo, 0, len(entries))
+	for _, info := range entries {
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list, nil
+}
+
+func (d *overlayDir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: syscall.EISDIR}
+}
+
+func (d *overlayDir) Write(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "write", Path: d.name, Err: syscall.EISDIR}
+}
+
+func (d *overlayDir) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekStart {
+		d.pos = 0
+		return 0, nil
+	}
+	return 0, &fs.PathError{Op: "seek", Path: d.name, Err: os.ErrInvalid}
+}
+
+func (d *overlayDir) Close() error {
+	return nil
+}
@@ -0,0 +1,208 @@
+package filesystem_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/local"
+	"github.com/octohelm/unifs/pkg/filesystem/testutil"
+)
+
+func TestOverlay(t *testing.T) {
+	t.Run("Simple", func(t *testing.T) {
+		testutil.TestSimpleFS(t, filesystem.Overlay(filesystem.NewMemFS(), filesystem.NewMemFS()))
+	})
+
+	t.Run("Full", func(t *testing.T) {
+		testutil.TestFullFS(t, filesystem.Overlay(filesystem.NewMemFS(), filesystem.NewMemFS()))
+	})
+
+	t.Run("with lower", func(t *testing.T) {
+		ctx := context.Background()
+
+		lower := filesystem.NewMemFS()
+		upper := filesystem.NewMemFS()
+
+		err := filesystem.MkdirAll(ctx, lower, "/d/e")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		err = filesystem.Write(ctx, lower, "/d/e/f.txt", []byte("f"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		err = filesystem.Write(ctx, lower, "/d/g.txt", []byte("g"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		fsys := filesystem.Overlay(lower, upper)
+
+		t.Run("read through lower", func(t *testing.T) {
+			testingx.Expect(t, readFile(t, fsys, "/d/e/f.txt"), testingx.Be("f"))
+		})
+
+		t.Run("write to upper only", func(t *testing.T) {
+			f, err := fsys.OpenFile(ctx, "/d/e/f.txt", os.O_RDWR, os.ModePerm)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			_, err = f.Seek(0, io.SeekEnd)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			_, err = f.Write([]byte("f"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			err = f.Close()
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			testingx.Expect(t, readFile(t, fsys, "/d/e/f.txt"), testingx.Be("ff"))
+			testingx.Expect(t, readFile(t, upper, "/d/e/f.txt"), testingx.Be("ff"))
+			testingx.Expect(t, readFile(t, lower, "/d/e/f.txt"), testingx.Be("f"))
+		})
+
+		t.Run("remove should hide lower", func(t *testing.T) {
+			err := fsys.RemoveAll(ctx, "/d/g.txt")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			_, err = fsys.Stat(ctx, "/d/g.txt")
+			testingx.Expect(t, os.IsNotExist(err), testingx.Be(true))
+			_, err = lower.Stat(ctx, "/d/g.txt")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			entries, err := filesystem.ReadDir(ctx, fsys, "/d")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, len(entries), testingx.Be(1))
+			testingx.Expect(t, entries[0].Name(), testingx.Be("e"))
+		})
+
+		t.Run("recreated dir should not merge lower", func(t *testing.T) {
+			err := fsys.RemoveAll(ctx, "/d")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			err = fsys.Mkdir(ctx, "/d", os.ModePerm)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			entries, err := filesystem.ReadDir(ctx, fsys, "/d")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, len(entries), testingx.Be(0))
+		})
+	})
+	t.Run("list prefix merged", func(t *testing.T) {
+		ctx := context.Background()
+
+		lower := filesystem.NewMemFS()
+		_ = filesystem.MkdirAll(ctx, lower, "/d/sub")
+		_ = filesystem.Write(ctx, lower, "/d/a.txt", []byte("a"))
+		_ = filesystem.Write(ctx, lower, "/d/b.txt", []byte("b"))
+		_ = filesystem.Write(ctx, lower, "/d/sub/c.txt", []byte("c"))
+		_ = filesystem.Write(ctx, lower, "/x", []byte("x"))
+
+		fsys := filesystem.Overlay(lower, filesystem.NewMemFS())
+
+		_ = filesystem.Write(ctx, fsys, "/d/a.txt", []byte("aa"))
+		_ = filesystem.Write(ctx, fsys, "/d/e.txt", []byte("e"))
+		_ = fsys.RemoveAll(ctx, "/d/b.txt")
+		_ = fsys.RemoveAll(ctx, "/d/sub")
+		_ = fsys.RemoveAll(ctx, "/x")
+		_ = filesystem.MkdirAll(ctx, fsys, "/x")
+		_ = filesystem.Write(ctx, fsys, "/x/y.txt", []byte("y"))
+
+		paths := make([]string, 0)
+		sizes := make([]int64, 0)
+		for e, err := range filesystem.ListPrefix(ctx, fsys, "/") {
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			paths = append(paths, e.Path)
+			sizes = append(sizes, e.Size())
+		}
+
+		testingx.Expect(t, paths, testingx.Equal([]string{"/d/a.txt", "/d/e.txt", "/x/y.txt"}))
+		testingx.Expect(t, sizes, testingx.Equal([]int64{2, 1, 1}))
+	})
+
+	t.Run("capabilities of upper kept", func(t *testing.T) {
+		caps := filesystem.CapabilitiesOf(filesystem.Overlay(filesystem.NewMemFS(), local.NewFS(t.TempDir())))
+
+		testingx.Expect(t, caps.Has(filesystem.CapSymlink|filesystem.CapAtomicWrite), testingx.Be(true))
+		testingx.Expect(t, caps.Has(filesystem.CapAtomicRename), testingx.Be(false))
+	})
+
+	t.Run("list lower without stat of upper for each file", func(t *testing.T) {
+		ctx := context.Background()
+
+		lower := filesystem.NewMemFS()
+		_ = filesystem.MkdirAll(ctx, lower, "/d")
+		for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+			_ = filesystem.Write(ctx, lower, "/d/"+name, []byte(name))
+		}
+
+		upper := &statCountingFS{Forwarder: filesystem.Forwarder{FileSystem: filesystem.NewMemFS()}}
+		fsys := filesystem.Overlay(lower, upper)
+
+		_ = filesystem.Write(ctx, fsys, "/d/b", []byte("bb"))
+		_ = fsys.RemoveAll(ctx, "/d/c")
+		_ = fsys.RemoveAll(ctx, "/d/e")
+		_ = filesystem.MkdirAll(ctx, fsys, "/d/e")
+
+		upper.count.Store(0)
+
+		paths := make([]string, 0)
+		for e, err := range filesystem.ListPrefix(ctx, fsys, "/") {
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			paths = append(paths, e.Path)
+		}
+
+		testingx.Expect(t, paths, testingx.Equal([]string{"/d/a", "/d/b", "/d/d", "/d/f"}))
+		testingx.Expect(t, upper.count.Load() < 6, testingx.Be(true))
+	})
+	t.Run("copy to dir in lower", func(t *testing.T) {
+		ctx := context.Background()
+
+		lower := filesystem.NewMemFS()
+		_ = filesystem.MkdirAll(ctx, lower, "/v")
+		_ = filesystem.Write(ctx, lower, "/v/old.txt", []byte("old"))
+
+		fsys := filesystem.Overlay(lower, filesystem.NewMemFS())
+
+		_ = filesystem.MkdirAll(ctx, fsys, "/u")
+		_ = filesystem.Write(ctx, fsys, "/u/1.txt", []byte("1"))
+
+		err := filesystem.Copy(ctx, fsys, "/u", fsys, "/v", true)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		entries, err := filesystem.ReadDir(ctx, fsys, "/v")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, len(entries), testingx.Be(1))
+		testingx.Expect(t, readFile(t, fsys, "/v/1.txt"), testingx.Be("1"))
+		testingx.Expect(t, readFile(t, lower, "/v/old.txt"), testingx.Be("old"))
+	})
+
+	t.Run("watch deletion of lower", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		lower := filesystem.NewMemFS()
+		_ = filesystem.MkdirAll(ctx, lower, "/in")
+		_ = filesystem.Write(ctx, lower, "/in/a.txt", []byte("a"))
+
+		fsys := filesystem.Overlay(lower, local.NewFS(t.TempDir()))
+		_ = filesystem.MkdirAll(ctx, fsys, "/in/sub")
+
+		events := make(chan *filesystem.Event, 16)
+
+		go func() {
+			for e, err := range filesystem.Watch(ctx, fsys, "/in", filesystem.WatchInterval(10*time.Millisecond)) {
+				if err == nil {
+					events <- e
+				}
+			}
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+
+		err := fsys.RemoveAll(ctx, "/in/a.txt")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		select {
+		case e := <-events:
+			testingx.Expect(t, *e, testingx.Equal(filesystem.Event{Op: filesystem.EventDelete, Path: "/in/a.txt"}))
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+	})
+}
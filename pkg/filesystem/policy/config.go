@@ -0,0 +1,38 @@
+package policy
+
+import (
+	"os"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+type Config struct {
+	// Deny all changes
+	ReadOnly bool `flag:"read-only,omitzero"`
+	// File of path rules, one rule per line as <allow|deny> <*|read,write,delete,rename,mkdir> <pattern>
+	Rules string `flag:"rules,omitzero"`
+}
+
+// Apply wraps fsys with policy, lock files are hidden even no policy set
+func (c *Config) Apply(fsys filesystem.FileSystem) (filesystem.FileSystem, error) {
+	opts := []Option{
+		WithReadOnly(c.ReadOnly),
+	}
+
+	if c.Rules != "" {
+		f, err := os.Open(c.Rules)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		rules, err := ParseRules(f)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, WithRules(rules...))
+	}
+
+	return Wrap(fsys, opts...), nil
+}
@@ -0,0 +1,382 @@
+package policy
+
+import (
+	"context"
+	"io/fs"
+	"iter"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+type Option func(p *policyFS)
+
+// WithReadOnly denies all ops except read
+func WithReadOnly(readOnly bool) Option {
+	return func(p *policyFS) {
+		p.readOnly = readOnly
+	}
+}
+
+// WithRules sets rules, first matched rule takes effect, and ops without matched rule are allowed
+func WithRules(rules ...Rule) Option {
+	return func(p *policyFS) {
+		p.rules = append(p.rules, rules...)
+	}
+}
+
+// Wrap enforces policy on fsys, violations return fs.ErrPermission.
+// Entries denied to read will be hidden from listing too.
+//
+// lock files are always denied, since they should only be changed by Lock,
+// and locks stored in fsys when no Locker implemented.
+func Wrap(fsys filesystem.FileSystem, opts ...Option) filesystem.FileSystem {
+	p := &policyFS{Forwarder: filesystem.Forwarder{FileSystem: fsys}}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+type policyFS struct {
+	filesystem.Forwarder
+
+	readOnly bool
+	rules    []Rule
+}
+
+func (p *policyFS) Capabilities() filesystem.Capability {
+	caps := filesystem.CapabilitiesOf(p.FileSystem)
+	if p.readOnly {
+		return caps & filesystem.CapReaderAt
+	}
+	return caps
+}
+
+// Allowed checks op on name allowed
+func (p *policyFS) Allowed(op Op, name string) bool {
+	name = slashClean(name)
+
+	if filesystem.IsLockName(name) {
+		return false
+	}
+
+	if p.readOnly && op != OpRead {
+		return false
+	}
+
+	for _, r := range p.rules {
+		if r.Match(op, name) {
+			return r.Allow
+		}
+	}
+
+	return true
+}
+
+func (p *policyFS) check(op Op, name string) error {
+	if !p.Allowed(op, name) {
+		return &fs.PathError{Op: string(op), Path: name, Err: fs.ErrPermission}
+	}
+	return nil
+}
+
+func (p *policyFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	if err := p.check(OpMkdir, name); err != nil {
+		return err
+	}
+	return p.FileSystem.Mkdir(ctx, name, perm)
+}
+
+func (p *policyFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (filesystem.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		if err := p.check(OpWrite, name); err != nil {
+			return nil, err
+		}
+		return p.FileSystem.OpenFile(ctx, name, flag, perm)
+	}
+
+	if err := p.check(OpRead, name); err != nil {
+		return nil, err
+	}
+
+	f, err := p.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := f.Stat(); err == nil && info.IsDir() {
+		d := &dir{File: f, p: p, name: slashClean(name)}
+		if it, ok := f.(filesystem.DirIterator); ok {
+			return filesystem.WithOptional(d, filesystem.OptionalFile{DirIterator: dirIterator{d: d, it: it}}), nil
+		}
+		return d, nil
+	}
+
+	return f, nil
+}
+
+// checkTree calls check with name and each entry under it,
+// since ops on directory apply to all entries under it.
+func (p *policyFS) checkTree(ctx context.Context, name string, check func(name string) error) error {
+	if len(p.rules) == 0 {
+		// without rules, entries under name share the decision of name
+		return nil
+	}
+
+	return filesystem.WalkDir(ctx, p.FileSystem, name, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		return check(name)
+	})
+}
+
+func (p *policyFS) RemoveAll(ctx context.Context, name string) error {
+	if err := p.check(OpDelete, name); err != nil {
+		return err
+	}
+	if err := p.checkTree(ctx, slashClean(name), func(name string) error {
+		return p.check(OpDelete, name)
+	}); err != nil {
+		return err
+	}
+	return p.FileSystem.RemoveAll(ctx, name)
+}
+
+func (p *policyFS) Rename(ctx context.Context, oldName, newName string) error {
+	if err := p.checkMove(ctx, OpRename, OpRename, oldName, newName, true); err != nil {
+		return err
+	}
+	return p.FileSystem.Rename(ctx, oldName, newName)
+}
+
+func (p *policyFS) Copy(ctx context.Context, oldName, newName string, recursive bool) error {
+	if err := p.checkMove(ctx, OpRead, OpWrite, oldName, newName, recursive); err != nil {
+		return err
+	}
+	if _, err := p.FileSystem.Stat(ctx, newName); err == nil {
+		// newName will be replaced
+		if err := p.checkTree(ctx, slashClean(newName), func(name string) error {
+			return p.check(OpDelete, name)
+		}); err != nil {
+			return err
+		}
+	}
+	return filesystem.Copy(ctx, p.FileSystem, oldName, p.FileSystem, newName, recursive)
+}
+
+// checkMove checks oldOp on oldName and newOp on newName,
+// and on each entry under oldName and its new path too when recursive.
+func (p *policyFS) checkMove(ctx context.Context, oldOp Op, newOp Op, oldName, newName string, recursive bool) error {
+	op := string(oldOp)
+	if oldOp != newOp {
+		op = "copy"
+	}
+
+	check := func(oldName, newName string) error {
+		if !p.Allowed(oldOp, oldName) || !p.Allowed(newOp, newName) {
+			return &os.LinkError{Op: op, Old: oldName, New: newName, Err: fs.ErrPermission}
+		}
+		return nil
+	}
+
+	if err := check(oldName, newName); err != nil {
+		return err
+	}
+
+	if !recursive {
+		return nil
+	}
+
+	oldDir, newDir := slashClean(oldName), slashClean(newName)
+
+	return p.checkTree(ctx, oldDir, func(name string) error {
+		return check(name, path.Join(newDir, strings.TrimPrefix(name, oldDir)))
+	})
+}
+
+func (p *policyFS) Hash(ctx context.Context, name string, algo filesystem.HashAlgorithm) (string, error) {
+	if err := p.check(OpRead, name); err != nil {
+		return "", err
+	}
+	return p.Forwarder.Hash(ctx, name, algo)
+}
+
+// ListPrefix hides files not allowed to read
+func (p *policyFS) ListPrefix(ctx context.Context, prefix string) iter.Seq2[*filesystem.FileEntry, error] {
+	return func(yield func(*filesystem.FileEntry, error) bool) {
+		for e, err := range p.Forwarder.ListPrefix(ctx, prefix) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !p.Allowed(OpRead, e.Path) {
+				continue
+			}
+			if !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Watch hides events of files not allowed to read
+func (p *policyFS) Watch(ctx context.Context, name string) iter.Seq2[*filesystem.Event, error] {
+	return func(yield func(*filesystem.Event, error) bool) {
+		for e, err := range p.Forwarder.Watch(ctx, name) {
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			if !p.Allowed(OpRead, e.Path) {
+				continue
+			}
+			if !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Lock requires write, since locks held will block writes of others
+func (p *policyFS) Lock(ctx context.Context, name string, opts filesystem.LockOptions) (*filesystem.LockInfo, error) {
+	if err := p.check(OpWrite, name); err != nil {
+		return nil, err
+	}
+	return filesystem.Lock(ctx, p.FileSystem, name, opts)
+}
+
+func (p *policyFS) RefreshLock(ctx context.Context, name string, token string, ttl time.Duration) (*filesystem.LockInfo, error) {
+	if err := p.check(OpWrite, name); err != nil {
+		return nil, err
+	}
+	return filesystem.RefreshLock(ctx, p.FileSystem, name, token, ttl)
+}
+
+func (p *policyFS) Unlock(ctx context.Context, name string, token string) error {
+	if err := p.check(OpWrite, name); err != nil {
+		return err
+	}
+	return filesystem.Unlock(ctx, p.FileSystem, name, token)
+}
+
+func (p *policyFS) Locks(ctx context.Context, name string) ([]*filesystem.LockInfo, error) {
+	if err := p.check(OpRead, name); err != nil {
+		return nil, err
+	}
+	return filesystem.Locks(ctx, p.FileSystem, name)
+}
+
+func (p *policyFS) Symlink(ctx context.Context, target string, name string) error {
+	if err := p.check(OpWrite, name); err != nil {
+		return err
+	}
+	return p.Forwarder.Symlink(ctx, target, name)
+}
+
+func (p *policyFS) Readlink(ctx context.Context, name string) (string, error) {
+	if err := p.check(OpRead, name); err != nil {
+		return "", err
+	}
+	return p.Forwarder.Readlink(ctx, name)
+}
+
+func (p *policyFS) Lstat(ctx context.Context, name string) (os.FileInfo, error) {
+	if err := p.check(OpRead, name); err != nil {
+		return nil, err
+	}
+	return p.Forwarder.Lstat(ctx, name)
+}
+
+func (p *policyFS) SetMode(ctx context.Context, name string, mode os.FileMode) error {
+	if err := p.check(OpWrite, name); err != nil {
+		return err
+	}
+	return p.Forwarder.SetMode(ctx, name, mode)
+}
+
+func (p *policyFS) SetModTime(ctx context.Context, name string, mtime time.Time) error {
+	if err := p.check(OpWrite, name); err != nil {
+		return err
+	}
+	return p.Forwarder.SetModTime(ctx, name, mtime)
+}
+
+func (p *policyFS) Chown(ctx context.Context, name string, uid, gid int) error {
+	if err := p.check(OpWrite, name); err != nil {
+		return err
+	}
+	return p.Forwarder.Chown(ctx, name, uid, gid)
+}
+
+func (p *policyFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	if err := p.check(OpRead, name); err != nil {
+		return nil, err
+	}
+	return p.FileSystem.Stat(ctx, name)
+}
+
+// dir hides entries denied to read
+type dir struct {
+	filesystem.File
+	p    *policyFS
+	name string
+}
+
+func (d *dir) Readdir(count int) ([]os.FileInfo, error) {
+	for {
+		infos, err := d.File.Readdir(count)
+
+		allowed := make([]os.FileInfo, 0, len(infos))
+		for _, info := range infos {
+			if d.p.Allowed(OpRead, path.Join(d.name, info.Name())) {
+				allowed = append(allowed, info)
+			}
+		}
+
+		// all hidden, read more to avoid returning empty list before the end
+		if count > 0 && len(allowed) == 0 && len(infos) > 0 && err == nil {
+			continue
+		}
+
+		return allowed, err
+	}
+}
+
+// dirIterator hides entries not allowed to read
+type dirIterator struct {
+	d  *dir
+	it filesystem.DirIterator
+}
+
+func (i dirIterator) ReadDirIter(ctx context.Context) iter.Seq2[fs.DirEntry, error] {
+	return func(yield func(fs.DirEntry, error) bool) {
+		d := i.d
+
+		for e, err := range i.it.ReadDirIter(ctx) {
+			if err == nil && !d.p.Allowed(OpRead, path.Join(d.name, e.Name())) {
+				continue
+			}
+
+			if !yield(e, err) {
+				return
+			}
+		}
+	}
+}
+
+func slashClean(name string) string {
+	if name == "" || name[0] != '/' {
+		name = "/" + name
+	}
+	return path.Clean(name)
+}
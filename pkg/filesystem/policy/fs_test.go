@@ -0,0 +1,234 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/testutil"
+)
+
+func TestPolicy(t *testing.T) {
+	t.Run("Simple", func(t *testing.T) {
+		testutil.TestSimpleFS(t, Wrap(filesystem.NewMemFS(), WithRules(Rule{Pattern: "/**", Allow: true})))
+	})
+
+	ctx := context.Background()
+
+	t.Run("read only", func(t *testing.T) {
+		source := filesystem.NewMemFS()
+		err := filesystem.Write(ctx, source, "/1.txt", []byte("1"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		fsys := Wrap(source, WithReadOnly(true))
+
+		data, err := read(ctx, fsys, "/1.txt")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, string(data), testingx.Be("1"))
+
+		err = filesystem.Write(ctx, fsys, "/1.txt", []byte("2"))
+		testingx.Expect(t, errors.Is(err, fs.ErrPermission), testingx.Be(true))
+
+		err = fsys.Mkdir(ctx, "/a", os.ModePerm)
+		testingx.Expect(t, errors.Is(err, fs.ErrPermission), testingx.Be(true))
+
+		err = fsys.RemoveAll(ctx, "/1.txt")
+		testingx.Expect(t, errors.Is(err, fs.ErrPermission), testingx.Be(true))
+
+		err = fsys.Rename(ctx, "/1.txt", "/2.txt")
+		testingx.Expect(t, errors.Is(err, fs.ErrPermission), testingx.Be(true))
+	})
+
+	t.Run("rules", func(t *testing.T) {
+		rules, err := ParseRules(strings.NewReader(`
+# archive could be read, but not changed
+deny write,delete,rename,mkdir /archive/**
+deny * /**/.secret
+`))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, len(rules), testingx.Be(2))
+
+		source := filesystem.NewMemFS()
+		err = filesystem.MkdirAll(ctx, source, "/archive/2024")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		err = filesystem.Write(ctx, source, "/archive/2024/1.txt", []byte("1"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		err = filesystem.Write(ctx, source, "/archive/2024/.secret", []byte("1"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		fsys := Wrap(source, WithRules(rules...))
+
+		_, err = read(ctx, fsys, "/archive/2024/1.txt")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		err = filesystem.Write(ctx, fsys, "/archive/2024/1.txt", []byte("2"))
+		testingx.Expect(t, errors.Is(err, fs.ErrPermission), testingx.Be(true))
+
+		err = fsys.RemoveAll(ctx, "/archive")
+		testingx.Expect(t, errors.Is(err, fs.ErrPermission), testingx.Be(true))
+
+		err = fsys.Rename(ctx, "/archive/2024/1.txt", "/1.txt")
+		testingx.Expect(t, errors.Is(err, fs.ErrPermission), testingx.Be(true))
+
+		err = filesystem.Write(ctx, fsys, "/1.txt", []byte("2"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = fsys.Stat(ctx, "/archive/2024/.secret")
+		testingx.Expect(t, errors.Is(err, fs.ErrPermission), testingx.Be(true))
+
+		infos, err := filesystem.ReadDir(ctx, fsys, "/archive/2024")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, len(infos), testingx.Be(1))
+		testingx.Expect(t, infos[0].Name(), testingx.Be("1.txt"))
+	})
+
+	t.Run("rules on entries under dir", func(t *testing.T) {
+		rules, err := ParseRules(strings.NewReader(`
+deny * /**/.secret
+deny delete,rename /a/keep/**
+`))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		source := filesystem.NewMemFS()
+		err = filesystem.MkdirAll(ctx, source, "/a/keep")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		err = filesystem.Write(ctx, source, "/a/keep/1.txt", []byte("1"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		err = filesystem.Write(ctx, source, "/a/.secret", []byte("1"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		fsys := Wrap(source, WithRules(rules...))
+
+		err = filesystem.Copy(ctx, fsys, "/a", fsys, "/b", true)
+		testingx.Expect(t, errors.Is(err, fs.ErrPermission), testingx.Be(true))
+		_, err = source.Stat(ctx, "/b/.secret")
+		testingx.Expect(t, os.IsNotExist(err), testingx.Be(true))
+
+		err = filesystem.Copy(ctx, fsys, "/a/keep", fsys, "/b", true)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		err = fsys.Rename(ctx, "/a", "/c")
+		testingx.Expect(t, errors.Is(err, fs.ErrPermission), testingx.Be(true))
+
+		err = fsys.RemoveAll(ctx, "/a")
+		testingx.Expect(t, errors.Is(err, fs.ErrPermission), testingx.Be(true))
+
+		_, err = source.Stat(ctx, "/a/keep/1.txt")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		err = fsys.Rename(ctx, "/b", "/c")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		err = fsys.RemoveAll(ctx, "/c")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+	})
+
+	t.Run("rules on entries iterated", func(t *testing.T) {
+		source := filesystem.NewMemFS()
+		for _, name := range []string{"/1.txt", "/.secret"} {
+			err := filesystem.Write(ctx, source, name, []byte("1"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+		}
+
+		fsys := Wrap(testutil.DirIterFS(source), WithRules(Rule{Pattern: "/**/.secret", Ops: []Op{OpRead}}))
+
+		d, err := filesystem.Open(ctx, fsys, "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		_, ok := d.(filesystem.DirIterator)
+		testingx.Expect(t, ok, testingx.Be(true))
+		_ = d.Close()
+
+		names := make([]string, 0)
+		for e, err := range filesystem.ReadDirIter(ctx, fsys, "/") {
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			names = append(names, e.Name())
+		}
+		testingx.Expect(t, names, testingx.Equal([]string{"1.txt"}))
+	})
+
+	t.Run("lock files hidden", func(t *testing.T) {
+		source := filesystem.NewMemFS()
+		err := filesystem.Write(ctx, source, "/1.txt", []byte("1"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		fsys := Wrap(source)
+
+		info, err := filesystem.Lock(ctx, fsys, "/1.txt", filesystem.LockOptions{})
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		locks, err := filesystem.Locks(ctx, fsys, "/1.txt")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, len(locks), testingx.Be(1))
+
+		infos, err := filesystem.ReadDir(ctx, fsys, "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, len(infos), testingx.Be(1))
+		testingx.Expect(t, infos[0].Name(), testingx.Be("1.txt"))
+
+		_, err = fsys.Stat(ctx, "/.unifs-locks")
+		testingx.Expect(t, errors.Is(err, fs.ErrPermission), testingx.Be(true))
+
+		err = fsys.RemoveAll(ctx, "/.unifs-locks")
+		testingx.Expect(t, errors.Is(err, fs.ErrPermission), testingx.Be(true))
+
+		err = filesystem.Unlock(ctx, fsys, "/1.txt", info.Token)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = filesystem.Lock(ctx, Wrap(source, WithReadOnly(true)), "/1.txt", filesystem.LockOptions{})
+		testingx.Expect(t, errors.Is(err, fs.ErrPermission), testingx.Be(true))
+	})
+
+	t.Run("invalid rules", func(t *testing.T) {
+		for _, text := range []string{
+			"deny /a",
+			"block * /a",
+			"deny list /a",
+			"deny * a/b",
+			"deny * /[",
+		} {
+			_, err := ParseRules(strings.NewReader(text))
+			testingx.Expect(t, err, testingx.Not(testingx.Be[error](nil)))
+		}
+	})
+}
+
+func read(ctx context.Context, fsys filesystem.FileSystem, name string) ([]byte, error) {
+	f, err := filesystem.Open(ctx, fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+func TestMatchPath(t *testing.T) {
+	cases := []struct {
+		pattern string
+		name    string
+		matched bool
+	}{
+		{"/**", "/", true},
+		{"/**", "/a/b", true},
+		{"/a/**", "/a", true},
+		{"/a/**", "/ab", false},
+		{"/a/*", "/a/b", true},
+		{"/a/*", "/a/b/c", false},
+		{"/**/*.log", "/a.log", true},
+		{"/**/*.log", "/a/b/c.log", true},
+		{"/**/b/*.log", "/a/b/c.log", true},
+		{"/**/b/*.log", "/a/c/c.log", false},
+		{"/*", "/", false},
+	}
+
+	for _, c := range cases {
+		testingx.Expect(t, MatchPath(c.pattern, c.name), testingx.Be(c.matched))
+	}
+}
@@ -0,0 +1,181 @@
+package policy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+type Op string
+
+const (
+	OpRead   Op = "read"
+	OpWrite  Op = "write"
+	OpDelete Op = "delete"
+	OpRename Op = "rename"
+	OpMkdir  Op = "mkdir"
+)
+
+var ops = []Op{OpRead, OpWrite, OpDelete, OpRename, OpMkdir}
+
+// Rule allows or denies ops on paths matched pattern
+type Rule struct {
+	Allow bool
+	// empty means all ops
+	Ops []Op
+	// glob pattern of absolute path, `**` matches any levels of directories
+	Pattern string
+}
+
+func (r Rule) Match(op Op, name string) bool {
+	if len(r.Ops) > 0 {
+		matched := false
+		for _, o := range r.Ops {
+			if o == op {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return MatchPath(r.Pattern, name)
+}
+
+func (r Rule) String() string {
+	action := "deny"
+	if r.Allow {
+		action = "allow"
+	}
+
+	o := "*"
+	if len(r.Ops) > 0 {
+		list := make([]string, len(r.Ops))
+		for i := range r.Ops {
+			list[i] = string(r.Ops[i])
+		}
+		o = strings.Join(list, ",")
+	}
+
+	return fmt.Sprintf("%s %s %s", action, o, r.Pattern)
+}
+
+// ParseRules parses rules, one rule per line as `<allow|deny> <ops> <pattern>`.
+//
+// ops could be `*` or comma separated read, write, delete, rename, mkdir.
+// empty lines and lines start with `#` will be ignored.
+//
+//	# archive could be read, but not changed
+//	deny write,delete,rename,mkdir /archive/**
+//	deny * /**/.git/**
+func ParseRules(r io.Reader) ([]Rule, error) {
+	rules := make([]Rule, 0)
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: rule should be `<allow|deny> <ops> <pattern>`, but got %q", line, text)
+		}
+
+		rule := Rule{}
+
+		switch fields[0] {
+		case "allow":
+			rule.Allow = true
+		case "deny":
+		default:
+			return nil, fmt.Errorf("line %d: unknown action %q", line, fields[0])
+		}
+
+		if fields[1] != "*" {
+			for _, o := range strings.Split(fields[1], ",") {
+				op := Op(o)
+				if !isOp(op) {
+					return nil, fmt.Errorf("line %d: unknown op %q", line, o)
+				}
+				rule.Ops = append(rule.Ops, op)
+			}
+		}
+
+		rule.Pattern = fields[2]
+		if !strings.HasPrefix(rule.Pattern, "/") {
+			return nil, fmt.Errorf("line %d: pattern should be absolute path, but got %q", line, rule.Pattern)
+		}
+		if _, err := path.Match(strings.ReplaceAll(rule.Pattern, "**", "*"), ""); err != nil {
+			return nil, fmt.Errorf("line %d: invalid pattern %q: %w", line, rule.Pattern, err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func isOp(op Op) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPath reports whether name matches the pattern,
+// same as path.Match, except `**` matches zero or more directories.
+func MatchPath(pattern string, name string) bool {
+	return matchSegments(segments(pattern), segments(name))
+}
+
+func segments(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+func matchSegments(patterns []string, names []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			// trailing ** matches all
+			if len(patterns) == 1 {
+				return true
+			}
+			for i := 0; i <= len(names); i++ {
+				if matchSegments(patterns[1:], names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(names) == 0 {
+			return false
+		}
+
+		if ok, _ := path.Match(patterns[0], names[0]); !ok {
+			return false
+		}
+
+		patterns, names = patterns[1:], names[1:]
+	}
+
+	return len(names) == 0
+}
@@ -0,0 +1,69 @@
+/*
+Package policy GENERATED BY gengo:runtimedoc
+DON'T EDIT THIS FILE
+*/
+package policy
+
+func (v *Config) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "ReadOnly":
+			return []string{
+				"Deny all changes",
+			}, true
+		case "Rules":
+			return []string{
+				"File of path rules, one rule per line as <allow|deny> <*|read,write,delete,rename,mkdir> <pattern>",
+			}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{}, true
+}
+
+func (*Op) RuntimeDoc(names ...string) ([]string, bool) {
+	return []string{}, true
+}
+
+func (v *Rule) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "Allow":
+			return []string{}, true
+		case "Ops":
+			return []string{
+				"empty means all ops",
+			}, true
+		case "Pattern":
+			return []string{
+				"glob pattern of absolute path, `**` matches any levels of directories",
+			}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{
+		"Rule allows or denies ops on paths matched pattern",
+	}, true
+}
+
+// nolint:deadcode,unused
+func runtimeDoc(v any, prefix string, names ...string) ([]string, bool) {
+	if c, ok := v.(interface {
+		RuntimeDoc(names ...string) ([]string, bool)
+	}); ok {
+		doc, ok := c.RuntimeDoc(names...)
+		if ok {
+			if prefix != "" && len(doc) > 0 {
+				doc[0] = prefix + doc[0]
+				return doc, true
+			}
+
+			return doc, true
+		}
+	}
+	return nil, false
+}
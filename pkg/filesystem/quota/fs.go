@@ -0,0 +1,422 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"sync"
+	"syscall"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+// ErrQuotaExceeded is syscall.ENOSPC, so errors.Is(err, ErrQuotaExceeded) works for both
+var ErrQuotaExceeded error = syscall.ENOSPC
+
+// Usager could report used bytes and limit
+type Usager interface {
+	Usage(ctx context.Context) (used int64, limit int64, err error)
+}
+
+type Option func(q *quotaFS)
+
+// WithUsage sets used bytes, the initial scan will be skipped
+func WithUsage(used int64) Option {
+	return func(q *quotaFS) {
+		q.used = used
+		q.scanned = true
+	}
+}
+
+// Wrap limits total size of files of fsys to limit bytes.
+//
+// usage initialized by walking the whole fsys on first use,
+// then accounted incrementally on write, truncate, remove, rename and copy.
+// changes not through the wrapped fsys will not be tracked.
+// writes over the limit return ErrQuotaExceeded.
+func Wrap(fsys filesystem.FileSystem, limit int64, opts ...Option) filesystem.FileSystem {
+	q := &quotaFS{Forwarder: filesystem.Forwarder{FileSystem: fsys}, limit: limit}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+type quotaFS struct {
+	filesystem.Forwarder
+
+	limit int64
+
+	mu      sync.Mutex
+	scanned bool
+	used    int64
+}
+
+func (q *quotaFS) Usage(ctx context.Context) (int64, int64, error) {
+	if err := q.scan(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.used, q.limit, nil
+}
+
+func (q *quotaFS) scan(ctx context.Context) error {
+	q.mu.Lock()
+	scanned := q.scanned
+	q.mu.Unlock()
+
+	if scanned {
+		return nil
+	}
+
+	// walk without lock, walking the whole fsys could be slow
+	used, err := sizeOf(ctx, q.FileSystem, "/")
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.scanned {
+		q.used = used
+		q.scanned = true
+	}
+
+	return nil
+}
+
+// reserve takes n bytes of quota
+func (q *quotaFS) reserve(n int64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if n > 0 && q.used+n > q.limit {
+		return false
+	}
+	q.used += n
+	return true
+}
+
+// release gives n bytes of quota back
+func (q *quotaFS) release(n int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.used -= n
+	if q.used < 0 {
+		q.used = 0
+	}
+}
+
+func (q *quotaFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (filesystem.File, error) {
+	// lock files not counted
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) == 0 || filesystem.IsLockName(name) {
+		return q.FileSystem.OpenFile(ctx, name, flag, perm)
+	}
+
+	if err := q.scan(ctx); err != nil {
+		return nil, err
+	}
+
+	size := int64(0)
+	if info, err := q.FileSystem.Stat(ctx, name); err == nil && !info.IsDir() {
+		size = info.Size()
+	}
+
+	f, err := q.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	atomic := flag&filesystem.O_ATOMIC != 0
+
+	truncated := int64(0)
+	if flag&os.O_TRUNC != 0 {
+		// with O_ATOMIC, old content kept until committed when closed
+		if !atomic {
+			q.release(size)
+		}
+		truncated, size = size, 0
+	}
+
+	qf := &file{
+		File:      f,
+		ctx:       ctx,
+		q:         q,
+		name:      name,
+		size:      size,
+		initial:   size,
+		truncated: truncated,
+		atomic:    atomic,
+	}
+	if flag&os.O_APPEND != 0 {
+		qf.offset = size
+	}
+
+	// writes at offset not accounted, so not exposed
+	return filesystem.WithOptional(qf, filesystem.OptionalFile{ReaderAt: filesystem.OptionalOf(f).ReaderAt}), nil
+}
+
+func (q *quotaFS) RemoveAll(ctx context.Context, name string) error {
+	if err := q.scan(ctx); err != nil {
+		return err
+	}
+
+	size, err := sizeOf(ctx, q.FileSystem, name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := q.FileSystem.RemoveAll(ctx, name); err != nil {
+		return err
+	}
+
+	q.release(size)
+	return nil
+}
+
+func (q *quotaFS) Rename(ctx context.Context, oldName, newName string) error {
+	if err := q.scan(ctx); err != nil {
+		return err
+	}
+
+	if path.Clean(oldName) == path.Clean(newName) {
+		return q.FileSystem.Rename(ctx, oldName, newName)
+	}
+
+	// newName will be replaced, with all children when it is a dir
+	replaced, err := sizeOf(ctx, q.FileSystem, newName)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := q.FileSystem.Rename(ctx, oldName, newName); err != nil {
+		return err
+	}
+
+	q.release(replaced)
+	return nil
+}
+
+func (q *quotaFS) Copy(ctx context.Context, oldName, newName string, recursive bool) error {
+	if err := q.scan(ctx); err != nil {
+		return err
+	}
+
+	size := int64(0)
+	if recursive {
+		s, err := sizeOf(ctx, q.FileSystem, oldName)
+		if err != nil {
+			return err
+		}
+		size = s
+	} else if info, err := q.FileSystem.Stat(ctx, oldName); err != nil {
+		return err
+	} else if !info.IsDir() {
+		size = info.Size()
+	}
+
+	replaced, err := sizeOf(ctx, q.FileSystem, newName)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if !q.reserve(size - replaced) {
+		return &os.LinkError{Op: "copy", Old: oldName, New: newName, Err: ErrQuotaExceeded}
+	}
+
+	if err := filesystem.Copy(ctx, q.FileSystem, oldName, q.FileSystem, newName, recursive); err != nil {
+		// give back reserved, and account partial copied by size of newName
+		q.account(context.WithoutCancel(ctx), newName, size)
+		return err
+	}
+
+	return nil
+}
+
+// account replaces counted bytes of name by its size,
+// usage will be re-scanned when size unknown.
+func (q *quotaFS) account(ctx context.Context, name string, counted int64) {
+	size, err := sizeOf(ctx, q.FileSystem, name)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		q.scanned = false
+		return
+	}
+
+	q.used = max(q.used-counted+size, 0)
+}
+
+func sizeOf(ctx context.Context, fsys filesystem.FileSystem, name string) (int64, error) {
+	size := int64(0)
+
+	err := filesystem.WalkDir(ctx, fsys, name, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filesystem.IsLockName(path) {
+			return filesystem.SkipDir
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+
+	return size, err
+}
+
+// file accounts bytes grown over its size when opened.
+// concurrent writers of the same file account separately, so usage could be over counted until removed.
+type file struct {
+	filesystem.File
+
+	ctx    context.Context
+	q      *quotaFS
+	name   string
+	size   int64
+	offset int64
+
+	// size after opened
+	initial int64
+	// size of content truncated by O_TRUNC
+	truncated int64
+	// with O_ATOMIC, truncated released only when committed
+	atomic bool
+}
+
+func (f *file) grow(op string, end int64) error {
+	if end <= f.size {
+		return nil
+	}
+	if !f.q.reserve(end - f.size) {
+		return &os.PathError{Op: op, Path: f.name, Err: ErrQuotaExceeded}
+	}
+	f.size = end
+	return nil
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	end := f.offset + int64(len(p))
+	size := f.size
+
+	if err := f.grow("write", end); err != nil {
+		return 0, err
+	}
+
+	n, err := f.File.Write(p)
+	f.offset += int64(n)
+
+	// give back not written
+	if f.offset < end && end > size {
+		actual := max(f.offset, size)
+		f.q.release(end - actual)
+		f.size = actual
+	}
+
+	return n, err
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	n, err := f.File.Seek(offset, whence)
+	if err != nil {
+		return n, err
+	}
+	f.offset = n
+	return n, nil
+}
+
+func (f *file) Sync() error {
+	if s, ok := f.File.(filesystem.FileSyncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+func (f *file) Truncate(size int64) error {
+	t, ok := f.File.(filesystem.FileTruncator)
+	if !ok {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: errors.ErrUnsupported}
+	}
+
+	prev := f.size
+	if err := f.grow("truncate", size); err != nil {
+		return err
+	}
+
+	if err := t.Truncate(size); err != nil {
+		if size > prev {
+			f.q.release(size - prev)
+			f.size = prev
+		}
+		return err
+	}
+
+	if size < prev {
+		f.q.release(prev - size)
+		f.size = size
+	}
+
+	return nil
+}
+
+// Close releases truncated old content when written with O_ATOMIC committed,
+// or gives back bytes grown since opened when not, since nothing left.
+// without O_ATOMIC, size left is unknown when failed, like nothing committed for backends like s3,
+// so accounted by size of name after closed.
+func (f *file) Close() error {
+	if err := f.File.Close(); err != nil {
+		if f.atomic {
+			f.q.release(f.size - f.initial)
+			f.size = f.initial
+			return err
+		}
+
+		f.q.account(context.WithoutCancel(f.ctx), f.name, f.size)
+		return err
+	}
+
+	if f.atomic {
+		f.q.release(f.truncated)
+	}
+	f.truncated = 0
+
+	return nil
+}
+
+// Abort gives back bytes grown since opened, since written discarded
+func (f *file) Abort(err error) error {
+	a, ok := f.File.(filesystem.FileAborter)
+	if !ok {
+		return &os.PathError{Op: "abort", Path: f.name, Err: errors.ErrUnsupported}
+	}
+
+	if err := a.Abort(err); err != nil {
+		return err
+	}
+
+	f.q.release(f.size - f.initial)
+	f.size, f.truncated = f.initial, 0
+
+	return nil
+}
@@ -0,0 +1,285 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/local"
+	"github.com/octohelm/unifs/pkg/filesystem/testutil"
+	"github.com/octohelm/unifs/pkg/units"
+)
+
+func TestQuota(t *testing.T) {
+	t.Run("Simple", func(t *testing.T) {
+		testutil.TestSimpleFS(t, Wrap(filesystem.NewMemFS(), int64(100*units.MiB)))
+	})
+
+	ctx := context.Background()
+
+	usage := func(t *testing.T, fsys filesystem.FileSystem) int64 {
+		used, _, err := fsys.(Usager).Usage(ctx)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		return used
+	}
+
+	t.Run("initial scan", func(t *testing.T) {
+		memfs := filesystem.NewMemFS()
+		_ = filesystem.MkdirAll(ctx, memfs, "/a/b")
+		_ = filesystem.Write(ctx, memfs, "/a/1.txt", make([]byte, 10))
+		_ = filesystem.Write(ctx, memfs, "/a/b/2.txt", make([]byte, 20))
+
+		testingx.Expect(t, usage(t, Wrap(memfs, 100)), testingx.Be[int64](30))
+	})
+
+	t.Run("write over limit", func(t *testing.T) {
+		fsys := Wrap(filesystem.NewMemFS(), 100)
+
+		err := filesystem.Write(ctx, fsys, "/1.txt", make([]byte, 60))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		err = filesystem.Write(ctx, fsys, "/2.txt", make([]byte, 60))
+		testingx.Expect(t, errors.Is(err, ErrQuotaExceeded), testingx.Be(true))
+
+		// overwrite should reuse quota
+		err = filesystem.Write(ctx, fsys, "/1.txt", make([]byte, 90))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, usage(t, fsys), testingx.Be[int64](90))
+	})
+
+	t.Run("write after seek to end", func(t *testing.T) {
+		fsys := Wrap(filesystem.NewMemFS(), 100)
+
+		_ = filesystem.Write(ctx, fsys, "/1.txt", make([]byte, 60))
+
+		f, err := fsys.OpenFile(ctx, "/1.txt", os.O_WRONLY, os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		defer f.Close()
+
+		_, err = f.Seek(0, io.SeekEnd)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = f.Write(make([]byte, 30))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = f.Write(make([]byte, 30))
+		testingx.Expect(t, errors.Is(err, ErrQuotaExceeded), testingx.Be(true))
+
+		testingx.Expect(t, usage(t, fsys), testingx.Be[int64](90))
+	})
+
+	t.Run("remove, rename and copy", func(t *testing.T) {
+		fsys := Wrap(filesystem.NewMemFS(), 100)
+
+		_ = filesystem.MkdirAll(ctx, fsys, "/a")
+		_ = filesystem.Write(ctx, fsys, "/a/1.txt", make([]byte, 30))
+		_ = filesystem.Write(ctx, fsys, "/a/2.txt", make([]byte, 20))
+		_ = filesystem.Write(ctx, fsys, "/3.txt", make([]byte, 10))
+		testingx.Expect(t, usage(t, fsys), testingx.Be[int64](60))
+
+		err := fsys.Rename(ctx, "/3.txt", "/a/2.txt")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, usage(t, fsys), testingx.Be[int64](40))
+
+		err = filesystem.Copy(ctx, fsys, "/a", fsys, "/b", true)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, usage(t, fsys), testingx.Be[int64](80))
+
+		err = filesystem.Copy(ctx, fsys, "/a", fsys, "/c", true)
+		testingx.Expect(t, errors.Is(err, ErrQuotaExceeded), testingx.Be(true))
+
+		err = fsys.RemoveAll(ctx, "/a")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, usage(t, fsys), testingx.Be[int64](40))
+	})
+
+	t.Run("abort gives back grown", func(t *testing.T) {
+		fsys := Wrap(local.NewFS(t.TempDir()), 100)
+
+		_ = filesystem.Write(ctx, fsys, "/1.txt", make([]byte, 30))
+
+		for _, c := range []struct {
+			name string
+			flag int
+		}{
+			{"/2.txt", os.O_WRONLY | os.O_CREATE},
+			{"/1.txt", os.O_WRONLY | os.O_CREATE | os.O_TRUNC | filesystem.O_ATOMIC},
+			{"/2.txt", os.O_WRONLY | os.O_CREATE | os.O_TRUNC | filesystem.O_ATOMIC},
+		} {
+			f, err := fsys.OpenFile(ctx, c.name, c.flag, os.ModePerm)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			_, err = f.Write(make([]byte, 60))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			err = filesystem.Abort(f, nil)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			testingx.Expect(t, usage(t, fsys), testingx.Be[int64](30))
+		}
+	})
+
+	t.Run("old content counted until atomic write committed", func(t *testing.T) {
+		fsys := Wrap(local.NewFS(t.TempDir()), 100)
+
+		_ = filesystem.Write(ctx, fsys, "/1.txt", make([]byte, 60))
+
+		f, err := fsys.OpenFile(ctx, "/1.txt", os.O_WRONLY|os.O_CREATE|os.O_TRUNC|filesystem.O_ATOMIC, os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = f.Write(make([]byte, 30))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, usage(t, fsys), testingx.Be[int64](90))
+
+		err = filesystem.Write(ctx, fsys, "/2.txt", make([]byte, 20))
+		testingx.Expect(t, errors.Is(err, ErrQuotaExceeded), testingx.Be(true))
+
+		err = f.Close()
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, usage(t, fsys), testingx.Be[int64](30))
+	})
+
+	t.Run("failed copy accounts partial copied", func(t *testing.T) {
+		memfs := filesystem.NewMemFS()
+		fsys := Wrap(&failedWriteFS{FileSystem: memfs, name: "/b/2.txt"}, 100)
+
+		_ = filesystem.MkdirAll(ctx, fsys, "/a")
+		_ = filesystem.Write(ctx, fsys, "/a/1.txt", make([]byte, 30))
+		_ = filesystem.Write(ctx, fsys, "/a/2.txt", make([]byte, 20))
+		testingx.Expect(t, usage(t, fsys), testingx.Be[int64](50))
+
+		// not tracked, counted only when re-scanned
+		_ = filesystem.Write(ctx, memfs, "/x.txt", make([]byte, 5))
+
+		err := filesystem.Copy(ctx, fsys, "/a", fsys, "/b", true)
+		testingx.Expect(t, errors.Is(err, errWriteFailed), testingx.Be(true))
+
+		// 1.txt may be copied before failed, since entries not in order
+		copied := int64(0)
+		if _, err := memfs.Stat(ctx, "/b/1.txt"); err == nil {
+			copied = 30
+		}
+		testingx.Expect(t, usage(t, fsys), testingx.Be(50+copied))
+	})
+
+	t.Run("failed close accounts size left", func(t *testing.T) {
+		memfs := filesystem.NewMemFS()
+		fsys := Wrap(&failedCommitFS{FileSystem: memfs}, 100)
+
+		_ = filesystem.Write(ctx, memfs, "/1.txt", make([]byte, 30))
+		testingx.Expect(t, usage(t, fsys), testingx.Be[int64](30))
+
+		for _, flag := range []int{
+			os.O_WRONLY | os.O_CREATE | os.O_TRUNC,
+			os.O_WRONLY | os.O_CREATE,
+		} {
+			for _, name := range []string{"/1.txt", "/2.txt"} {
+				f, err := fsys.OpenFile(ctx, name, flag, os.ModePerm)
+				testingx.Expect(t, err, testingx.Be[error](nil))
+
+				_, err = f.Write(make([]byte, 60))
+				testingx.Expect(t, err, testingx.Be[error](nil))
+
+				err = f.Close()
+				testingx.Expect(t, errors.Is(err, errCommitFailed), testingx.Be(true))
+
+				// nothing committed
+				testingx.Expect(t, usage(t, fsys), testingx.Be[int64](30))
+			}
+		}
+	})
+
+	t.Run("failed open with O_TRUNC keeps old content counted", func(t *testing.T) {
+		fsys := Wrap(filesystem.NewMemFS(), 100)
+
+		_ = filesystem.Write(ctx, fsys, "/1.txt", make([]byte, 30))
+
+		_, err := fsys.OpenFile(ctx, "/1.txt", os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_TRUNC, os.ModePerm)
+		testingx.Expect(t, errors.Is(err, os.ErrExist), testingx.Be(true))
+
+		testingx.Expect(t, usage(t, fsys), testingx.Be[int64](30))
+	})
+
+	t.Run("rename replaces dir", func(t *testing.T) {
+		fsys := Wrap(filesystem.NewMemFS(), 100)
+
+		_ = filesystem.MkdirAll(ctx, fsys, "/a/b")
+		_ = filesystem.Write(ctx, fsys, "/a/b/1.txt", make([]byte, 30))
+		_ = filesystem.Write(ctx, fsys, "/2.txt", make([]byte, 20))
+		testingx.Expect(t, usage(t, fsys), testingx.Be[int64](50))
+
+		err := fsys.Rename(ctx, "/2.txt", "/a")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, usage(t, fsys), testingx.Be[int64](20))
+
+		err = fsys.Rename(ctx, "/a", "/a")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, usage(t, fsys), testingx.Be[int64](20))
+	})
+
+	t.Run("ReaderAt only when implemented", func(t *testing.T) {
+		fsys := Wrap(filesystem.NewMemFS(), 100)
+
+		_ = filesystem.Write(ctx, fsys, "/1.txt", make([]byte, 10))
+
+		f, err := fsys.OpenFile(ctx, "/1.txt", os.O_RDWR, os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		defer f.Close()
+
+		_, ok := f.(io.ReaderAt)
+		testingx.Expect(t, ok, testingx.Be(false))
+	})
+}
+
+var errWriteFailed = errors.New("write failed")
+
+// failedWriteFS fails to rename to name, which is the last step of writing by OpenByRename
+type failedWriteFS struct {
+	filesystem.FileSystem
+	name string
+}
+
+func (fs *failedWriteFS) Rename(ctx context.Context, oldName, newName string) error {
+	if newName == fs.name {
+		return errWriteFailed
+	}
+	return fs.FileSystem.Rename(ctx, oldName, newName)
+}
+
+var errCommitFailed = errors.New("commit failed")
+
+// failedCommitFS writes to temp file and drops it when closed, like failed to commit by backends like s3
+type failedCommitFS struct {
+	filesystem.FileSystem
+}
+
+func (fs *failedCommitFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (filesystem.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return fs.FileSystem.OpenFile(ctx, name, flag, perm)
+	}
+
+	f, err := fs.FileSystem.OpenFile(ctx, name+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &failedCommitFile{File: f, ctx: ctx, fs: fs.FileSystem, name: name + ".tmp"}, nil
+}
+
+type failedCommitFile struct {
+	filesystem.File
+
+	ctx  context.Context
+	fs   filesystem.FileSystem
+	name string
+}
+
+func (f *failedCommitFile) Close() error {
+	_ = f.File.Close()
+	_ = f.fs.RemoveAll(f.ctx, f.name)
+	return errCommitFailed
+}
@@ -0,0 +1,65 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+)
+
+// DirIterator could be implemented by directory File to stream entries without loading all of them
+type DirIterator interface {
+	ReadDirIter(ctx context.Context) iter.Seq2[fs.DirEntry, error]
+}
+
+const readDirBatchSize = 1000
+
+// ReadDirIter iterates entries of dir in order of backend, stops at the first error.
+// DirIterator of directory File will be used when implemented, otherwise Readdir page by page.
+func ReadDirIter(ctx context.Context, fsys FileSystem, name string) iter.Seq2[fs.DirEntry, error] {
+	return func(yield func(fs.DirEntry, error) bool) {
+		f, err := fsys.OpenFile(ctx, name, os.O_RDONLY, os.ModeDir)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer f.Close()
+
+		if it, ok := f.(DirIterator); ok {
+			for e, err := range it.ReadDirIter(ctx) {
+				if !yield(e, err) || err != nil {
+					return
+				}
+			}
+			return
+		}
+
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			infos, err := f.Readdir(readDirBatchSize)
+			for _, info := range infos {
+				if !yield(fs.FileInfoToDirEntry(info), nil) {
+					return
+				}
+			}
+
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					yield(nil, err)
+				}
+				return
+			}
+
+			// not follow os.File, but avoid endless loop
+			if len(infos) == 0 {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,431 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"math/rand/v2"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultRetryInterval    = 200 * time.Millisecond
+	DefaultRetryMaxInterval = 5 * time.Second
+)
+
+// TransientErrorClassifier could be implemented by FileSystem to tell which backend errors are worth to retry,
+// like 503 of http or 421 of ftp.
+type TransientErrorClassifier interface {
+	IsTransientError(err error) bool
+}
+
+// IsTransientError checks err is network error which may pass when retry
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ETIMEDOUT) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return false
+}
+
+type RetryOption func(r *retryFS)
+
+// WithMaxRetries sets max retries after first failure
+func WithMaxRetries(n int) RetryOption {
+	return func(r *retryFS) {
+		r.maxRetries = n
+	}
+}
+
+// WithRetryInterval sets initial and max interval of exponential backoff
+func WithRetryInterval(interval time.Duration, maxInterval time.Duration) RetryOption {
+	return func(r *retryFS) {
+		if interval > 0 {
+			r.interval = interval
+		}
+		if maxInterval > 0 {
+			r.maxInterval = maxInterval
+		}
+	}
+}
+
+// Retry retries idempotent operations of fsys when failed with transient error,
+// with jittered exponential backoff.
+//
+// Only Stat, Mkdir, OpenFile for read, Readdir and reads of files opened for read will be retried,
+// errors of writes will be returned directly, since partial written could not be recovered.
+// Lock and Watch are not retried, since lock may be acquired when response lost, and events may be lost when watched again.
+func Retry(fsys FileSystem, opts ...RetryOption) FileSystem {
+	r := &retryFS{
+		Forwarder:   Forwarder{FileSystem: fsys},
+		maxRetries:  3,
+		interval:    DefaultRetryInterval,
+		maxInterval: DefaultRetryMaxInterval,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+type retryFS struct {
+	Forwarder
+
+	maxRetries  int
+	interval    time.Duration
+	maxInterval time.Duration
+}
+
+func (r *retryFS) isTransient(err error) bool {
+	if c, ok := r.FileSystem.(TransientErrorClassifier); ok && c.IsTransientError(err) {
+		return true
+	}
+	return IsTransientError(err)
+}
+
+// wait backoff of the attempt, returns false when ctx done
+func (r *retryFS) wait(ctx context.Context, attempt int) bool {
+	d := r.interval << attempt
+	if d <= 0 || d > r.maxInterval {
+		d = r.maxInterval
+	}
+	// equal jitter, to avoid retries from different clients at the same time
+	d = d/2 + rand.N(d/2+1)
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func (r *retryFS) do(ctx context.Context, fn func(attempt int) error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn(attempt)
+		if err == nil || attempt >= r.maxRetries || !r.isTransient(err) {
+			return err
+		}
+		if !r.wait(ctx, attempt) {
+			return err
+		}
+	}
+}
+
+func (r *retryFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return r.do(ctx, func(attempt int) error {
+		err := r.FileSystem.Mkdir(ctx, name, perm)
+		// previous attempt may be done, but response lost
+		if attempt > 0 && errors.Is(err, os.ErrExist) {
+			if info, e := r.FileSystem.Stat(ctx, name); e == nil && info.IsDir() {
+				return nil
+			}
+		}
+		return err
+	})
+}
+
+func (r *retryFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return r.FileSystem.OpenFile(ctx, name, flag, perm)
+	}
+
+	f, err := r.open(ctx, name, flag)
+	if err != nil {
+		return nil, err
+	}
+
+	rf := &retryFile{File: f, ctx: ctx, r: r, name: name, flag: flag}
+
+	o := OptionalFile{}
+	if _, ok := f.(io.ReaderAt); ok {
+		o.ReaderAt = retryReaderAt{f: rf}
+	}
+	return WithOptional(rf, o), nil
+}
+
+func (r *retryFS) open(ctx context.Context, name string, flag int) (f File, err error) {
+	err = r.do(ctx, func(attempt int) error {
+		f, err = r.FileSystem.OpenFile(ctx, name, flag, 0)
+		return err
+	})
+	return f, err
+}
+
+func (r *retryFS) RemoveAll(ctx context.Context, name string) error {
+	return r.FileSystem.RemoveAll(ctx, name)
+}
+
+func (r *retryFS) Rename(ctx context.Context, oldName, newName string) error {
+	return r.FileSystem.Rename(ctx, oldName, newName)
+}
+
+func (r *retryFS) Copy(ctx context.Context, oldName, newName string, recursive bool) error {
+	return Copy(ctx, r.FileSystem, oldName, r.FileSystem, newName, recursive)
+}
+
+func (r *retryFS) Hash(ctx context.Context, name string, algo HashAlgorithm) (sum string, err error) {
+	err = r.do(ctx, func(attempt int) error {
+		sum, err = r.Forwarder.Hash(ctx, name, algo)
+		return err
+	})
+	return sum, err
+}
+
+// ListPrefix restarts listing when failed with transient error,
+// and resumes after the path last listed, since entries listed in lexical order of paths.
+func (r *retryFS) ListPrefix(ctx context.Context, prefix string) iter.Seq2[*FileEntry, error] {
+	return func(yield func(*FileEntry, error) bool) {
+		last := ""
+		stopped := false
+
+		err := r.do(ctx, func(attempt int) error {
+			for e, err := range r.Forwarder.ListPrefix(ctx, prefix) {
+				if err != nil {
+					return err
+				}
+				if last != "" && e.Path <= last {
+					continue
+				}
+				last = e.Path
+				if !yield(e, nil) {
+					stopped = true
+					return nil
+				}
+			}
+			return nil
+		})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
+
+func (r *retryFS) RefreshLock(ctx context.Context, name string, token string, ttl time.Duration) (info *LockInfo, err error) {
+	err = r.do(ctx, func(attempt int) error {
+		info, err = r.Forwarder.RefreshLock(ctx, name, token, ttl)
+		return err
+	})
+	return info, err
+}
+
+func (r *retryFS) Unlock(ctx context.Context, name string, token string) error {
+	return r.do(ctx, func(attempt int) error {
+		err := r.Forwarder.Unlock(ctx, name, token)
+		// unlocked by previous attempt when response lost
+		if attempt > 0 && errors.Is(err, ErrLockNotFound) {
+			return nil
+		}
+		return err
+	})
+}
+
+func (r *retryFS) Locks(ctx context.Context, name string) (locks []*LockInfo, err error) {
+	err = r.do(ctx, func(attempt int) error {
+		locks, err = r.Forwarder.Locks(ctx, name)
+		return err
+	})
+	return locks, err
+}
+
+func (r *retryFS) Symlink(ctx context.Context, target string, name string) error {
+	return r.do(ctx, func(attempt int) error {
+		err := r.Forwarder.Symlink(ctx, target, name)
+		// created by previous attempt when response lost
+		if attempt > 0 && errors.Is(err, os.ErrExist) {
+			return nil
+		}
+		return err
+	})
+}
+
+func (r *retryFS) Readlink(ctx context.Context, name string) (target string, err error) {
+	err = r.do(ctx, func(attempt int) error {
+		target, err = r.Forwarder.Readlink(ctx, name)
+		return err
+	})
+	return target, err
+}
+
+func (r *retryFS) Lstat(ctx context.Context, name string) (info os.FileInfo, err error) {
+	err = r.do(ctx, func(attempt int) error {
+		info, err = r.Forwarder.Lstat(ctx, name)
+		return err
+	})
+	return info, err
+}
+
+func (r *retryFS) SetMode(ctx context.Context, name string, mode os.FileMode) error {
+	return r.do(ctx, func(attempt int) error {
+		return r.Forwarder.SetMode(ctx, name, mode)
+	})
+}
+
+func (r *retryFS) SetModTime(ctx context.Context, name string, mtime time.Time) error {
+	return r.do(ctx, func(attempt int) error {
+		return r.Forwarder.SetModTime(ctx, name, mtime)
+	})
+}
+
+func (r *retryFS) Chown(ctx context.Context, name string, uid, gid int) error {
+	return r.do(ctx, func(attempt int) error {
+		return r.Forwarder.Chown(ctx, name, uid, gid)
+	})
+}
+
+func (r *retryFS) Stat(ctx context.Context, name string) (info os.FileInfo, err error) {
+	err = r.do(ctx, func(attempt int) error {
+		info, err = r.FileSystem.Stat(ctx, name)
+		return err
+	})
+	return info, err
+}
+
+// retryFile reopens the file at the same offset when read failed with transient error
+type retryFile struct {
+	File
+
+	ctx  context.Context
+	r    *retryFS
+	name string
+	flag int
+
+	offset int64
+	// file need to be reopened before next read
+	broken bool
+	// count of entries returned by Readdir
+	listed int
+}
+
+func (f *retryFile) reopen() error {
+	_ = f.File.Close()
+
+	// retried by caller
+	file, err := f.r.FileSystem.OpenFile(f.ctx, f.name, f.flag, 0)
+	if err != nil {
+		return err
+	}
+
+	if f.offset > 0 {
+		if _, err := file.Seek(f.offset, io.SeekStart); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+
+	f.File = file
+	f.broken = false
+	return nil
+}
+
+func (f *retryFile) Read(p []byte) (n int, err error) {
+	err = f.r.do(f.ctx, func(attempt int) error {
+		if f.broken {
+			if err := f.reopen(); err != nil {
+				return err
+			}
+		}
+
+		n, err = f.File.Read(p)
+		f.offset += int64(n)
+
+		if err != nil && f.r.isTransient(err) {
+			f.broken = true
+			// return what already read, and reopen in next read
+			if n > 0 {
+				return nil
+			}
+		}
+		return err
+	})
+	return n, err
+}
+
+func (f *retryFile) Seek(offset int64, whence int) (int64, error) {
+	if f.broken {
+		if err := f.reopen(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.File.Seek(offset, whence)
+	if err != nil {
+		return n, err
+	}
+	f.offset = n
+	return n, nil
+}
+
+func (f *retryFile) Readdir(count int) (infos []os.FileInfo, err error) {
+	// partial listed could not be resumed
+	if f.listed > 0 {
+		return f.File.Readdir(count)
+	}
+
+	err = f.r.do(f.ctx, func(attempt int) error {
+		if f.broken {
+			if err := f.reopen(); err != nil {
+				return err
+			}
+		}
+
+		infos, err = f.File.Readdir(count)
+		if err != nil && f.r.isTransient(err) {
+			f.broken = true
+		}
+		return err
+	})
+	f.listed += len(infos)
+	return infos, err
+}
+
+// retryReaderAt reads at of file reopened when broken
+type retryReaderAt struct {
+	f *retryFile
+}
+
+func (a retryReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	f := a.f
+
+	err = f.r.do(f.ctx, func(attempt int) error {
+		if f.broken {
+			if err := f.reopen(); err != nil {
+				return err
+			}
+		}
+
+		r, ok := f.File.(io.ReaderAt)
+		if !ok {
+			return &os.PathError{Op: "readat", Path: f.name, Err: errors.ErrUnsupported}
+		}
+
+		n, err = r.ReadAt(p, off)
+		return err
+	})
+	return n, err
+}
@@ -0,0 +1,217 @@
+package filesystem_test
+
+import (
+	"context"
+	"io"
+	"iter"
+	"os"
+	"slices"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/testutil"
+)
+
+func TestRetry(t *testing.T) {
+	t.Run("Simple", func(t *testing.T) {
+		testutil.TestSimpleFS(t, filesystem.Retry(filesystem.NewMemFS()))
+	})
+
+	t.Run("Full", func(t *testing.T) {
+		testutil.TestFullFS(t, filesystem.Retry(filesystem.NewMemFS()))
+	})
+
+	ctx := context.Background()
+
+	t.Run("retry transient error", func(t *testing.T) {
+		source := &flakyFS{FileSystem: filesystem.NewMemFS()}
+		fsys := filesystem.Retry(source, filesystem.WithRetryInterval(time.Millisecond, 0))
+
+		source.failures.Store(2)
+
+		_, err := fsys.Stat(ctx, "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, source.calls.Load(), testingx.Be(int64(3)))
+	})
+
+	t.Run("give up after max retries", func(t *testing.T) {
+		source := &flakyFS{FileSystem: filesystem.NewMemFS()}
+		fsys := filesystem.Retry(source, filesystem.WithMaxRetries(1), filesystem.WithRetryInterval(time.Millisecond, 0))
+
+		source.failures.Store(5)
+
+		_, err := fsys.Stat(ctx, "/")
+		testingx.Expect(t, filesystem.IsTransientError(err), testingx.Be(true))
+		testingx.Expect(t, source.calls.Load(), testingx.Be(int64(2)))
+	})
+
+	t.Run("not retry other errors", func(t *testing.T) {
+		source := &flakyFS{FileSystem: filesystem.NewMemFS()}
+		fsys := filesystem.Retry(source, filesystem.WithRetryInterval(time.Millisecond, 0))
+
+		_, err := fsys.Stat(ctx, "/not-exists")
+		testingx.Expect(t, os.IsNotExist(err), testingx.Be(true))
+		testingx.Expect(t, source.calls.Load(), testingx.Be(int64(1)))
+	})
+
+	t.Run("mkdir done but response lost", func(t *testing.T) {
+		source := &flakyFS{FileSystem: filesystem.NewMemFS(), mkdirLost: true}
+		fsys := filesystem.Retry(source, filesystem.WithRetryInterval(time.Millisecond, 0))
+
+		err := fsys.Mkdir(ctx, "/a", os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+	})
+
+	t.Run("resume read after connection reset", func(t *testing.T) {
+		source := &flakyFS{FileSystem: filesystem.NewMemFS()}
+		fsys := filesystem.Retry(source, filesystem.WithRetryInterval(time.Millisecond, 0))
+
+		err := filesystem.Write(ctx, fsys, "/1.txt", []byte("0123456789"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		source.breakReadAfter.Store(4)
+
+		f, err := fsys.OpenFile(ctx, "/1.txt", os.O_RDONLY, os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, string(data), testingx.Be("0123456789"))
+	})
+
+	t.Run("list prefix", func(t *testing.T) {
+		source := &flakyFS{FileSystem: filesystem.NewMemFS(), entries: []string{"/a.txt", "/a/1.txt", "/b.txt"}}
+		fsys := filesystem.Retry(source, filesystem.WithRetryInterval(time.Millisecond, 0))
+
+		list := func(t *testing.T) []string {
+			paths := make([]string, 0)
+			for e, err := range filesystem.ListPrefix(ctx, fsys, "/") {
+				testingx.Expect(t, err, testingx.Be[error](nil))
+				paths = append(paths, e.Path)
+			}
+			return paths
+		}
+
+		t.Run("without retry", func(t *testing.T) {
+			testingx.Expect(t, list(t), testingx.Equal(source.entries))
+		})
+
+		t.Run("restarted after connection reset", func(t *testing.T) {
+			source.breakListAfter.Store(2)
+
+			testingx.Expect(t, list(t), testingx.Equal(source.entries))
+		})
+
+		t.Run("restarted after entries added", func(t *testing.T) {
+			source.breakListAfter.Store(2)
+			source.addedWhenBroken = []string{"/0.txt", "/a/2.txt"}
+
+			// entries added before the last listed are skipped
+			testingx.Expect(t, list(t), testingx.Equal([]string{"/a.txt", "/a/1.txt", "/a/2.txt", "/b.txt"}))
+		})
+	})
+
+	t.Run("ReaderAt only when implemented", func(t *testing.T) {
+		fsys := filesystem.Retry(filesystem.NewMemFS())
+
+		err := filesystem.Write(ctx, fsys, "/1.txt", []byte("0123456789"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		f, err := filesystem.Open(ctx, fsys, "/1.txt")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		defer f.Close()
+
+		_, ok := f.(io.ReaderAt)
+		testingx.Expect(t, ok, testingx.Be(false))
+	})
+}
+
+// flakyFS fails Stat with connection reset until failures used up
+type flakyFS struct {
+	filesystem.FileSystem
+
+	calls     atomic.Int64
+	failures  atomic.Int64
+	mkdirLost bool
+	// break first read stream after n bytes
+	breakReadAfter atomic.Int64
+
+	// entries listed by ListPrefix
+	entries []string
+	// break first listing after n entries
+	breakListAfter atomic.Int64
+	// entries added when listing broken
+	addedWhenBroken []string
+}
+
+func (fs *flakyFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	fs.calls.Add(1)
+	if fs.failures.Add(-1) >= 0 {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: syscall.ECONNRESET}
+	}
+	return fs.FileSystem.Stat(ctx, name)
+}
+
+func (fs *flakyFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	err := fs.FileSystem.Mkdir(ctx, name, perm)
+	if err == nil && fs.mkdirLost {
+		fs.mkdirLost = false
+		return &os.PathError{Op: "mkdir", Path: name, Err: syscall.ECONNRESET}
+	}
+	return err
+}
+
+func (fs *flakyFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (filesystem.File, error) {
+	f, err := fs.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	if n := fs.breakReadAfter.Swap(0); n > 0 {
+		return &brokenFile{File: f, remain: n}, nil
+	}
+	return f, nil
+}
+
+func (fs *flakyFS) ListPrefix(ctx context.Context, prefix string) iter.Seq2[*filesystem.FileEntry, error] {
+	return func(yield func(*filesystem.FileEntry, error) bool) {
+		n := fs.breakListAfter.Swap(0)
+
+		for i, p := range fs.entries {
+			if n > 0 && int64(i) == n {
+				if len(fs.addedWhenBroken) > 0 {
+					fs.entries = append(fs.entries, fs.addedWhenBroken...)
+					slices.Sort(fs.entries)
+					fs.addedWhenBroken = nil
+				}
+				yield(nil, &os.PathError{Op: "list", Path: prefix, Err: syscall.ECONNRESET})
+				return
+			}
+			if !yield(&filesystem.FileEntry{Path: p}, nil) {
+				return
+			}
+		}
+	}
+}
+
+type brokenFile struct {
+	filesystem.File
+	remain int64
+}
+
+func (f *brokenFile) Read(p []byte) (int, error) {
+	if f.remain <= 0 {
+		return 0, syscall.ECONNRESET
+	}
+	if int64(len(p)) > f.remain {
+		p = p[:f.remain]
+	}
+	n, err := f.File.Read(p)
+	f.remain -= int64(n)
+	return n, err
+}
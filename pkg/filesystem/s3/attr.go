@@ -0,0 +1,102 @@
+package s3
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+var _ filesystem.AttrSetter = &fs{}
+
+// attributes stored in user metadata, since objects have no mode and LastModified could not be changed.
+// stored by copying object to itself with metadata replaced.
+// like links, only known when Stat, Readdir lists objects with LastModified.
+const (
+	modTimeMetadataKey = "unifs-mtime"
+	modeMetadataKey    = "unifs-mode"
+)
+
+func (fsys *fs) SetMode(ctx context.Context, name string, mode os.FileMode) error {
+	return fsys.setAttr(ctx, "chmod", name, modeMetadataKey, strconv.FormatUint(uint64(mode.Perm()), 8))
+}
+
+func (fsys *fs) SetModTime(ctx context.Context, name string, mtime time.Time) error {
+	return fsys.setAttr(ctx, "chtimes", name, modTimeMetadataKey, formatModTime(mtime))
+}
+
+func (fsys *fs) setAttr(ctx context.Context, op string, name string, key string, value string) error {
+	if name == "/" || name == "." {
+		return nil
+	}
+
+	info, err := fsys.s3Client.StatObject(ctx, fsys.bucket, fsys.path(name), minio.StatObjectOptions{})
+	if err != nil {
+		if isNotFound(err) {
+			// dirs are virtual, nothing could be stored
+			if _, err := fsys.statDirectory(ctx, name); err == nil {
+				return nil
+			}
+			return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+		}
+		return &os.PathError{Op: op, Path: name, Err: err}
+	}
+
+	meta := map[string]string{}
+	for k, v := range info.UserMetadata {
+		meta[strings.ToLower(k)] = v
+	}
+	meta[key] = value
+
+	dst := minio.CopyDestOptions{
+		Bucket:          fsys.bucket,
+		Object:          fsys.path(name),
+		UserMetadata:    meta,
+		ReplaceMetadata: true,
+		ContentType:     info.ContentType,
+		CacheControl:    info.Metadata.Get("Cache-Control"),
+	}
+
+	// not overwrite content changed after stat
+	src := minio.CopySrcOptions{
+		Bucket:    fsys.bucket,
+		Object:    fsys.path(name),
+		MatchETag: info.ETag,
+	}
+
+	if info.Size > maxCopyObjectSize {
+		_, err = fsys.s3Client.ComposeObject(ctx, dst, src)
+	} else {
+		_, err = fsys.s3Client.CopyObject(ctx, dst, src)
+	}
+	if err != nil {
+		return &os.PathError{Op: op, Path: name, Err: err}
+	}
+	return nil
+}
+
+func formatModTime(mtime time.Time) string {
+	return mtime.UTC().Format(time.RFC3339Nano)
+}
+
+// attrsOf returns mode and mtime stored in user metadata
+func attrsOf(info minio.ObjectInfo) (mode os.FileMode, mtime time.Time) {
+	for k, v := range info.UserMetadata {
+		switch strings.ToLower(k) {
+		case modeMetadataKey:
+			if m, err := strconv.ParseUint(v, 8, 32); err == nil {
+				mode = os.FileMode(m).Perm()
+			}
+		case modTimeMetadataKey:
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				mtime = t
+			}
+		}
+	}
+	return
+}
@@ -1 +1,21 @@
 package s3
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// IsTransientError checks err is 5xx or throttled by server
+func (fsys *fs) IsTransientError(err error) bool {
+	var errorResponse minio.ErrorResponse
+	if errors.As(err, &errorResponse) {
+		switch errorResponse.Code {
+		case "SlowDown", "RequestTimeout", "InternalError", "ServiceUnavailable":
+			return true
+		}
+		return errorResponse.StatusCode >= http.StatusInternalServerError || errorResponse.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
@@ -2,8 +2,12 @@ package s3
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"io"
+	iofs "io/fs"
+	"iter"
+	"maps"
 	"net/http"
 	"net/url"
 	"os"
@@ -61,12 +65,18 @@ func openFileForWrite(ctx context.Context, fs *fs, name string, flags int) (file
 		}
 	}
 
+	userMetadata, err := filesystem.UserMetadataFromContext(ctx).Normalize()
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+
 	f := &file{
-		name:      name,
-		flags:     flags,
-		ctx:       ctx,
-		fs:        fs,
-		writeable: true,
+		name:         name,
+		flags:        flags,
+		ctx:          ctx,
+		fs:           fs,
+		writeable:    true,
+		userMetadata: userMetadata,
 	}
 
 	// wrap as pre-signed
@@ -144,68 +154,83 @@ type file struct {
 
 	// write
 	writeable     bool
+	userMetadata  filesystem.UserMetadata
 	pw            *io.PipeWriter
 	errCh         chan error
 	writeInitOnce sync.Once
+	// stops aborting when ctx canceled
+	stop    func() bool
+	aborted bool
 
 	// read
 	object *minio.Object
+
+	// readdir
+	dirReader *fsutil.DirReader
 }
 
 func (f *file) Name() string { return f.name }
 
 func (f *file) Readdir(n int) ([]os.FileInfo, error) {
-	// ListObjects treats leading slashes as part of the directory name
-	// It also needs a trailing slash to list contents of a directory.
-	name := strings.TrimPrefix(f.fs.path(f.Name()), "/")
-
-	// For the root of the bucket, we need to remove any prefix
-	if name != "" && !strings.HasSuffix(name, "/") {
-		name += "/"
+	if f.dirReader == nil {
+		// ctx of open could be done before Readdir
+		f.dirReader = fsutil.NewDirReader(f.infos(context.WithoutCancel(f.ctx)))
 	}
+	return f.dirReader.Readdir(n)
+}
 
-	objCh := f.fs.s3Client.ListObjects(context.Background(), f.fs.bucket, minio.ListObjectsOptions{
-		Prefix: name,
-	})
-
-	var fileInfos []os.FileInfo
-
-	if n > 0 {
-		fileInfos = make([]os.FileInfo, 0, n)
-	}
+func (f *file) ReadDirIter(ctx context.Context) iter.Seq2[iofs.DirEntry, error] {
+	return fsutil.DirEntries(f.infos(ctx))
+}
 
-	idx := 0
+// infos lists objects by pages of ListObjectsV2, listing will be canceled when iteration stopped
+func (f *file) infos(ctx context.Context) iter.Seq2[os.FileInfo, error] {
+	return func(yield func(os.FileInfo, error) bool) {
+		// ListObjects treats leading slashes as part of the directory name
+		// It also needs a trailing slash to list contents of a directory.
+		name := strings.TrimPrefix(f.fs.path(f.Name()), "/")
 
-	for obj := range objCh {
-		if obj.Err != nil {
-			return nil, obj.Err
+		// For the root of the bucket, we need to remove any prefix
+		if name != "" && !strings.HasSuffix(name, "/") {
+			name += "/"
 		}
 
-		if strings.HasSuffix(obj.Key, dirHolder) {
-			continue
-		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
 
-		var fi filesystem.FileInfo
+		objCh := f.fs.s3Client.ListObjects(ctx, f.fs.bucket, minio.ListObjectsOptions{
+			Prefix: name,
+		})
 
-		if strings.HasSuffix(obj.Key, "/") {
-			fi = fsutil.NewDirFileInfo(path.Base("/" + obj.Key))
-		} else {
-			fi = fsutil.NewFileInfo(
-				path.Base("/"+obj.Key),
-				obj.Size,
-				obj.LastModified,
-			)
-		}
+		for obj := range objCh {
+			if obj.Err != nil {
+				yield(nil, obj.Err)
+				return
+			}
+
+			if strings.HasSuffix(obj.Key, dirHolder) {
+				continue
+			}
 
-		fileInfos = append(fileInfos, fi)
-		idx++
+			var fi filesystem.FileInfo
+
+			if strings.HasSuffix(obj.Key, "/") {
+				fi = fsutil.NewDirFileInfo(path.Base("/" + obj.Key))
+			} else {
+				fi = fsutil.NewFileInfo(
+					path.Base("/"+obj.Key),
+					obj.Size,
+					obj.LastModified,
+					fsutil.WithETag(obj.ETag),
+					fsutil.WithContentType(obj.ContentType),
+				)
+			}
 
-		if n > 0 && idx > n {
-			break
+			if !yield(fi, nil) {
+				return
+			}
 		}
 	}
-
-	return fileInfos, nil
 }
 
 func (f *file) Stat() (os.FileInfo, error) {
@@ -224,26 +249,44 @@ func (f *file) Read(p []byte) (int, error) {
 	return f.object.Read(p)
 }
 
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if f.object == nil {
+		return 0, os.ErrNotExist
+	}
+
+	return f.object.ReadAt(p, off)
+}
+
 func (f *file) Write(p []byte) (int, error) {
 	if !f.writeable {
 		return -1, os.ErrPermission
 	}
 
+	if f.aborted {
+		return 0, os.ErrClosed
+	}
+
+	if err := f.ctx.Err(); err != nil {
+		return 0, context.Cause(f.ctx)
+	}
+
+	// empty object of atomic write will be put when closed, streaming nothing is rejected
+	if len(p) == 0 && f.pw == nil && f.flags&filesystem.O_ATOMIC != 0 {
+		return 0, nil
+	}
+
 	f.writeInitOnce.Do(func() {
 		pr, pw := io.Pipe()
 
 		f.errCh = make(chan error, 1)
 		f.pw = pw
 
-		putObjectOptions := minio.PutObjectOptions{}
+		// body failed to abort multipart upload when canceled
+		f.stop = context.AfterFunc(f.ctx, func() {
+			_ = pw.CloseWithError(context.Cause(f.ctx))
+		})
 
-		metadata := filesystem.MetadataFromContext(f.ctx)
-		if v := metadata.Get("Content-Type"); v != "" {
-			putObjectOptions.ContentType = v
-		}
-		if v := metadata.Get("Cache-Control"); v != "" {
-			putObjectOptions.CacheControl = v
-		}
+		putObjectOptions := f.putObjectOptions()
 
 		go func() {
 			defer pr.Close()
@@ -255,13 +298,21 @@ func (f *file) Write(p []byte) (int, error) {
 
 			c := context.WithoutCancel(f.ctx)
 
-			if f.flags&os.O_CREATE != 0 {
+			// object of atomic write only visible when PUT completed
+			if f.flags&os.O_CREATE != 0 && f.flags&filesystem.O_ATOMIC == 0 {
 				// when create new file
 				// to put 0x00 as placeholder
 				_, err = f.fs.s3Client.PutObject(c, f.fs.bucket, f.fs.path(f.name), bytes.NewBuffer([]byte{0x00}), 1, putObjectOptions)
 				if err != nil {
 					return
 				}
+
+				defer func() {
+					// placeholder should not be left when failed or aborted
+					if err != nil {
+						_ = f.fs.s3Client.RemoveObject(c, f.fs.bucket, f.fs.path(f.name), minio.RemoveObjectOptions{})
+					}
+				}()
 			}
 
 			// https://github.com/minio/minio-go/issues?q=PartSize%20
@@ -275,8 +326,59 @@ func (f *file) Write(p []byte) (int, error) {
 	return f.pw.Write(p)
 }
 
+func (f *file) putObjectOptions() minio.PutObjectOptions {
+	putObjectOptions := minio.PutObjectOptions{}
+
+	metadata := filesystem.MetadataFromContext(f.ctx)
+	if v := metadata.Get("Content-Type"); v != "" {
+		putObjectOptions.ContentType = v
+	}
+	if v := metadata.Get("Cache-Control"); v != "" {
+		putObjectOptions.CacheControl = v
+	}
+	if len(f.userMetadata) > 0 {
+		putObjectOptions.UserMetadata = f.userMetadata
+	}
+
+	// mtime always stored,
+	// since some servers carry metadata over when object overwritten, stale mtime should not be left.
+	mtime, ok := filesystem.ModTimeFromContext(f.ctx)
+	if !ok {
+		mtime = time.Now()
+	}
+	putObjectOptions.UserMetadata = maps.Clone(putObjectOptions.UserMetadata)
+	if putObjectOptions.UserMetadata == nil {
+		putObjectOptions.UserMetadata = map[string]string{}
+	}
+	putObjectOptions.UserMetadata[modTimeMetadataKey] = formatModTime(mtime)
+
+	return putObjectOptions
+}
+
 func (f *file) Close() error {
+	if f.dirReader != nil {
+		f.dirReader.Close()
+	}
+
+	if f.aborted {
+		return nil
+	}
+
+	// written should not be committed when canceled
+	if f.writeable && f.ctx.Err() != nil {
+		err := context.Cause(f.ctx)
+		_ = f.Abort(err)
+		return err
+	}
+
+	// empty file of atomic write, since no placeholder put when opened
+	if f.writeable && f.pw == nil && f.flags&filesystem.O_ATOMIC != 0 && f.flags&(os.O_CREATE|os.O_TRUNC) != 0 {
+		_, err := f.fs.s3Client.PutObject(context.WithoutCancel(f.ctx), f.fs.bucket, f.fs.path(f.name), bytes.NewReader(nil), 0, f.putObjectOptions())
+		return err
+	}
+
 	if f.pw != nil {
+		f.stop()
 		if err := f.pw.Close(); err != nil {
 			return err
 		}
@@ -289,3 +391,24 @@ func (f *file) Close() error {
 
 	return nil
 }
+
+// Abort fails the body of PUT, then multipart upload will be aborted,
+// placeholder put when opened will be removed too.
+func (f *file) Abort(err error) error {
+	if f.aborted {
+		return nil
+	}
+	f.aborted = true
+
+	if f.pw != nil {
+		f.stop()
+		_ = f.pw.CloseWithError(cmp.Or(err, filesystem.ErrAborted))
+		<-f.errCh
+	}
+
+	if f.object != nil {
+		return f.object.Close()
+	}
+
+	return nil
+}
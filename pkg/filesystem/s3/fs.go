@@ -25,6 +25,10 @@ type fs struct {
 	prefix string
 }
 
+func (fsys *fs) Capabilities() filesystem.Capability {
+	return filesystem.CapServerSideCopy | filesystem.CapReaderAt | filesystem.CapAtomicWrite | filesystem.CapSymlink | filesystem.CapSetModTime | filesystem.CapSetMode
+}
+
 func (fsys *fs) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
 	if _, err := fsys.Stat(ctx, name); err == nil {
 		return &os.PathError{
@@ -117,24 +121,106 @@ func (fsys *fs) Rename(ctx context.Context, oldName, newName string) error {
 		return fsys.forceRemove(ctx, oldName, true)
 	}
 
-	_, err = fsys.s3Client.CopyObject(
-		ctx,
-		minio.CopyDestOptions{
-			Bucket: fsys.bucket,
-			Object: fsys.path(newName),
-		},
-		minio.CopySrcOptions{
-			Bucket: fsys.bucket,
-			Object: fsys.path(oldName),
-		},
-	)
-	if err != nil {
-		return fmt.Errorf("copy failed: %w", err)
+	if err := fsys.copyObject(ctx, fsys.path(oldName), fsys.path(newName), info.Size()); err != nil {
+		return err
 	}
 
 	return fsys.forceRemove(ctx, oldName, false)
 }
 
+func (fsys *fs) Copy(ctx context.Context, oldName, newName string, recursive bool) error {
+	if newName == oldName {
+		return nil
+	}
+
+	info, err := fsys.Stat(ctx, oldName)
+	if err != nil {
+		return err
+	}
+
+	//  /x could not cp to its child path like /x/a/b/x
+	if oldName == "/" || strings.HasPrefix(newName, oldName+"/") {
+		return &os.LinkError{
+			Op:  "copy",
+			Old: oldName,
+			New: newName,
+			Err: os.ErrPermission,
+		}
+	}
+
+	if parent := path.Dir(newName); parent != "/" {
+		if _, err := fsys.Stat(ctx, parent); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fsys.Stat(ctx, newName); err == nil {
+		if err := fsys.RemoveAll(ctx, newName); err != nil {
+			return err
+		}
+	}
+
+	if !info.IsDir() {
+		return fsys.copyObject(ctx, fsys.path(oldName), fsys.path(newName), info.Size())
+	}
+
+	if err := fsys.Mkdir(ctx, newName, os.ModePerm); err != nil {
+		return err
+	}
+
+	if !recursive {
+		return nil
+	}
+
+	srcPrefix := fsys.path(oldName) + "/"
+	dstPrefix := fsys.path(newName) + "/"
+
+	objCh := fsys.s3Client.ListObjects(ctx, fsys.bucket, minio.ListObjectsOptions{
+		Prefix:    srcPrefix,
+		Recursive: true,
+	})
+
+	for obj := range objCh {
+		if obj.Err != nil {
+			return obj.Err
+		}
+
+		if err := fsys.copyObject(ctx, obj.Key, dstPrefix+strings.TrimPrefix(obj.Key, srcPrefix), obj.Size); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// max size of object could be copied by single CopyObject
+const maxCopyObjectSize = 5 * 1024 * 1024 * 1024
+
+func (fsys *fs) copyObject(ctx context.Context, srcKey string, dstKey string, size int64) error {
+	dst := minio.CopyDestOptions{
+		Bucket: fsys.bucket,
+		Object: dstKey,
+	}
+
+	src := minio.CopySrcOptions{
+		Bucket: fsys.bucket,
+		Object: srcKey,
+	}
+
+	// ComposeObject will copy by multipart upload with UploadPartCopy
+	if size > maxCopyObjectSize {
+		if _, err := fsys.s3Client.ComposeObject(ctx, dst, src); err != nil {
+			return fmt.Errorf("compose failed: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := fsys.s3Client.CopyObject(ctx, dst, src); err != nil {
+		return fmt.Errorf("copy failed: %w", err)
+	}
+	return nil
+}
+
 func (fsys *fs) RemoveAll(ctx context.Context, name string) error {
 	if name == "/" {
 		return fmt.Errorf("rm '/' not allow: %w", os.ErrPermission)
@@ -215,11 +301,40 @@ func (fsys *fs) Stat(ctx context.Context, name string) (os.FileInfo, error) {
 		}
 	}
 
-	return fsutil.NewFileInfo(
-		path.Base(name),
-		info.Size,
-		info.LastModified,
-	), nil
+	opts := []fsutil.FileInfoOption{
+		fsutil.WithETag(info.ETag),
+		fsutil.WithContentType(info.ContentType),
+		fsutil.WithUserMetadata(userMetadataOf(info)),
+	}
+
+	if _, ok := linkTargetOf(info); ok {
+		opts = append(opts, fsutil.WithSymlink())
+	}
+
+	modTime := info.LastModified
+
+	mode, mtime := attrsOf(info)
+	if mode != 0 {
+		opts = append(opts, fsutil.WithMode(mode))
+	}
+	if !mtime.IsZero() {
+		modTime = mtime
+	}
+
+	return fsutil.NewFileInfo(path.Base(name), info.Size, modTime, opts...), nil
+}
+
+// userMetadataOf returns user metadata of object, without link target and attributes
+func userMetadataOf(info minio.ObjectInfo) filesystem.UserMetadata {
+	meta := filesystem.UserMetadata{}
+	for k, v := range info.UserMetadata {
+		switch k := strings.ToLower(k); k {
+		case linkTargetMetadataKey, modTimeMetadataKey, modeMetadataKey:
+		default:
+			meta[k] = v
+		}
+	}
+	return meta
 }
 
 func (fsys *fs) statDirectory(ctx context.Context, name string) (os.FileInfo, error) {
@@ -0,0 +1,86 @@
+package s3
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+var _ filesystem.Hasher = &fs{}
+
+// Hash returns md5 from ETag of object not uploaded by multipart and not encrypted by SSE-KMS or SSE-C,
+// and others from checksums of object uploaded with checksum.
+// errors.ErrUnsupported returned when not available.
+func (fsys *fs) Hash(ctx context.Context, name string, algo filesystem.HashAlgorithm) (string, error) {
+	info, err := fsys.s3Client.StatObject(ctx, fsys.bucket, fsys.path(name), minio.StatObjectOptions{
+		Checksum: algo != filesystem.HashMD5,
+	})
+	if err != nil {
+		return "", &os.PathError{Op: "hash", Path: name, Err: err}
+	}
+
+	if algo == filesystem.HashMD5 {
+		if etag, ok := md5OfETag(info); ok {
+			return etag, nil
+		}
+		return "", errors.ErrUnsupported
+	}
+
+	// composite checksums of multipart object are not the hash of content
+	if info.ChecksumMode == minio.ChecksumCompositeMode.String() {
+		return "", errors.ErrUnsupported
+	}
+
+	checksum := ""
+
+	switch algo {
+	case filesystem.HashSHA1:
+		checksum = info.ChecksumSHA1
+	case filesystem.HashSHA256:
+		checksum = info.ChecksumSHA256
+	case filesystem.HashCRC32:
+		checksum = info.ChecksumCRC32
+	case filesystem.HashCRC32C:
+		checksum = info.ChecksumCRC32C
+	}
+
+	if checksum == "" || strings.Contains(checksum, "-") {
+		return "", errors.ErrUnsupported
+	}
+
+	sum, err := base64.StdEncoding.DecodeString(checksum)
+	if err != nil {
+		return "", errors.ErrUnsupported
+	}
+
+	return hex.EncodeToString(sum), nil
+}
+
+// md5OfETag returns ETag as md5 of content, only when object not uploaded by multipart,
+// and not encrypted by SSE-KMS or SSE-C, ETag of which is not md5 of content.
+func md5OfETag(info minio.ObjectInfo) (string, bool) {
+	if sse := info.Metadata.Get(encrypt.SseGenericHeader); strings.HasPrefix(sse, "aws:kms") {
+		return "", false
+	}
+	if info.Metadata.Get(encrypt.SseCustomerAlgorithm) != "" {
+		return "", false
+	}
+
+	// ETag of multipart object is like <md5 of md5s>-<parts>
+	etag := strings.Trim(info.ETag, `"`)
+	if len(etag) != 32 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(etag); err != nil {
+		return "", false
+	}
+	return strings.ToLower(etag), true
+}
@@ -0,0 +1,78 @@
+package s3
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+var _ filesystem.Linker = &fs{}
+
+// links emulated as marker objects, target stored as content and escaped in user metadata,
+// so links could be known by StatObject without reading content.
+// links are listed as files by Readdir, only known as links when Stat or Lstat.
+const linkTargetMetadataKey = "unifs-link-target"
+
+func (fsys *fs) Symlink(ctx context.Context, target string, name string) error {
+	if _, err := fsys.Stat(ctx, name); err == nil {
+		return &os.LinkError{Op: "symlink", Old: target, New: name, Err: os.ErrExist}
+	}
+
+	opts := minio.PutObjectOptions{
+		ContentType: "text/plain",
+		UserMetadata: map[string]string{
+			linkTargetMetadataKey: url.PathEscape(target),
+		},
+	}
+	// not overwrite file created at the same time
+	opts.SetMatchETagExcept("*")
+
+	_, err := fsys.s3Client.PutObject(ctx, fsys.bucket, fsys.path(name), strings.NewReader(target), int64(len(target)), opts)
+	if err != nil {
+		if isPreconditionFailed(err) {
+			return &os.LinkError{Op: "symlink", Old: target, New: name, Err: os.ErrExist}
+		}
+		return &os.LinkError{Op: "symlink", Old: target, New: name, Err: err}
+	}
+	return nil
+}
+
+func (fsys *fs) Readlink(ctx context.Context, name string) (string, error) {
+	info, err := fsys.s3Client.StatObject(ctx, fsys.bucket, fsys.path(name), minio.StatObjectOptions{})
+	if err != nil {
+		if isNotFound(err) {
+			return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrNotExist}
+		}
+		return "", &os.PathError{Op: "readlink", Path: name, Err: err}
+	}
+
+	target, ok := linkTargetOf(info)
+	if !ok {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: syscall.EINVAL}
+	}
+	return target, nil
+}
+
+// Lstat same as Stat, links are never followed
+func (fsys *fs) Lstat(ctx context.Context, name string) (os.FileInfo, error) {
+	return fsys.Stat(ctx, name)
+}
+
+func linkTargetOf(info minio.ObjectInfo) (string, bool) {
+	for k, v := range info.UserMetadata {
+		if strings.ToLower(k) == linkTargetMetadataKey {
+			target, err := url.PathUnescape(v)
+			if err != nil {
+				return "", false
+			}
+			return target, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,68 @@
+package s3
+
+import (
+	"context"
+	"iter"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/fsutil"
+)
+
+var _ filesystem.PrefixLister = &fs{}
+
+// ListPrefix lists all objects with prefix by single recursive ListObjects,
+// listing will be canceled when iteration stopped
+func (fsys *fs) ListPrefix(ctx context.Context, prefix string) iter.Seq2[*filesystem.FileEntry, error] {
+	return func(yield func(*filesystem.FileEntry, error) bool) {
+		base := fsys.path("/")
+		if base != "" {
+			base += "/"
+		}
+
+		keyPrefix := fsys.path(prefix)
+		// path.Join drops trailing slash
+		if strings.HasSuffix(prefix, "/") && keyPrefix != "" && !strings.HasSuffix(keyPrefix, "/") {
+			keyPrefix += "/"
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		objCh := fsys.s3Client.ListObjects(ctx, fsys.bucket, minio.ListObjectsOptions{
+			Prefix:    keyPrefix,
+			Recursive: true,
+		})
+
+		for obj := range objCh {
+			if obj.Err != nil {
+				yield(nil, obj.Err)
+				return
+			}
+
+			if strings.HasSuffix(obj.Key, "/") || path.Base(obj.Key) == dirHolder {
+				continue
+			}
+
+			name := "/" + strings.TrimPrefix(obj.Key, base)
+
+			e := &filesystem.FileEntry{
+				Path: name,
+				FileInfo: fsutil.NewFileInfo(
+					path.Base(name),
+					obj.Size,
+					obj.LastModified,
+					fsutil.WithETag(obj.ETag),
+					fsutil.WithContentType(obj.ContentType),
+				),
+			}
+
+			if !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
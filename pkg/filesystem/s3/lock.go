@@ -0,0 +1,183 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+var _ filesystem.Locker = &fs{}
+
+// locks of name stored in one object <prefix>/.unifs-locks/<name>/.lock,
+// updated by conditional put with If-Match or If-None-Match: *,
+// so concurrent updates from different clients will not be lost.
+const lockPrefix = "/.unifs-locks"
+
+type lockState struct {
+	Locks []*filesystem.LockInfo `json:"locks"`
+}
+
+func (fsys *fs) Lock(ctx context.Context, name string, opts filesystem.LockOptions) (*filesystem.LockInfo, error) {
+	var info *filesystem.LockInfo
+
+	err := fsys.updateLocks(ctx, "lock", name, func(s *lockState) error {
+		for _, l := range s.Locks {
+			if l.ConflictsWith(opts.Shared) {
+				return filesystem.ErrLocked
+			}
+		}
+		info = filesystem.NewLockInfo(name, opts)
+		s.Locks = append(s.Locks, info)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (fsys *fs) RefreshLock(ctx context.Context, name string, token string, ttl time.Duration) (*filesystem.LockInfo, error) {
+	var info *filesystem.LockInfo
+
+	err := fsys.updateLocks(ctx, "refresh", name, func(s *lockState) error {
+		i := slices.IndexFunc(s.Locks, func(l *filesystem.LockInfo) bool { return l.Token == token })
+		if i < 0 {
+			return filesystem.ErrLockNotFound
+		}
+		info = s.Locks[i]
+		info.ExpiresAt = time.Now().Add(filesystem.LockOptions{TTL: ttl}.LockTTL())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (fsys *fs) Unlock(ctx context.Context, name string, token string) error {
+	return fsys.updateLocks(ctx, "unlock", name, func(s *lockState) error {
+		i := slices.IndexFunc(s.Locks, func(l *filesystem.LockInfo) bool { return l.Token == token })
+		if i < 0 {
+			return filesystem.ErrLockNotFound
+		}
+		s.Locks = slices.Delete(s.Locks, i, i+1)
+		return nil
+	})
+}
+
+func (fsys *fs) Locks(ctx context.Context, name string) ([]*filesystem.LockInfo, error) {
+	s, _, err := fsys.loadLocks(ctx, name)
+	if err != nil {
+		return nil, &os.PathError{Op: "locks", Path: name, Err: err}
+	}
+	return s.Locks, nil
+}
+
+func (fsys *fs) lockKey(name string) string {
+	return fsys.path(path.Join(lockPrefix, name, ".lock"))
+}
+
+// updateLocks applies fn to locks not expired, and retries when updated by others at the same time.
+// state object will be kept even no locks held, to avoid conflicts between delete and create.
+func (fsys *fs) updateLocks(ctx context.Context, op string, name string, fn func(s *lockState) error) error {
+	for {
+		s, etag, err := fsys.loadLocks(ctx, name)
+		if err != nil {
+			return &os.PathError{Op: op, Path: name, Err: err}
+		}
+
+		if err := fn(s); err != nil {
+			return &os.PathError{Op: op, Path: name, Err: err}
+		}
+
+		data, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+
+		opts := minio.PutObjectOptions{ContentType: "application/json"}
+		if etag == "" {
+			opts.SetMatchETagExcept("*")
+		} else {
+			opts.SetMatchETag(etag)
+		}
+
+		_, err = fsys.s3Client.PutObject(ctx, fsys.bucket, fsys.lockKey(name), bytes.NewReader(data), int64(len(data)), opts)
+		if err != nil {
+			if isPreconditionFailed(err) {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				continue
+			}
+			return &os.PathError{Op: op, Path: name, Err: err}
+		}
+
+		return nil
+	}
+}
+
+// loadLocks returns locks not expired with etag of state object, etag will be empty when not exists
+func (fsys *fs) loadLocks(ctx context.Context, name string) (*lockState, string, error) {
+	s := &lockState{}
+
+	obj, err := fsys.s3Client.GetObject(ctx, fsys.bucket, fsys.lockKey(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+	defer obj.Close()
+
+	info, err := obj.Stat()
+	if err != nil {
+		if isNotFound(err) {
+			return s, "", nil
+		}
+		return nil, "", err
+	}
+
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, s); err != nil {
+			return nil, "", err
+		}
+	}
+
+	now := time.Now()
+	s.Locks = slices.DeleteFunc(s.Locks, func(l *filesystem.LockInfo) bool {
+		return l.Expired(now)
+	})
+
+	return s, info.ETag, nil
+}
+
+func isNotFound(err error) bool {
+	var errorResponse minio.ErrorResponse
+	if errors.As(err, &errorResponse) {
+		return errorResponse.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+func isPreconditionFailed(err error) bool {
+	var errorResponse minio.ErrorResponse
+	if errors.As(err, &errorResponse) {
+		// ConditionalRequestConflict returned by aws s3 when updated by others at the same time
+		return errorResponse.StatusCode == http.StatusPreconditionFailed || errorResponse.StatusCode == http.StatusConflict
+	}
+	return false
+}
@@ -0,0 +1,359 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"iter"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultStatTTL         = 5 * time.Second
+	DefaultNegativeStatTTL = 1 * time.Second
+)
+
+type StatCacheOption func(c *statCacheFS)
+
+// WithStatTTL sets how long Stat results kept
+func WithStatTTL(ttl time.Duration) StatCacheOption {
+	return func(c *statCacheFS) {
+		c.ttl = ttl
+	}
+}
+
+// WithNegativeStatTTL sets how long not exists results kept, zero to disable
+func WithNegativeStatTTL(ttl time.Duration) StatCacheOption {
+	return func(c *statCacheFS) {
+		c.negativeTTL = ttl
+	}
+}
+
+// StatCache caches Stat and Lstat results of fsys, which also filled by Readdir of directories opened through it,
+// entries of dir fill Lstat results, and Stat results too except links, since Stat could follow links.
+//
+// Entries will be invalidated by Mkdir, Rename, RemoveAll and OpenFile for write made through the returned FileSystem,
+// changes made by others will be seen after ttl.
+func StatCache(fsys FileSystem, opts ...StatCacheOption) FileSystem {
+	c := &statCacheFS{
+		Forwarder:   Forwarder{FileSystem: fsys},
+		ttl:         DefaultStatTTL,
+		negativeTTL: DefaultNegativeStatTTL,
+		entries:     map[statKey]*statEntry{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type statKey struct {
+	name string
+	// result of Lstat
+	lstat bool
+}
+
+type statEntry struct {
+	// nil means not exists
+	info    FileInfo
+	expires time.Time
+}
+
+type statCacheFS struct {
+	Forwarder
+
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	mu        sync.RWMutex
+	entries   map[statKey]*statEntry
+	lastPrune time.Time
+}
+
+func (c *statCacheFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	defer c.invalidate(name, false)
+
+	return c.FileSystem.Mkdir(ctx, name, perm)
+}
+
+func (c *statCacheFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		c.invalidate(name, false)
+
+		f, err := c.FileSystem.OpenFile(ctx, name, flag, perm)
+		if err != nil {
+			return nil, err
+		}
+
+		return WithOptional(&statCacheWriteFile{File: f, name: name, c: c}, OptionalOf(f)), nil
+	}
+
+	f, err := c.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := c.Stat(ctx, name); err == nil && info.IsDir() {
+		d := &statCacheDir{File: f, name: name, c: c}
+
+		o := OptionalOf(f)
+		if o.DirIterator != nil {
+			o.DirIterator = statCacheDirIterator{d: d, it: o.DirIterator}
+		}
+		return WithOptional(d, o), nil
+	}
+
+	return f, nil
+}
+
+func (c *statCacheFS) RemoveAll(ctx context.Context, name string) error {
+	defer c.invalidate(name, true)
+
+	return c.FileSystem.RemoveAll(ctx, name)
+}
+
+func (c *statCacheFS) Rename(ctx context.Context, oldName, newName string) error {
+	defer c.invalidate(newName, true)
+	defer c.invalidate(oldName, true)
+
+	return c.FileSystem.Rename(ctx, oldName, newName)
+}
+
+func (c *statCacheFS) Copy(ctx context.Context, oldName, newName string, recursive bool) error {
+	defer c.invalidate(newName, true)
+
+	return Copy(ctx, c.FileSystem, oldName, c.FileSystem, newName, recursive)
+}
+
+func (c *statCacheFS) Lock(ctx context.Context, name string, opts LockOptions) (*LockInfo, error) {
+	// empty file may be created by LOCK of webdav
+	defer c.invalidate(name, false)
+
+	return c.Forwarder.Lock(ctx, name, opts)
+}
+
+func (c *statCacheFS) Symlink(ctx context.Context, target string, name string) error {
+	defer c.invalidate(name, false)
+
+	return c.Forwarder.Symlink(ctx, target, name)
+}
+
+func (c *statCacheFS) SetMode(ctx context.Context, name string, mode os.FileMode) error {
+	defer c.invalidate(name, false)
+
+	return c.Forwarder.SetMode(ctx, name, mode)
+}
+
+func (c *statCacheFS) SetModTime(ctx context.Context, name string, mtime time.Time) error {
+	defer c.invalidate(name, false)
+
+	return c.Forwarder.SetModTime(ctx, name, mtime)
+}
+
+func (c *statCacheFS) Chown(ctx context.Context, name string, uid, gid int) error {
+	defer c.invalidate(name, false)
+
+	return c.Forwarder.Chown(ctx, name, uid, gid)
+}
+
+func (c *statCacheFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	name = slashClean(name)
+
+	if e, ok := c.lookup(statKey{name: name}); ok {
+		if e.info == nil {
+			return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+		}
+		return e.info, nil
+	}
+
+	info, err := c.FileSystem.Stat(ctx, name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			c.store(statKey{name: name}, nil, c.negativeTTL)
+		}
+		return nil, err
+	}
+
+	c.store(statKey{name: name}, info, c.ttl)
+
+	return info, nil
+}
+
+func (c *statCacheFS) Lstat(ctx context.Context, name string) (os.FileInfo, error) {
+	name = slashClean(name)
+
+	if e, ok := c.lookup(statKey{name: name, lstat: true}); ok {
+		if e.info == nil {
+			return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+		}
+		return e.info, nil
+	}
+
+	info, err := c.Forwarder.Lstat(ctx, name)
+	if err != nil {
+		// ErrUnsupported not cached, Stat will be used by callers
+		if errors.Is(err, os.ErrNotExist) {
+			c.store(statKey{name: name, lstat: true}, nil, c.negativeTTL)
+		}
+		return nil, err
+	}
+
+	c.storeLstat(name, info)
+
+	return info, nil
+}
+
+// storeLstat stores info as Stat result too, when name is not a link
+func (c *statCacheFS) storeLstat(name string, info FileInfo) {
+	c.store(statKey{name: name, lstat: true}, info, c.ttl)
+	if !IsSymlink(info) {
+		c.store(statKey{name: name}, info, c.ttl)
+	}
+}
+
+func (c *statCacheFS) lookup(k statKey) (*statEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, ok := c.entries[k]
+	if !ok || time.Now().After(e.expires) {
+		return nil, false
+	}
+	return e, true
+}
+
+func (c *statCacheFS) store(k statKey, info FileInfo, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[k] = &statEntry{info: info, expires: now.Add(ttl)}
+
+	// drop expired entries from time to time, to avoid memory leak
+	if now.Sub(c.lastPrune) > c.ttl {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastPrune = now
+	}
+}
+
+// invalidate name and its parent, and children too when recursive
+func (c *statCacheFS) invalidate(name string, recursive bool) {
+	name = slashClean(name)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, n := range []string{name, path.Dir(name)} {
+		delete(c.entries, statKey{name: n})
+		delete(c.entries, statKey{name: n, lstat: true})
+	}
+
+	if recursive {
+		prefix := name + "/"
+		if name == "/" {
+			prefix = name
+		}
+
+		for k := range c.entries {
+			if strings.HasPrefix(k.name, prefix) {
+				delete(c.entries, k)
+			}
+		}
+	}
+}
+
+type statCacheWriteFile struct {
+	File
+	name string
+	c    *statCacheFS
+}
+
+func (f *statCacheWriteFile) Close() error {
+	// size and mtime changed after written
+	defer f.c.invalidate(f.name, false)
+
+	return f.File.Close()
+}
+
+func (f *statCacheWriteFile) Sync() error {
+	if s, ok := f.File.(FileSyncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+func (f *statCacheWriteFile) Truncate(size int64) error {
+	if t, ok := f.File.(FileTruncator); ok {
+		return t.Truncate(size)
+	}
+	return &os.PathError{Op: "truncate", Path: f.name, Err: errors.ErrUnsupported}
+}
+
+func (f *statCacheWriteFile) Abort(err error) error {
+	a, ok := f.File.(FileAborter)
+	if !ok {
+		return &os.PathError{Op: "abort", Path: f.name, Err: errors.ErrUnsupported}
+	}
+
+	// created file may be removed
+	defer f.c.invalidate(f.name, false)
+
+	return a.Abort(err)
+}
+
+type statCacheDir struct {
+	File
+	name string
+	c    *statCacheFS
+}
+
+func (d *statCacheDir) Readdir(count int) ([]os.FileInfo, error) {
+	infos, err := d.File.Readdir(count)
+
+	for _, info := range infos {
+		d.store(info)
+	}
+
+	return infos, err
+}
+
+// store fills cache by entry of dir, which is result of Lstat,
+// since entries of dir are links themselves.
+func (d *statCacheDir) store(info os.FileInfo) {
+	d.c.storeLstat(path.Join(slashClean(d.name), info.Name()), info)
+}
+
+// statCacheDirIterator fills cache by entries iterated
+type statCacheDirIterator struct {
+	d  *statCacheDir
+	it DirIterator
+}
+
+func (i statCacheDirIterator) ReadDirIter(ctx context.Context) iter.Seq2[fs.DirEntry, error] {
+	return func(yield func(fs.DirEntry, error) bool) {
+		for e, err := range i.it.ReadDirIter(ctx) {
+			if err == nil {
+				if info, err := e.Info(); err == nil {
+					i.d.store(info)
+				}
+			}
+
+			if !yield(e, err) {
+				return
+			}
+		}
+	}
+}
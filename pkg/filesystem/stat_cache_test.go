@@ -0,0 +1,184 @@
+package filesystem_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/local"
+	"github.com/octohelm/unifs/pkg/filesystem/testutil"
+)
+
+func TestStatCache(t *testing.T) {
+	t.Run("Simple", func(t *testing.T) {
+		testutil.TestSimpleFS(t, filesystem.StatCache(filesystem.NewMemFS()))
+	})
+
+	t.Run("Full", func(t *testing.T) {
+		testutil.TestFullFS(t, filesystem.StatCache(filesystem.NewMemFS()))
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		ctx := context.Background()
+
+		source := &statCountingFS{Forwarder: filesystem.Forwarder{FileSystem: filesystem.NewMemFS()}}
+		fsys := filesystem.StatCache(source, filesystem.WithStatTTL(time.Hour), filesystem.WithNegativeStatTTL(time.Hour))
+
+		err := filesystem.MkdirAll(ctx, fsys, "/a")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		err = filesystem.Write(ctx, fsys, "/a/1.txt", []byte("1"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		t.Run("filled by readdir", func(t *testing.T) {
+			_, err := filesystem.ReadDir(ctx, fsys, "/a")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			n := source.count.Load()
+
+			info, err := fsys.Stat(ctx, "/a/1.txt")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, info.Size(), testingx.Be(int64(1)))
+			testingx.Expect(t, source.count.Load(), testingx.Be(n))
+		})
+
+		t.Run("negative lookup cached", func(t *testing.T) {
+			_, err := fsys.Stat(ctx, "/a/2.txt")
+			testingx.Expect(t, os.IsNotExist(err), testingx.Be(true))
+
+			n := source.count.Load()
+
+			_, err = fsys.Stat(ctx, "/a/2.txt")
+			testingx.Expect(t, os.IsNotExist(err), testingx.Be(true))
+			testingx.Expect(t, source.count.Load(), testingx.Be(n))
+		})
+
+		t.Run("invalidated by write", func(t *testing.T) {
+			err := filesystem.Write(ctx, fsys, "/a/2.txt", []byte("22"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			err = filesystem.Write(ctx, fsys, "/a/1.txt", []byte("111"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			info, err := fsys.Stat(ctx, "/a/2.txt")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, info.Size(), testingx.Be(int64(2)))
+
+			info, err = fsys.Stat(ctx, "/a/1.txt")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, info.Size(), testingx.Be(int64(3)))
+		})
+
+		t.Run("invalidated by remove", func(t *testing.T) {
+			err := fsys.RemoveAll(ctx, "/a")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			_, err = fsys.Stat(ctx, "/a/1.txt")
+			testingx.Expect(t, os.IsNotExist(err), testingx.Be(true))
+		})
+	})
+
+	t.Run("filled by dir iterator", func(t *testing.T) {
+		ctx := context.Background()
+
+		source := &statCountingFS{Forwarder: filesystem.Forwarder{FileSystem: testutil.DirIterFS(filesystem.NewMemFS())}}
+		fsys := filesystem.StatCache(source, filesystem.WithStatTTL(time.Hour))
+
+		err := filesystem.Write(ctx, fsys, "/1.txt", []byte("1"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		d, err := filesystem.Open(ctx, fsys, "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		_, ok := d.(filesystem.DirIterator)
+		testingx.Expect(t, ok, testingx.Be(true))
+		_ = d.Close()
+
+		for _, err := range filesystem.ReadDirIter(ctx, fsys, "/") {
+			testingx.Expect(t, err, testingx.Be[error](nil))
+		}
+
+		n := source.count.Load()
+
+		info, err := fsys.Stat(ctx, "/1.txt")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, info.Size(), testingx.Be(int64(1)))
+		testingx.Expect(t, source.count.Load(), testingx.Be(n))
+	})
+
+	t.Run("links filled by readdir as lstat", func(t *testing.T) {
+		ctx := context.Background()
+
+		source := &statCountingFS{Forwarder: filesystem.Forwarder{FileSystem: local.NewFS(t.TempDir())}}
+		fsys := filesystem.StatCache(source, filesystem.WithStatTTL(time.Hour))
+
+		err := filesystem.Write(ctx, fsys, "/1.txt", []byte("111"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		err = filesystem.Symlink(ctx, fsys, "1.txt", "/link")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = filesystem.ReadDir(ctx, fsys, "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		n := source.count.Load()
+
+		info, err := filesystem.Lstat(ctx, fsys, "/link")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, filesystem.IsSymlink(info), testingx.Be(true))
+
+		info, err = filesystem.Lstat(ctx, fsys, "/1.txt")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, info.Size(), testingx.Be(int64(3)))
+		testingx.Expect(t, source.count.Load(), testingx.Be(n))
+
+		t.Run("links followed by stat", func(t *testing.T) {
+			info, err := fsys.Stat(ctx, "/link")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, filesystem.IsSymlink(info), testingx.Be(false))
+			testingx.Expect(t, info.Size(), testingx.Be(int64(3)))
+		})
+	})
+
+	t.Run("WriterAt forwarded", func(t *testing.T) {
+		ctx := context.Background()
+
+		fsys := filesystem.StatCache(local.NewFS(t.TempDir()))
+
+		err := filesystem.Write(ctx, fsys, "/1.txt", []byte("111"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		f, err := fsys.OpenFile(ctx, "/1.txt", os.O_RDWR, os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = f.(io.WriterAt).WriteAt([]byte("2"), 1)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		_ = f.Close()
+
+		f, err = filesystem.Open(ctx, fsys, "/1.txt")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, string(data), testingx.Be("121"))
+	})
+}
+
+// statCountingFS counts Stat and Lstat calls
+type statCountingFS struct {
+	filesystem.Forwarder
+	count atomic.Int64
+}
+
+func (fs *statCountingFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	fs.count.Add(1)
+	return fs.FileSystem.Stat(ctx, name)
+}
+
+func (fs *statCountingFS) Lstat(ctx context.Context, name string) (os.FileInfo, error) {
+	fs.count.Add(1)
+	return fs.Forwarder.Lstat(ctx, name)
+}
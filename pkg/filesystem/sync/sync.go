@@ -0,0 +1,456 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+type Compare string
+
+const (
+	// CompareSize treats files with same size as same
+	CompareSize Compare = "size"
+	// CompareModTime treats files with same size and target not older than source as same
+	CompareModTime Compare = "mtime"
+	// CompareChecksum treats files with same size and same ETag or content hash as same
+	CompareChecksum Compare = "checksum"
+)
+
+var compares = []Compare{CompareSize, CompareModTime, CompareChecksum}
+
+func ParseCompare(s string) (Compare, error) {
+	for _, c := range compares {
+		if string(c) == s {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported compare %q, should be one of %v", s, compares)
+}
+
+type Action string
+
+const (
+	ActionMkdir  Action = "mkdir"
+	ActionCopy   Action = "copy"
+	ActionDelete Action = "delete"
+)
+
+// Change describes an action applied (or to apply when dry-run) on target
+type Change struct {
+	Action Action
+	Path   string
+	Size   int64
+}
+
+type Option func(s *syncer)
+
+func WithCompare(compare Compare) Option {
+	return func(s *syncer) {
+		s.compare = compare
+	}
+}
+
+// WithDryRun only reports changes without applying
+func WithDryRun(dryRun bool) Option {
+	return func(s *syncer) {
+		s.dryRun = dryRun
+	}
+}
+
+// WithDelete deletes extraneous files from target.
+// Files not included by WithInclude or excluded by WithExclude in target will never be deleted.
+func WithDelete(deleteExtraneous bool) Option {
+	return func(s *syncer) {
+		s.deleteExtraneous = deleteExtraneous
+	}
+}
+
+// WithInclude only syncs files matched one of patterns,
+// directories will be created only when included files under them copied.
+// Pattern follows path.Match, and pattern without '/' matches base name too.
+func WithInclude(patterns ...string) Option {
+	return func(s *syncer) {
+		s.include = append(s.include, patterns...)
+	}
+}
+
+// WithExclude skips files or directories matched one of patterns.
+// Excluded files in target will never be deleted.
+func WithExclude(patterns ...string) Option {
+	return func(s *syncer) {
+		s.exclude = append(s.exclude, patterns...)
+	}
+}
+
+// WithConcurrency limits the count of files compared or copied at the same time
+func WithConcurrency(n int) Option {
+	return func(s *syncer) {
+		s.concurrency = n
+	}
+}
+
+// WithReporter receives each change
+func WithReporter(report func(c Change)) Option {
+	return func(s *syncer) {
+		s.report = report
+	}
+}
+
+type Syncer interface {
+	Sync(ctx context.Context) error
+}
+
+// New creates a Syncer to make target tree match source
+func New(source filesystem.FileSystem, target filesystem.FileSystem, opts ...Option) Syncer {
+	s := &syncer{
+		source:      source,
+		target:      target,
+		compare:     CompareModTime,
+		concurrency: 4,
+	}
+	s.Build(opts...)
+	return s
+}
+
+// files with mtime diff in this window are treated as same,
+// because some backends only keep mtime in seconds or less precision.
+const modTimeWindow = 2 * time.Second
+
+type syncer struct {
+	source filesystem.FileSystem
+	target filesystem.FileSystem
+
+	compare          Compare
+	dryRun           bool
+	deleteExtraneous bool
+	include          []string
+	exclude          []string
+	concurrency      int
+	report           func(c Change)
+
+	mu sync.Mutex
+}
+
+func (s *syncer) Build(opts ...Option) {
+	for _, opt := range opts {
+		opt(s)
+	}
+}
+
+type targetDir struct {
+	name    string
+	entries map[string]filesystem.FileInfo
+	seen    map[string]bool
+
+	// not created yet, and replace the file of the same name when created
+	pending bool
+	replace bool
+}
+
+func (s *syncer) Sync(ctx context.Context) error {
+	if _, err := ParseCompare(string(s.compare)); err != nil {
+		return err
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+	if s.concurrency > 0 {
+		eg.SetLimit(s.concurrency)
+	}
+
+	dirs := map[string]*targetDir{}
+
+	err := filesystem.WalkDir(ctx, s.source, "/", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		srcInfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if name == "/" {
+			dir, err := s.listTarget(ctx, name)
+			if err != nil {
+				return err
+			}
+			dirs[name] = dir
+			return nil
+		}
+
+		if s.isExcluded(name, d.IsDir()) {
+			if d.IsDir() {
+				return filesystem.SkipDir
+			}
+			return nil
+		}
+
+		parent := dirs[path.Dir(name)]
+		parent.seen[d.Name()] = true
+		dstInfo := parent.entries[d.Name()]
+
+		if d.IsDir() {
+			if dstInfo == nil || !dstInfo.IsDir() {
+				dir := &targetDir{name: name, seen: map[string]bool{}, pending: true, replace: dstInfo != nil}
+				dirs[name] = dir
+
+				// created once included files under it copied
+				if len(s.include) > 0 {
+					return nil
+				}
+				return s.ensureDir(ctx, dirs, name)
+			}
+
+			dir, err := s.listTarget(ctx, name)
+			if err != nil {
+				return err
+			}
+			dirs[name] = dir
+			return nil
+		}
+
+		if !s.isIncluded(name) {
+			return nil
+		}
+
+		if err := s.ensureDir(ctx, dirs, path.Dir(name)); err != nil {
+			return err
+		}
+
+		eg.Go(func() error {
+			if dstInfo != nil && !dstInfo.IsDir() {
+				same, err := s.isSame(ctx, name, srcInfo, dstInfo)
+				if err != nil {
+					return err
+				}
+				if same {
+					return nil
+				}
+			}
+			return s.copy(ctx, name, srcInfo)
+		})
+
+		return nil
+	})
+	if err != nil {
+		// walking stops with ctx canceled when copying failed, which should be returned
+		if copyErr := eg.Wait(); copyErr != nil {
+			return copyErr
+		}
+		return err
+	}
+
+	if s.deleteExtraneous {
+		for _, dir := range dirs {
+			for n, info := range dir.entries {
+				if dir.seen[n] {
+					continue
+				}
+
+				name := path.Join(dir.name, n)
+
+				if s.isProtected(name, info.IsDir()) {
+					continue
+				}
+
+				eg.Go(func() error {
+					return s.remove(ctx, name, info)
+				})
+			}
+		}
+	}
+
+	return eg.Wait()
+}
+
+func (s *syncer) listTarget(ctx context.Context, name string) (*targetDir, error) {
+	dir := &targetDir{
+		name:    name,
+		entries: map[string]filesystem.FileInfo{},
+		seen:    map[string]bool{},
+	}
+
+	f, err := s.target.OpenFile(ctx, name, os.O_RDONLY, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return dir, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	infos, err := f.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range infos {
+		dir.entries[info.Name()] = info
+	}
+
+	return dir, nil
+}
+
+func (s *syncer) isSame(ctx context.Context, name string, srcInfo filesystem.FileInfo, dstInfo filesystem.FileInfo) (bool, error) {
+	if srcInfo.Size() != dstInfo.Size() {
+		return false, nil
+	}
+
+	switch s.compare {
+	case CompareSize:
+		return true, nil
+	case CompareChecksum:
+		if srcETag, dstETag := filesystem.ETagOf(ctx, srcInfo), filesystem.ETagOf(ctx, dstInfo); srcETag != "" && srcETag == dstETag {
+			return true, nil
+		}
+
+		srcSum, err := filesystem.Hash(ctx, s.source, name, filesystem.HashSHA256)
+		if err != nil {
+			return false, err
+		}
+		dstSum, err := filesystem.Hash(ctx, s.target, name, filesystem.HashSHA256)
+		if err != nil {
+			return false, err
+		}
+		return srcSum == dstSum, nil
+	default:
+		return srcInfo.ModTime().Sub(dstInfo.ModTime()) <= modTimeWindow, nil
+	}
+}
+
+// ensureDir creates pending dir of name and its pending parents
+func (s *syncer) ensureDir(ctx context.Context, dirs map[string]*targetDir, name string) error {
+	dir := dirs[name]
+	if dir == nil || !dir.pending {
+		return nil
+	}
+
+	if err := s.ensureDir(ctx, dirs, path.Dir(name)); err != nil {
+		return err
+	}
+
+	if err := s.mkdir(ctx, name, dir.replace); err != nil {
+		return err
+	}
+	dir.pending = false
+	return nil
+}
+
+func (s *syncer) mkdir(ctx context.Context, name string, replace bool) error {
+	s.emit(Change{Action: ActionMkdir, Path: name})
+
+	if s.dryRun {
+		return nil
+	}
+
+	if replace {
+		if err := s.target.RemoveAll(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	return s.target.Mkdir(ctx, name, os.ModePerm)
+}
+
+func (s *syncer) copy(ctx context.Context, name string, srcInfo filesystem.FileInfo) error {
+	s.emit(Change{Action: ActionCopy, Path: name, Size: srcInfo.Size()})
+
+	if s.dryRun {
+		return nil
+	}
+
+	return filesystem.Copy(ctx, s.source, name, s.target, name, false)
+}
+
+func (s *syncer) remove(ctx context.Context, name string, dstInfo filesystem.FileInfo) error {
+	change := Change{Action: ActionDelete, Path: name}
+	if !dstInfo.IsDir() {
+		change.Size = dstInfo.Size()
+	}
+	s.emit(change)
+
+	if s.dryRun {
+		return nil
+	}
+
+	if err := s.target.RemoveAll(ctx, name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *syncer) emit(c Change) {
+	if s.report == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.report(c)
+}
+
+func (s *syncer) isIncluded(name string) bool {
+	if len(s.include) == 0 {
+		return true
+	}
+	return matchAny(s.include, name)
+}
+
+// isProtected returns true when entry of target should not be deleted,
+// which is excluded, or not included when includes set.
+func (s *syncer) isProtected(name string, isDir bool) bool {
+	if s.isExcluded(name, isDir) {
+		return true
+	}
+	return len(s.include) > 0 && !s.isIncluded(name)
+}
+
+func (s *syncer) isExcluded(name string, isDir bool) bool {
+	// locks are of the fs stored them
+	if filesystem.IsLockName(name) {
+		return true
+	}
+	if matchAny(s.exclude, name) {
+		return true
+	}
+	// pattern like "node_modules/" only matches directories
+	if isDir {
+		return matchAny(s.exclude, name+"/")
+	}
+	return false
+}
+
+func matchAny(patterns []string, name string) bool {
+	rel := strings.TrimPrefix(name, "/")
+	base := path.Base(name)
+
+	for _, p := range patterns {
+		target := rel
+		if !strings.Contains(strings.TrimSuffix(p, "/"), "/") {
+			target = base
+			if strings.HasSuffix(rel, "/") {
+				target += "/"
+			}
+		}
+
+		if ok, _ := path.Match(strings.TrimPrefix(p, "/"), target); ok {
+			return true
+		}
+	}
+
+	return false
+}
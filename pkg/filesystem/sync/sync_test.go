@@ -0,0 +1,200 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"testing"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/local"
+)
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+
+	source := filesystem.NewMemFS()
+	target := local.NewFS(t.TempDir())
+
+	for name, content := range map[string]string{
+		"/a.txt":          "a",
+		"/b/c.txt":        "c",
+		"/b/d.log":        "d",
+		"/tmp/ignore.txt": "x",
+	} {
+		err := filesystem.MkdirAll(ctx, source, path.Dir(name))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		err = filesystem.Write(ctx, source, name, []byte(content))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+	}
+
+	err := filesystem.MkdirAll(ctx, target, "/extra")
+	testingx.Expect(t, err, testingx.Be[error](nil))
+	err = filesystem.Write(ctx, target, "/extra/e.txt", []byte("e"))
+	testingx.Expect(t, err, testingx.Be[error](nil))
+
+	run := func(opts ...Option) []Change {
+		changes := make([]Change, 0)
+		err := New(source, target, append(opts, WithReporter(func(c Change) {
+			changes = append(changes, c)
+		}))...).Sync(ctx)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+		return changes
+	}
+
+	t.Run("dry run should not apply", func(t *testing.T) {
+		changes := run(WithDryRun(true), WithDelete(true), WithExclude("tmp/", "*.log"))
+
+		testingx.Expect(t, changes, testingx.Equal([]Change{
+			{Action: ActionCopy, Path: "/a.txt", Size: 1},
+			{Action: ActionMkdir, Path: "/b"},
+			{Action: ActionCopy, Path: "/b/c.txt", Size: 1},
+			{Action: ActionDelete, Path: "/extra"},
+		}))
+
+		_, err := target.Stat(ctx, "/a.txt")
+		testingx.Expect(t, os.IsNotExist(err), testingx.Be(true))
+	})
+
+	t.Run("sync", func(t *testing.T) {
+		_ = run(WithDelete(true), WithExclude("tmp/", "*.log"), WithCompare(CompareChecksum))
+
+		testingx.Expect(t, readFile(t, target, "/b/c.txt"), testingx.Be("c"))
+
+		for _, name := range []string{"/extra", "/b/d.log", "/tmp"} {
+			_, err := target.Stat(ctx, name)
+			testingx.Expect(t, os.IsNotExist(err), testingx.Be(true))
+		}
+
+		t.Run("then sync again should do nothing", func(t *testing.T) {
+			changes := run(WithDelete(true), WithExclude("tmp/", "*.log"), WithCompare(CompareChecksum))
+			testingx.Expect(t, changes, testingx.Equal([]Change{}))
+		})
+
+		t.Run("then sync changed file only", func(t *testing.T) {
+			err := filesystem.Write(ctx, source, "/b/c.txt", []byte("cc"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			changes := run(WithExclude("tmp/", "*.log"), WithCompare(CompareSize))
+			testingx.Expect(t, changes, testingx.Equal([]Change{
+				{Action: ActionCopy, Path: "/b/c.txt", Size: 2},
+			}))
+			testingx.Expect(t, readFile(t, target, "/b/c.txt"), testingx.Be("cc"))
+		})
+	})
+}
+
+func TestSyncInclude(t *testing.T) {
+	ctx := context.Background()
+
+	source := filesystem.NewMemFS()
+	target := filesystem.NewMemFS()
+
+	for name, content := range map[string]string{
+		"/a/1.txt": "1",
+		"/a/2.log": "2",
+		"/b/3.log": "3",
+	} {
+		_ = filesystem.MkdirAll(ctx, source, path.Dir(name))
+		_ = filesystem.Write(ctx, source, name, []byte(content))
+	}
+
+	for name, content := range map[string]string{
+		"/keep.log":  "k",
+		"/extra.txt": "e",
+		"/c/4.log":   "4",
+	} {
+		_ = filesystem.MkdirAll(ctx, target, path.Dir(name))
+		_ = filesystem.Write(ctx, target, name, []byte(content))
+	}
+
+	changes := make([]Change, 0)
+	err := New(source, target, WithInclude("*.txt"), WithDelete(true), WithReporter(func(c Change) {
+		changes = append(changes, c)
+	})).Sync(ctx)
+	testingx.Expect(t, err, testingx.Be[error](nil))
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+
+	// files not included and dirs without included files are kept, dirs without included files not created
+	testingx.Expect(t, changes, testingx.Equal([]Change{
+		{Action: ActionMkdir, Path: "/a"},
+		{Action: ActionCopy, Path: "/a/1.txt", Size: 1},
+		{Action: ActionDelete, Path: "/extra.txt", Size: 1},
+	}))
+
+	for _, name := range []string{"/keep.log", "/c/4.log"} {
+		_, err := target.Stat(ctx, name)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+	}
+
+	_, err = target.Stat(ctx, "/b")
+	testingx.Expect(t, os.IsNotExist(err), testingx.Be(true))
+}
+
+func TestSyncFailed(t *testing.T) {
+	ctx := context.Background()
+
+	source := filesystem.NewMemFS()
+	for _, name := range []string{"/a.txt", "/b.txt", "/c.txt"} {
+		err := filesystem.Write(ctx, source, name, []byte(name))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+	}
+
+	errCopy := errors.New("copy failed")
+	target := &failedFS{FileSystem: filesystem.NewMemFS(), name: "/a.txt", err: errCopy}
+
+	// copy of next file waits the failed one, and walking continues with ctx canceled
+	err := New(source, target, WithConcurrency(1)).Sync(ctx)
+	testingx.Expect(t, errors.Is(err, errCopy), testingx.Be(true))
+}
+
+func TestSyncInvalidCompare(t *testing.T) {
+	_, err := ParseCompare("hash")
+	testingx.Expect(t, err, testingx.Not(testingx.Be[error](nil)))
+
+	c, err := ParseCompare("checksum")
+	testingx.Expect(t, err, testingx.Be[error](nil))
+	testingx.Expect(t, c, testingx.Be(CompareChecksum))
+
+	err = New(filesystem.NewMemFS(), filesystem.NewMemFS(), WithCompare("hash")).Sync(context.Background())
+	testingx.Expect(t, err, testingx.Not(testingx.Be[error](nil)))
+}
+
+type failedFS struct {
+	filesystem.FileSystem
+
+	name string
+	err  error
+}
+
+func (f *failedFS) Rename(ctx context.Context, oldName, newName string) error {
+	if newName == f.name {
+		return f.err
+	}
+	return f.FileSystem.Rename(ctx, oldName, newName)
+}
+
+func TestMatchAny(t *testing.T) {
+	testingx.Expect(t, matchAny([]string{"*.log"}, "/a/b.log"), testingx.Be(true))
+	testingx.Expect(t, matchAny([]string{"a/*.log"}, "/a/b.log"), testingx.Be(true))
+	testingx.Expect(t, matchAny([]string{"a/*.log"}, "/c/b.log"), testingx.Be(false))
+	testingx.Expect(t, matchAny([]string{"tmp/"}, "/x/tmp"), testingx.Be(false))
+	testingx.Expect(t, matchAny([]string{"tmp/"}, "/x/tmp/"), testingx.Be(true))
+}
+
+func readFile(t testing.TB, fsys filesystem.FileSystem, name string) string {
+	f, err := filesystem.Open(context.Background(), fsys, name)
+	testingx.Expect(t, err, testingx.Be[error](nil))
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	testingx.Expect(t, err, testingx.Be[error](nil))
+	return string(data)
+}
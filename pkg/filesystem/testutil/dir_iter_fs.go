@@ -0,0 +1,52 @@
+package testutil
+
+import (
+	"context"
+	"io/fs"
+	"iter"
+	"os"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+// DirIterFS wraps fsys with dirs implemented filesystem.DirIterator,
+// to check wrappers forward it like backends streaming entries.
+func DirIterFS(fsys filesystem.FileSystem) filesystem.FileSystem {
+	return &dirIterFS{FileSystem: fsys}
+}
+
+type dirIterFS struct {
+	filesystem.FileSystem
+}
+
+func (d *dirIterFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (filesystem.File, error) {
+	f, err := d.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := f.Stat(); err == nil && info.IsDir() {
+		return &iterDir{File: f}, nil
+	}
+	return f, nil
+}
+
+type iterDir struct {
+	filesystem.File
+}
+
+func (d *iterDir) ReadDirIter(ctx context.Context) iter.Seq2[fs.DirEntry, error] {
+	return func(yield func(fs.DirEntry, error) bool) {
+		infos, err := d.Readdir(-1)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for _, info := range infos {
+			if !yield(fs.FileInfoToDirEntry(info), nil) {
+				return
+			}
+		}
+	}
+}
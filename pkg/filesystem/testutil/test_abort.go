@@ -0,0 +1,89 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+// TestAbort checks nothing committed when writing aborted or canceled
+func TestAbort(t *testing.T, fsys filesystem.FileSystem) {
+	ctx := context.Background()
+
+	readFile := func(t *testing.T, name string) string {
+		f, err := fsys.OpenFile(ctx, name, os.O_RDONLY, os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		return string(data)
+	}
+
+	notExists := func(t *testing.T, name string) {
+		_, err := fsys.Stat(ctx, name)
+		testingx.Expect(t, os.IsNotExist(err), testingx.Be(true))
+	}
+
+	t.Run("aborted new file", func(t *testing.T) {
+		f, err := filesystem.OpenAtomic(ctx, fsys, "/aborted.txt", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = f.Write([]byte("partial"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		err = filesystem.Abort(f, errors.New("producer failed"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		// no-op after aborted
+		err = f.Close()
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		notExists(t, "/aborted.txt")
+	})
+
+	t.Run("aborted existing file", func(t *testing.T) {
+		err := filesystem.WriteAtomic(ctx, fsys, "/existing.txt", []byte("old"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		f, err := filesystem.OpenAtomic(ctx, fsys, "/existing.txt", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = f.Write([]byte("partial"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		err = filesystem.Abort(f, nil)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		testingx.Expect(t, readFile(t, "/existing.txt"), testingx.Be("old"))
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+
+		f, err := filesystem.OpenAtomic(ctx, fsys, "/canceled.txt", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = f.Write([]byte("partial"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		cancel()
+
+		err = f.Close()
+		testingx.Expect(t, errors.Is(err, context.Canceled), testingx.Be(true))
+
+		notExists(t, "/canceled.txt")
+	})
+
+	t.Run("no temp files left", func(t *testing.T) {
+		entries, err := filesystem.ReadDir(ctx, fsys, "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		for _, e := range entries {
+			testingx.Expect(t, filesystem.IsTempName(e.Name()), testingx.Be(false))
+		}
+	})
+}
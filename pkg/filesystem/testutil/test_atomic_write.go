@@ -0,0 +1,64 @@
+package testutil
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+// TestAtomicWrite checks files written by OpenAtomic visible only after closed
+func TestAtomicWrite(t *testing.T, fsys filesystem.FileSystem) {
+	ctx := context.Background()
+
+	readFile := func(t *testing.T, name string) string {
+		f, err := fsys.OpenFile(ctx, name, os.O_RDONLY, os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		return string(data)
+	}
+
+	t.Run("visible after closed", func(t *testing.T) {
+		f, err := filesystem.OpenAtomic(ctx, fsys, "/atomic.txt", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = f.Write([]byte("hello"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = fsys.Stat(ctx, "/atomic.txt")
+		testingx.Expect(t, os.IsNotExist(err), testingx.Be(true))
+
+		err = f.Close()
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, readFile(t, "/atomic.txt"), testingx.Be("hello"))
+	})
+
+	t.Run("replace existing", func(t *testing.T) {
+		err := filesystem.WriteAtomic(ctx, fsys, "/atomic.txt", []byte("world"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, readFile(t, "/atomic.txt"), testingx.Be("world"))
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		err := filesystem.WriteAtomic(ctx, fsys, "/empty.txt", nil)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		info, err := fsys.Stat(ctx, "/empty.txt")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, info.Size(), testingx.Be[int64](0))
+	})
+
+	t.Run("no temp files left", func(t *testing.T) {
+		entries, err := filesystem.ReadDir(ctx, fsys, "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		for _, e := range entries {
+			testingx.Expect(t, filesystem.IsTempName(e.Name()), testingx.Be(false))
+		}
+	})
+}
@@ -234,7 +234,7 @@ func TestFullFS(t *testing.T, fs filesystem.FileSystem) {
 				if parts[1] == "d=∞" {
 					depth = infiniteDepth
 				}
-				_, opErr = copyFiles(ctx, fs, parts[2], parts[3], parts[0] == "o=T", depth, 0)
+				opErr = copyFiles(ctx, fs, parts[2], parts[3], parts[0] == "o=T", depth)
 			case "mk-dir":
 				opErr = fs.Mkdir(ctx, parts[0], 0o777)
 			case "move__":
@@ -305,5 +305,13 @@ func find(ctx context.Context, ss []string, fs filesystem.FileSystem, name strin
 //go:linkname moveFiles golang.org/x/net/webdav.moveFiles
 func moveFiles(ctx context.Context, fs webdav.FileSystem, src, dst string, overwrite bool) (status int, err error)
 
-//go:linkname copyFiles golang.org/x/net/webdav.copyFiles
-func copyFiles(ctx context.Context, fs webdav.FileSystem, src, dst string, overwrite bool, depth int, recursion int) (status int, err error)
+func copyFiles(ctx context.Context, fs filesystem.FileSystem, src, dst string, overwrite bool, depth int) error {
+	if _, err := fs.Stat(ctx, dst); err == nil {
+		if !overwrite {
+			return os.ErrExist
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return filesystem.Copy(ctx, fs, src, fs, dst, depth == infiniteDepth)
+}
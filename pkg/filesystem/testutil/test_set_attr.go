@@ -0,0 +1,68 @@
+package testutil
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+// TestSetAttr checks mode and mtime set by AttrSetter or injected when written are reported by Stat
+func TestSetAttr(t *testing.T, fsys filesystem.FileSystem) {
+	ctx := context.Background()
+
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	err := filesystem.MkdirAll(ctx, fsys, "/attrs")
+	testingx.Expect(t, err, testingx.Be[error](nil))
+
+	err = filesystem.Write(ctx, fsys, "/attrs/a.txt", []byte("hello"))
+	testingx.Expect(t, err, testingx.Be[error](nil))
+
+	t.Run("mod time", func(t *testing.T) {
+		err := filesystem.SetModTime(ctx, fsys, "/attrs/a.txt", mtime)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		info, err := fsys.Stat(ctx, "/attrs/a.txt")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, info.ModTime().Equal(mtime), testingx.Be(true))
+		testingx.Expect(t, info.Size(), testingx.Be(int64(5)))
+	})
+
+	t.Run("mode", func(t *testing.T) {
+		err := filesystem.SetMode(ctx, fsys, "/attrs/a.txt", 0o600)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		info, err := fsys.Stat(ctx, "/attrs/a.txt")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, info.Mode().Perm(), testingx.Be(os.FileMode(0o600)))
+		testingx.Expect(t, info.ModTime().Equal(mtime), testingx.Be(true))
+	})
+
+	t.Run("mod time injected when written", func(t *testing.T) {
+		err := filesystem.Write(filesystem.ModTimeInjectContext(ctx, mtime), fsys, "/attrs/b.txt", []byte("hello"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		info, err := fsys.Stat(ctx, "/attrs/b.txt")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, info.ModTime().Equal(mtime), testingx.Be(true))
+	})
+
+	t.Run("mod time updated when rewritten", func(t *testing.T) {
+		err := filesystem.Write(ctx, fsys, "/attrs/b.txt", []byte("rewritten"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		info, err := fsys.Stat(ctx, "/attrs/b.txt")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, info.ModTime().After(mtime), testingx.Be(true))
+	})
+
+	t.Run("not exists", func(t *testing.T) {
+		err := filesystem.SetModTime(ctx, fsys, "/attrs/not-exists.txt", mtime)
+		testingx.Expect(t, os.IsNotExist(err), testingx.Be(true))
+	})
+}
@@ -99,6 +99,50 @@ func TestSimpleFS(t *testing.T, fs filesystem.FileSystem) {
 		testingx.Expect(t, f.Size() > 0, testingx.Be(true))
 	})
 
+	t.Run("append file", func(t *testing.T) {
+		if !filesystem.HasCapabilities(fs, filesystem.CapAppend) {
+			t.Skip("append not supported")
+		}
+
+		for _, content := range []string{"1", "2"} {
+			f, err := fs.OpenFile(context.Background(), "/x/append.txt", os.O_WRONLY|os.O_CREATE|os.O_APPEND, os.ModePerm)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			_, err = f.Write([]byte(content))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			err = f.Close()
+			testingx.Expect(t, err, testingx.Be[error](nil))
+		}
+
+		f, err := fs.OpenFile(context.Background(), "/x/append.txt", os.O_RDONLY, os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, string(data), testingx.Be("12"))
+	})
+
+	t.Run("read at", func(t *testing.T) {
+		if !filesystem.HasCapabilities(fs, filesystem.CapReaderAt) {
+			t.Skip("reader at not supported")
+		}
+
+		f, err := fs.OpenFile(context.Background(), "/1.json", os.O_RDONLY, os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		defer f.Close()
+
+		full, err := io.ReadAll(f)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		r, ok := f.(io.ReaderAt)
+		testingx.Expect(t, ok, testingx.Be(true))
+
+		p := make([]byte, 8)
+		n, err := r.ReadAt(p, 4)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, string(p[:n]), testingx.Be(string(full[4:12])))
+	})
+
 	t.Run("list", func(t *testing.T) {
 		f, err := fs.OpenFile(context.Background(), "/", os.O_RDONLY, os.ModePerm)
 		testingx.Expect(t, err, testingx.Be[error](nil))
@@ -108,6 +152,31 @@ func TestSimpleFS(t *testing.T, fs filesystem.FileSystem) {
 		testingx.Expect(t, len(list), testingx.Be(2))
 	})
 
+	t.Run("list by page", func(t *testing.T) {
+		f, err := fs.OpenFile(context.Background(), "/", os.O_RDONLY, os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		defer f.Close()
+
+		for range 2 {
+			list, err := f.Readdir(1)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, len(list), testingx.Be(1))
+		}
+
+		list, err := f.Readdir(1)
+		testingx.Expect(t, err, testingx.Be(io.EOF))
+		testingx.Expect(t, len(list), testingx.Be(0))
+	})
+
+	t.Run("list by iter", func(t *testing.T) {
+		names := make([]string, 0)
+		for e, err := range filesystem.ReadDirIter(context.Background(), fs, "/") {
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			names = append(names, e.Name())
+		}
+		testingx.Expect(t, len(names), testingx.Be(2))
+	})
+
 	t.Run("rename", func(t *testing.T) {
 		err := fs.Mkdir(context.Background(), "/x/a", os.ModeDir|os.ModePerm)
 		testingx.Expect(t, err, testingx.Be[error](nil))
@@ -0,0 +1,92 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+// TestSymlink checks links created by Linker could be read, renamed and removed without touching targets
+func TestSymlink(t *testing.T, fsys filesystem.FileSystem) {
+	ctx := context.Background()
+
+	err := filesystem.MkdirAll(ctx, fsys, "/links")
+	testingx.Expect(t, err, testingx.Be[error](nil))
+
+	err = filesystem.Write(ctx, fsys, "/links/target.txt", []byte("hello"))
+	testingx.Expect(t, err, testingx.Be[error](nil))
+
+	t.Run("create and read", func(t *testing.T) {
+		err := filesystem.Symlink(ctx, fsys, "target.txt", "/links/link")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		target, err := filesystem.Readlink(ctx, fsys, "/links/link")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, target, testingx.Be("target.txt"))
+
+		info, err := filesystem.Lstat(ctx, fsys, "/links/link")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, info.Name(), testingx.Be("link"))
+		testingx.Expect(t, filesystem.IsSymlink(info), testingx.Be(true))
+
+		info, err = filesystem.Lstat(ctx, fsys, "/links/target.txt")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, filesystem.IsSymlink(info), testingx.Be(false))
+	})
+
+	t.Run("exists", func(t *testing.T) {
+		err := filesystem.Symlink(ctx, fsys, "other.txt", "/links/link")
+		testingx.Expect(t, os.IsExist(err), testingx.Be(true))
+
+		target, err := filesystem.Readlink(ctx, fsys, "/links/link")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, target, testingx.Be("target.txt"))
+	})
+
+	t.Run("dangling", func(t *testing.T) {
+		err := filesystem.Symlink(ctx, fsys, "../not exists/x.txt", "/links/dangling")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		target, err := filesystem.Readlink(ctx, fsys, "/links/dangling")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, target, testingx.Be("../not exists/x.txt"))
+	})
+
+	t.Run("not link", func(t *testing.T) {
+		_, err := filesystem.Readlink(ctx, fsys, "/links/target.txt")
+		testingx.Expect(t, errors.Is(err, syscall.EINVAL), testingx.Be(true))
+
+		_, err = filesystem.Readlink(ctx, fsys, "/links/not-exists")
+		testingx.Expect(t, os.IsNotExist(err), testingx.Be(true))
+	})
+
+	t.Run("rename and remove", func(t *testing.T) {
+		err := fsys.Rename(ctx, "/links/link", "/links/renamed")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		target, err := filesystem.Readlink(ctx, fsys, "/links/renamed")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, target, testingx.Be("target.txt"))
+
+		err = fsys.RemoveAll(ctx, "/links/renamed")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = filesystem.Lstat(ctx, fsys, "/links/renamed")
+		testingx.Expect(t, os.IsNotExist(err), testingx.Be(true))
+
+		f, err := fsys.OpenFile(ctx, "/links/target.txt", os.O_RDONLY, os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, string(data), testingx.Be("hello"))
+	})
+}
@@ -0,0 +1,79 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NewBucket creates token bucket filled with rate tokens per second, and holds at most rate tokens.
+// nil returned when rate is zero, which means no limit.
+func NewBucket(rate int64) *Bucket {
+	if rate <= 0 {
+		return nil
+	}
+
+	return &Bucket{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+type Bucket struct {
+	rate float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// Burst returns max tokens could be taken without waiting
+func (b *Bucket) Burst() int64 {
+	if b == nil {
+		return 0
+	}
+	return int64(b.rate)
+}
+
+// Wait takes n tokens, and blocks until tokens refilled when not enough.
+//
+// n could be more than Burst, which just waits longer.
+// tokens taken will be given back when ctx done before refilled.
+func (b *Bucket) Wait(ctx context.Context, n int64) error {
+	if b == nil || n <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	b.refill()
+	// tokens could be negative, which makes later waits longer
+	b.tokens -= float64(n)
+	d := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.refill()
+		b.tokens = min(b.rate, b.tokens+float64(n))
+		b.mu.Unlock()
+
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (b *Bucket) refill() {
+	now := time.Now()
+	b.tokens = min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
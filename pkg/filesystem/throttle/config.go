@@ -0,0 +1,40 @@
+package throttle
+
+import (
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/units"
+)
+
+type Config struct {
+	// Max bytes read per second in total, like 50Mi
+	ReadRate units.BinarySize `flag:"read-rate,omitzero"`
+	// Max bytes written per second in total, like 50Mi
+	WriteRate units.BinarySize `flag:"write-rate,omitzero"`
+	// Max operations per second in total
+	OpRate int `flag:"op-rate,omitzero"`
+	// Max bytes read per second of each user, only when users are verified, like by auth of reverse proxy
+	UserReadRate units.BinarySize `flag:"user-read-rate,omitzero"`
+	// Max bytes written per second of each user
+	UserWriteRate units.BinarySize `flag:"user-write-rate,omitzero"`
+	// Max operations per second of each user
+	UserOpRate int `flag:"user-op-rate,omitzero"`
+	// Max bytes read per second of each client ip, which could not be bypassed by changing user
+	ClientReadRate units.BinarySize `flag:"client-read-rate,omitzero"`
+	// Max bytes written per second of each client ip
+	ClientWriteRate units.BinarySize `flag:"client-write-rate,omitzero"`
+	// Max operations per second of each client ip
+	ClientOpRate int `flag:"client-op-rate,omitzero"`
+}
+
+// Apply wraps fsys with limits, fsys returned directly when no limits set
+func (c *Config) Apply(fsys filesystem.FileSystem) filesystem.FileSystem {
+	global := Limits{ReadRate: c.ReadRate, WriteRate: c.WriteRate, OpRate: c.OpRate}
+	perUser := Limits{ReadRate: c.UserReadRate, WriteRate: c.UserWriteRate, OpRate: c.UserOpRate}
+	perClient := Limits{ReadRate: c.ClientReadRate, WriteRate: c.ClientWriteRate, OpRate: c.ClientOpRate}
+
+	if global.IsZero() && perUser.IsZero() && perClient.IsZero() {
+		return fsys
+	}
+
+	return Wrap(fsys, WithLimits(global), WithUserLimits(perUser), WithClientLimits(perClient))
+}
@@ -0,0 +1,497 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/units"
+)
+
+// Limits of rates, zero means no limit
+type Limits struct {
+	// bytes read per second
+	ReadRate units.BinarySize
+	// bytes written per second
+	WriteRate units.BinarySize
+	// operations per second
+	OpRate int
+}
+
+func (l Limits) IsZero() bool {
+	return l.ReadRate == 0 && l.WriteRate == 0 && l.OpRate == 0
+}
+
+func (l Limits) buckets() *buckets {
+	return &buckets{
+		read:  NewBucket(int64(l.ReadRate)),
+		write: NewBucket(int64(l.WriteRate)),
+		op:    NewBucket(int64(l.OpRate)),
+	}
+}
+
+type buckets struct {
+	read  *Bucket
+	write *Bucket
+	op    *Bucket
+}
+
+type Option func(t *throttledFS)
+
+// WithLimits sets limits shared by all requests
+func WithLimits(limits Limits) Option {
+	return func(t *throttledFS) {
+		t.global = limits.buckets()
+	}
+}
+
+// WithUserLimits sets limits for each user from filesystem.UserFromContext,
+// requests without user only limited by other limits.
+//
+// user must be trusted, like verified by auth of servers or reverse proxy before injected,
+// otherwise limits could be bypassed by changing user claimed by client.
+func WithUserLimits(limits Limits) Option {
+	return func(t *throttledFS) {
+		t.users.limits = limits
+	}
+}
+
+// WithClientLimits sets limits for each client from filesystem.ClientFromContext,
+// requests without client only limited by other limits.
+//
+// ip of client could not be changed like user, so works even users not verified.
+func WithClientLimits(limits Limits) Option {
+	return func(t *throttledFS) {
+		t.clients.limits = limits
+	}
+}
+
+// buckets idle over idleTimeout will be dropped,
+// since buckets refilled in seconds, new ones for the same key make no difference.
+const idleTimeout = 10 * time.Minute
+
+// Wrap limits bytes read, bytes written and operations per second of fsys.
+func Wrap(fsys filesystem.FileSystem, opts ...Option) filesystem.FileSystem {
+	t := &throttledFS{
+		Forwarder: filesystem.Forwarder{FileSystem: fsys},
+		global:    Limits{}.buckets(),
+		users:     newKeyedBuckets(filesystem.UserFromContext),
+		clients:   newKeyedBuckets(filesystem.ClientFromContext),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+type throttledFS struct {
+	filesystem.Forwarder
+
+	global  *buckets
+	users   *keyedBuckets
+	clients *keyedBuckets
+}
+
+func newKeyedBuckets(keyOf func(ctx context.Context) string) *keyedBuckets {
+	return &keyedBuckets{
+		keyOf:       keyOf,
+		entries:     map[string]*idleBuckets{},
+		idleTimeout: idleTimeout,
+	}
+}
+
+// keyedBuckets holds buckets for each key from context, like user or client
+type keyedBuckets struct {
+	limits Limits
+	keyOf  func(ctx context.Context) string
+
+	mu          sync.Mutex
+	entries     map[string]*idleBuckets
+	idleTimeout time.Duration
+	evictedAt   time.Time
+}
+
+type idleBuckets struct {
+	*buckets
+	usedAt time.Time
+}
+
+// of returns buckets of key from ctx, nil when no limits or no key
+func (k *keyedBuckets) of(ctx context.Context) *buckets {
+	if k.limits.IsZero() {
+		return nil
+	}
+
+	key := k.keyOf(ctx)
+	if key == "" {
+		return nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := time.Now()
+	k.evictIdle(now)
+
+	b, ok := k.entries[key]
+	if !ok {
+		b = &idleBuckets{buckets: k.limits.buckets()}
+		k.entries[key] = b
+	}
+	b.usedAt = now
+
+	return b.buckets
+}
+
+// evictIdle drops idle buckets, at most once per idleTimeout
+func (k *keyedBuckets) evictIdle(now time.Time) {
+	if now.Sub(k.evictedAt) < k.idleTimeout {
+		return
+	}
+	k.evictedAt = now
+
+	for key, b := range k.entries {
+		if now.Sub(b.usedAt) >= k.idleTimeout {
+			delete(k.entries, key)
+		}
+	}
+}
+
+// bucketsOf returns all buckets should be waited for the request
+func (t *throttledFS) bucketsOf(ctx context.Context) []*buckets {
+	all := []*buckets{t.global}
+	if b := t.users.of(ctx); b != nil {
+		all = append(all, b)
+	}
+	if b := t.clients.of(ctx); b != nil {
+		all = append(all, b)
+	}
+	return all
+}
+
+func (t *throttledFS) wait(ctx context.Context, pick func(b *buckets) *Bucket, n int64) error {
+	for _, b := range t.bucketsOf(ctx) {
+		if err := pick(b).Wait(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *throttledFS) waitOp(ctx context.Context) error {
+	return t.wait(ctx, func(b *buckets) *Bucket { return b.op }, 1)
+}
+
+// burst returns max bytes could be read or written once
+func (t *throttledFS) burst(ctx context.Context, pick func(b *buckets) *Bucket) int64 {
+	n := int64(0)
+	for _, b := range t.bucketsOf(ctx) {
+		if m := pick(b).Burst(); m > 0 && (n == 0 || m < n) {
+			n = m
+		}
+	}
+	return n
+}
+
+func (t *throttledFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	if err := t.waitOp(ctx); err != nil {
+		return err
+	}
+	return t.FileSystem.Mkdir(ctx, name, perm)
+}
+
+func (t *throttledFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (filesystem.File, error) {
+	if err := t.waitOp(ctx); err != nil {
+		return nil, err
+	}
+
+	f, err := t.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	tf := &file{File: f, ctx: ctx, t: t, name: name}
+
+	// writes at offset and iterating entries not throttled, so not exposed
+	o := filesystem.OptionalFile{}
+	if r, ok := f.(io.ReaderAt); ok {
+		o.ReaderAt = readerAt{f: tf, r: r}
+	}
+	return filesystem.WithOptional(tf, o), nil
+}
+
+func (t *throttledFS) RemoveAll(ctx context.Context, name string) error {
+	if err := t.waitOp(ctx); err != nil {
+		return err
+	}
+	return t.FileSystem.RemoveAll(ctx, name)
+}
+
+func (t *throttledFS) Rename(ctx context.Context, oldName, newName string) error {
+	if err := t.waitOp(ctx); err != nil {
+		return err
+	}
+	return t.FileSystem.Rename(ctx, oldName, newName)
+}
+
+// Copy only forwards native copy, streaming copy will be throttled as reading and writing
+func (t *throttledFS) Copy(ctx context.Context, oldName, newName string, recursive bool) error {
+	c, ok := t.FileSystem.(filesystem.Copier)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	if err := t.waitOp(ctx); err != nil {
+		return err
+	}
+	return c.Copy(ctx, oldName, newName, recursive)
+}
+
+// Hash only forwards native hash, streaming hash will be throttled as reading
+func (t *throttledFS) Hash(ctx context.Context, name string, algo filesystem.HashAlgorithm) (string, error) {
+	h, ok := t.FileSystem.(filesystem.Hasher)
+	if !ok {
+		return "", errors.ErrUnsupported
+	}
+	if err := t.waitOp(ctx); err != nil {
+		return "", err
+	}
+	return h.Hash(ctx, name, algo)
+}
+
+// ListPrefix counts as one op, since pages are fetched by backend
+func (t *throttledFS) ListPrefix(ctx context.Context, prefix string) iter.Seq2[*filesystem.FileEntry, error] {
+	return func(yield func(*filesystem.FileEntry, error) bool) {
+		l, ok := t.FileSystem.(filesystem.PrefixLister)
+		if !ok {
+			yield(nil, errors.ErrUnsupported)
+			return
+		}
+		if err := t.waitOp(ctx); err != nil {
+			yield(nil, err)
+			return
+		}
+		for e, err := range l.ListPrefix(ctx, prefix) {
+			if !yield(e, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (t *throttledFS) Lock(ctx context.Context, name string, opts filesystem.LockOptions) (*filesystem.LockInfo, error) {
+	l, ok := t.FileSystem.(filesystem.Locker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	if err := t.waitOp(ctx); err != nil {
+		return nil, err
+	}
+	return l.Lock(ctx, name, opts)
+}
+
+func (t *throttledFS) RefreshLock(ctx context.Context, name string, token string, ttl time.Duration) (*filesystem.LockInfo, error) {
+	l, ok := t.FileSystem.(filesystem.Locker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	if err := t.waitOp(ctx); err != nil {
+		return nil, err
+	}
+	return l.RefreshLock(ctx, name, token, ttl)
+}
+
+func (t *throttledFS) Unlock(ctx context.Context, name string, token string) error {
+	l, ok := t.FileSystem.(filesystem.Locker)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	if err := t.waitOp(ctx); err != nil {
+		return err
+	}
+	return l.Unlock(ctx, name, token)
+}
+
+func (t *throttledFS) Locks(ctx context.Context, name string) ([]*filesystem.LockInfo, error) {
+	l, ok := t.FileSystem.(filesystem.Locker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	if err := t.waitOp(ctx); err != nil {
+		return nil, err
+	}
+	return l.Locks(ctx, name)
+}
+
+func (t *throttledFS) Symlink(ctx context.Context, target string, name string) error {
+	l, ok := t.FileSystem.(filesystem.Linker)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	if err := t.waitOp(ctx); err != nil {
+		return err
+	}
+	return l.Symlink(ctx, target, name)
+}
+
+func (t *throttledFS) Readlink(ctx context.Context, name string) (string, error) {
+	l, ok := t.FileSystem.(filesystem.Linker)
+	if !ok {
+		return "", errors.ErrUnsupported
+	}
+	if err := t.waitOp(ctx); err != nil {
+		return "", err
+	}
+	return l.Readlink(ctx, name)
+}
+
+func (t *throttledFS) Lstat(ctx context.Context, name string) (os.FileInfo, error) {
+	l, ok := t.FileSystem.(filesystem.Linker)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	if err := t.waitOp(ctx); err != nil {
+		return nil, err
+	}
+	return l.Lstat(ctx, name)
+}
+
+func (t *throttledFS) SetMode(ctx context.Context, name string, mode os.FileMode) error {
+	s, ok := t.FileSystem.(filesystem.AttrSetter)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	if err := t.waitOp(ctx); err != nil {
+		return err
+	}
+	return s.SetMode(ctx, name, mode)
+}
+
+func (t *throttledFS) SetModTime(ctx context.Context, name string, mtime time.Time) error {
+	s, ok := t.FileSystem.(filesystem.AttrSetter)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	if err := t.waitOp(ctx); err != nil {
+		return err
+	}
+	return s.SetModTime(ctx, name, mtime)
+}
+
+func (t *throttledFS) Chown(ctx context.Context, name string, uid, gid int) error {
+	c, ok := t.FileSystem.(filesystem.Chowner)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	if err := t.waitOp(ctx); err != nil {
+		return err
+	}
+	return c.Chown(ctx, name, uid, gid)
+}
+
+func (t *throttledFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	if err := t.waitOp(ctx); err != nil {
+		return nil, err
+	}
+	return t.FileSystem.Stat(ctx, name)
+}
+
+func readBucket(b *buckets) *Bucket {
+	return b.read
+}
+
+func writeBucket(b *buckets) *Bucket {
+	return b.write
+}
+
+type file struct {
+	filesystem.File
+
+	ctx  context.Context
+	t    *throttledFS
+	name string
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if burst := f.t.burst(f.ctx, readBucket); burst > 0 && int64(len(p)) > burst {
+		p = p[:burst]
+	}
+
+	n, err := f.File.Read(p)
+	if e := f.t.wait(f.ctx, readBucket, int64(n)); e != nil && err == nil {
+		err = e
+	}
+	return n, err
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	written := 0
+
+	// split to avoid holding too many tokens
+	for len(p) > 0 {
+		chunk := p
+		if burst := f.t.burst(f.ctx, writeBucket); burst > 0 && int64(len(chunk)) > burst {
+			chunk = chunk[:burst]
+		}
+
+		if err := f.t.wait(f.ctx, writeBucket, int64(len(chunk))); err != nil {
+			return written, err
+		}
+
+		n, err := f.File.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+
+	return written, nil
+}
+
+func (f *file) Readdir(count int) ([]os.FileInfo, error) {
+	if err := f.t.waitOp(f.ctx); err != nil {
+		return nil, err
+	}
+	return f.File.Readdir(count)
+}
+
+func (f *file) Sync() error {
+	if s, ok := f.File.(filesystem.FileSyncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+func (f *file) Truncate(size int64) error {
+	if t, ok := f.File.(filesystem.FileTruncator); ok {
+		return t.Truncate(size)
+	}
+	return &os.PathError{Op: "truncate", Path: f.name, Err: errors.ErrUnsupported}
+}
+
+func (f *file) Abort(err error) error {
+	if a, ok := f.File.(filesystem.FileAborter); ok {
+		return a.Abort(err)
+	}
+	return &os.PathError{Op: "abort", Path: f.name, Err: errors.ErrUnsupported}
+}
+
+type readerAt struct {
+	f *file
+	r io.ReaderAt
+}
+
+func (a readerAt) ReadAt(p []byte, off int64) (int, error) {
+	f := a.f
+
+	n, err := a.r.ReadAt(p, off)
+	if e := f.t.wait(f.ctx, readBucket, int64(n)); e != nil && err == nil {
+		err = e
+	}
+	return n, err
+}
@@ -0,0 +1,143 @@
+package throttle
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/testutil"
+	"github.com/octohelm/unifs/pkg/units"
+)
+
+func TestThrottle(t *testing.T) {
+	t.Run("Simple", func(t *testing.T) {
+		testutil.TestSimpleFS(t, Wrap(filesystem.NewMemFS(), WithLimits(Limits{ReadRate: 100 * units.MiB, WriteRate: 100 * units.MiB, OpRate: 10000})))
+	})
+
+	ctx := context.Background()
+
+	t.Run("limit read rate", func(t *testing.T) {
+		fsys := Wrap(filesystem.NewMemFS(), WithLimits(Limits{ReadRate: 10 * units.KiB}))
+
+		err := filesystem.Write(ctx, fsys, "/1.txt", make([]byte, 20*units.KiB))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		f, err := fsys.OpenFile(ctx, "/1.txt", os.O_RDONLY, os.ModePerm)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		defer f.Close()
+
+		startedAt := time.Now()
+		data, err := io.ReadAll(f)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, len(data), testingx.Be(int(20*units.KiB)))
+
+		// first 10KiB burst, then wait for next 10KiB
+		testingx.Expect(t, time.Since(startedAt) >= 900*time.Millisecond, testingx.Be(true))
+
+		_, ok := f.(io.ReaderAt)
+		testingx.Expect(t, ok, testingx.Be(false))
+	})
+
+	t.Run("limit ops per client", func(t *testing.T) {
+		fsys := Wrap(filesystem.NewMemFS(), WithClientLimits(Limits{OpRate: 1}))
+
+		clientCtx := filesystem.ClientInjectContext(ctx, "10.0.0.1")
+
+		_, err := fsys.Stat(filesystem.UserInjectContext(clientCtx, "user"), "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		// other clients and unknown not affected
+		_, err = fsys.Stat(filesystem.ClientInjectContext(ctx, "10.0.0.2"), "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		_, err = fsys.Stat(ctx, "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		// changing user should not bypass limits of client
+		timeoutCtx, cancel := context.WithTimeout(filesystem.UserInjectContext(clientCtx, "other"), 100*time.Millisecond)
+		defer cancel()
+
+		_, err = fsys.Stat(timeoutCtx, "/")
+		testingx.Expect(t, err, testingx.Be(context.DeadlineExceeded))
+	})
+
+	t.Run("drop buckets of idle clients", func(t *testing.T) {
+		fsys := Wrap(filesystem.NewMemFS(), WithClientLimits(Limits{OpRate: 100})).(*throttledFS)
+		fsys.clients.idleTimeout = 10 * time.Millisecond
+
+		_, err := fsys.Stat(filesystem.ClientInjectContext(ctx, "10.0.0.1"), "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		time.Sleep(20 * time.Millisecond)
+
+		_, err = fsys.Stat(filesystem.ClientInjectContext(ctx, "10.0.0.2"), "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, len(fsys.clients.entries), testingx.Be(1))
+	})
+
+	t.Run("limit ops per user", func(t *testing.T) {
+		fsys := Wrap(filesystem.NewMemFS(), WithUserLimits(Limits{OpRate: 1}))
+
+		userCtx := filesystem.UserInjectContext(ctx, "user")
+
+		_, err := fsys.Stat(filesystem.ClientInjectContext(userCtx, "10.0.0.1"), "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		// other users not affected
+		_, err = fsys.Stat(filesystem.UserInjectContext(ctx, "other"), "/")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		// same user from other clients limited
+		timeoutCtx, cancel := context.WithTimeout(filesystem.ClientInjectContext(userCtx, "10.0.0.2"), 100*time.Millisecond)
+		defer cancel()
+
+		_, err = fsys.Stat(timeoutCtx, "/")
+		testingx.Expect(t, err, testingx.Be(context.DeadlineExceeded))
+	})
+
+	t.Run("streaming copy limited", func(t *testing.T) {
+		fsys := Wrap(&noCopierFS{FileSystem: filesystem.NewMemFS()}, WithLimits(Limits{WriteRate: 10 * units.KiB}))
+
+		err := filesystem.Write(ctx, fsys, "/1.txt", make([]byte, 10*units.KiB))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		startedAt := time.Now()
+		err = filesystem.Copy(ctx, fsys, "/1.txt", fsys, "/2.txt", false)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		// tokens used by first write, then wait for copy
+		testingx.Expect(t, time.Since(startedAt) >= 900*time.Millisecond, testingx.Be(true))
+	})
+}
+
+func TestBucket(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("tokens given back when canceled", func(t *testing.T) {
+		b := NewBucket(100)
+
+		err := b.Wait(ctx, 100)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		err = b.Wait(timeoutCtx, 1000)
+		testingx.Expect(t, err, testingx.Be(context.DeadlineExceeded))
+
+		// only tokens of first wait used
+		startedAt := time.Now()
+		err = b.Wait(ctx, 10)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, time.Since(startedAt) < 500*time.Millisecond, testingx.Be(true))
+	})
+}
+
+// noCopierFS hides Copier of FileSystem
+type noCopierFS struct {
+	filesystem.FileSystem
+}
@@ -0,0 +1,105 @@
+/*
+Package throttle GENERATED BY gengo:runtimedoc
+DON'T EDIT THIS FILE
+*/
+package throttle
+
+func (v *Bucket) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		}
+
+		return nil, false
+	}
+	return []string{}, true
+}
+
+func (v *Config) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "ReadRate":
+			return []string{
+				"Max bytes read per second in total, like 50Mi",
+			}, true
+		case "WriteRate":
+			return []string{
+				"Max bytes written per second in total, like 50Mi",
+			}, true
+		case "OpRate":
+			return []string{
+				"Max operations per second in total",
+			}, true
+		case "UserReadRate":
+			return []string{
+				"Max bytes read per second of each user, only when users are verified, like by auth of reverse proxy",
+			}, true
+		case "UserWriteRate":
+			return []string{
+				"Max bytes written per second of each user",
+			}, true
+		case "UserOpRate":
+			return []string{
+				"Max operations per second of each user",
+			}, true
+		case "ClientReadRate":
+			return []string{
+				"Max bytes read per second of each client ip, which could not be bypassed by changing user",
+			}, true
+		case "ClientWriteRate":
+			return []string{
+				"Max bytes written per second of each client ip",
+			}, true
+		case "ClientOpRate":
+			return []string{
+				"Max operations per second of each client ip",
+			}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{}, true
+}
+
+func (v *Limits) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "ReadRate":
+			return []string{
+				"bytes read per second",
+			}, true
+		case "WriteRate":
+			return []string{
+				"bytes written per second",
+			}, true
+		case "OpRate":
+			return []string{
+				"operations per second",
+			}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{
+		"Limits of rates, zero means no limit",
+	}, true
+}
+
+// nolint:deadcode,unused
+func runtimeDoc(v any, prefix string, names ...string) ([]string, bool) {
+	if c, ok := v.(interface {
+		RuntimeDoc(names ...string) ([]string, bool)
+	}); ok {
+		doc, ok := c.RuntimeDoc(names...)
+		if ok {
+			if prefix != "" && len(doc) > 0 {
+				doc[0] = prefix + doc[0]
+				return doc, true
+			}
+
+			return doc, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,36 @@
+package filesystem
+
+import (
+	"context"
+)
+
+type userCtx struct{}
+
+// UserFromContext returns identity of who made the request, empty when unknown
+func UserFromContext(ctx context.Context) string {
+	if v, ok := ctx.Value(userCtx{}).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// UserInjectContext injects user claimed by client, like login user of ftp or basic auth user of webdav.
+// user is not verified by servers of unifs, so should be trusted only when verified before, like by reverse proxy.
+func UserInjectContext(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userCtx{}, user)
+}
+
+type clientCtx struct{}
+
+// ClientFromContext returns ip of client who made the request, empty when unknown
+func ClientFromContext(ctx context.Context) string {
+	if v, ok := ctx.Value(clientCtx{}).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// ClientInjectContext injects ip of client, which could not be changed by client like user
+func ClientInjectContext(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientCtx{}, ip)
+}
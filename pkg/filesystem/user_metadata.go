@@ -0,0 +1,65 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+)
+
+// UserMetadata of file, like x-amz-meta-* of s3.
+// keys are case-insensitive and stored in lower case.
+type UserMetadata map[string]string
+
+var reUserMetadataKey = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Normalize returns copy with lower case keys, and checks keys could be used as http header names
+func (m UserMetadata) Normalize() (UserMetadata, error) {
+	normalized := make(UserMetadata, len(m))
+	for k, v := range m {
+		key := strings.ToLower(k)
+		if !reUserMetadataKey.MatchString(key) {
+			return nil, fmt.Errorf("invalid user metadata key %q, should match %s", k, reUserMetadataKey)
+		}
+		normalized[key] = v
+	}
+	return normalized, nil
+}
+
+func (m UserMetadata) Get(key string) string {
+	return m[strings.ToLower(key)]
+}
+
+type userMetadataCtx struct{}
+
+// UserMetadataInjectContext sets user metadata of files written with ctx.
+// metadata will be replaced when file overwritten.
+func UserMetadataInjectContext(ctx context.Context, meta UserMetadata) context.Context {
+	return context.WithValue(ctx, userMetadataCtx{}, maps.Clone(meta))
+}
+
+func UserMetadataFromContext(ctx context.Context) UserMetadata {
+	if v, ok := ctx.Value(userMetadataCtx{}).(UserMetadata); ok {
+		return v
+	}
+	return nil
+}
+
+// UserMetadataProvider could be implemented by FileInfo of backends which stores user metadata.
+//
+// errors.ErrUnsupported could be returned when metadata not loaded, like FileInfo from Readdir,
+// FileSystem.Stat should be used for it.
+type UserMetadataProvider interface {
+	UserMetadata(ctx context.Context) (UserMetadata, error)
+}
+
+// UserMetadataOf returns user metadata of info, nil when not provided
+func UserMetadataOf(ctx context.Context, info FileInfo) UserMetadata {
+	if p, ok := info.(UserMetadataProvider); ok {
+		if meta, err := p.UserMetadata(ctx); err == nil {
+			return meta
+		}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sort"
+	"strings"
+	"time"
+)
+
+type EventOp string
+
+const (
+	EventCreate EventOp = "create"
+	EventModify EventOp = "modify"
+	EventDelete EventOp = "delete"
+	EventRename EventOp = "rename"
+)
+
+// Event of file changed
+type Event struct {
+	Op EventOp `json:"op"`
+	// Path of file, starts with /
+	Path string `json:"path"`
+	// OldPath of file when renamed
+	OldPath string `json:"oldPath,omitempty"`
+	// User who made the change, only known when changed through servers of unifs
+	User string `json:"user,omitempty"`
+}
+
+// Watcher could be implemented by FileSystem which could watch changes natively, like inotify.
+// errors.ErrUnsupported could be yielded first to fall back to polling.
+//
+// events of all files under name should be yielded until ctx canceled or iteration stopped.
+type Watcher interface {
+	Watch(ctx context.Context, name string) iter.Seq2[*Event, error]
+}
+
+type WatchOption func(w *watcher)
+
+// WatchInterval sets interval of polling, default 10s
+func WatchInterval(interval time.Duration) WatchOption {
+	return func(w *watcher) {
+		w.interval = interval
+	}
+}
+
+type watcher struct {
+	interval time.Duration
+}
+
+// Watch iterates events of files under name until ctx canceled or iteration stopped.
+// Watcher will be used when implemented, otherwise polling by PollWatch.
+func Watch(ctx context.Context, fsys FileSystem, name string, opts ...WatchOption) iter.Seq2[*Event, error] {
+	return func(yield func(*Event, error) bool) {
+		if w, ok := fsys.(Watcher); ok {
+			watching := false
+			for e, err := range w.Watch(ctx, name) {
+				if !watching && errors.Is(err, errors.ErrUnsupported) {
+					break
+				}
+				watching = true
+				if !yield(e, err) {
+					return
+				}
+			}
+			if watching {
+				return
+			}
+		}
+
+		for e, err := range PollWatch(ctx, fsys, name, opts...) {
+			if !yield(e, err) {
+				return
+			}
+		}
+	}
+}
+
+// PollWatch diffs listings of files under name by interval.
+// files are listed by ListPrefix, so one request each time for s3,
+// and changes detected by ETag when provided, otherwise by size and mtime.
+// file deleted and created with same ETag and size will be treated as renamed.
+//
+// errors of listing will be yielded, and polling continues until ctx canceled or iteration stopped.
+func PollWatch(ctx context.Context, fsys FileSystem, name string, opts ...WatchOption) iter.Seq2[*Event, error] {
+	w := &watcher{interval: 10 * time.Second}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	prefix := slashClean(name)
+	if prefix != "/" {
+		prefix += "/"
+	}
+
+	return func(yield func(*Event, error) bool) {
+		var last map[string]fileState
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			current, err := snapshot(ctx, fsys, prefix)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if !yield(nil, err) {
+					return
+				}
+			} else {
+				if last != nil {
+					for _, e := range diff(last, current) {
+						if !yield(e, nil) {
+							return
+						}
+					}
+				}
+				last = current
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+	etag    string
+}
+
+func (s fileState) changed(o fileState) bool {
+	if s.etag != "" && o.etag != "" {
+		return s.etag != o.etag
+	}
+	return s.size != o.size || !s.modTime.Equal(o.modTime)
+}
+
+func snapshot(ctx context.Context, fsys FileSystem, prefix string) (map[string]fileState, error) {
+	states := map[string]fileState{}
+
+	for e, err := range ListPrefix(ctx, fsys, prefix) {
+		if err != nil {
+			return nil, err
+		}
+		if IsTempName(e.Path) || IsLockName(e.Path) {
+			continue
+		}
+		states[e.Path] = fileState{
+			size:    e.Size(),
+			modTime: e.ModTime(),
+			etag:    strings.Trim(ETagOf(ctx, e.FileInfo), `"`),
+		}
+	}
+
+	return states, nil
+}
+
+func diff(last map[string]fileState, current map[string]fileState) []*Event {
+	events := make([]*Event, 0)
+
+	created := map[string]fileState{}
+	deleted := map[string]fileState{}
+
+	for p, s := range current {
+		prev, ok := last[p]
+		if !ok {
+			created[p] = s
+			continue
+		}
+		if s.changed(prev) {
+			events = append(events, &Event{Op: EventModify, Path: p})
+		}
+	}
+
+	for p, s := range last {
+		if _, ok := current[p]; !ok {
+			deleted[p] = s
+		}
+	}
+
+	for _, oldPath := range sortedKeys(deleted) {
+		s := deleted[oldPath]
+
+		if s.etag != "" {
+			for _, p := range sortedKeys(created) {
+				if c := created[p]; c.etag == s.etag && c.size == s.size {
+					events = append(events, &Event{Op: EventRename, Path: p, OldPath: oldPath})
+					delete(created, p)
+					delete(deleted, oldPath)
+					break
+				}
+			}
+		}
+	}
+
+	for p := range deleted {
+		events = append(events, &Event{Op: EventDelete, Path: p})
+	}
+
+	for p := range created {
+		events = append(events, &Event{Op: EventCreate, Path: p})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Path < events[j].Path
+	})
+
+	return events
+}
+
+func sortedKeys(m map[string]fileState) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Contains checks name is dir or under dir
+func Contains(dir string, name string) bool {
+	dir = slashClean(dir)
+	return dir == "/" || name == dir || strings.HasPrefix(name, dir+"/")
+}
@@ -0,0 +1,68 @@
+package filesystem_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+)
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fsys := filesystem.NewMemFS()
+	_ = filesystem.MkdirAll(ctx, fsys, "/in")
+	_ = filesystem.Write(ctx, fsys, "/in/a.txt", []byte("1"))
+	_ = filesystem.Write(ctx, fsys, "/other.txt", []byte("1"))
+
+	events := make(chan *filesystem.Event, 16)
+
+	go func() {
+		for e, err := range filesystem.Watch(ctx, fsys, "/in", filesystem.WatchInterval(10*time.Millisecond)) {
+			if err == nil {
+				events <- e
+			}
+		}
+	}()
+
+	// wait first listing
+	time.Sleep(100 * time.Millisecond)
+
+	t.Run("polling diff", func(t *testing.T) {
+		_ = filesystem.Write(ctx, fsys, "/in/b.txt", []byte("1"))
+		_ = filesystem.Write(ctx, fsys, "/in/a.txt", []byte("22"))
+		_ = filesystem.Write(ctx, fsys, "/other.txt", []byte("22"))
+
+		testingx.Expect(t, receive(t, events, 2), testingx.Equal([]filesystem.Event{
+			{Op: filesystem.EventModify, Path: "/in/a.txt"},
+			{Op: filesystem.EventCreate, Path: "/in/b.txt"},
+		}))
+
+		_ = fsys.RemoveAll(ctx, "/in/b.txt")
+
+		testingx.Expect(t, receive(t, events, 1), testingx.Equal([]filesystem.Event{
+			{Op: filesystem.EventDelete, Path: "/in/b.txt"},
+		}))
+	})
+}
+
+func receive(t *testing.T, events <-chan *filesystem.Event, n int) []filesystem.Event {
+	received := make([]filesystem.Event, 0, n)
+
+	for len(received) < n {
+		select {
+		case e := <-events:
+			received = append(received, *e)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout, only %d events received", len(received))
+		}
+	}
+
+	sort.Slice(received, func(i, j int) bool { return received[i].Path < received[j].Path })
+	return received
+}
@@ -0,0 +1,76 @@
+package webdav
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/webdav/client"
+)
+
+var _ filesystem.AttrSetter = &fs{}
+
+// SetMode stores mode as dead property, since no live property of mode defined by webdav
+func (fs *fs) SetMode(ctx context.Context, name string, mode os.FileMode) error {
+	prop, err := client.EncodeProp(client.NewMode(mode))
+	if err != nil {
+		return err
+	}
+	return fs.propPatch(ctx, "chmod", name, prop)
+}
+
+// SetModTime sets getlastmodified by PROPPATCH, which is protected by most servers,
+// then falls back to dead property bound to current ETag, and ignored once content rewritten.
+// dirs are listed without mtime, so nothing set for them.
+func (fs *fs) SetModTime(ctx context.Context, name string, mtime time.Time) error {
+	info, err := fs.Stat(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return nil
+	}
+
+	prop, err := client.EncodeProp(&client.GetLastModified{LastModified: client.Time(mtime)})
+	if err != nil {
+		return err
+	}
+
+	err = fs.propPatch(ctx, "chtimes", name, prop)
+	if err == nil || !isProtected(err) {
+		return err
+	}
+
+	prop, err = client.EncodeProp(&client.ModTime{
+		ETag:    strings.Trim(filesystem.ETagOf(ctx, info), `"`),
+		ModTime: client.Time(mtime),
+	})
+	if err != nil {
+		return err
+	}
+	return fs.propPatch(ctx, "chtimes", name, prop)
+}
+
+func (fs *fs) propPatch(ctx context.Context, op string, name string, prop *client.Prop) error {
+	if err := fs.c.PropPatch(ctx, name, &client.PropertyUpdate{Set: []client.Set{{Prop: *prop}}}); err != nil {
+		if client.IsNotFound(err) {
+			return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+		}
+		return &os.PathError{Op: op, Path: name, Err: err}
+	}
+	return nil
+}
+
+// isProtected checks property rejected by server, https://tools.ietf.org/html/rfc4918#section-9.2
+func isProtected(err error) bool {
+	var httpErr *client.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code == http.StatusForbidden || httpErr.Code == http.StatusConflict
+	}
+	return false
+}
@@ -4,22 +4,34 @@ import (
 	"bytes"
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"mime"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"strings"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
 )
 
 type Client interface {
 	MkCol(ctx context.Context, name string) error
 	PropFind(ctx context.Context, path string, depth Depth, propfind *PropFind) (*MultiStatus, error)
+	PropFindIter(ctx context.Context, path string, depth Depth, propfind *PropFind) iter.Seq2[*Response, error]
+	PropPatch(ctx context.Context, path string, update *PropertyUpdate) error
 	Move(ctx context.Context, src string, dest string, overwrite bool) error
+	Copy(ctx context.Context, src string, dest string, depth Depth, overwrite bool) error
 	Delete(ctx context.Context, name string) error
 
+	Lock(ctx context.Context, name string, depth Depth, timeout Timeout, lockInfo *LockInfo) (*ActiveLock, error)
+	RefreshLock(ctx context.Context, name string, token string, timeout Timeout) (*ActiveLock, error)
+	Unlock(ctx context.Context, name string, token string) error
+
 	OpenWrite(ctx context.Context, name string) (io.WriteCloser, error)
 	Open(ctx context.Context, name string) (File, error)
 }
@@ -76,9 +88,25 @@ func (c *client) Open(ctx context.Context, name string) (File, error) {
 			}
 
 			if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+				_ = resp.Body.Close()
 				return nil, io.EOF
 			}
 
+			if resp.StatusCode >= http.StatusBadRequest {
+				_ = resp.Body.Close()
+				return nil, &HTTPError{
+					Code: resp.StatusCode,
+				}
+			}
+
+			// server ignored Range
+			if resp.StatusCode == http.StatusOK && offset > 0 {
+				if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+					_ = resp.Body.Close()
+					return nil, err
+				}
+			}
+
 			return resp.Body, nil
 		},
 	}
@@ -106,19 +134,91 @@ func setRange(o http.Header, start, end int64) {
 
 func (c *client) OpenWrite(ctx context.Context, name string) (io.WriteCloser, error) {
 	pr, pw := io.Pipe()
+
+	w := &writeCloser{
+		PipeWriter: pw,
+		ctx:        ctx,
+		done:       make(chan error, 1),
+	}
+
+	ctx, w.cancel = context.WithCancelCause(ctx)
+
 	go func() {
-		req, err := c.req(ctx, http.MethodPut, name, pr)
-		if err != nil {
-			return
-		}
-		resp, err := c.do(req)
-		if err != nil {
-			return
-		}
-		_ = resp.Body.Close()
+		defer w.cancel(nil)
+
+		accepted, err := c.put(ctx, name, pr)
+		// unblock writer when request failed before body consumed
+		_ = pr.CloseWithError(err)
+		w.modTimeAccepted = accepted
+		w.done <- err
 	}()
 
-	return pw, nil
+	return w, nil
+}
+
+// put uploads body, with mtime injected by filesystem.ModTimeInjectContext as X-OC-Mtime header,
+// returns whether the mtime accepted by server, like ownCloud and Nextcloud.
+func (c *client) put(ctx context.Context, name string, body io.Reader) (bool, error) {
+	req, err := c.req(ctx, http.MethodPut, name, body)
+	if err != nil {
+		return false, err
+	}
+
+	mtime, ok := filesystem.ModTimeFromContext(ctx)
+	if ok {
+		req.Header.Set("X-OC-Mtime", strconv.FormatInt(mtime.Unix(), 10))
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return false, &HTTPError{
+			Code: resp.StatusCode,
+		}
+	}
+
+	return ok && resp.Header.Get("X-OC-Mtime") == "accepted", nil
+}
+
+type writeCloser struct {
+	*io.PipeWriter
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	done   chan error
+
+	modTimeAccepted bool
+}
+
+// ModTimeAccepted checks mtime of X-OC-Mtime accepted by server, only valid after closed
+func (w *writeCloser) ModTimeAccepted() bool {
+	return w.modTimeAccepted
+}
+
+// Close closes the body and waits the PUT request done.
+func (w *writeCloser) Close() error {
+	// body should not be completed when canceled
+	if w.ctx.Err() != nil {
+		err := context.Cause(w.ctx)
+		_ = w.Abort(err)
+		return err
+	}
+
+	if err := w.PipeWriter.Close(); err != nil {
+		return err
+	}
+	return <-w.done
+}
+
+// Abort cancels the PUT request before the body completed, and waits the request done.
+func (w *writeCloser) Abort(err error) error {
+	_ = w.PipeWriter.CloseWithError(err)
+	w.cancel(err)
+	<-w.done
+	return nil
 }
 
 func (c *client) Move(ctx context.Context, src string, dest string, overwrite bool) error {
@@ -138,6 +238,24 @@ func (c *client) Move(ctx context.Context, src string, dest string, overwrite bo
 	return c.doSimple(r)
 }
 
+func (c *client) Copy(ctx context.Context, src string, dest string, depth Depth, overwrite bool) error {
+	d, err := c.ResolveHref(dest)
+	if err != nil {
+		return err
+	}
+
+	r, err := c.req(ctx, "COPY", src, nil)
+	if err != nil {
+		return err
+	}
+
+	r.Header.Set("Destination", d.String())
+	r.Header.Set("Overwrite", FormatOverwrite(overwrite))
+	r.Header.Set("Depth", depth.String())
+
+	return c.doSimple(r)
+}
+
 func (c *client) Delete(ctx context.Context, name string) error {
 	r, err := c.req(ctx, "DELETE", name, nil)
 	if err != nil {
@@ -175,6 +293,170 @@ func (c *client) PropFind(ctx context.Context, path string, depth Depth, propfin
 	return c.doMultiStatus(r)
 }
 
+// PropFindIter decodes Response elements one by one, without loading whole multistatus
+func (c *client) PropFindIter(ctx context.Context, path string, depth Depth, propfind *PropFind) iter.Seq2[*Response, error] {
+	return func(yield func(*Response, error) bool) {
+		if propfind == nil {
+			propfind = FileInfoPropFind
+		}
+
+		r, err := c.reqXML(ctx, "PROPFIND", path, propfind)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		r.Header.Add("Depth", depth.String())
+
+		resp, err := c.do(r)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusMultiStatus {
+			yield(NewErrorResponse(r.URL.Path, &HTTPError{Code: resp.StatusCode}), nil)
+			return
+		}
+
+		d := xml.NewDecoder(resp.Body)
+
+		for {
+			tok, err := d.Token()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					yield(nil, err)
+				}
+				return
+			}
+
+			se, ok := tok.(xml.StartElement)
+			if !ok || se.Name != responseName {
+				continue
+			}
+
+			resp := &Response{}
+			if err := d.DecodeElement(resp, &se); err != nil {
+				yield(nil, err)
+				return
+			}
+			resp.Prefix = c.endpoint.Path
+
+			if !yield(resp, nil) {
+				return
+			}
+		}
+	}
+}
+
+var responseName = xml.Name{Space: Namespace, Local: "response"}
+
+func (c *client) PropPatch(ctx context.Context, path string, update *PropertyUpdate) error {
+	r, err := c.reqXML(ctx, "PROPPATCH", path, update)
+	if err != nil {
+		return err
+	}
+
+	ms, err := c.doMultiStatus(r)
+	if err != nil {
+		return err
+	}
+
+	for _, resp := range ms.Responses {
+		if err := resp.Err(); err != nil {
+			return err
+		}
+		for _, propStat := range resp.PropStats {
+			if err := propStat.Status.Err(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *client) Lock(ctx context.Context, name string, depth Depth, timeout Timeout, lockInfo *LockInfo) (*ActiveLock, error) {
+	r, err := c.reqXML(ctx, "LOCK", name, lockInfo)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Depth", depth.String())
+	r.Header.Set("Timeout", timeout.String())
+	return c.doLock(r)
+}
+
+func (c *client) RefreshLock(ctx context.Context, name string, token string, timeout Timeout) (*ActiveLock, error) {
+	r, err := c.req(ctx, "LOCK", name, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("If", fmt.Sprintf("(<%s>)", token))
+	r.Header.Set("Timeout", timeout.String())
+
+	l, err := c.doLock(r)
+	if err != nil {
+		return nil, err
+	}
+	if l.LockToken.String() == "" {
+		if err := l.LockToken.UnmarshalText([]byte(token)); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func (c *client) Unlock(ctx context.Context, name string, token string) error {
+	r, err := c.req(ctx, "UNLOCK", name, nil)
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Lock-Token", fmt.Sprintf("<%s>", token))
+	return c.doSimple(r)
+}
+
+// doLock returns the active lock of Lock-Token from response, or the first one when refreshed
+func (c *client) doLock(req *http.Request) (*ActiveLock, error) {
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, &HTTPError{
+			Code: resp.StatusCode,
+		}
+	}
+
+	var prop struct {
+		XMLName       xml.Name      `xml:"DAV: prop"`
+		LockDiscovery LockDiscovery `xml:"lockdiscovery"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&prop); err != nil {
+		return nil, err
+	}
+
+	token := strings.Trim(resp.Header.Get("Lock-Token"), "<>")
+
+	for i := range prop.LockDiscovery.ActiveLocks {
+		l := &prop.LockDiscovery.ActiveLocks[i]
+		if token == "" || l.LockToken.String() == token {
+			return l, nil
+		}
+	}
+
+	if token != "" {
+		l := &ActiveLock{}
+		if err := l.LockToken.UnmarshalText([]byte(token)); err != nil {
+			return nil, err
+		}
+		return l, nil
+	}
+
+	return nil, fmt.Errorf("webdav: no active lock in response")
+}
+
 func (c *client) reqXML(ctx context.Context, method string, path string, v any) (*http.Request, error) {
 	buf := bytes.NewBuffer(nil)
 	buf.WriteString(xml.Header)
@@ -199,7 +481,21 @@ func (c *client) req(ctx context.Context, method string, path string, reader io.
 	if err != nil {
 		return nil, err
 	}
-	return http.NewRequestWithContext(ctx, method, h.String(), reader)
+	req, err := http.NewRequestWithContext(ctx, method, h.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+
+	// submit tokens of locks held, or requests will be rejected by server when locked
+	if tokens := filesystem.LockTokensFromContext(ctx); len(tokens) > 0 {
+		lists := make([]string, len(tokens))
+		for i, token := range tokens {
+			lists[i] = fmt.Sprintf("(<%s>)", token)
+		}
+		req.Header.Set("If", strings.Join(lists, " "))
+	}
+
+	return req, nil
 }
 
 func (c *client) ResolveHref(p string) (*url.URL, error) {
@@ -243,7 +539,7 @@ func (c *client) doMultiStatus(req *http.Request) (*MultiStatus, error) {
 		)), nil
 	}
 
-	// TODO: the response can be quite large, support streaming Response elements
+	// the response can be quite large, PropFindIter should be used for listing
 	var ms MultiStatus
 	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
 		return nil, err
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"errors"
 	"io"
 	"os"
 	"sync"
@@ -10,6 +11,7 @@ import (
 
 type File interface {
 	io.Seeker
+	io.ReaderAt
 	io.ReadCloser
 }
 
@@ -76,6 +78,32 @@ func (f *file) Read(p []byte) (n int, err error) {
 	return readBytes, nil
 }
 
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+
+	if off >= f.info.Size() {
+		return 0, io.EOF
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	body, err := f.doRequest(off, off+int64(len(p))-1)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	n, err := io.ReadFull(body, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return n, io.EOF
+	}
+	return n, err
+}
+
 func (f *file) Close() error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
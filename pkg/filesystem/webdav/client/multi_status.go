@@ -62,7 +62,57 @@ func (resp *Response) FileInfo() (filesystem.FileInfo, error) {
 		return nil, err
 	}
 
-	return fsutil.NewFileInfo(path.Base(pathname), getLen.Length, time.Time(getLastModified.LastModified)), nil
+	var getETag GetETag
+	if err := resp.DecodeProp(&getETag); err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+
+	var getContentType GetContentType
+	if err := resp.DecodeProp(&getContentType); err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+
+	var userMetadata UserMetadata
+	if err := resp.DecodeProp(&userMetadata); err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+
+	opts := []fsutil.FileInfoOption{
+		fsutil.WithETag(string(getETag.ETag)),
+		fsutil.WithContentType(getContentType.Type),
+		fsutil.WithUserMetadata(userMetadata.Metadata()),
+	}
+
+	var linkTarget LinkTarget
+	if err := resp.DecodeProp(&linkTarget); err != nil {
+		if !IsNotFound(err) {
+			return nil, err
+		}
+	} else {
+		opts = append(opts, fsutil.WithSymlink())
+	}
+
+	var mode Mode
+	if err := resp.DecodeProp(&mode); err != nil {
+		if !IsNotFound(err) {
+			return nil, err
+		}
+	} else if m, ok := mode.FileMode(); ok {
+		opts = append(opts, fsutil.WithMode(m))
+	}
+
+	modTime := time.Time(getLastModified.LastModified)
+
+	var setModTime ModTime
+	if err := resp.DecodeProp(&setModTime); err != nil {
+		if !IsNotFound(err) {
+			return nil, err
+		}
+	} else if setModTime.ETag == string(getETag.ETag) {
+		modTime = time.Time(setModTime.ModTime)
+	}
+
+	return fsutil.NewFileInfo(path.Base(pathname), getLen.Length, modTime, opts...), nil
 }
 
 func NewOKResponse(path string) *Response {
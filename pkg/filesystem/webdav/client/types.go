@@ -3,24 +3,122 @@ package client
 import (
 	"encoding/xml"
 	"fmt"
+	"maps"
 	"net/http"
+	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
 )
 
 const Namespace = "DAV:"
 
 var (
-	ResourceTypeName     = xml.Name{Namespace, "resourcetype"}
-	DisplayNameName      = xml.Name{Namespace, "displayname"}
-	GetContentLengthName = xml.Name{Namespace, "getcontentlength"}
-	GetContentTypeName   = xml.Name{Namespace, "getcontenttype"}
-	GetLastModifiedName  = xml.Name{Namespace, "getlastmodified"}
-	GetETagName          = xml.Name{Namespace, "getetag"}
-
-	CurrentUserPrincipalName = xml.Name{Namespace, "current-user-principal"}
+	ResourceTypeName     = xml.Name{Space: Namespace, Local: "resourcetype"}
+	DisplayNameName      = xml.Name{Space: Namespace, Local: "displayname"}
+	GetContentLengthName = xml.Name{Space: Namespace, Local: "getcontentlength"}
+	GetContentTypeName   = xml.Name{Space: Namespace, Local: "getcontenttype"}
+	GetLastModifiedName  = xml.Name{Space: Namespace, Local: "getlastmodified"}
+	GetETagName          = xml.Name{Space: Namespace, Local: "getetag"}
+
+	CurrentUserPrincipalName = xml.Name{Space: Namespace, Local: "current-user-principal"}
 )
 
+const OwnCloudNamespace = "http://owncloud.org/ns"
+
+var ChecksumsName = xml.Name{Space: OwnCloudNamespace, Local: "checksums"}
+
+// Checksums of ownCloud and Nextcloud, each checksum like `SHA1:<hex> MD5:<hex> ADLER32:<hex>`
+type Checksums struct {
+	XMLName   xml.Name `xml:"http://owncloud.org/ns checksums"`
+	Checksums []string `xml:"checksum"`
+}
+
+// Get returns hex checksum of algorithm like SHA1, MD5
+func (c *Checksums) Get(algorithm string) (string, bool) {
+	for _, line := range c.Checksums {
+		for _, field := range strings.Fields(line) {
+			if a, sum, ok := strings.Cut(field, ":"); ok && strings.EqualFold(a, algorithm) {
+				return strings.ToLower(sum), true
+			}
+		}
+	}
+	return "", false
+}
+
+const UnifsNamespace = "https://github.com/octohelm/unifs"
+
+var UserMetadataName = xml.Name{Space: UnifsNamespace, Local: "metadata"}
+
+// UserMetadata stored as dead property
+type UserMetadata struct {
+	XMLName xml.Name            `xml:"https://github.com/octohelm/unifs metadata"`
+	Entries []UserMetadataEntry `xml:"https://github.com/octohelm/unifs entry"`
+}
+
+func NewUserMetadata(meta filesystem.UserMetadata) *UserMetadata {
+	m := &UserMetadata{}
+	for _, k := range slices.Sorted(maps.Keys(meta)) {
+		m.Entries = append(m.Entries, UserMetadataEntry{Key: k, Value: meta[k]})
+	}
+	return m
+}
+
+func (m *UserMetadata) Metadata() filesystem.UserMetadata {
+	meta := filesystem.UserMetadata{}
+	for _, e := range m.Entries {
+		meta[e.Key] = e.Value
+	}
+	return meta
+}
+
+type UserMetadataEntry struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+var LinkTargetName = xml.Name{Space: UnifsNamespace, Local: "link-target"}
+
+// LinkTarget of symbolic link emulated by file, stored as dead property
+type LinkTarget struct {
+	XMLName xml.Name `xml:"https://github.com/octohelm/unifs link-target"`
+	Target  string   `xml:",chardata"`
+}
+
+var ModeName = xml.Name{Space: UnifsNamespace, Local: "mode"}
+
+// Mode of permission bits in octal, stored as dead property
+type Mode struct {
+	XMLName xml.Name `xml:"https://github.com/octohelm/unifs mode"`
+	Mode    string   `xml:",chardata"`
+}
+
+func NewMode(mode os.FileMode) *Mode {
+	return &Mode{Mode: strconv.FormatUint(uint64(mode.Perm()), 8)}
+}
+
+func (m *Mode) FileMode() (os.FileMode, bool) {
+	v, err := strconv.ParseUint(m.Mode, 8, 32)
+	if err != nil {
+		return 0, false
+	}
+	return os.FileMode(v).Perm(), true
+}
+
+var ModTimeName = xml.Name{Space: UnifsNamespace, Local: "mtime"}
+
+// ModTime stored as dead property when getlastmodified protected by server,
+// only valid for content of the ETag, so it will be ignored once content rewritten.
+// values stored as elements, since attributes of dead properties could be dropped by servers.
+type ModTime struct {
+	XMLName xml.Name `xml:"https://github.com/octohelm/unifs mtime"`
+	ETag    string   `xml:"https://github.com/octohelm/unifs etag"`
+	ModTime Time     `xml:"https://github.com/octohelm/unifs time"`
+}
+
 // https://tools.ietf.org/html/rfc4918#section-14.9
 type Location struct {
 	XMLName xml.Name `xml:"DAV: location"`
@@ -105,6 +203,10 @@ var FileInfoPropFind = NewPropNamePropFind(
 	GetLastModifiedName,
 	GetContentTypeName,
 	GetETagName,
+	UserMetadataName,
+	LinkTargetName,
+	ModeName,
+	ModTimeName,
 )
 
 // https://tools.ietf.org/html/rfc4918#section-14.8
@@ -132,7 +234,7 @@ func (t *ResourceType) Is(name xml.Name) bool {
 	return false
 }
 
-var CollectionName = xml.Name{Namespace, "collection"}
+var CollectionName = xml.Name{Space: Namespace, Local: "collection"}
 
 // https://tools.ietf.org/html/rfc4918#section-15.4
 type GetContentLength struct {
@@ -251,6 +353,91 @@ type Limit struct {
 	NResults uint     `xml:"nresults"`
 }
 
+var LockDiscoveryName = xml.Name{Space: Namespace, Local: "lockdiscovery"}
+
+// https://tools.ietf.org/html/rfc4918#section-14.11
+type LockInfo struct {
+	XMLName   xml.Name  `xml:"DAV: lockinfo"`
+	LockScope LockScope `xml:"lockscope"`
+	LockType  LockType  `xml:"locktype"`
+	Owner     *Owner    `xml:"owner,omitzero"`
+}
+
+// https://tools.ietf.org/html/rfc4918#section-14.13
+type LockScope struct {
+	Exclusive *struct{} `xml:"exclusive,omitzero"`
+	Shared    *struct{} `xml:"shared,omitzero"`
+}
+
+// https://tools.ietf.org/html/rfc4918#section-14.15
+type LockType struct {
+	Write *struct{} `xml:"write,omitzero"`
+}
+
+// https://tools.ietf.org/html/rfc4918#section-14.17
+type Owner struct {
+	InnerXML string `xml:",innerxml"`
+}
+
+// https://tools.ietf.org/html/rfc4918#section-14.1
+type ActiveLock struct {
+	XMLName   xml.Name  `xml:"DAV: activelock"`
+	LockScope LockScope `xml:"lockscope"`
+	LockType  LockType  `xml:"locktype"`
+	Depth     Depth     `xml:"depth"`
+	Owner     *Owner    `xml:"owner,omitzero"`
+	Timeout   Timeout   `xml:"timeout,omitzero"`
+	LockToken Href      `xml:"locktoken>href"`
+	LockRoot  Href      `xml:"lockroot>href"`
+}
+
+// https://tools.ietf.org/html/rfc4918#section-15.8
+type LockDiscovery struct {
+	XMLName     xml.Name     `xml:"DAV: lockdiscovery"`
+	ActiveLocks []ActiveLock `xml:"activelock"`
+}
+
+// Timeout of lock, zero for Infinite.
+// https://tools.ietf.org/html/rfc4918#section-10.7
+type Timeout time.Duration
+
+// ParseTimeout parses the first value of Timeout header, like `Second-3600, Infinite`
+func ParseTimeout(s string) (Timeout, error) {
+	s, _, _ = strings.Cut(s, ",")
+	s = strings.TrimSpace(s)
+	if s == "Infinite" {
+		return 0, nil
+	}
+	if v, ok := strings.CutPrefix(s, "Second-"); ok {
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err == nil {
+			return Timeout(time.Duration(n) * time.Second), nil
+		}
+	}
+	return 0, fmt.Errorf("webdav: invalid Timeout value")
+}
+
+// String formats the timeout.
+func (t Timeout) String() string {
+	if t <= 0 {
+		return "Infinite"
+	}
+	return fmt.Sprintf("Second-%d", int64(time.Duration(t)/time.Second))
+}
+
+func (t *Timeout) UnmarshalText(b []byte) error {
+	v, err := ParseTimeout(string(b))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+func (t Timeout) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
 // Depth indicates whether a request applies to the resource's members. It's
 // defined in RFC 4918 section 10.2.
 type Depth int
@@ -292,6 +479,19 @@ func (d Depth) String() string {
 	panic("webdav: invalid Depth value")
 }
 
+func (d *Depth) UnmarshalText(b []byte) error {
+	v, err := ParseDepth(string(b))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+func (d Depth) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
 // ParseOverwrite parses an Overwrite header.
 func ParseOverwrite(s string) (bool, error) {
 	switch s {
@@ -4,6 +4,52 @@ DON'T EDIT THIS FILE
 */
 package client
 
+func (v *ActiveLock) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "XMLName":
+			return []string{}, true
+		case "LockScope":
+			return []string{}, true
+		case "LockType":
+			return []string{}, true
+		case "Depth":
+			return []string{}, true
+		case "Owner":
+			return []string{}, true
+		case "Timeout":
+			return []string{}, true
+		case "LockToken":
+			return []string{}, true
+		case "LockRoot":
+			return []string{}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{
+		"https://tools.ietf.org/html/rfc4918#section-14.1",
+	}, true
+}
+
+func (v *Checksums) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "XMLName":
+			return []string{}, true
+		case "Checksums":
+			return []string{}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{
+		"Checksums of ownCloud and Nextcloud, each checksum like `SHA1:<hex> MD5:<hex> ADLER32:<hex>`",
+	}, true
+}
+
 func (v *CurrentUserPrincipal) RuntimeDoc(names ...string) ([]string, bool) {
 	if len(names) > 0 {
 		switch names[0] {
@@ -236,6 +282,23 @@ func (v *Limit) RuntimeDoc(names ...string) ([]string, bool) {
 	}, true
 }
 
+func (v *LinkTarget) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "XMLName":
+			return []string{}, true
+		case "Target":
+			return []string{}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{
+		"LinkTarget of symbolic link emulated by file, stored as dead property",
+	}, true
+}
+
 func (v *Location) RuntimeDoc(names ...string) ([]string, bool) {
 	if len(names) > 0 {
 		switch names[0] {
@@ -253,6 +316,114 @@ func (v *Location) RuntimeDoc(names ...string) ([]string, bool) {
 	}, true
 }
 
+func (v *LockDiscovery) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "XMLName":
+			return []string{}, true
+		case "ActiveLocks":
+			return []string{}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{
+		"https://tools.ietf.org/html/rfc4918#section-15.8",
+	}, true
+}
+
+func (v *LockInfo) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "XMLName":
+			return []string{}, true
+		case "LockScope":
+			return []string{}, true
+		case "LockType":
+			return []string{}, true
+		case "Owner":
+			return []string{}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{
+		"https://tools.ietf.org/html/rfc4918#section-14.11",
+	}, true
+}
+
+func (v *LockScope) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "Exclusive":
+			return []string{}, true
+		case "Shared":
+			return []string{}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{
+		"https://tools.ietf.org/html/rfc4918#section-14.13",
+	}, true
+}
+
+func (v *LockType) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "Write":
+			return []string{}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{
+		"https://tools.ietf.org/html/rfc4918#section-14.15",
+	}, true
+}
+
+func (v *ModTime) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "XMLName":
+			return []string{}, true
+		case "ETag":
+			return []string{}, true
+		case "ModTime":
+			return []string{}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{
+		"ModTime stored as dead property when getlastmodified protected by server,",
+		"only valid for content of the ETag, so it will be ignored once content rewritten.",
+		"values stored as elements, since attributes of dead properties could be dropped by servers.",
+	}, true
+}
+
+func (v *Mode) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "XMLName":
+			return []string{}, true
+		case "Mode":
+			return []string{}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{
+		"Mode of permission bits in octal, stored as dead property",
+	}, true
+}
+
 func (v *MultiStatus) RuntimeDoc(names ...string) ([]string, bool) {
 	if len(names) > 0 {
 		switch names[0] {
@@ -274,6 +445,21 @@ func (v *MultiStatus) RuntimeDoc(names ...string) ([]string, bool) {
 	}, true
 }
 
+func (v *Owner) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "InnerXML":
+			return []string{}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{
+		"https://tools.ietf.org/html/rfc4918#section-14.17",
+	}, true
+}
+
 func (v *Prop) RuntimeDoc(names ...string) ([]string, bool) {
 	if len(names) > 0 {
 		switch names[0] {
@@ -475,6 +661,45 @@ func (v *SyncCollectionQuery) RuntimeDoc(names ...string) ([]string, bool) {
 }
 
 // nolint:deadcode,unused
+func (*Timeout) RuntimeDoc(names ...string) ([]string, bool) {
+	return []string{
+		"of lock, zero for Infinite.",
+		"https://tools.ietf.org/html/rfc4918#section-10.7",
+	}, true
+}
+
+func (v *UserMetadata) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "XMLName":
+			return []string{}, true
+		case "Entries":
+			return []string{}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{
+		"UserMetadata stored as dead property",
+	}, true
+}
+
+func (v *UserMetadataEntry) RuntimeDoc(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "Key":
+			return []string{}, true
+		case "Value":
+			return []string{}, true
+
+		}
+
+		return nil, false
+	}
+	return []string{}, true
+}
+
 func runtimeDoc(v any, prefix string, names ...string) ([]string, bool) {
 	if c, ok := v.(interface {
 		RuntimeDoc(names ...string) ([]string, bool)
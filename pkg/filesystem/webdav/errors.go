@@ -2,6 +2,9 @@ package webdav
 
 import (
 	"errors"
+	"net/http"
+
+	"github.com/octohelm/unifs/pkg/filesystem/webdav/client"
 )
 
 var (
@@ -10,3 +13,15 @@ var (
 	ErrAlreadyOpened  = errors.New("already opened")
 	ErrInvalidSeek    = errors.New("invalid seek")
 )
+
+// IsTransientError checks err is response of busy, locked or bad gateway
+func (fs *fs) IsTransientError(err error) bool {
+	var httpErr *client.HTTPError
+	if errors.As(err, &httpErr) {
+		switch httpErr.Code {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout, http.StatusLocked, http.StatusTooManyRequests:
+			return true
+		}
+	}
+	return false
+}
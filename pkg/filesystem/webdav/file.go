@@ -1,14 +1,19 @@
 package webdav
 
 import (
+	"cmp"
 	"context"
 	"io"
+	iofs "io/fs"
+	"iter"
 	"os"
 	"strings"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 
 	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/fsutil"
 	"github.com/octohelm/unifs/pkg/filesystem/webdav/client"
 )
 
@@ -16,6 +21,12 @@ type file struct {
 	node   *node
 	writer io.WriteCloser
 	file   client.File
+
+	userMetadata filesystem.UserMetadata
+	// injected by filesystem.ModTimeInjectContext, set after written when X-OC-Mtime not accepted
+	modTime   time.Time
+	dirReader *fsutil.DirReader
+	aborted   bool
 }
 
 func (f *file) c() client.Client {
@@ -25,52 +36,55 @@ func (f *file) c() client.Client {
 func (f *file) Name() string { return f.node.name }
 
 func (f *file) Readdir(n int) ([]os.FileInfo, error) {
-	// ListObjects treats leading slashes as part of the directory name
-	// It also needs a trailing slash to list contents of a directory.
-	name := strings.TrimPrefix(f.Name(), "/")
-
-	// For the root of the bucket, we need to remove any prefix
-	if name != "" && !strings.HasSuffix(name, "/") {
-		name += "/"
-	}
-
-	ms, err := f.c().PropFind(context.Background(), name, 1, client.FileInfoPropFind)
-	if err != nil {
-		return nil, err
-	}
-
-	var fileInfos []os.FileInfo
-
-	if n > 0 {
-		fileInfos = make([]filesystem.FileInfo, 0, len(ms.Responses))
+	if f.dirReader == nil {
+		f.dirReader = fsutil.NewDirReader(f.infos(context.Background()))
 	}
+	return f.dirReader.Readdir(n)
+}
 
-	idx := 0
+func (f *file) ReadDirIter(ctx context.Context) iter.Seq2[iofs.DirEntry, error] {
+	return fsutil.DirEntries(f.infos(ctx))
+}
 
-	for _, resp := range ms.Responses {
-		p, err := resp.Path()
-		if err != nil {
-			return nil, err
-		}
+// infos parses responses of PROPFIND incrementally
+func (f *file) infos(ctx context.Context) iter.Seq2[os.FileInfo, error] {
+	return func(yield func(os.FileInfo, error) bool) {
+		// ListObjects treats leading slashes as part of the directory name
+		// It also needs a trailing slash to list contents of a directory.
+		name := strings.TrimPrefix(f.Name(), "/")
 
-		if p == f.Name() || (p == f.Name()+"/") {
-			continue
+		// For the root of the bucket, we need to remove any prefix
+		if name != "" && !strings.HasSuffix(name, "/") {
+			name += "/"
 		}
 
-		fi, err := resp.FileInfo()
-		if err != nil {
-			return nil, err
-		}
-
-		fileInfos = append(fileInfos, fi)
-
-		idx++
-		if n > 0 && idx > n {
-			break
+		for resp, err := range f.c().PropFindIter(ctx, name, 1, client.FileInfoPropFind) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			p, err := resp.Path()
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if p == f.Name() || (p == f.Name()+"/") {
+				continue
+			}
+
+			fi, err := resp.FileInfo()
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(fi, nil) {
+				return
+			}
 		}
 	}
-
-	return fileInfos, nil
 }
 
 func (f *file) Stat() (os.FileInfo, error) {
@@ -79,11 +93,25 @@ func (f *file) Stat() (os.FileInfo, error) {
 }
 
 func (f *file) Close() error {
+	if f.dirReader != nil {
+		f.dirReader.Close()
+	}
+
+	if f.aborted {
+		return nil
+	}
+
 	eg := errgroup.Group{}
 
 	eg.Go(func() error {
 		if f.writer != nil {
-			return f.writer.Close()
+			if err := f.writer.Close(); err != nil {
+				return err
+			}
+			if err := f.setUserMetadata(); err != nil {
+				return err
+			}
+			return f.setModTime()
 		}
 		return nil
 	})
@@ -98,6 +126,53 @@ func (f *file) Close() error {
 	return eg.Wait()
 }
 
+// Abort cancels the PUT request, and user metadata will not be set
+func (f *file) Abort(err error) error {
+	if f.aborted {
+		return nil
+	}
+	f.aborted = true
+
+	if f.writer != nil {
+		if a, ok := f.writer.(filesystem.FileAborter); ok {
+			return a.Abort(cmp.Or(err, filesystem.ErrAborted))
+		}
+		return f.writer.Close()
+	}
+
+	if f.file != nil {
+		return f.file.Close()
+	}
+
+	return nil
+}
+
+// setUserMetadata sets user metadata as dead property after content written
+func (f *file) setUserMetadata() error {
+	if len(f.userMetadata) == 0 {
+		return nil
+	}
+
+	prop, err := client.EncodeProp(client.NewUserMetadata(f.userMetadata))
+	if err != nil {
+		return err
+	}
+
+	return f.c().PropPatch(context.Background(), f.Name(), &client.PropertyUpdate{
+		Set: []client.Set{{Prop: *prop}},
+	})
+}
+
+func (f *file) setModTime() error {
+	if f.modTime.IsZero() {
+		return nil
+	}
+	if a, ok := f.writer.(interface{ ModTimeAccepted() bool }); ok && a.ModTimeAccepted() {
+		return nil
+	}
+	return f.node.root.SetModTime(context.Background(), f.Name(), f.modTime)
+}
+
 func (f *file) Write(p []byte) (int, error) {
 	if f.writer == nil {
 		return 0, os.ErrInvalid
@@ -109,6 +184,13 @@ func (f *file) Seek(offset int64, whence int) (int64, error) {
 	return f.file.Seek(offset, whence)
 }
 
+func (f *file) ReadAt(p []byte, off int64) (n int, err error) {
+	if f.file == nil {
+		return 0, os.ErrInvalid
+	}
+	return f.file.ReadAt(p, off)
+}
+
 func (f *file) Read(p []byte) (n int, err error) {
 	if f.file == nil {
 		return 0, os.ErrInvalid
@@ -0,0 +1,55 @@
+package webdav
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/webdav/client"
+)
+
+var _ filesystem.Hasher = &fs{}
+
+var checksumsPropFind = client.NewPropNamePropFind(client.ChecksumsName)
+
+// Hash returns checksums from ownCloud and Nextcloud, which supports md5 and sha1 only.
+// errors.ErrUnsupported returned when not available.
+func (fs *fs) Hash(ctx context.Context, name string, algo filesystem.HashAlgorithm) (string, error) {
+	algorithm := ""
+
+	switch algo {
+	case filesystem.HashMD5:
+		algorithm = "MD5"
+	case filesystem.HashSHA1:
+		algorithm = "SHA1"
+	default:
+		return "", errors.ErrUnsupported
+	}
+
+	ms, err := fs.c.PropFind(ctx, name, 0, checksumsPropFind)
+	if err != nil {
+		if client.IsNotFound(err) {
+			return "", &os.PathError{Op: "hash", Path: name, Err: os.ErrNotExist}
+		}
+		return "", err
+	}
+
+	if len(ms.Responses) != 1 {
+		return "", errors.ErrUnsupported
+	}
+
+	var checksums client.Checksums
+	if err := ms.Responses[0].DecodeProp(&checksums); err != nil {
+		if _, err := ms.Responses[0].Path(); client.IsNotFound(err) {
+			return "", &os.PathError{Op: "hash", Path: name, Err: os.ErrNotExist}
+		}
+		return "", errors.ErrUnsupported
+	}
+
+	if sum, ok := checksums.Get(algorithm); ok {
+		return sum, nil
+	}
+
+	return "", errors.ErrUnsupported
+}
@@ -0,0 +1,87 @@
+package webdav
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/webdav/client"
+)
+
+var _ filesystem.Linker = &fs{}
+
+// Symlink emulates link as file with target as content, and target stored as dead property,
+// so links could be known by PROPFIND, both Stat and Readdir.
+func (fs *fs) Symlink(ctx context.Context, target string, name string) error {
+	if _, err := fs.Stat(ctx, name); err == nil {
+		return &os.LinkError{Op: "symlink", Old: target, New: name, Err: os.ErrExist}
+	}
+
+	if parent := path.Dir(name); parent != "/" {
+		if _, err := fs.Stat(ctx, parent); err != nil {
+			return err
+		}
+	}
+
+	w, err := fs.c.OpenWrite(ctx, name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, target); err != nil {
+		_ = w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	prop, err := client.EncodeProp(&client.LinkTarget{Target: target})
+	if err != nil {
+		return err
+	}
+
+	if err := fs.c.PropPatch(ctx, name, &client.PropertyUpdate{Set: []client.Set{{Prop: *prop}}}); err != nil {
+		// file left will be treated as regular file
+		_ = fs.c.Delete(context.WithoutCancel(ctx), name)
+		return &os.LinkError{Op: "symlink", Old: target, New: name, Err: err}
+	}
+
+	return nil
+}
+
+func (fs *fs) Readlink(ctx context.Context, name string) (string, error) {
+	ms, err := fs.c.PropFind(ctx, name, 0, client.NewPropNamePropFind(client.LinkTargetName))
+	if err != nil {
+		return "", err
+	}
+
+	if len(ms.Responses) != 1 {
+		return "", fmt.Errorf("PROPFIND with Depth: 0 returned %d responses", len(ms.Responses))
+	}
+
+	if err := ms.Responses[0].Err(); err != nil {
+		if client.IsNotFound(err) {
+			return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrNotExist}
+		}
+		return "", err
+	}
+
+	var linkTarget client.LinkTarget
+	if err := ms.Responses[0].DecodeProp(&linkTarget); err != nil {
+		if client.IsNotFound(err) {
+			return "", &os.PathError{Op: "readlink", Path: name, Err: syscall.EINVAL}
+		}
+		return "", err
+	}
+
+	return linkTarget.Target, nil
+}
+
+// Lstat same as Stat, links are never followed
+func (fs *fs) Lstat(ctx context.Context, name string) (os.FileInfo, error) {
+	return fs.Stat(ctx, name)
+}
@@ -0,0 +1,140 @@
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/webdav/client"
+)
+
+var _ filesystem.Locker = &fs{}
+
+var lockDiscoveryPropFind = client.NewPropNamePropFind(client.LockDiscoveryName)
+
+// Lock by LOCK of server, tokens of locks held should be injected by filesystem.LockTokensInjectContext for writing.
+// shared lock may be rejected by servers, like golang.org/x/net/webdav.
+func (fs *fs) Lock(ctx context.Context, name string, opts filesystem.LockOptions) (*filesystem.LockInfo, error) {
+	lockInfo := &client.LockInfo{
+		LockType: client.LockType{Write: &struct{}{}},
+	}
+
+	if opts.Shared {
+		lockInfo.LockScope.Shared = &struct{}{}
+	} else {
+		lockInfo.LockScope.Exclusive = &struct{}{}
+	}
+
+	if opts.Owner != "" {
+		lockInfo.Owner = &client.Owner{InnerXML: ownerXML(opts.Owner)}
+	}
+
+	depth := client.DepthZero
+	if opts.Recursive {
+		depth = client.DepthInfinity
+	}
+
+	l, err := fs.c.Lock(ctx, name, depth, client.Timeout(opts.LockTTL()), lockInfo)
+	if err != nil {
+		return nil, &os.PathError{Op: "lock", Path: name, Err: lockErr(err)}
+	}
+
+	return lockInfoOf(name, l, opts.LockTTL()), nil
+}
+
+func (fs *fs) RefreshLock(ctx context.Context, name string, token string, ttl time.Duration) (*filesystem.LockInfo, error) {
+	ttl = filesystem.LockOptions{TTL: ttl}.LockTTL()
+
+	l, err := fs.c.RefreshLock(ctx, name, token, client.Timeout(ttl))
+	if err != nil {
+		return nil, &os.PathError{Op: "refresh", Path: name, Err: lockErr(err)}
+	}
+
+	return lockInfoOf(name, l, ttl), nil
+}
+
+func (fs *fs) Unlock(ctx context.Context, name string, token string) error {
+	if err := fs.c.Unlock(ctx, name, token); err != nil {
+		return &os.PathError{Op: "unlock", Path: name, Err: lockErr(err)}
+	}
+	return nil
+}
+
+// Locks from lockdiscovery, empty when not provided by server.
+func (fs *fs) Locks(ctx context.Context, name string) ([]*filesystem.LockInfo, error) {
+	ms, err := fs.c.PropFind(ctx, name, client.DepthZero, lockDiscoveryPropFind)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ms.Responses) != 1 {
+		return nil, nil
+	}
+
+	var discovery client.LockDiscovery
+	if err := ms.Responses[0].DecodeProp(&discovery); err != nil {
+		if _, err := ms.Responses[0].Path(); err != nil && !client.IsNotFound(err) {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	locks := make([]*filesystem.LockInfo, 0, len(discovery.ActiveLocks))
+	for i := range discovery.ActiveLocks {
+		locks = append(locks, lockInfoOf(name, &discovery.ActiveLocks[i], 0))
+	}
+	return locks, nil
+}
+
+func lockInfoOf(name string, l *client.ActiveLock, ttl time.Duration) *filesystem.LockInfo {
+	info := &filesystem.LockInfo{
+		Token:     l.LockToken.String(),
+		Name:      path.Clean("/" + name),
+		Shared:    l.LockScope.Shared != nil,
+		Recursive: l.Depth == client.DepthInfinity,
+	}
+
+	if l.Owner != nil {
+		info.Owner = l.Owner.InnerXML
+	}
+
+	// timeout in response is the remaining time
+	if l.Timeout > 0 {
+		ttl = time.Duration(l.Timeout)
+	}
+	if ttl <= 0 {
+		ttl = filesystem.DefaultLockTTL
+	}
+	info.ExpiresAt = time.Now().Add(ttl)
+
+	return info
+}
+
+// ownerXML escapes plain owner, owner of webdav lock is xml like <D:href>user</D:href>
+func ownerXML(owner string) string {
+	if strings.HasPrefix(owner, "<") {
+		return owner
+	}
+	b := &strings.Builder{}
+	_ = xml.EscapeText(b, []byte(owner))
+	return b.String()
+}
+
+func lockErr(err error) error {
+	var httpErr *client.HTTPError
+	if errors.As(err, &httpErr) {
+		switch httpErr.Code {
+		case http.StatusLocked:
+			return filesystem.ErrLocked
+		case http.StatusPreconditionFailed, http.StatusConflict:
+			return filesystem.ErrLockNotFound
+		}
+	}
+	return err
+}
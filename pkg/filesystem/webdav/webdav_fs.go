@@ -2,6 +2,7 @@ package webdav
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path"
@@ -23,6 +24,10 @@ type fs struct {
 	c client.Client
 }
 
+func (fs *fs) Capabilities() filesystem.Capability {
+	return filesystem.CapAtomicRename | filesystem.CapServerSideCopy | filesystem.CapReaderAt | filesystem.CapAtomicWrite | filesystem.CapSymlink | filesystem.CapSetModTime | filesystem.CapSetMode
+}
+
 func (fs *fs) addNode(fi filesystem.FileInfo) *node {
 	return &node{
 		root:    fs,
@@ -64,6 +69,54 @@ func (fs *fs) Rename(ctx context.Context, oldName, newName string) error {
 	return fs.c.Move(ctx, oldName, newName, false)
 }
 
+// overwriteFS renames with Overwrite: T, to replace existing file by temp file
+type overwriteFS struct {
+	*fs
+}
+
+func (fs *overwriteFS) Rename(ctx context.Context, oldName, newName string) error {
+	return fs.c.Move(ctx, oldName, newName, true)
+}
+
+// OpenFile opens temp file, PUT of which is completed even aborted or canceled,
+// since server may still create the file after removed when request canceled.
+// canceling is checked by filesystem.OpenByRename, and temp file removed after PUT done.
+func (fs *overwriteFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	f, err := fs.openFile(context.WithoutCancel(ctx), name, flag)
+	if err != nil {
+		return nil, err
+	}
+	return &tempFile{File: f}, nil
+}
+
+type tempFile struct {
+	filesystem.File
+}
+
+// Abort is unsupported, then closed to complete the PUT
+func (f *tempFile) Abort(err error) error {
+	return errors.ErrUnsupported
+}
+
+func (fs *fs) Copy(ctx context.Context, oldName, newName string, recursive bool) error {
+	depth := client.DepthZero
+	if recursive {
+		depth = client.DepthInfinity
+	}
+
+	if err := fs.c.Copy(ctx, oldName, newName, depth, true); err != nil {
+		if client.IsNotFound(err) {
+			return &os.PathError{
+				Op:   "copy",
+				Path: oldName,
+				Err:  os.ErrNotExist,
+			}
+		}
+		return err
+	}
+	return nil
+}
+
 func (fs *fs) Stat(ctx context.Context, name string) (os.FileInfo, error) {
 	ms, err := fs.c.PropFind(ctx, name, 0, client.FileInfoPropFind)
 	if err != nil {
@@ -103,6 +156,11 @@ func (fs *fs) OpenFile(ctx context.Context, name string, flag int, perm os.FileM
 		return fs.openDir(ctx, name)
 	}
 
+	// PUT streams into the final name, so write to temp and MOVE when closed
+	if flag&filesystem.O_ATOMIC != 0 {
+		return filesystem.OpenByRename(ctx, &overwriteFS{fs: fs}, name, flag, perm)
+	}
+
 	return fs.openFile(ctx, name, flag)
 }
 
@@ -154,7 +212,15 @@ func (fs *fs) openFile(ctx context.Context, name string, flag int) (filesystem.F
 	}
 
 	if flag&os.O_WRONLY != 0 || flag&os.O_RDWR != 0 {
-		w, err := fs.c.OpenWrite(context.Background(), f.Name())
+		userMetadata, err := filesystem.UserMetadataFromContext(ctx).Normalize()
+		if err != nil {
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+		f.userMetadata = userMetadata
+		f.modTime, _ = filesystem.ModTimeFromContext(ctx)
+
+		// PUT request canceled with ctx, then nothing committed
+		w, err := fs.c.OpenWrite(ctx, f.Name())
 		if err != nil {
 			return nil, err
 		}
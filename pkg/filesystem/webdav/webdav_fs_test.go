@@ -2,14 +2,18 @@ package webdav
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"golang.org/x/net/webdav"
 
+	testingx "github.com/octohelm/x/testing"
+
 	"github.com/octohelm/unifs/pkg/filesystem"
 	"github.com/octohelm/unifs/pkg/filesystem/testutil"
 	"github.com/octohelm/unifs/pkg/strfmt"
@@ -24,6 +28,22 @@ func TestWebdavFs(t *testing.T) {
 		testutil.TestFullFS(t, newWebdavFS(t, true))
 	})
 
+	t.Run("AtomicWrite", func(t *testing.T) {
+		testutil.TestAtomicWrite(t, newWebdavFS(t, false))
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		testutil.TestAbort(t, newWebdavFS(t, false))
+	})
+
+	t.Run("Symlink", func(t *testing.T) {
+		testutil.TestSymlink(t, newWebdavFS(t, false))
+	})
+
+	t.Run("SetAttr", func(t *testing.T) {
+		testutil.TestSetAttr(t, newWebdavFS(t, false))
+	})
+
 	t.Run("Bench", func(t *testing.T) {
 		b := &testutil.Benchmark{}
 		b.SetDefaults()
@@ -31,6 +51,88 @@ func TestWebdavFs(t *testing.T) {
 	})
 }
 
+func TestWebdavHash(t *testing.T) {
+	ctx := context.Background()
+	fsys := newWebdavFS(t, false)
+
+	err := filesystem.Write(ctx, fsys, "/hello.txt", []byte("hello"))
+	testingx.Expect(t, err, testingx.Be[error](nil))
+
+	info, err := fsys.Stat(ctx, "/hello.txt")
+	testingx.Expect(t, err, testingx.Be[error](nil))
+	testingx.Expect(t, filesystem.ETagOf(ctx, info), testingx.Not(testingx.Be("")))
+	testingx.Expect(t, filesystem.ContentTypeOf(ctx, info), testingx.Be("text/plain; charset=utf-8"))
+
+	// x/net/webdav not provide checksums, fall back to streaming
+	sum, err := filesystem.Hash(ctx, fsys, "/hello.txt", filesystem.HashMD5)
+	testingx.Expect(t, err, testingx.Be[error](nil))
+	testingx.Expect(t, sum, testingx.Be("5d41402abc4b2a76b9719d911017c592"))
+}
+
+func TestWebdavUserMetadata(t *testing.T) {
+	ctx := context.Background()
+	fsys := newWebdavFS(t, false)
+
+	err := filesystem.Write(
+		filesystem.UserMetadataInjectContext(ctx, filesystem.UserMetadata{"Producer": "ci", "commit-id": "abc"}),
+		fsys, "/artifact.tgz", []byte("1"),
+	)
+	testingx.Expect(t, err, testingx.Be[error](nil))
+
+	info, err := fsys.Stat(ctx, "/artifact.tgz")
+	testingx.Expect(t, err, testingx.Be[error](nil))
+	testingx.Expect(t, filesystem.UserMetadataOf(ctx, info), testingx.Equal(filesystem.UserMetadata{"producer": "ci", "commit-id": "abc"}))
+
+	entries, err := filesystem.ReadDir(ctx, fsys, "/")
+	testingx.Expect(t, err, testingx.Be[error](nil))
+	entryInfo, _ := entries[0].Info()
+	testingx.Expect(t, filesystem.UserMetadataOf(ctx, entryInfo).Get("producer"), testingx.Be("ci"))
+}
+
+func TestWebdavLock(t *testing.T) {
+	ctx := context.Background()
+	fsys := newWebdavFS(t, false)
+
+	err := filesystem.Write(ctx, fsys, "/locked.txt", []byte("1"))
+	testingx.Expect(t, err, testingx.Be[error](nil))
+
+	l, err := filesystem.Lock(ctx, fsys, "/locked.txt", filesystem.LockOptions{Owner: "a", TTL: time.Minute})
+	testingx.Expect(t, err, testingx.Be[error](nil))
+	testingx.Expect(t, l.Token, testingx.Not(testingx.Be("")))
+
+	_, err = filesystem.Lock(ctx, fsys, "/locked.txt", filesystem.LockOptions{})
+	testingx.Expect(t, errors.Is(err, filesystem.ErrLocked), testingx.Be(true))
+
+	t.Run("write requires token", func(t *testing.T) {
+		write := func(ctx context.Context) error {
+			f, err := filesystem.Create(ctx, fsys, "/locked.txt")
+			if err != nil {
+				return err
+			}
+			_, _ = f.Write([]byte("2"))
+			return f.Close()
+		}
+
+		testingx.Expect(t, write(ctx), testingx.Not(testingx.Be[error](nil)))
+		testingx.Expect(t, write(filesystem.LockTokensInjectContext(ctx, l.Token)), testingx.Be[error](nil))
+	})
+
+	t.Run("refresh and unlock", func(t *testing.T) {
+		refreshed, err := filesystem.RefreshLock(ctx, fsys, "/locked.txt", l.Token, time.Minute)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, refreshed.Token, testingx.Be(l.Token))
+
+		err = filesystem.Unlock(ctx, fsys, "/locked.txt", l.Token)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		err = filesystem.Unlock(ctx, fsys, "/locked.txt", l.Token)
+		testingx.Expect(t, errors.Is(err, filesystem.ErrLockNotFound), testingx.Be(true))
+
+		_, err = filesystem.RefreshLock(ctx, fsys, "/locked.txt", l.Token, time.Minute)
+		testingx.Expect(t, errors.Is(err, filesystem.ErrLockNotFound), testingx.Be(true))
+	})
+}
+
 func newWebdavFS(t *testing.T, debug bool) filesystem.FileSystem {
 	e := os.Getenv("TEST_WEBDAV_ENDPOINT")
 	if e == "" {
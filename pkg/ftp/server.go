@@ -3,8 +3,13 @@ package ftp
 import (
 	"context"
 	"crypto/tls"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -18,6 +23,9 @@ import (
 	"github.com/octohelm/unifs/pkg/aferofsutil"
 	"github.com/octohelm/unifs/pkg/filesystem"
 	fslogr "github.com/octohelm/unifs/pkg/filesystem/logr"
+	"github.com/octohelm/unifs/pkg/filesystem/notify"
+	"github.com/octohelm/unifs/pkg/filesystem/policy"
+	"github.com/octohelm/unifs/pkg/filesystem/throttle"
 )
 
 var _ configuration.Server = &Server{}
@@ -28,6 +36,12 @@ type Server struct {
 	DisableMLST bool   `flag:"disable-mlst,omitzero"`
 	DisableMLSD bool   `flag:"disable-mlsd,omitzero"`
 
+	policy.Config
+
+	Throttle throttle.Config
+
+	Notify notify.Config
+
 	ftp *ftpserver.FtpServer
 }
 
@@ -46,15 +60,27 @@ func (s *Server) SetDefaults() {
 
 func (s *Server) Serve(ctx context.Context) error {
 	if s.ftp == nil {
+		// events only emitted for changes allowed by policy
+		fsys, err := s.Config.Apply(s.Notify.Apply(ctx, s.Throttle.Apply(filesystem.Context.From(ctx))))
+		if err != nil {
+			return err
+		}
+
 		d := &driver{
-			fs:     filesystem.Context.From(ctx),
+			ctx:    ctx,
+			fs:     fsys,
 			logger: logr.FromContext(ctx),
 		}
 
+		d.caps = filesystem.CapabilitiesOf(d.fs)
+
 		d.ListenAddr = s.Addr
 		d.PublicHost = s.PublicHost
 		d.DisableMLST = s.DisableMLST
 		d.DisableMLSD = s.DisableMLSD
+		// MFMT will always fail when the backend could not set mtime
+		d.DisableMFMT = !d.caps.Has(filesystem.CapSetModTime)
+		d.EnableHASH = true
 
 		s.ftp = ftpserver.NewFtpServer(d)
 
@@ -75,9 +101,11 @@ func (s *Server) Shutdown(ctx context.Context) error {
 type driver struct {
 	ftpserver.Settings
 
+	ctx    context.Context
 	logger logr.Logger
 
-	fs filesystem.FileSystem
+	fs   filesystem.FileSystem
+	caps filesystem.Capability
 
 	nbClients       atomic.Int64
 	zeroClientEvent chan error
@@ -88,11 +116,24 @@ func (s *driver) GetSettings() (*ftpserver.Settings, error) {
 }
 
 func (s *driver) AuthUser(cc ftpserver.ClientContext, user, pass string) (ftpserver.ClientDriver, error) {
-	fs := aferofsutil.From(fslogr.Wrap(s.fs, s.logger.WithValues("ftp", "server")))
+	ctx := filesystem.UserInjectContext(s.ctx, user)
+	// login user is not verified, so limits of throttle for each user could be bypassed, unlike ones for each client ip
+	if addr := cc.RemoteAddr(); addr != nil {
+		if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+			ctx = filesystem.ClientInjectContext(ctx, host)
+		}
+	}
+
+	fsys := fslogr.Wrap(s.fs, s.logger.WithValues("ftp", "server"))
 
 	s.logger.WithValues("user", user).Info("auth")
 
-	return &ClientDriver{Fs: fs}, nil
+	return &ClientDriver{
+		Fs:   aferofsutil.WithContext(ctx, fsys),
+		ctx:  ctx,
+		fsys: fsys,
+		caps: s.caps,
+	}, nil
 }
 
 func (s *driver) GetTLSConfig() (*tls.Config, error) {
@@ -101,6 +142,73 @@ func (s *driver) GetTLSConfig() (*tls.Config, error) {
 
 type ClientDriver struct {
 	afero.Fs
+
+	ctx  context.Context
+	fsys filesystem.FileSystem
+	caps filesystem.Capability
+}
+
+var _ ftpserver.ClientDriverExtentionFileTransfer = &ClientDriver{}
+var _ ftpserver.ClientDriverExtensionHasher = &ClientDriver{}
+
+// GetHandle rejects APPE and REST STOR early when backend not support
+func (c *ClientDriver) GetHandle(name string, flags int, offset int64) (ftpserver.FileTransfer, error) {
+	if flags&os.O_APPEND != 0 && !c.caps.Has(filesystem.CapAppend) {
+		return nil, &fs.PathError{Op: "append", Path: name, Err: errors.ErrUnsupported}
+	}
+
+	if flags&(os.O_WRONLY|os.O_RDWR) != 0 && offset > 0 && !c.caps.Has(filesystem.CapRandomAccessWrite) {
+		return nil, &fs.PathError{Op: "resume", Path: name, Err: errors.ErrUnsupported}
+	}
+
+	return c.OpenFile(name, flags, os.ModePerm)
+}
+
+var hashAlgorithms = map[ftpserver.HASHAlgo]filesystem.HashAlgorithm{
+	ftpserver.HASHAlgoCRC32:  filesystem.HashCRC32,
+	ftpserver.HASHAlgoMD5:    filesystem.HashMD5,
+	ftpserver.HASHAlgoSHA1:   filesystem.HashSHA1,
+	ftpserver.HASHAlgoSHA256: filesystem.HashSHA256,
+	ftpserver.HASHAlgoSHA512: filesystem.HashSHA512,
+}
+
+// ComputeHash uses native hash of backend for whole file, and streams content for partial range
+func (c *ClientDriver) ComputeHash(name string, algo ftpserver.HASHAlgo, start, end int64) (string, error) {
+	a, ok := hashAlgorithms[algo]
+	if !ok {
+		return "", &fs.PathError{Op: "hash", Path: name, Err: errors.ErrUnsupported}
+	}
+
+	if start == 0 {
+		info, err := c.fsys.Stat(c.ctx, name)
+		if err != nil {
+			return "", err
+		}
+		if end == info.Size() {
+			return filesystem.Hash(c.ctx, c.fsys, name, a)
+		}
+	}
+
+	h, err := a.New()
+	if err != nil {
+		return "", err
+	}
+
+	f, err := filesystem.Open(c.ctx, c.fsys, name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	if _, err := io.CopyN(h, f, end-start); err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 var ErrTimeout = errors.New("timeout")
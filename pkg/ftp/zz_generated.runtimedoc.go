@@ -28,7 +28,13 @@ func (v *Server) RuntimeDoc(names ...string) ([]string, bool) {
 			return []string{}, true
 		case "DisableMLSD":
 			return []string{}, true
-
+		case "Throttle":
+			return []string{}, true
+		case "Notify":
+			return []string{}, true
+		}
+		if doc, ok := runtimeDoc(&v.Config, "", names...); ok {
+			return doc, ok
 		}
 
 		return nil, false
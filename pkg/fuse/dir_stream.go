@@ -0,0 +1,66 @@
+package fuse
+
+import (
+	iofs "io/fs"
+	"iter"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// dirStream pulls entries when kernel reads, instead of listing all entries at once
+type dirStream struct {
+	next func() (iofs.DirEntry, error, bool)
+	stop func()
+
+	entry iofs.DirEntry
+	err   error
+	ok    bool
+}
+
+func newDirStream(seq iter.Seq2[iofs.DirEntry, error]) *dirStream {
+	d := &dirStream{}
+	d.next, d.stop = iter.Pull2(seq)
+	d.advance()
+	return d
+}
+
+func (d *dirStream) advance() {
+	d.entry, d.err, d.ok = d.next()
+}
+
+func (d *dirStream) HasNext() bool {
+	return d.ok
+}
+
+func (d *dirStream) Next() (fuse.DirEntry, syscall.Errno) {
+	e, err := d.entry, d.err
+	if err != nil {
+		d.ok = false
+		return fuse.DirEntry{}, fs.ToErrno(err)
+	}
+	d.advance()
+
+	entry := fuse.DirEntry{
+		Name: e.Name(),
+	}
+
+	if e.IsDir() {
+		entry.Mode = syscall.S_IFDIR
+	} else if e.Type()&iofs.ModeSymlink != 0 {
+		entry.Mode = syscall.S_IFLNK
+	} else {
+		info, err := e.Info()
+		if err != nil {
+			return fuse.DirEntry{}, fs.ToErrno(err)
+		}
+		entry.Mode = uint32(info.Mode())
+	}
+
+	return entry, 0
+}
+
+func (d *dirStream) Close() {
+	d.stop()
+}
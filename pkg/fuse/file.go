@@ -2,8 +2,12 @@ package fuse
 
 import (
 	"context"
+	"errors"
 	"io"
+	"os"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/hanwen/go-fuse/v2/fs"
 	"github.com/hanwen/go-fuse/v2/fuse"
@@ -15,25 +19,193 @@ type File interface {
 	fs.FileHandle
 	fs.FileReader
 	fs.FileWriter
+	fs.FileFlusher
 	fs.FileReleaser
 	fs.FileFsyncer
 }
 
 var _ File = &file{}
 
+func (r *root) newFile(f filesystem.File, name string, flags uint32) *file {
+	return &file{
+		f:      f,
+		fsi:    r.fsi,
+		name:   name,
+		caps:   r.caps,
+		flags:  flags,
+		append: int(flags)&os.O_APPEND != 0,
+		uid:    -1,
+		gid:    -1,
+	}
+}
+
 type file struct {
-	f filesystem.File
+	f      filesystem.File
+	fsi    filesystem.FileSystem
+	name   string
+	caps   filesystem.Capability
+	flags  uint32
+	append bool
+
+	mu sync.Mutex
+	// offset of the underlying file, to avoid useless Seek
+	offset int64
+
+	// nothing written yet, so could be reopened with O_TRUNC
+	written bool
+	// underlying file committed when flushed, will be reopened for following reads and writes
+	committed bool
+	// closed when released
+	closed bool
+
+	// attributes set by Setattr, will be set when flushed
+	setsMode bool
+	mode     os.FileMode
+	modTime  time.Time
+	uid      int
+	gid      int
+}
+
+func (f *file) writable() bool {
+	return int(f.flags)&(os.O_WRONLY|os.O_RDWR) != 0
+}
+
+func (f *file) setMode(mode os.FileMode) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.setsMode = true
+	f.mode = mode
+}
+
+func (f *file) setModTime(mtime time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.modTime = mtime
+}
+
+func (f *file) setOwner(uid, gid int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if uid != -1 {
+		f.uid = uid
+	}
+	if gid != -1 {
+		f.gid = gid
+	}
+}
+
+// truncatable checks file could be truncated to size by truncate
+func (f *file) truncatable(size int64) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.f.(filesystem.FileTruncator); ok && f.caps.Has(filesystem.CapTruncate) {
+		return true
+	}
+	return size == 0 && !f.written
+}
+
+// truncate by FileTruncator,
+// or reopen with O_TRUNC when truncating to 0 before written, since O_TRUNC of open is sent as Setattr by kernel.
+func (f *file) truncate(ctx context.Context, size int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.reopen(ctx); err != nil {
+		return err
+	}
+
+	if t, ok := f.f.(filesystem.FileTruncator); ok && f.caps.Has(filesystem.CapTruncate) {
+		return t.Truncate(size)
+	}
+
+	if size != 0 || f.written {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: errors.ErrUnsupported}
+	}
+
+	// file handle outlives the request, writes should not be canceled with it
+	reopened, err := f.fsi.OpenFile(context.WithoutCancel(ctx), f.name, int(f.flags)|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	_ = filesystem.Abort(f.f, nil)
+
+	f.f = reopened
+	f.offset = 0
+	return nil
+}
+
+// withAttrs returns fi with attributes set by Setattr
+func (f *file) withAttrs(fi os.FileInfo) os.FileInfo {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info := &fileInfo{
+		name:    fi.Name(),
+		mode:    fi.Mode(),
+		size:    fi.Size(),
+		modTime: fi.ModTime(),
+	}
+	if f.setsMode {
+		info.mode = f.mode
+	}
+	if !f.modTime.IsZero() {
+		info.modTime = f.modTime
+	}
+	return info
 }
 
 func (f *file) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
-	if off > 0 {
-		if _, err := f.f.Seek(off, 0); err != nil {
+	if f.caps.Has(filesystem.CapReaderAt) {
+		// f.f could be replaced when truncating or reopening
+		f.mu.Lock()
+		if f.closed {
+			f.mu.Unlock()
+			return nil, syscall.EBADF
+		}
+		if err := f.reopen(ctx); err != nil {
+			f.mu.Unlock()
+			return nil, toErrno(err)
+		}
+		ff := f.f
+		f.mu.Unlock()
+
+		if r, ok := ff.(io.ReaderAt); ok {
+			n, err := r.ReadAt(dest, off)
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, fs.ToErrno(err)
+			}
+			return fuse.ReadResultData(dest[:n]), 0
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil, syscall.EBADF
+	}
+
+	if err := f.reopen(ctx); err != nil {
+		return nil, toErrno(err)
+	}
+
+	if off != f.offset {
+		if _, err := f.f.Seek(off, io.SeekStart); err != nil {
 			return nil, syscall.ENOENT
 		}
+		f.offset = off
 	}
-	n, err := f.f.Read(dest)
+
+	// short read means EOF for fuse
+	n, err := io.ReadFull(f.f, dest)
+	f.offset += int64(n)
 	if err != nil {
-		if err != io.EOF {
+		if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
 			return nil, syscall.ENOENT
 		}
 	}
@@ -53,20 +225,137 @@ func (f *file) Write(ctx context.Context, data []byte, off int64) (written uint3
 		return 0, syscall.EFBIG
 	}
 
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, syscall.EBADF
+	}
+
+	if err := f.reopen(ctx); err != nil {
+		return 0, toErrno(err)
+	}
+
+	// offset is always the end of file when append
+	if !f.append && off != f.offset {
+		if !f.caps.Has(filesystem.CapRandomAccessWrite) {
+			return 0, syscall.ENOTSUP
+		}
+
+		if w, ok := f.f.(io.WriterAt); ok {
+			n, err := w.WriteAt(data, off)
+			f.written = true
+			if err != nil {
+				return 0, fs.ToErrno(err)
+			}
+			return uint32(n), 0
+		}
+
+		if _, err := f.f.Seek(off, io.SeekStart); err != nil {
+			return 0, fs.ToErrno(err)
+		}
+		f.offset = off
+	}
+
 	n, err := f.f.Write(data)
+	f.offset += int64(n)
+	f.written = true
 	if err != nil {
 		return 0, fs.ToErrno(err)
 	}
 	return uint32(n), 0
 }
 
-func (f *file) Release(ctx context.Context) syscall.Errno {
-	if err := f.f.Close(); err != nil {
-		return fs.ToErrno(err)
+// Flush commits file opened for write, and applies attributes set by Setattr,
+// so errors of committing could be returned to close.
+//
+// Flush sent on each close of duplicated fds, like fds inherited by forked child,
+// file will be reopened for following reads and writes through fds not closed yet,
+// which fail with ENOTSUP when backend could not write at offset.
+func (f *file) Flush(ctx context.Context) syscall.Errno {
+	if !f.writable() {
+		return 0
 	}
-	return 0
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0
+	}
+	return toErrno(f.commit(ctx))
 }
 
+// Fsync commits like Flush, since content could not be synced without committed for backends like s3
 func (f *file) Fsync(ctx context.Context, flags uint32) syscall.Errno {
-	return 0
+	return f.Flush(ctx)
+}
+
+func (f *file) Release(ctx context.Context) syscall.Errno {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0
+	}
+	f.closed = true
+
+	if !f.writable() {
+		return toErrno(f.f.Close())
+	}
+	// error returned to nobody, should be returned by Flush before
+	return toErrno(f.commit(ctx))
+}
+
+// commit closes underlying file when not committed, then applies attributes set by Setattr
+func (f *file) commit(ctx context.Context) error {
+	if !f.committed {
+		f.committed = true
+		if err := f.f.Close(); err != nil {
+			return err
+		}
+	}
+
+	if f.setsMode {
+		if err := filesystem.SetMode(ctx, f.fsi, f.name, f.mode); err != nil {
+			return err
+		}
+		f.setsMode = false
+	}
+	if !f.modTime.IsZero() {
+		if err := filesystem.SetModTime(ctx, f.fsi, f.name, f.modTime); err != nil {
+			return err
+		}
+		f.modTime = time.Time{}
+	}
+	if f.uid != -1 || f.gid != -1 {
+		if err := filesystem.Chown(ctx, f.fsi, f.name, f.uid, f.gid); err != nil {
+			return err
+		}
+		f.uid, f.gid = -1, -1
+	}
+	return nil
+}
+
+// reopen file committed by Flush, without O_TRUNC to keep content committed,
+// only when backend could write at offset or append, since content is replaced by new writes for others.
+func (f *file) reopen(ctx context.Context) error {
+	if !f.committed {
+		return nil
+	}
+
+	if !(f.caps.Has(filesystem.CapRandomAccessWrite) || f.append && f.caps.Has(filesystem.CapAppend)) {
+		return &os.PathError{Op: "reopen", Path: f.name, Err: errors.ErrUnsupported}
+	}
+
+	// file handle outlives the request, writes should not be canceled with it
+	reopened, err := f.fsi.OpenFile(context.WithoutCancel(ctx), f.name, int(f.flags)&^(os.O_TRUNC|os.O_CREATE|os.O_EXCL), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	f.f = reopened
+	f.offset = 0
+	f.committed = false
+	return nil
 }
@@ -2,7 +2,9 @@ package fuse
 
 import (
 	"context"
+	"errors"
 	"os"
+	"path"
 	"syscall"
 
 	"github.com/hanwen/go-fuse/v2/fs"
@@ -27,6 +29,9 @@ type Node interface {
 	fs.NodeRmdirer
 
 	fs.NodeRenamer
+
+	fs.NodeSymlinker
+	fs.NodeReadlinker
 }
 
 var _ Node = &node{}
@@ -36,8 +41,132 @@ type node struct {
 	root *root
 }
 
+// Setattr changes mode and mtime by filesystem.AttrSetter, owner by filesystem.Chowner, and size by truncating,
+// ENOTSUP returned before any change applied when one of them not supported, atime is ignored since not stored by backends.
+// attributes of file opened for write will be set when flushed, since content not committed before closed for backends like s3.
 func (n *node) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
-	return 0
+	mode, setMode := in.GetMode()
+	mtime, setModTime := in.GetMTime()
+	size, setSize := in.GetSize()
+	uid, setUID := in.GetUID()
+	gid, setGID := in.GetGID()
+	setOwner := setUID || setGID
+
+	// mtime updated with truncating, like O_TRUNC of open, which is sent as Setattr by kernel
+	if setSize && in.Valid&fuse.FATTR_MTIME_NOW != 0 {
+		setModTime = false
+	}
+
+	fh, ok := f.(*file)
+	if ok && !fh.writable() {
+		fh = nil
+	}
+
+	caps := n.root.caps
+
+	if setMode && !caps.Has(filesystem.CapSetMode) ||
+		setModTime && !caps.Has(filesystem.CapSetModTime) ||
+		setOwner && !caps.Has(filesystem.CapChown) {
+		return syscall.ENOTSUP
+	}
+
+	if setSize {
+		if fh != nil {
+			if !fh.truncatable(int64(size)) {
+				return syscall.ENOTSUP
+			}
+		} else if size != 0 && !caps.Has(filesystem.CapTruncate) {
+			return syscall.ENOTSUP
+		}
+	}
+
+	if fh != nil {
+		if setSize {
+			if err := fh.truncate(ctx, int64(size)); err != nil {
+				return toErrno(err)
+			}
+		}
+		if setMode {
+			fh.setMode(os.FileMode(mode).Perm())
+		}
+		if setModTime {
+			fh.setModTime(mtime)
+		}
+		if setOwner {
+			fh.setOwner(ownerID(uid, setUID), ownerID(gid, setGID))
+		}
+
+		fi, err := filesystem.Lstat(ctx, n.fsi(), fh.name)
+		if err != nil {
+			// not exists before content committed
+			fi = newFileInfo(path.Base(fh.name), 0o664)
+		}
+		n.root.setAttrFromFileInfo(fh.withAttrs(fi), &out.Attr)
+		return 0
+	}
+
+	name := n.path()
+
+	if setSize {
+		if err := n.truncate(ctx, name, int64(size)); err != nil {
+			return toErrno(err)
+		}
+	}
+
+	if setMode {
+		if err := filesystem.SetMode(ctx, n.fsi(), name, os.FileMode(mode).Perm()); err != nil {
+			return toErrno(err)
+		}
+	}
+
+	if setModTime {
+		if err := filesystem.SetModTime(ctx, n.fsi(), name, mtime); err != nil {
+			return toErrno(err)
+		}
+	}
+
+	if setOwner {
+		if err := filesystem.Chown(ctx, n.fsi(), name, ownerID(uid, setUID), ownerID(gid, setGID)); err != nil {
+			return toErrno(err)
+		}
+	}
+
+	return n.Getattr(ctx, f, out)
+}
+
+// truncate by FileTruncator of file opened, or rewrite as empty when size is 0
+func (n *node) truncate(ctx context.Context, name string, size int64) error {
+	flag := os.O_WRONLY
+	if size == 0 {
+		flag |= os.O_TRUNC
+	}
+
+	f, err := n.fsi().OpenFile(ctx, name, flag, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	if size != 0 {
+		t, ok := f.(filesystem.FileTruncator)
+		if !ok {
+			_ = filesystem.Abort(f, errors.ErrUnsupported)
+			return &os.PathError{Op: "truncate", Path: name, Err: errors.ErrUnsupported}
+		}
+		if err := t.Truncate(size); err != nil {
+			_ = filesystem.Abort(f, err)
+			return err
+		}
+	}
+
+	return f.Close()
+}
+
+// ownerID returns -1 to keep the current one when not set
+func ownerID(id uint32, set bool) int {
+	if !set {
+		return -1
+	}
+	return int(id)
 }
 
 func (n *node) path(names ...string) string {
@@ -49,7 +178,8 @@ func (n *node) fsi() filesystem.FileSystem {
 }
 
 func (n *node) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
-	fi, err := n.fsi().Stat(ctx, n.path(name))
+	// links resolved by kernel
+	fi, err := filesystem.Lstat(ctx, n.fsi(), n.path(name))
 	if err != nil {
 		return nil, fs.ToErrno(err)
 	}
@@ -59,7 +189,7 @@ func (n *node) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs
 }
 
 func (n *node) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
-	fi, err := n.fsi().Stat(ctx, n.path())
+	fi, err := filesystem.Lstat(ctx, n.fsi(), n.path())
 	if err != nil {
 		return fs.ToErrno(err)
 	}
@@ -87,7 +217,12 @@ func (n *node) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.En
 func (n *node) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (node *fs.Inode, fh fs.FileHandle, fuseFlags uint32, errno syscall.Errno) {
 	fullname := n.path(name)
 
-	f, err := n.fsi().OpenFile(ctx, fullname, int(flags), os.FileMode(mode))
+	if errno := n.checkOpenFlags(flags); errno != 0 {
+		return nil, nil, 0, errno
+	}
+
+	// file handle outlives the request, writes should not be canceled with it
+	f, err := n.fsi().OpenFile(context.WithoutCancel(ctx), fullname, int(flags), os.FileMode(mode))
 	if err != nil {
 		return nil, nil, 0, fs.ToErrno(err)
 	}
@@ -97,15 +232,27 @@ func (n *node) Create(ctx context.Context, name string, flags uint32, mode uint3
 	n.root.setAttrFromFileInfo(fi, &out.Attr)
 	ch := n.NewInode(ctx, n.root.newNode(n.EmbeddedInode(), fi), fs.StableAttr{Mode: out.Attr.Mode})
 
-	return ch, &file{f: f}, 0, 0
+	return ch, n.root.newFile(f, fullname, flags), 0, 0
 }
 
 func (n *node) Open(ctx context.Context, flags uint32) (fh fs.FileHandle, fuseFlags uint32, errno syscall.Errno) {
-	f, err := n.fsi().OpenFile(ctx, n.path(), int(flags), os.ModePerm)
+	if errno := n.checkOpenFlags(flags); errno != 0 {
+		return nil, 0, errno
+	}
+
+	// file handle outlives the request, writes should not be canceled with it
+	f, err := n.fsi().OpenFile(context.WithoutCancel(ctx), n.path(), int(flags), os.ModePerm)
 	if err != nil {
 		return nil, 0, fs.ToErrno(err)
 	}
-	return &file{f: f}, 0, 0
+	return n.root.newFile(f, n.path(), flags), 0, 0
+}
+
+func (n *node) checkOpenFlags(flags uint32) syscall.Errno {
+	if int(flags)&os.O_APPEND != 0 && !n.root.caps.Has(filesystem.CapAppend) {
+		return syscall.ENOTSUP
+	}
+	return 0
 }
 
 func (n *node) Unlink(ctx context.Context, name string) syscall.Errno {
@@ -131,30 +278,47 @@ func (n *node) Rename(ctx context.Context, name string, newParent fs.InodeEmbedd
 }
 
 func (n *node) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
-	f, err := n.fsi().OpenFile(ctx, n.path(), os.O_RDONLY, os.ModeDir)
-	if err != nil {
-		return nil, fs.ToErrno(err)
+	// entries will be read after Readdir returned
+	d := newDirStream(filesystem.ReadDirIter(context.WithoutCancel(ctx), n.fsi(), n.path()))
+
+	// failed to open
+	if d.ok && d.err != nil {
+		d.Close()
+		return nil, fs.ToErrno(d.err)
 	}
 
-	list, err := f.Readdir(-1)
+	return d, 0
+}
+
+func (n *node) Symlink(ctx context.Context, target, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	fullname := n.path(name)
+
+	if err := filesystem.Symlink(ctx, n.fsi(), target, fullname); err != nil {
+		return nil, toErrno(err)
+	}
+
+	fi, err := filesystem.Lstat(ctx, n.fsi(), fullname)
 	if err != nil {
 		return nil, fs.ToErrno(err)
 	}
+	n.root.setAttrFromFileInfo(fi, &out.Attr)
 
-	entries := make([]fuse.DirEntry, 0, len(list))
-
-	for _, fi := range list {
-		entry := fuse.DirEntry{
-			Name: fi.Name(),
-			Mode: uint32(fi.Mode()),
-		}
-
-		if fi.IsDir() {
-			entry.Mode = syscall.S_IFDIR
-		}
+	ch := n.NewInode(ctx, n.root.newNode(n.EmbeddedInode(), fi), fs.StableAttr{Mode: out.Attr.Mode})
+	return ch, 0
+}
 
-		entries = append(entries, entry)
+func (n *node) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
+	target, err := filesystem.Readlink(ctx, n.fsi(), n.path())
+	if err != nil {
+		return nil, toErrno(err)
 	}
+	return []byte(target), 0
+}
 
-	return fs.NewListDirStream(entries), 0
+// toErrno maps errors.ErrUnsupported to ENOTSUP, which is ENOSYS by fs.ToErrno
+func toErrno(err error) syscall.Errno {
+	if errors.Is(err, errors.ErrUnsupported) {
+		return syscall.ENOTSUP
+	}
+	return fs.ToErrno(err)
 }
@@ -0,0 +1,329 @@
+package fuse
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	testingx "github.com/octohelm/x/testing"
+
+	"github.com/octohelm/unifs/pkg/filesystem"
+	"github.com/octohelm/unifs/pkg/filesystem/compress"
+	"github.com/octohelm/unifs/pkg/filesystem/local"
+)
+
+func TestNode(t *testing.T) {
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	backend := local.NewFS(dir)
+	d := mount(t, backend, false)
+
+	t.Run("Symlink", func(t *testing.T) {
+		err := os.WriteFile(filepath.Join(d, "target.txt"), []byte("target"), 0o644)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		err = os.Symlink("target.txt", filepath.Join(d, "link"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		target, err := os.Readlink(filepath.Join(d, "link"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, target, testingx.Be("target.txt"))
+
+		fi, err := os.Lstat(filepath.Join(d, "link"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, fi.Mode()&os.ModeSymlink != 0, testingx.Be(true))
+
+		data, err := os.ReadFile(filepath.Join(d, "link"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, string(data), testingx.Be("target"))
+
+		target, err = filesystem.Readlink(ctx, backend, "/link")
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, target, testingx.Be("target.txt"))
+	})
+
+	t.Run("Setattr", func(t *testing.T) {
+		mtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		t.Run("by path", func(t *testing.T) {
+			name := filepath.Join(d, "attr.txt")
+
+			err := os.WriteFile(name, []byte("attr"), 0o644)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			err = os.Chmod(name, 0o600)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			err = os.Chtimes(name, mtime, mtime)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			fi, err := backend.Stat(ctx, "/attr.txt")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, fi.Mode().Perm(), testingx.Be(os.FileMode(0o600)))
+			testingx.Expect(t, fi.ModTime().Equal(mtime), testingx.Be(true))
+		})
+
+		t.Run("by handle opened for write", func(t *testing.T) {
+			f, err := os.OpenFile(filepath.Join(d, "attr-open.txt"), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			_, err = f.Write([]byte("attr"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			err = f.Chmod(0o600)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			err = f.Close()
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			fi, err := backend.Stat(ctx, "/attr-open.txt")
+			testingx.Expect(t, err, testingx.Be[error](nil))
+			testingx.Expect(t, fi.Mode().Perm(), testingx.Be(os.FileMode(0o600)))
+		})
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		name := filepath.Join(d, "truncate.txt")
+
+		t.Run("rewrite existing", func(t *testing.T) {
+			_ = os.WriteFile(name, []byte("0123456789"), 0o644)
+
+			err := os.WriteFile(name, []byte("abc"), 0o644)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			data, _ := os.ReadFile(filepath.Join(dir, "truncate.txt"))
+			testingx.Expect(t, string(data), testingx.Be("abc"))
+		})
+
+		t.Run("by path", func(t *testing.T) {
+			err := os.Truncate(name, 1)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			data, _ := os.ReadFile(filepath.Join(dir, "truncate.txt"))
+			testingx.Expect(t, string(data), testingx.Be("a"))
+		})
+
+		t.Run("by handle opened for write", func(t *testing.T) {
+			f, err := os.OpenFile(name, os.O_RDWR, 0o644)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			_, err = f.Write([]byte("xyz"))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			err = f.Truncate(2)
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			err = f.Close()
+			testingx.Expect(t, err, testingx.Be[error](nil))
+
+			data, _ := os.ReadFile(filepath.Join(dir, "truncate.txt"))
+			testingx.Expect(t, string(data), testingx.Be("xy"))
+		})
+	})
+
+	t.Run("Chown", func(t *testing.T) {
+		if os.Getuid() != 0 {
+			t.Skip("chown needs root")
+		}
+
+		name := filepath.Join(d, "chown.txt")
+		_ = os.WriteFile(name, []byte("chown"), 0o644)
+
+		err := os.Chown(name, 1000, 1000)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		fi, err := os.Stat(filepath.Join(dir, "chown.txt"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, fi.Sys().(*syscall.Stat_t).Uid, testingx.Be(uint32(1000)))
+		testingx.Expect(t, fi.Sys().(*syscall.Stat_t).Gid, testingx.Be(uint32(1000)))
+
+		fi, err = os.Stat(name)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, fi.Sys().(*syscall.Stat_t).Uid, testingx.Be(uint32(1000)))
+	})
+
+	t.Run("random write", func(t *testing.T) {
+		name := filepath.Join(d, "random.txt")
+
+		err := os.WriteFile(name, []byte("0123456789"), 0o644)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		f, err := os.OpenFile(name, os.O_RDWR, 0o644)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = f.WriteAt([]byte("xx"), 3)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		_, err = f.WriteAt([]byte("yy"), 8)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		err = f.Close()
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		data, err := os.ReadFile(filepath.Join(dir, "random.txt"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, string(data), testingx.Be("012xx567yy"))
+	})
+}
+
+func TestNodeUnsupported(t *testing.T) {
+	d := mount(t, compress.Wrap(filesystem.NewMemFS()), false)
+
+	name := filepath.Join(d, "unsupported.txt")
+	_ = os.WriteFile(name, []byte("0123456789"), 0o644)
+
+	t.Run("rewrite existing", func(t *testing.T) {
+		err := os.WriteFile(name, []byte("abc"), 0o644)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		data, err := os.ReadFile(name)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, string(data), testingx.Be("abc"))
+	})
+
+	t.Run("random write", func(t *testing.T) {
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_TRUNC, 0o644)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		defer f.Close()
+
+		_, err = f.Write([]byte("0123456789"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = f.WriteAt([]byte("xx"), 3)
+		testingx.Expect(t, errors.Is(err, syscall.ENOTSUP), testingx.Be(true))
+
+		err = f.Truncate(1)
+		testingx.Expect(t, errors.Is(err, syscall.ENOTSUP), testingx.Be(true))
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		err := os.Truncate(name, 1)
+		testingx.Expect(t, errors.Is(err, syscall.ENOTSUP), testingx.Be(true))
+	})
+
+	t.Run("chmod and chown", func(t *testing.T) {
+		err := os.Chmod(name, 0o600)
+		testingx.Expect(t, errors.Is(err, syscall.ENOTSUP), testingx.Be(true))
+
+		err = os.Chown(name, 1000, 1000)
+		testingx.Expect(t, errors.Is(err, syscall.ENOTSUP), testingx.Be(true))
+	})
+}
+
+func TestNodeStatCache(t *testing.T) {
+	ctx := context.Background()
+
+	backend := &statCountingFS{Forwarder: filesystem.Forwarder{FileSystem: local.NewFS(t.TempDir())}}
+	d := mount(t, filesystem.StatCache(backend, filesystem.WithStatTTL(time.Hour)), false)
+
+	err := filesystem.Write(ctx, backend, "/1.txt", []byte("1"))
+	testingx.Expect(t, err, testingx.Be[error](nil))
+	err = filesystem.Symlink(ctx, backend, "1.txt", "/link")
+	testingx.Expect(t, err, testingx.Be[error](nil))
+
+	t.Run("ls -l served from entries of dir", func(t *testing.T) {
+		entries, err := os.ReadDir(d)
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, len(entries), testingx.Be(2))
+
+		n := backend.count.Load()
+
+		for _, e := range entries {
+			_, err := os.Lstat(filepath.Join(d, e.Name()))
+			testingx.Expect(t, err, testingx.Be[error](nil))
+		}
+
+		testingx.Expect(t, backend.count.Load(), testingx.Be(n))
+	})
+}
+
+func TestNodeCommit(t *testing.T) {
+	dir := t.TempDir()
+	backend := &commitFailedFS{Forwarder: filesystem.Forwarder{FileSystem: local.NewFS(dir)}}
+	d := mount(t, backend, false)
+
+	t.Run("error of commit returned by close", func(t *testing.T) {
+		f, err := os.Create(filepath.Join(d, "failed.txt"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = f.Write([]byte("1"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		err = f.Close()
+		testingx.Expect(t, errors.Is(err, syscall.EIO), testingx.Be(true))
+	})
+
+	t.Run("writes through duplicated fd after closed", func(t *testing.T) {
+		f, err := os.Create(filepath.Join(d, "dup.txt"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		_, err = f.Write([]byte("12"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		fd, err := syscall.Dup(int(f.Fd()))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		dup := os.NewFile(uintptr(fd), f.Name())
+
+		err = f.Close()
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		data, err := os.ReadFile(filepath.Join(dir, "dup.txt"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, string(data), testingx.Be("12"))
+
+		_, err = dup.Write([]byte("34"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		err = dup.Close()
+		testingx.Expect(t, err, testingx.Be[error](nil))
+
+		data, err = os.ReadFile(filepath.Join(dir, "dup.txt"))
+		testingx.Expect(t, err, testingx.Be[error](nil))
+		testingx.Expect(t, string(data), testingx.Be("1234"))
+	})
+}
+
+// commitFailedFS fails to close files named failed.txt opened for write
+type commitFailedFS struct {
+	filesystem.Forwarder
+}
+
+func (fs *commitFailedFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (filesystem.File, error) {
+	f, err := fs.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Base(name) == "failed.txt" && flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		return &commitFailedFile{File: f}, nil
+	}
+	return f, nil
+}
+
+type commitFailedFile struct {
+	filesystem.File
+}
+
+func (f *commitFailedFile) Close() error {
+	_ = f.File.Close()
+	return syscall.EIO
+}
+
+// statCountingFS counts Stat and Lstat calls
+type statCountingFS struct {
+	filesystem.Forwarder
+	count atomic.Int64
+}
+
+func (fs *statCountingFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	fs.count.Add(1)
+	return fs.FileSystem.Stat(ctx, name)
+}
+
+func (fs *statCountingFS) Lstat(ctx context.Context, name string) (os.FileInfo, error) {
+	fs.count.Add(1)
+	return fs.Forwarder.Lstat(ctx, name)
+}
@@ -18,6 +18,7 @@ func FS(fsi filesystem.FileSystem) fs.InodeEmbedder {
 		root: &root{
 			base: "/",
 			fsi:  fsi,
+			caps: filesystem.CapabilitiesOf(fsi),
 		},
 	}
 }
@@ -25,6 +26,7 @@ func FS(fsi filesystem.FileSystem) fs.InodeEmbedder {
 type root struct {
 	base string
 	fsi  filesystem.FileSystem
+	caps filesystem.Capability
 }
 
 func (r *root) path(base *fs.Inode, names ...string) string {
@@ -40,12 +42,20 @@ func (r *root) newNode(parent *fs.Inode, fi os.FileInfo) fs.InodeEmbedder {
 
 func (r *root) setAttrFromFileInfo(fi os.FileInfo, out *fuse.Attr) {
 	if fi.IsDir() {
-		out.Mode = syscall.S_IFDIR
+		out.Mode = syscall.S_IFDIR | uint32(fi.Mode().Perm())
+	} else if fi.Mode()&os.ModeSymlink != 0 {
+		out.Mode = syscall.S_IFLNK | 0o777
+		out.Size = uint64(fi.Size())
 	} else {
 		out.Mode = uint32(fi.Mode())
 		out.Size = uint64(fi.Size())
 	}
 	out.Mtime = uint64(fi.ModTime().Unix())
+
+	// owner only known for backends like local
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		out.Owner = fuse.Owner{Uid: st.Uid, Gid: st.Gid}
+	}
 }
 
 func newFileInfo(name string, mode os.FileMode) os.FileInfo {